@@ -16,9 +16,14 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/spf13/cobra"
@@ -85,10 +90,35 @@ const (
 	connKey                     = "conn"
 	stopNodeFlag                = "stop-hosts"
 	// VER-90436: restart -> start
-	startNodeFlag = "restart"
-	startHostFlag = "start-hosts"
+	startNodeFlag      = "restart"
+	startHostFlag      = "start-hosts"
+	dryRunFlag         = "dry-run"
+	eventsJSONFlag     = "events-json"
+	resumeFlag         = "resume"
+	rollbackFlag       = "rollback"
+	nmaPortFlag        = "nma-port"
+	httpsPortFlag      = "https-port"
+	maxParallelFlag    = "max-parallel"
+	requestTimeoutFlag = "request-timeout"
+	opTimeoutFlag      = "op-timeout"
+	deadlineFlag       = "deadline"
+	tlsInsecureFlag    = "tls-insecure"
+	tlsServerNameFlag  = "tls-server-name"
+	tlsPinFlag         = "tls-pin"
+	recordFlag         = "record"
+	replayFlag         = "replay"
+	injectFaultsFlag   = "inject-faults"
+	metricsFileFlag    = "metrics-file"
+	traceFileFlag      = "trace-file"
+	auditLogFlag       = "audit-log"
+	forceUnlockFlag    = "force-unlock"
+	outputFormatFlag   = "output"
 )
 
+// injectFaultsEnv is the environment variable of the faults to inject when
+// the inject-faults flag is not set
+const injectFaultsEnv = "VCLUSTER_INJECT_FAULTS"
+
 // Flag and key for database replication
 const (
 	targetDBNameFlag       = "target-db-name"
@@ -147,9 +177,13 @@ const (
 	stopDBSubCmd            = "stop_db"
 	reviveDBSubCmd          = "revive_db"
 	manageConfigSubCmd      = "manage_config"
+	auditSubCmd             = "audit"
+	auditVerifySubCmd       = "verify"
+	auditShowSubCmd         = "show"
 	createConnectionSubCmd  = "create_connection"
 	configRecoverSubCmd     = "recover"
 	configShowSubCmd        = "show"
+	configDiffSubCmd        = "diff"
 	replicationSubCmd       = "replication"
 	startReplicationSubCmd  = "start"
 	listAllNodesSubCmd      = "list_allnodes"
@@ -169,6 +203,9 @@ const (
 	scrutinizeSubCmd        = "scrutinize"
 	showRestorePointsSubCmd = "show_restore_points"
 	installPkgSubCmd        = "install_packages"
+	statusSubCmd            = "status"
+	doctorSubCmd            = "doctor"
+	applySubCmd             = "apply"
 )
 
 // cmdGlobals holds global variables shared by multiple
@@ -178,6 +215,29 @@ type cmdGlobals struct {
 	file     *os.File
 	keyFile  string
 	certFile string
+	// file the op events are written to, as newline-delimited JSON
+	eventsJSONFile string
+	// file the metrics of the command are written to, in the Prometheus
+	// text format
+	metricsFile string
+	// file the spans of the command are written to, with the stdout exporter of OpenTelemetry
+	traceFile string
+	// file the commands are recorded in, "" for the default one
+	auditLog string
+	// seconds the command has to complete, 0 for no deadline
+	deadline int
+	// timeouts read from the configuration file
+	configTimeouts *TimeoutConfig
+	// ports of the database read from the configuration file
+	configPorts vclusterops.HostPorts
+	// hooks read from the configuration file
+	configHooks []*HookConfig
+	// faults to inject into the requests, as parsed by vclusterops.ParseFaults
+	faults string
+	// format of the result document of the command, "" for the usual output
+	outputFormat string
+	// result document of the command, nil if it has no output format
+	result *commandResult
 
 	// Global variables for targetDB are used for the replication subcommand
 	targetHosts        []string
@@ -221,7 +281,7 @@ var logPath = defaultLogPath
 // for making a basic cobra command
 type cmdInterface interface {
 	Parse(inputArgv []string, logger vlog.Printer) error
-	Run(vcc vclusterops.ClusterCommands) error
+	Run(ctx context.Context, vcc vclusterops.ClusterCommands) error
 	SetDatabaseOptions(opt *vclusterops.DatabaseOptions)
 	SetParser(parser *pflag.FlagSet)
 	setCommonFlags(cmd *cobra.Command, flags []string)
@@ -229,29 +289,88 @@ type cmdInterface interface {
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	// cancel the context on SIGINT or SIGTERM so that the running command
+	// stops between ops and aborts its in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	// a command run with --output reports its error in its result document
+	resultWritten := writeCommandResult(err)
+	closeFile(globals.file)
 	if err != nil {
-		fmt.Printf("Error during execution: %s\n", err)
+		if !resultWritten {
+			fmt.Printf("Error during execution: %s\n", err)
+			var lockedErr *vclusterops.DatabaseLockedError
+			if errors.As(err, &lockedErr) {
+				fmt.Printf("To force the unlock, run the command again with --%s\n", forceUnlockFlag)
+			}
+		}
 		os.Exit(1)
 	}
 }
 
 // initVcc will initialize a vclusterops.VClusterCommands which contains a logger
 func initVcc(cmd *cobra.Command) vclusterops.VClusterCommands {
-	// setup logs
-	logger := vlog.Printer{ForCli: true}
+	// setup logs. The progress spinners are not shown when the result
+	// document of the command is written to stdout instead.
+	logger := vlog.Printer{ForCli: globals.result == nil}
 	logger.SetupOrDie(dbOptions.LogPath)
+	if globals.result != nil {
+		logger.OnWarning = globals.result.addWarning
+	}
 
 	vcc := vclusterops.VClusterCommands{
 		VClusterCommandsLogger: vclusterops.VClusterCommandsLogger{
 			Log: logger.WithName(cmd.CalledAs()),
 		},
+		AuditLogPath: auditLogPath(),
+		LockDir:      lockDir(),
 	}
 	vcc.LogInfo("New VCluster command initialization")
 
 	return vcc
 }
 
+// lockDir returns the directory of the lock files of the databases, the one
+// of the log file, so that the commands run by the users who share it lock
+// each other out
+func lockDir() string {
+	logFile := dbOptions.LogPath
+	if logFile == "" {
+		logFile = defaultLogPath
+	}
+	return filepath.Dir(logFile)
+}
+
+// commandContext returns the context a command runs under, which expires
+// once the deadline of the command, if any, is reached
+func commandContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if globals.deadline <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(globals.deadline)*time.Second)
+}
+
+// applyFaults sets the faults to inject into the requests of the command,
+// from the flag or else from the environment variable. They are for chaos
+// testing only, so the command warns about them.
+func applyFaults(flags *pflag.FlagSet, vcc vclusterops.VClusterCommands) error {
+	spec := globals.faults
+	if !flags.Changed(injectFaultsFlag) {
+		spec = os.Getenv(injectFaultsEnv)
+	}
+	if spec == "" {
+		return nil
+	}
+	faults, err := vclusterops.ParseFaults(spec)
+	if err != nil {
+		return fmt.Errorf("fail to parse the faults to inject: %w", err)
+	}
+	dbOptions.Faults = faults
+	vcc.PrintWarning("injecting %d faults into the requests to the hosts", len(faults))
+	return nil
+}
+
 // setDBOptionsUsingViper can set the value of flag using the relevant key in viper
 func setDBOptionsUsingViper(flag string) error {
 	switch flag {
@@ -324,8 +443,9 @@ func configViper(cmd *cobra.Command, flagsInConfig []string) error {
 	// - manage_config
 	// - manage_config show
 	// - create_connection
-	if cmd.CalledAs() != manageConfigSubCmd &&
-		cmd.CalledAs() != configShowSubCmd && cmd.CalledAs() != createConnectionSubCmd {
+	// - audit verify and audit show
+	if cmd.CalledAs() != manageConfigSubCmd && !isLocalOnlyCmd(cmd) &&
+		cmd.CalledAs() != createConnectionSubCmd {
 		flagsInConfig = append(flagsInConfig, certFileFlag, keyFileFlag)
 	}
 
@@ -378,7 +498,7 @@ func loadConfig(cmd *cobra.Command) (err error) {
 	if cmd.CalledAs() != createDBSubCmd &&
 		cmd.CalledAs() != reviveDBSubCmd &&
 		cmd.CalledAs() != configRecoverSubCmd &&
-		cmd.CalledAs() != configShowSubCmd {
+		!isLocalOnlyCmd(cmd) {
 		err := loadConfigToViper()
 		if err != nil {
 			return err
@@ -405,7 +525,7 @@ func handleViperUserInput(flagsInConfig []string) error {
 	// if the flag is not set in viper, the default value of it will be used
 	for _, flag := range flagsInConfig {
 		if _, ok := flagKeyMap[flag]; !ok {
-			fmt.Printf("Warning: cannot find a relevant viper key for flag %q\n", flag)
+			printCmdWarning("cannot find a relevant viper key for flag %q", flag)
 			continue
 		}
 		if viper.IsSet(flagKeyMap[flag]) {
@@ -446,6 +566,9 @@ func makeBasicCobraCmd(i cmdInterface, use, short, long string, commonFlags []st
 			if globals.verbose {
 				fmt.Println("---{VCluster begin}---")
 			}
+			if err := startCommandResult(cmd.CalledAs()); err != nil {
+				return err
+			}
 			flagsInConfig := filterFlagsInConfig(commonFlags)
 			return configViper(cmd, flagsInConfig)
 		},
@@ -456,8 +579,35 @@ func makeBasicCobraCmd(i cmdInterface, use, short, long string, commonFlags []st
 			if err != nil {
 				return err
 			}
-			defer closeFile(globals.file)
+			// the file is closed once the result document, if any, is written
 			globals.file = f
+			eventsFile, err := openEventsFile(globals.eventsJSONFile)
+			if err != nil {
+				return err
+			}
+			defer closeFile(eventsFile)
+			if eventsFile != nil {
+				dbOptions.Observer = makeJSONEventWriter(eventsFile)
+			}
+			dbOptions.Observer = globals.result.observer(dbOptions.Observer)
+			if globals.metricsFile != "" {
+				dbOptions.Metrics = vclusterops.NewMetrics()
+			}
+			closeTrace, err := setTracerProvider(globals.traceFile, &dbOptions, vcc)
+			if err != nil {
+				return err
+			}
+			defer closeTrace()
+			applyConfigTimeouts(cmd.Flags())
+			applyConfigPorts(cmd.Flags())
+			err = applyConfigHooks()
+			if err != nil {
+				return err
+			}
+			err = applyFaults(cmd.Flags(), vcc)
+			if err != nil {
+				return err
+			}
 			i.SetDatabaseOptions(&dbOptions)
 			// parseError and runError will be printed by the command invoker.
 			// we silence them in cobra for not printing duplicate error messages.
@@ -467,11 +617,17 @@ func makeBasicCobraCmd(i cmdInterface, use, short, long string, commonFlags []st
 				vcc.LogError(parseError, "fail to parse command")
 				return parseError
 			}
-			runError := i.Run(vcc)
+			ctx, cancel := commandContext(cmd.Context())
+			defer cancel()
+			runError := i.Run(ctx, vcc)
 			if runError != nil {
 				cmd.SilenceUsage = true // don't show usage when vcluster fails and operation has started
 				vcc.LogError(runError, "fail to run command")
 			}
+			// the metrics of a failed run are written too
+			if err := writeMetricsFile(globals.metricsFile, dbOptions.Metrics); err != nil {
+				vcc.PrintWarning("%v", err)
+			}
 
 			return runError
 		},
@@ -487,6 +643,17 @@ func makeBasicCobraCmd(i cmdInterface, use, short, long string, commonFlags []st
 	return cmd
 }
 
+// localOnlyAnnotation marks the subcommands that only read local files. They
+// have no flags to reach the cluster and do not load the config file. The
+// subcommands are told apart this way rather than by name, as the names of
+// subcommands, such as show, are not unique.
+const localOnlyAnnotation = "localOnly"
+
+func isLocalOnlyCmd(cmd *cobra.Command) bool {
+	_, ok := cmd.Annotations[localOnlyAnnotation]
+	return ok
+}
+
 // makeSimpleCobraCmd can make a simple cobra command for some vcluster commands
 // such as replication and manage_config
 func makeSimpleCobraCmd(use, short, long string) *cobra.Command {
@@ -512,6 +679,9 @@ func constructCmds() []*cobra.Command {
 		makeCmdReIP(),
 		makeCmdShowRestorePoints(),
 		makeCmdInstallPackages(),
+		makeCmdStatus(),
+		makeCmdDoctor(),
+		makeCmdApply(),
 		// sc-scope cmds
 		makeCmdAddSubcluster(),
 		makeCmdRemoveSubcluster(),
@@ -527,6 +697,7 @@ func constructCmds() []*cobra.Command {
 		// others
 		makeCmdScrutinize(),
 		makeCmdManageConfig(),
+		makeCmdAudit(),
 		makeCmdReplication(),
 		makeCmdCreateConnection(),
 	}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -112,3 +113,21 @@ func TestSetLogPathImpl(t *testing.T) {
 	expectedLogPath = defaultHomeConfigDirLogPath
 	assert.Equal(t, expectedLogPath, logPath)
 }
+
+func TestLocalOnlyCmds(t *testing.T) {
+	// the flags are bound to the global options, which they reset
+	savedOptions, savedGlobals := dbOptions, globals
+	defer func() { dbOptions, globals = savedOptions, savedGlobals }()
+
+	// the subcommands are told apart whatever their names, as audit show and
+	// manage_config show have the same one
+	for _, cmd := range []*cobra.Command{makeCmdAuditShow(), makeCmdAuditVerify(), makeCmdConfigShow()} {
+		assert.True(t, isLocalOnlyCmd(cmd), cmd.Name())
+		assert.Nil(t, cmd.Flags().Lookup(keyFileFlag), cmd.Name())
+		assert.Nil(t, cmd.Flags().Lookup(dryRunFlag), cmd.Name())
+	}
+	cmd := makeCmdStopDB()
+	assert.False(t, isLocalOnlyCmd(cmd))
+	assert.NotNil(t, cmd.Flags().Lookup(keyFileFlag))
+	assert.NotNil(t, cmd.Flags().Lookup(dryRunFlag))
+}
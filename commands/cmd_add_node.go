@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -72,7 +73,7 @@ Examples:
     --node-names v_test_db_node0001,v_test_db_node0002
 `,
 		[]string{dbNameFlag, configFlag, hostsFlag, ipv6Flag, dataPathFlag, depotPathFlag,
-			passwordFlag},
+			passwordFlag, resumeFlag},
 	)
 
 	// local flags
@@ -123,6 +124,12 @@ func (c *CmdAddNode) setLocalFlags(cmd *cobra.Command) {
 		"",
 		"Comma-separated list of node names that exist in the cluster",
 	)
+	cmd.Flags().BoolVar(
+		&c.addNodeOptions.AutoRollback,
+		"auto-rollback",
+		false,
+		"If the command fails after creating the new nodes, stop and drop them, and delete their directories",
+	)
 }
 
 func (c *CmdAddNode) Parse(inputArgv []string, logger vlog.Printer) error {
@@ -188,16 +195,27 @@ func (c *CmdAddNode) parseNodeNameList() error {
 	return nil
 }
 
-func (c *CmdAddNode) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdAddNode) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
 	options := c.addNodeOptions
+	if c.rollbackJournal != "" {
+		return c.rollbackFromJournal(ctx, vcc, &options.DatabaseOptions)
+	}
 
-	vdb, addNodeError := vcc.VAddNode(options)
+	ctx, journalPath := c.journalContext(ctx, addNodeSubCmd, &options.DatabaseOptions)
+	vdb, addNodeError := vcc.VAddNode(ctx, options)
+	c.finishJournal(vcc, journalPath, addNodeError)
 	if addNodeError != nil {
 		return addNodeError
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	globals.result.addDatabaseNodes(&vdb)
 	// write db info to vcluster config file
 	err := writeConfig(&vdb)
 	if err != nil {
@@ -16,7 +16,7 @@
 package commands
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -77,7 +77,7 @@ Examples:
 	--hosts 10.20.30.40,10.20.30.41,10.20.30.42 \
 	--is-primary --control-set-size -1 --new-hosts 10.20.30.43
 `,
-		[]string{dbNameFlag, configFlag, hostsFlag, ipv6Flag, eonModeFlag, passwordFlag,
+		[]string{dbNameFlag, configFlag, hostsFlag, ipv6Flag, eonModeFlag, passwordFlag, resumeFlag,
 			dataPathFlag, depotPathFlag},
 	)
 
@@ -141,6 +141,13 @@ func (c *CmdAddSubcluster) setLocalFlags(cmd *cobra.Command) {
 		"",
 		util.GetEonFlagMsg("Size of depot"),
 	)
+	cmd.Flags().BoolVar(
+		&c.addSubclusterOptions.AutoRollback,
+		"auto-rollback",
+		false,
+		"If the command fails after creating the subcluster, drop the subcluster and the nodes added to it,"+
+			" and delete the directories of the nodes",
+	)
 }
 
 // setHiddenFlags will set the hidden flags the command has.
@@ -196,42 +203,74 @@ func (c *CmdAddSubcluster) Analyze(logger vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdAddSubcluster) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdAddSubcluster) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
+	options := c.addSubclusterOptions
+	if c.rollbackJournal != "" {
+		return c.rollbackFromJournal(ctx, vcc, &options.DatabaseOptions)
+	}
+
+	// adding the subcluster and its hosts share one journal, so a failure
+	// while adding the hosts can be resumed or rolled back as a whole
+	ctx, journalPath := c.journalContext(ctx, addSCSubCmd, &options.DatabaseOptions)
+	err := c.addSubcluster(ctx, vcc)
+	c.finishJournal(vcc, journalPath, err)
+	if err != nil {
+		return err
+	}
+
+	if options.Plan {
+		return nil
+	}
+	if len(options.NewHosts) > 0 {
+		vcc.PrintInfo("Added subcluster %s with nodes %v to database %s",
+			options.SCName, options.NewHosts, options.DBName)
+	} else {
+		vcc.PrintInfo("Added subcluster %s to database %s", options.SCName, options.DBName)
+	}
+	return nil
+}
+
+// addSubcluster adds the subcluster, then the new hosts to it
+func (c *CmdAddSubcluster) addSubcluster(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	options := c.addSubclusterOptions
 
-	err := vcc.VAddSubcluster(options)
+	err := vcc.VAddSubcluster(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "failed to add subcluster")
 		return err
 	}
 
+	// the new hosts can only be planned once the subcluster exists, so a
+	// dry run stops after the subcluster is planned
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		if len(options.NewHosts) > 0 {
+			vcc.PrintInfo("Hosts %v would then be added to subcluster %s", options.NewHosts, options.SCName)
+		}
+		return nil
+	}
+
 	if len(options.NewHosts) > 0 {
-		fmt.Printf("Adding hosts %v to subcluster %s\n",
+		printCmdMessage("Adding hosts %v to subcluster %s\n",
 			options.NewHosts, options.SCName)
 
 		options.VAddNodeOptions.DatabaseOptions = c.addSubclusterOptions.DatabaseOptions
 		options.VAddNodeOptions.SCName = c.addSubclusterOptions.SCName
 
-		vdb, err := vcc.VAddNode(&options.VAddNodeOptions)
+		vdb, err := vcc.VAddNode(ctx, &options.VAddNodeOptions)
 		if err != nil {
 			vcc.LogError(err, "failed to add nodes into the new subcluster")
 			return err
 		}
+		globals.result.addDatabaseNodes(&vdb)
 		// update db info in the config file
 		err = writeConfig(&vdb)
 		if err != nil {
 			vcc.PrintWarning("fail to write config file, details: %s", err)
 		}
 	}
-
-	if len(options.NewHosts) > 0 {
-		vcc.PrintInfo("Added subcluster %s with nodes %v to database %s",
-			options.SCName, options.NewHosts, options.DBName)
-	} else {
-		vcc.PrintInfo("Added subcluster %s to database %s", options.SCName, options.DBName)
-	}
 	return nil
 }
 
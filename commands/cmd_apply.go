@@ -0,0 +1,576 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/util"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+/* CmdApply
+ *
+ * Implements ClusterCommand interface
+ */
+type CmdApply struct {
+	applyOptions vclusterops.DatabaseOptions
+	// path of the file that describes the desired topology
+	desiredPath string
+	desired     *DatabaseConfig
+	// whether the nodes and subclusters that are not in the file are removed
+	prune bool
+
+	CmdBase
+}
+
+// applyStep is one change that brings the database closer to the desired
+// topology
+type applyStep struct {
+	// Action is the subcommand that makes the change, such as db_add_node
+	Action     string `json:"action"`
+	Subcluster string `json:"subcluster,omitempty"`
+	// Sandbox is the sandbox the subcluster goes in when sandboxing it, and
+	// the one it leaves when unsandboxing it
+	Sandbox string   `json:"sandbox,omitempty"`
+	Hosts   []string `json:"hosts,omitempty"`
+	// paths holds the catalog, data and depot paths of the nodes to add
+	paths *NodeConfig
+}
+
+func makeCmdApply() *cobra.Command {
+	newCmd := &CmdApply{}
+	newCmd.applyOptions = vclusterops.DatabaseOptionsFactory()
+
+	cmd := makeBasicCobraCmd(
+		newCmd,
+		applySubCmd,
+		"Change the database to match a config file",
+		`This subcommand compares the nodes, subclusters and sandboxes described in a
+config file, in the format of vertica_cluster.yaml, with the ones of the
+database, shows the steps that make the database match the file, and then
+runs them in this order:
+
+  1. unsandbox the subclusters that must leave their sandbox, and, with
+     --prune, the sandboxed subclusters that are not in the file
+  2. add the subclusters that are missing, as secondary subclusters
+  3. add the nodes that are missing
+  4. sandbox the subclusters that must be in a sandbox
+  5. with --prune, remove the nodes that are not in the file
+  6. with --prune, remove the subclusters that are not in the file, and
+     delete their directories
+
+Without --prune, nothing is removed, and the nodes and subclusters that are
+not in the file are only listed. A node cannot move to another subcluster, it
+must be removed first. The config file is updated after each step. When the
+database already matches the file, nothing is done, so applying the same file
+again after a step failed resumes from that step.
+
+Use --dry-run to only show the steps.
+
+Examples:
+  # Show the steps that make the database match desired.yaml
+  vcluster apply -f desired.yaml --dry-run \
+    --config /opt/vertica/config/vertica_cluster.yaml
+
+  # Make the database match desired.yaml
+  vcluster apply -f desired.yaml --password testpassword \
+    --config /opt/vertica/config/vertica_cluster.yaml
+
+  # Make the database match desired.yaml, removing the nodes and subclusters
+  # that are not in it
+  vcluster apply -f desired.yaml --prune --password testpassword \
+    --config /opt/vertica/config/vertica_cluster.yaml
+`,
+		[]string{dbNameFlag, hostsFlag, passwordFlag, ipv6Flag, configFlag, outputFileFlag},
+	)
+
+	// local flags
+	newCmd.setLocalFlags(cmd)
+
+	// require the desired config file
+	markFlagsRequired(cmd, []string{"file"})
+	markFlagsFileName(cmd, map[string][]string{"file": {"yaml"}})
+
+	return cmd
+}
+
+// setLocalFlags will set the local flags the command has
+func (c *CmdApply) setLocalFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(
+		&c.desiredPath,
+		"file",
+		"f",
+		"",
+		"Path to the config file that describes the desired nodes, subclusters and sandboxes",
+	)
+	cmd.Flags().BoolVar(
+		&c.prune,
+		"prune",
+		false,
+		"Remove the nodes and subclusters that are not in the config file, and delete their directories",
+	)
+}
+
+func (c *CmdApply) Parse(inputArgv []string, logger vlog.Printer) error {
+	c.argv = inputArgv
+	logger.LogArgParse(&c.argv)
+
+	// for some options, we do not want to use their default values,
+	// if they are not provided in cli,
+	// reset the value of those options to nil
+	c.ResetUserInputOptions(&c.applyOptions)
+	return c.validateParse(logger)
+}
+
+func (c *CmdApply) validateParse(logger vlog.Printer) error {
+	logger.Info("Called validateParse()", "command", applySubCmd)
+	desired, err := readConfigFile(c.desiredPath)
+	if err != nil {
+		return err
+	}
+	if len(desired.Nodes) == 0 {
+		return fmt.Errorf("config file %q has no nodes", c.desiredPath)
+	}
+	options := &c.applyOptions
+	if options.DBName == "" {
+		options.DBName = desired.Name
+	} else if options.DBName != desired.Name {
+		return fmt.Errorf("database %q does not match name %q found in config file %q", options.DBName, desired.Name, c.desiredPath)
+	}
+	if !c.parser.Changed(ipv6Flag) {
+		options.IPv6 = desired.Ipv6
+	}
+	options.IsEon = desired.IsEon
+	// without the hosts of the database, the ones of the desired nodes that
+	// are in the database answer
+	if len(options.RawHosts) == 0 {
+		options.RawHosts = desired.getHosts()
+	}
+	if options.CatalogPrefix == "" {
+		options.CatalogPrefix, options.DataPrefix, options.DepotPrefix = desired.getPathPrefixes()
+	}
+	for _, node := range desired.Nodes {
+		addresses, resolveErr := util.ResolveRawHostsToAddresses([]string{node.Address}, options.IPv6)
+		if resolveErr != nil {
+			return resolveErr
+		}
+		node.Address = addresses[0]
+	}
+	c.desired = desired
+
+	err = c.getCertFilesFromCertPaths(options)
+	if err != nil {
+		return err
+	}
+
+	err = c.ValidateParseBaseOptions(options)
+	if err != nil {
+		return err
+	}
+	return c.setDBPassword(options)
+}
+
+func (c *CmdApply) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
+	vcc.V(1).Info("Called method Run()")
+
+	statusOptions := vclusterops.VClusterStatusOptionsFactory()
+	statusOptions.DatabaseOptions = c.applyOptions
+	statusOptions.Plan = false
+	live, err := vcc.VClusterStatus(ctx, &statusOptions)
+	if err != nil {
+		vcc.PrintError("fail to get the topology of the database: %s", err)
+		return err
+	}
+	steps, pruneSteps, err := planApply(c.desired, live, c.prune)
+	if err != nil {
+		return err
+	}
+
+	plan := renderApplySteps(c.desiredPath, steps, pruneSteps)
+	if globals.result != nil {
+		globals.result.setOutputValue(steps)
+		globals.result.setOutputTable(plan)
+	} else {
+		c.writeCmdOutputToFile(globals.file, plan, vcc.GetLog())
+	}
+	if c.applyOptions.Plan || len(steps) == 0 {
+		return nil
+	}
+
+	// the sandbox of each subcluster, as the steps change it
+	sandboxes := make(map[string]string, len(live.Subclusters))
+	for i := range live.Subclusters {
+		sandboxes[live.Subclusters[i].Name] = live.Subclusters[i].Sandbox
+	}
+	var vdb *vclusterops.VCoordinationDatabase
+	for i := range steps {
+		step := &steps[i]
+		vcc.PrintInfo("Step %d of %d: %s", i+1, len(steps), step.describe())
+		stepVDB, stepErr := c.runStep(ctx, vcc, step)
+		if stepErr != nil {
+			vcc.PrintError("fail to %s, the steps before it were applied and applying %s again resumes from there: %s",
+				step.describe(), c.desiredPath, stepErr)
+			return stepErr
+		}
+		switch step.Action {
+		case sandboxSubCmd:
+			sandboxes[step.Subcluster] = step.Sandbox
+		case unsandboxSubCmd:
+			sandboxes[step.Subcluster] = ""
+		}
+		// the config file follows the database, so that it is right even
+		// if a later step fails
+		if stepVDB != nil {
+			vdb = stepVDB
+		}
+		c.updateConfig(vcc, vdb, sandboxes)
+	}
+	vcc.PrintInfo("Successfully applied %s to database %s", c.desiredPath, c.applyOptions.DBName)
+	return nil
+}
+
+// runStep makes the change of step, and returns the database when the call
+// that made it returns one
+func (c *CmdApply) runStep(ctx context.Context, vcc vclusterops.ClusterCommands,
+	step *applyStep) (*vclusterops.VCoordinationDatabase, error) {
+	switch step.Action {
+	case addSCSubCmd:
+		options := vclusterops.VAddSubclusterOptionsFactory()
+		options.DatabaseOptions = c.applyOptions
+		options.SCName = step.Subcluster
+		return nil, vcc.VAddSubcluster(ctx, &options)
+	case addNodeSubCmd:
+		options := vclusterops.VAddNodeOptionsFactory()
+		options.DatabaseOptions = c.applyOptions
+		options.NewHosts = step.Hosts
+		options.SCName = step.Subcluster
+		options.CatalogPrefix = step.paths.CatalogPath
+		options.DataPrefix = step.paths.DataPath
+		options.DepotPrefix = step.paths.DepotPath
+		vdb, err := vcc.VAddNode(ctx, &options)
+		return &vdb, err
+	case unsandboxSubCmd:
+		options := vclusterops.VUnsandboxOptionsFactory()
+		options.DatabaseOptions = c.applyOptions
+		options.SCName = step.Subcluster
+		return nil, vcc.VUnsandbox(ctx, &options)
+	case sandboxSubCmd:
+		options := vclusterops.VSandboxOptionsFactory()
+		options.DatabaseOptions = c.applyOptions
+		options.SCName = step.Subcluster
+		options.SandboxName = step.Sandbox
+		return nil, vcc.VSandbox(ctx, &options)
+	case removeNodeSubCmd:
+		options := vclusterops.VRemoveNodeOptionsFactory()
+		options.DatabaseOptions = c.applyOptions
+		options.HostsToRemove = step.Hosts
+		vdb, err := vcc.VRemoveNode(ctx, &options)
+		return &vdb, err
+	case removeSCSubCmd:
+		options := vclusterops.VRemoveScOptionsFactory()
+		options.DatabaseOptions = c.applyOptions
+		options.SubclusterToRemove = step.Subcluster
+		// only planned with --prune, which deletes the directories
+		options.ForceDelete = true
+		vdb, err := vcc.VRemoveSubcluster(ctx, &options)
+		return &vdb, err
+	}
+	return nil, fmt.Errorf("unknown step %q", step.Action)
+}
+
+// updateConfig writes the database to the config file after a step. The
+// sandboxes are the ones the steps left, as vdb may predate the last sandbox
+// steps, and the ports are taken from the desired topology, as they are not in
+// the catalog.
+func (c *CmdApply) updateConfig(vcc vclusterops.ClusterCommands, vdb *vclusterops.VCoordinationDatabase,
+	sandboxes map[string]string) {
+	var dbConfig *DatabaseConfig
+	var err error
+	if vdb != nil {
+		var vdbConfig DatabaseConfig
+		vdbConfig, err = readVDBToDBConfig(vdb)
+		dbConfig = &vdbConfig
+	} else {
+		dbConfig, err = readConfig()
+	}
+	if err != nil {
+		vcc.PrintWarning("fail to read the database info, skipping config file update, details: %s", err)
+		return
+	}
+
+	dbConfig.NMAPort = c.desired.NMAPort
+	dbConfig.HTTPSPort = c.desired.HTTPSPort
+	desiredNodes := make(map[string]*NodeConfig, len(c.desired.Nodes))
+	for _, node := range c.desired.Nodes {
+		desiredNodes[node.Address] = node
+	}
+	for _, node := range dbConfig.Nodes {
+		node.Sandbox = sandboxes[node.Subcluster]
+		if desiredNode, ok := desiredNodes[node.Address]; ok {
+			node.NMAPort = desiredNode.NMAPort
+			node.HTTPSPort = desiredNode.HTTPSPort
+		}
+	}
+	err = dbConfig.writeAtomically(dbOptions.ConfigPath)
+	if err != nil {
+		vcc.PrintWarning("fail to write config file, details: %s", err)
+	}
+}
+
+// SetDatabaseOptions will assign a vclusterops.DatabaseOptions instance to the one in CmdApply
+func (c *CmdApply) SetDatabaseOptions(opt *vclusterops.DatabaseOptions) {
+	c.applyOptions = *opt
+}
+
+// describe returns what the step does, as a sentence
+func (step *applyStep) describe() string {
+	hosts := strings.Join(step.Hosts, ", ")
+	switch step.Action {
+	case addSCSubCmd:
+		return fmt.Sprintf("add subcluster %s", step.Subcluster)
+	case addNodeSubCmd:
+		if step.Subcluster == "" {
+			return fmt.Sprintf("add nodes %s", hosts)
+		}
+		return fmt.Sprintf("add nodes %s to subcluster %s", hosts, step.Subcluster)
+	case unsandboxSubCmd:
+		return fmt.Sprintf("unsandbox subcluster %s from sandbox %s", step.Subcluster, step.Sandbox)
+	case sandboxSubCmd:
+		return fmt.Sprintf("sandbox subcluster %s in sandbox %s", step.Subcluster, step.Sandbox)
+	case removeNodeSubCmd:
+		if step.Subcluster == "" {
+			return fmt.Sprintf("remove nodes %s", hosts)
+		}
+		return fmt.Sprintf("remove nodes %s from subcluster %s", hosts, step.Subcluster)
+	case removeSCSubCmd:
+		return fmt.Sprintf("remove subcluster %s", step.Subcluster)
+	}
+	return step.Action
+}
+
+// renderApplySteps renders the steps as a numbered list, followed by the
+// steps that only run with --prune
+func renderApplySteps(desiredPath string, steps, pruneSteps []applyStep) []byte {
+	var buf bytes.Buffer
+	if len(steps) == 0 && len(pruneSteps) == 0 {
+		fmt.Fprintf(&buf, "The database already matches %s, there is nothing to apply\n", desiredPath)
+		return buf.Bytes()
+	}
+	if len(steps) == 0 {
+		fmt.Fprintf(&buf, "There is nothing to apply from %s without --prune\n", desiredPath)
+	} else {
+		fmt.Fprintf(&buf, "Steps to apply %s:\n", desiredPath)
+	}
+	for i := range steps {
+		fmt.Fprintf(&buf, "%d. %s\n", i+1, steps[i].describe())
+	}
+	if len(pruneSteps) > 0 {
+		buf.WriteString("Steps skipped without --prune:\n")
+		for i := range pruneSteps {
+			fmt.Fprintf(&buf, "- %s\n", pruneSteps[i].describe())
+		}
+	}
+	return buf.Bytes()
+}
+
+// applyPlanner computes the steps that change the live topology of a
+// database to the desired one
+type applyPlanner struct {
+	isEon bool
+	// the desired subclusters, in the order of the config file, with their
+	// sandbox and their nodes
+	names          []string
+	desiredSandbox map[string]string
+	desiredNodes   map[string][]*NodeConfig
+	desiredHosts   map[string]bool
+	live           *vclusterops.ClusterStatus
+	liveSandbox    map[string]string
+	// the subcluster of each live node, by address
+	liveHosts map[string]string
+	// the live subclusters that are not desired
+	removed []string
+	prune   bool
+	steps   []applyStep
+	// the steps that remove nodes and subclusters, skipped without prune
+	pruneSteps []applyStep
+}
+
+// planApply returns the steps that change the live topology to the desired
+// one, in the order they must run. It returns no steps when they match. The
+// steps that remove nodes and subclusters are only planned with prune, and are
+// otherwise returned apart.
+func planApply(desired *DatabaseConfig, live *vclusterops.ClusterStatus, prune bool) (steps, pruneSteps []applyStep, err error) {
+	planner := applyPlanner{
+		isEon:          desired.IsEon,
+		desiredSandbox: make(map[string]string),
+		desiredNodes:   make(map[string][]*NodeConfig),
+		desiredHosts:   make(map[string]bool),
+		live:           live,
+		liveSandbox:    make(map[string]string),
+		liveHosts:      make(map[string]string),
+		prune:          prune,
+		steps:          []applyStep{},
+	}
+	err = planner.collect(desired)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the subclusters leave their sandbox first, so that the nodes added and
+	// removed afterwards are in the main cluster
+	planner.planUnsandboxes()
+	planner.planAdditions()
+	planner.planSandboxes()
+	planner.planRemovals()
+	return planner.steps, planner.pruneSteps, nil
+}
+
+// addPruneStep plans a step that removes nodes or a subcluster, or sets it
+// apart without prune
+func (planner *applyPlanner) addPruneStep(step applyStep) {
+	if planner.prune {
+		planner.steps = append(planner.steps, step)
+	} else {
+		planner.pruneSteps = append(planner.pruneSteps, step)
+	}
+}
+
+// subclusterName returns the name the subcluster is planned under. An
+// Enterprise database has a single subcluster, whatever its name.
+func (planner *applyPlanner) subclusterName(name string) string {
+	if !planner.isEon {
+		return ""
+	}
+	return name
+}
+
+// collect indexes the desired and the live topologies, and checks that no
+// node has to move to another subcluster
+func (planner *applyPlanner) collect(desired *DatabaseConfig) error {
+	for _, node := range desired.Nodes {
+		name := planner.subclusterName(node.Subcluster)
+		sandbox, ok := planner.desiredSandbox[name]
+		if !ok {
+			planner.names = append(planner.names, name)
+			planner.desiredSandbox[name] = node.Sandbox
+		} else if sandbox != node.Sandbox {
+			return fmt.Errorf("the nodes of subcluster %s are in different sandboxes in the config file", name)
+		}
+		planner.desiredNodes[name] = append(planner.desiredNodes[name], node)
+		planner.desiredHosts[node.Address] = true
+	}
+	for i := range planner.live.Subclusters {
+		subcluster := &planner.live.Subclusters[i]
+		name := planner.subclusterName(subcluster.Name)
+		planner.liveSandbox[name] = subcluster.Sandbox
+		if _, ok := planner.desiredSandbox[name]; !ok {
+			planner.removed = append(planner.removed, name)
+		}
+		for j := range subcluster.Nodes {
+			planner.liveHosts[subcluster.Nodes[j].Address] = name
+		}
+	}
+	for _, node := range desired.Nodes {
+		name := planner.subclusterName(node.Subcluster)
+		if liveName, ok := planner.liveHosts[node.Address]; ok && liveName != name {
+			return fmt.Errorf("node %s is in subcluster %s, but in subcluster %s in the config file, "+
+				"a node cannot move to another subcluster", node.Address, liveName, name)
+		}
+	}
+	return nil
+}
+
+// planAdditions plans the missing subclusters, then the missing nodes
+func (planner *applyPlanner) planAdditions() {
+	for _, name := range planner.names {
+		if _, ok := planner.liveSandbox[name]; !ok {
+			planner.steps = append(planner.steps, applyStep{Action: addSCSubCmd, Subcluster: name})
+		}
+	}
+	for _, name := range planner.names {
+		var newNodes []*NodeConfig
+		for _, node := range planner.desiredNodes[name] {
+			if _, ok := planner.liveHosts[node.Address]; !ok {
+				newNodes = append(newNodes, node)
+			}
+		}
+		if len(newNodes) == 0 {
+			continue
+		}
+		step := applyStep{Action: addNodeSubCmd, Subcluster: name, paths: newNodes[0]}
+		for _, node := range newNodes {
+			step.Hosts = append(step.Hosts, node.Address)
+		}
+		planner.steps = append(planner.steps, step)
+	}
+}
+
+// planUnsandboxes plans the subclusters that leave their sandbox: the ones
+// that must be in another sandbox or in none, and the removed ones, as a
+// sandboxed subcluster cannot be removed
+func (planner *applyPlanner) planUnsandboxes() {
+	for _, name := range planner.names {
+		liveSandbox, ok := planner.liveSandbox[name]
+		if ok && liveSandbox != "" && liveSandbox != planner.desiredSandbox[name] {
+			planner.steps = append(planner.steps, applyStep{Action: unsandboxSubCmd, Subcluster: name, Sandbox: liveSandbox})
+		}
+	}
+	for _, name := range planner.removed {
+		if liveSandbox := planner.liveSandbox[name]; liveSandbox != "" {
+			planner.addPruneStep(applyStep{Action: unsandboxSubCmd, Subcluster: name, Sandbox: liveSandbox})
+		}
+	}
+}
+
+// planSandboxes plans the subclusters that go in a sandbox, once they left
+// the one they were in
+func (planner *applyPlanner) planSandboxes() {
+	for _, name := range planner.names {
+		sandbox := planner.desiredSandbox[name]
+		if sandbox != "" && planner.liveSandbox[name] != sandbox {
+			planner.steps = append(planner.steps, applyStep{Action: sandboxSubCmd, Subcluster: name, Sandbox: sandbox})
+		}
+	}
+}
+
+// planRemovals plans the nodes that are not desired, then the subclusters
+// that are not desired
+func (planner *applyPlanner) planRemovals() {
+	for i := range planner.live.Subclusters {
+		subcluster := &planner.live.Subclusters[i]
+		name := planner.subclusterName(subcluster.Name)
+		if _, ok := planner.desiredSandbox[name]; !ok {
+			continue
+		}
+		step := applyStep{Action: removeNodeSubCmd, Subcluster: name}
+		for j := range subcluster.Nodes {
+			if address := subcluster.Nodes[j].Address; !planner.desiredHosts[address] {
+				step.Hosts = append(step.Hosts, address)
+			}
+		}
+		if len(step.Hosts) > 0 {
+			planner.addPruneStep(step)
+		}
+	}
+	for _, name := range planner.removed {
+		planner.addPruneStep(applyStep{Action: removeSCSubCmd, Subcluster: name})
+	}
+}
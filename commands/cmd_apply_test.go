@@ -0,0 +1,127 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+func TestPlanApply(t *testing.T) {
+	live := &vclusterops.ClusterStatus{Database: "test_db", Subclusters: []vclusterops.SubclusterStatus{
+		{Name: "sc1", IsPrimary: true, Nodes: []vclusterops.NodeStatus{
+			{Name: "v_test_db_node0001", Address: "192.168.1.101"},
+			{Name: "v_test_db_node0002", Address: "192.168.1.102"},
+			{Name: "v_test_db_node0003", Address: "192.168.1.103"},
+		}},
+		{Name: "sc2", Nodes: []vclusterops.NodeStatus{{Name: "v_test_db_node0004", Address: "192.168.1.104"}}},
+		{Name: "sc3", Nodes: []vclusterops.NodeStatus{{Name: "v_test_db_node0005", Address: "192.168.1.105"}}},
+		{Name: "sc4", Sandbox: "sand1", Nodes: []vclusterops.NodeStatus{{Name: "v_test_db_node0006", Address: "192.168.1.106"}}},
+		{Name: "sc6", Sandbox: "sand1", Nodes: []vclusterops.NodeStatus{{Name: "v_test_db_node0009", Address: "192.168.1.109"}}},
+	}}
+	node := func(address, subcluster, sandbox string) *NodeConfig {
+		return &NodeConfig{Address: address, Subcluster: subcluster, Sandbox: sandbox, CatalogPath: "/catalog", DataPath: "/data"}
+	}
+	// node0003 goes, sc2 is sandboxed, sc3 and the sandboxed sc6 go, sc4
+	// leaves its sandbox, sc5 is created with two nodes in a sandbox
+	desired := &DatabaseConfig{Name: "test_db", IsEon: true, Nodes: []*NodeConfig{
+		node("192.168.1.101", "sc1", ""),
+		node("192.168.1.102", "sc1", ""),
+		node("192.168.1.104", "sc2", "sand2"),
+		node("192.168.1.106", "sc4", ""),
+		node("192.168.1.107", "sc5", "sand3"),
+		node("192.168.1.108", "sc5", "sand3"),
+	}}
+	steps, pruneSteps, err := planApply(desired, live, true)
+	assert.NoError(t, err)
+	assert.Empty(t, pruneSteps)
+	assert.Equal(t, []string{
+		"unsandbox subcluster sc4 from sandbox sand1",
+		"unsandbox subcluster sc6 from sandbox sand1",
+		"add subcluster sc5",
+		"add nodes 192.168.1.107, 192.168.1.108 to subcluster sc5",
+		"sandbox subcluster sc2 in sandbox sand2",
+		"sandbox subcluster sc5 in sandbox sand3",
+		"remove nodes 192.168.1.103 from subcluster sc1",
+		"remove subcluster sc3",
+		"remove subcluster sc6",
+	}, describeSteps(steps))
+	assert.Equal(t, "/catalog", steps[3].paths.CatalogPath)
+
+	// without prune, nothing is removed, and the removals are listed apart
+	steps, pruneSteps, err = planApply(desired, live, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"unsandbox subcluster sc4 from sandbox sand1",
+		"add subcluster sc5",
+		"add nodes 192.168.1.107, 192.168.1.108 to subcluster sc5",
+		"sandbox subcluster sc2 in sandbox sand2",
+		"sandbox subcluster sc5 in sandbox sand3",
+	}, describeSteps(steps))
+	assert.Equal(t, []string{
+		"unsandbox subcluster sc6 from sandbox sand1",
+		"remove nodes 192.168.1.103 from subcluster sc1",
+		"remove subcluster sc3",
+		"remove subcluster sc6",
+	}, describeSteps(pruneSteps))
+	assert.Contains(t, string(renderApplySteps("desired.yaml", steps, pruneSteps)),
+		"Steps skipped without --prune:\n- unsandbox subcluster sc6 from sandbox sand1\n")
+
+	// once applied, the topologies match and there is nothing to do
+	applied := &vclusterops.ClusterStatus{Database: "test_db", Subclusters: []vclusterops.SubclusterStatus{
+		{Name: "sc1", IsPrimary: true, Nodes: []vclusterops.NodeStatus{{Address: "192.168.1.101"}, {Address: "192.168.1.102"}}},
+		{Name: "sc4", Nodes: []vclusterops.NodeStatus{{Address: "192.168.1.106"}}},
+		{Name: "sc2", Sandbox: "sand2", Nodes: []vclusterops.NodeStatus{{Address: "192.168.1.104"}}},
+		{Name: "sc5", Sandbox: "sand3", Nodes: []vclusterops.NodeStatus{{Address: "192.168.1.107"}, {Address: "192.168.1.108"}}},
+	}}
+	steps, pruneSteps, err = planApply(desired, applied, true)
+	assert.NoError(t, err)
+	assert.Empty(t, steps)
+	assert.Empty(t, pruneSteps)
+	assert.Contains(t, string(renderApplySteps("desired.yaml", steps, pruneSteps)), "already matches desired.yaml")
+
+	// a node cannot move to another subcluster
+	desired.Nodes[3].Subcluster = "sc2"
+	desired.Nodes[3].Sandbox = "sand2"
+	_, _, err = planApply(desired, applied, true)
+	assert.ErrorContains(t, err, "node 192.168.1.106 is in subcluster sc4, but in subcluster sc2 in the config file")
+
+	// the nodes of a subcluster are in the same sandbox
+	desired.Nodes[3].Subcluster = "sc4"
+	desired.Nodes[5].Sandbox = ""
+	_, _, err = planApply(desired, applied, true)
+	assert.ErrorContains(t, err, "the nodes of subcluster sc5 are in different sandboxes")
+
+	// an Enterprise database has a single subcluster, whatever its name
+	enterprise := &DatabaseConfig{Name: "test_db", Nodes: []*NodeConfig{
+		node("192.168.1.101", "", ""), node("192.168.1.109", "", ""),
+	}}
+	steps, _, err = planApply(enterprise, &vclusterops.ClusterStatus{Subclusters: []vclusterops.SubclusterStatus{
+		{Name: "default_subcluster", Nodes: []vclusterops.NodeStatus{{Address: "192.168.1.101"}, {Address: "192.168.1.102"}}},
+	}}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"add nodes 192.168.1.109", "remove nodes 192.168.1.102"}, describeSteps(steps))
+}
+
+func describeSteps(steps []applyStep) []string {
+	var actions []string
+	for i := range steps {
+		actions = append(actions, steps[i].describe())
+	}
+	return actions
+}
@@ -0,0 +1,65 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// auditLogFileName is the name of the audit log in the directory of the log
+// file, when no audit log is given
+const auditLogFileName = "vcluster_audit.log"
+
+/* CmdAudit
+ *
+ * A subcommand reading the audit log,
+ * which records the commands that were run.
+ */
+
+func makeCmdAudit() *cobra.Command {
+	cmd := makeSimpleCobraCmd(
+		auditSubCmd,
+		"Verify or show the audit log",
+		`This subcommand verifies or shows the audit log.
+
+Each command that runs against a database is recorded in the audit log, with
+the OS user who ran it, the database and the hosts, the options without the
+secrets, and when and how it ended. A command is recorded a first time, as
+started, before it changes anything, so that a command that was killed still
+leaves an entry. Each entry holds the hash of the one before it, so that the
+entries that were changed or removed can be detected.`)
+
+	cmd.AddCommand(makeCmdAuditVerify())
+	cmd.AddCommand(makeCmdAuditShow())
+
+	return cmd
+}
+
+// auditLogPath returns the path of the audit log the commands are recorded
+// in: the one of the audit-log flag, or else the default one next to the log
+// file
+func auditLogPath() string {
+	if globals.auditLog != "" {
+		return globals.auditLog
+	}
+	logFile := dbOptions.LogPath
+	if logFile == "" {
+		logFile = defaultLogPath
+	}
+	return filepath.Join(filepath.Dir(logFile), auditLogFileName)
+}
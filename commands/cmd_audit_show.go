@@ -0,0 +1,112 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+/* CmdAuditShow
+ *
+ * A subcommand printing the entries
+ * of the audit log.
+ *
+ * Implements ClusterCommand interface
+ */
+type CmdAuditShow struct {
+	CmdBase
+}
+
+func makeCmdAuditShow() *cobra.Command {
+	newCmd := &CmdAuditShow{}
+	newCmd.localOnly = true
+
+	cmd := makeBasicCobraCmd(
+		newCmd,
+		auditShowSubCmd,
+		"Show the audit log",
+		`This subcommand prints the entries of the audit log, oldest first. It does
+not verify them, use audit verify for that.
+
+Examples:
+  # Show the audit log in the default location
+  vcluster audit show
+
+  # Show the audit log at /var/log/vcluster_audit.log
+  vcluster audit show --audit-log /var/log/vcluster_audit.log
+`,
+		[]string{auditLogFlag},
+	)
+
+	return cmd
+}
+
+func (c *CmdAuditShow) Parse(inputArgv []string, logger vlog.Printer) error {
+	c.argv = inputArgv
+	logger.LogArgParse(&c.argv)
+
+	return nil
+}
+
+func (c *CmdAuditShow) Run(_ context.Context, _ vclusterops.ClusterCommands) error {
+	entries, err := vclusterops.ReadAuditLog(auditLogPath())
+	if err != nil {
+		return err
+	}
+	if globals.result != nil {
+		globals.result.setOutputValue(entries)
+		return nil
+	}
+	writeAuditEntries(os.Stdout, entries)
+
+	return nil
+}
+
+// writeAuditEntries prints the entries as a table, one per line
+func writeAuditEntries(f *os.File, entries []vclusterops.AuditEntry) {
+	const padding = 2
+	w := tabwriter.NewWriter(f, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(w, "SEQ\tSTART TIME\tDURATION\tUSER\tCOMMAND\tDATABASE\tHOSTS\tOUTCOME\tERROR")
+	for i := range entries {
+		entry := &entries[i]
+		user := entry.User
+		if entry.SudoUser != "" {
+			user = fmt.Sprintf("%s (sudo by %s)", entry.User, entry.SudoUser)
+		}
+		// the entry of a command that started has no duration yet
+		duration := ""
+		if !entry.EndTime.IsZero() {
+			duration = entry.EndTime.Sub(entry.StartTime).Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Seq, entry.StartTime.Local().Format(time.RFC3339),
+			duration, user, entry.Command, entry.Database,
+			strings.Join(entry.Hosts, ","), entry.Outcome, strings.ReplaceAll(entry.Error, "\n", " "))
+	}
+	w.Flush()
+}
+
+// SetDatabaseOptions will assign a vclusterops.DatabaseOptions instance
+func (c *CmdAuditShow) SetDatabaseOptions(_ *vclusterops.DatabaseOptions) {
+}
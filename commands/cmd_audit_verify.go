@@ -0,0 +1,93 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+/* CmdAuditVerify
+ *
+ * A subcommand checking that the audit log
+ * was not tampered with.
+ *
+ * Implements ClusterCommand interface
+ */
+type CmdAuditVerify struct {
+	CmdBase
+}
+
+func makeCmdAuditVerify() *cobra.Command {
+	newCmd := &CmdAuditVerify{}
+	newCmd.localOnly = true
+
+	cmd := makeBasicCobraCmd(
+		newCmd,
+		auditVerifySubCmd,
+		"Verify the audit log",
+		`This subcommand checks the hash chain of the audit log. It fails at the
+first entry that was changed, inserted, removed or moved.
+
+Removing entries from the end of the audit log cannot be detected from the
+log alone. Keep the last sequence number and hash it prints somewhere else,
+and check that they are still in the log later on.
+
+Examples:
+  # Verify the audit log in the default location
+  vcluster audit verify
+
+  # Verify the audit log at /var/log/vcluster_audit.log
+  vcluster audit verify --audit-log /var/log/vcluster_audit.log
+`,
+		[]string{auditLogFlag},
+	)
+
+	return cmd
+}
+
+func (c *CmdAuditVerify) Parse(inputArgv []string, logger vlog.Printer) error {
+	c.argv = inputArgv
+	logger.LogArgParse(&c.argv)
+
+	return nil
+}
+
+func (c *CmdAuditVerify) Run(_ context.Context, _ vclusterops.ClusterCommands) error {
+	path := auditLogPath()
+	entries, err := vclusterops.VerifyAuditLog(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		printCmdMessage("The audit log %s has no entries\n", path)
+		return nil
+	}
+	last := entries[len(entries)-1]
+	globals.result.setOutputValue(map[string]any{"path": path, "entries": len(entries),
+		"last_seq": last.Seq, "last_hash": last.Hash})
+	printCmdMessage("The audit log %s is intact, with %d entries. The last one is entry %d with hash %s\n",
+		path, len(entries), last.Seq, last.Hash)
+
+	return nil
+}
+
+// SetDatabaseOptions will assign a vclusterops.DatabaseOptions instance
+func (c *CmdAuditVerify) SetDatabaseOptions(_ *vclusterops.DatabaseOptions) {
+}
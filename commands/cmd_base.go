@@ -16,8 +16,12 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -45,6 +49,14 @@ type CmdBase struct {
 	output                 string
 	passwordFile           string
 	readPasswordFromPrompt bool
+
+	// the journal of a failed run, to resume the command from, or to undo
+	resumeJournal   string
+	rollbackJournal string
+
+	// localOnly is set by the subcommands that only read local files, such as
+	// manage_config show and the audit ones
+	localOnly bool
 }
 
 // ValidateParseBaseOptions will validate and parse the required base options in each command
@@ -91,9 +103,21 @@ func (c *CmdBase) setCommonFlags(cmd *cobra.Command, flags []string) {
 		false,
 		"Show the details of VCluster run in the console",
 	)
+	// output is a flag that all the subcommands need
+	cmd.Flags().StringVar(
+		&globals.outputFormat,
+		outputFormatFlag,
+		"",
+		fmt.Sprintf("Write a result document of the command, with the nodes it acted on, its operations and"+
+			" their timings, its warnings and its error, instead of its usual output. One of %s, %s or %s",
+			outputFormatJSON, outputFormatYAML, outputFormatTable),
+	)
+	if c.localOnly {
+		cmd.Annotations = map[string]string{localOnlyAnnotation: "true"}
+	}
 	// keyFile and certFile are flags that all subcommands require,
-	// except for create_connection and manage_config show
-	if cmd.Name() != configShowSubCmd && cmd.Name() != createConnectionSubCmd {
+	// except for create_connection, manage_config show and the audit ones
+	if !c.localOnly && cmd.Name() != createConnectionSubCmd {
 		cmd.Flags().StringVar(
 			&globals.keyFile,
 			keyFileFlag,
@@ -110,6 +134,84 @@ func (c *CmdBase) setCommonFlags(cmd *cobra.Command, flags []string) {
 		)
 		markFlagsFileName(cmd, map[string][]string{certFileFlag: {"pem", "crt"}})
 		cmd.MarkFlagsRequiredTogether(keyFileFlag, certFileFlag)
+
+		// dry-run is for all subcommands that talk to the cluster
+		cmd.Flags().BoolVar(
+			&dbOptions.Plan,
+			dryRunFlag,
+			false,
+			"Show the operations the command would run, and the requests they would send, without changing the cluster."+
+				" Read-only operations are still run against the cluster",
+		)
+
+		cmd.Flags().StringVar(
+			&globals.eventsJSONFile,
+			eventsJSONFlag,
+			"",
+			"Write the progress events of the operations, as newline-delimited JSON, to this file."+
+				" If - is passed, the events are written to stdout",
+		)
+		markFlagsFileName(cmd, map[string][]string{eventsJSONFlag: {"json", "ndjson"}})
+
+		cmd.Flags().StringVar(
+			&globals.metricsFile,
+			metricsFileFlag,
+			"",
+			"Write the metrics of the operations and of their requests to this file, in the Prometheus text format,"+
+				" e.g. for the textfile collector of the node exporter. The file is replaced by each run",
+		)
+		markFlagsFileName(cmd, map[string][]string{metricsFileFlag: {"prom"}})
+
+		cmd.Flags().StringVar(
+			&globals.traceFile,
+			traceFileFlag,
+			"",
+			"Write the spans of the command, of its operations and of their requests to this file, as the JSON"+
+				" of the stdout exporter of OpenTelemetry, one span per line. If - is passed, the spans are written to stdout",
+		)
+		markFlagsFileName(cmd, map[string][]string{traceFileFlag: {"json", "ndjson"}})
+
+		cmd.Flags().IntVar(
+			&dbOptions.NMAPort,
+			nmaPortFlag,
+			0,
+			fmt.Sprintf("Port of the node management agent on the hosts (default %d)."+
+				" The port of the database in the configuration file is used when it is not passed, and the port of a node"+
+				" there takes precedence", util.DefaultNMAPort),
+		)
+		cmd.Flags().IntVar(
+			&dbOptions.HTTPSPort,
+			httpsPortFlag,
+			0,
+			fmt.Sprintf("Port of the HTTPS service on the hosts (default %d)."+
+				" The port of the database in the configuration file is used when it is not passed, and the port of a node"+
+				" there takes precedence", util.DefaultHTTPPort),
+		)
+
+		cmd.Flags().IntVar(
+			&dbOptions.MaxParallel,
+			maxParallelFlag,
+			0,
+			"Maximum number of requests sent to the hosts at the same time by each operation. 0 means no limit",
+		)
+		setTimeoutFlags(cmd)
+		setTLSFlags(cmd)
+		setReplayFlags(cmd)
+		setFaultFlags(cmd)
+		setAuditLogFlag(cmd)
+
+		cmd.Flags().BoolVar(
+			&dbOptions.ForceUnlock,
+			forceUnlockFlag,
+			false,
+			"Break the lock or the lease another command holds on the database. Use it only if that command is no longer running",
+		)
+	}
+	if util.StringInArray(auditLogFlag, flags) {
+		setAuditLogFlag(cmd)
+	}
+	if util.StringInArray(resumeFlag, flags) {
+		c.setJournalFlags(cmd)
 	}
 	if util.StringInArray(outputFileFlag, flags) {
 		cmd.Flags().StringVarP(
@@ -238,6 +340,128 @@ func (c *CmdBase) setPasswordFlags(cmd *cobra.Command) {
 		readPasswordFromPromptFlag}...)
 }
 
+// setTimeoutFlags sets the flags of the request and command timeouts. They
+// take precedence over the timeouts in the configuration file.
+func setTimeoutFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&dbOptions.RequestTimeout,
+		requestTimeoutFlag,
+		0,
+		"Timeout in seconds of each request sent to the hosts, for the operations that do not set their own."+
+			" -1 means no timeout",
+	)
+	cmd.Flags().StringToIntVar(
+		&dbOptions.OpTimeouts,
+		opTimeoutFlag,
+		nil,
+		"Comma-separated list of timeouts in seconds by operation name, e.g. NMADownloadFileOp=900."+
+			" For a polling operation, such as HTTPSPollNodeStateOp, it is the time the operation polls for",
+	)
+	cmd.Flags().IntVar(
+		&globals.deadline,
+		deadlineFlag,
+		0,
+		"Time in seconds the command has to complete. 0 means no deadline",
+	)
+}
+
+// setTLSFlags sets the flags that tell how the certificates of the hosts are
+// verified
+func setTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&dbOptions.TLSVerification.Insecure,
+		tlsInsecureFlag,
+		false,
+		"Do not verify the TLS certificates of the hosts. This is insecure, use it only if the certificates"+
+			" cannot be verified against the CA certificate or pinned",
+	)
+	cmd.Flags().StringToStringVar(
+		&dbOptions.TLSVerification.ServerNames,
+		tlsServerNameFlag,
+		nil,
+		"Comma-separated list of the names the TLS certificates of the hosts are valid for, by host,"+
+			" e.g. 192.168.1.101=node1.example.com. By default, a certificate must be valid for the host address",
+	)
+	cmd.Flags().StringSliceVar(
+		&dbOptions.TLSVerification.PinnedFingerprints,
+		tlsPinFlag,
+		nil,
+		"Comma-separated list of the SHA-256 fingerprints of the TLS certificates the hosts may present."+
+			" A host with a pinned certificate is trusted without checking who signed it",
+	)
+	cmd.MarkFlagsMutuallyExclusive(tlsInsecureFlag, tlsPinFlag)
+}
+
+// setReplayFlags sets the flags to record the requests of a command to a
+// replay bundle, and to replay one. The replay flag is hidden: it is meant
+// to reproduce the failures of a recorded run.
+func setReplayFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&dbOptions.RecordPath,
+		recordFlag,
+		"",
+		"Save the requests sent to the hosts and their responses, with the secrets redacted, to this replay bundle."+
+			" The bundle is written as the command runs, so it covers a run that fails",
+	)
+	cmd.Flags().StringVar(
+		&dbOptions.ReplayPath,
+		replayFlag,
+		"",
+		"Run the command against the responses saved in this replay bundle instead of the hosts",
+	)
+	hideLocalFlags(cmd, []string{replayFlag})
+	markFlagsFileName(cmd, map[string][]string{recordFlag: {"json"}, replayFlag: {"json"}})
+	cmd.MarkFlagsMutuallyExclusive(recordFlag, replayFlag)
+}
+
+// setFaultFlags sets the hidden flag of the faults injected into the
+// requests of the command, for chaos testing
+func setFaultFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&globals.faults,
+		injectFaultsFlag,
+		"",
+		fmt.Sprintf("Faults to inject into the requests to the hosts, for testing only, e.g."+
+			" kind=error,host=192.168.1.101,endpoint=v1/nodes,probability=0.5. It takes precedence over %s",
+			injectFaultsEnv),
+	)
+	hideLocalFlags(cmd, []string{injectFaultsFlag})
+}
+
+// setAuditLogFlag sets the flag of the audit log the commands are recorded
+// in, and read from by the audit subcommands
+func setAuditLogFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&globals.auditLog,
+		auditLogFlag,
+		"",
+		fmt.Sprintf("Path of the audit log, which records who ran each command and how it ended."+
+			" By default, it is %s in the directory of the log file", auditLogFileName),
+	)
+	markFlagsFileName(cmd, map[string][]string{auditLogFlag: {"log"}})
+}
+
+// setJournalFlags sets the flags to resume or roll back a command that
+// failed, from the journal it wrote
+func (c *CmdBase) setJournalFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&c.resumeJournal,
+		resumeFlag,
+		"",
+		"Resume the command from the journal written by a failed run. The operations that"+
+			" completed in that run are skipped. The other options must be the same as in that run",
+	)
+	cmd.Flags().StringVar(
+		&c.rollbackJournal,
+		rollbackFlag,
+		"",
+		"Undo the changes saved in the journal written by a failed run, instead of running the command."+
+			" The other options must be the same as in that run",
+	)
+	markFlagsFileName(cmd, map[string][]string{resumeFlag: {"journal"}, rollbackFlag: {"journal"}})
+	cmd.MarkFlagsMutuallyExclusive(resumeFlag, rollbackFlag)
+}
+
 // ResetUserInputOptions reset password option to nil in each command
 // if it is not provided in cli
 func (c *CmdBase) ResetUserInputOptions(opt *vclusterops.DatabaseOptions) {
@@ -313,8 +537,13 @@ func (c *CmdBase) usePassword() bool {
 }
 
 // writeCmdOutputToFile if output-file is set, writes the output of the command
-// to a file, otherwise to stdout
+// to a file, otherwise to stdout. With --output, the output is put in the
+// result document of the command.
 func (c *CmdBase) writeCmdOutputToFile(f *os.File, output []byte, logger vlog.Printer) {
+	if globals.result != nil {
+		globals.result.setOutput(output)
+		return
+	}
 	_, err := f.Write(output)
 	if err != nil {
 		if f == os.Stdout {
@@ -325,6 +554,39 @@ func (c *CmdBase) writeCmdOutputToFile(f *os.File, output []byte, logger vlog.Pr
 	}
 }
 
+// printPlan writes the plan built by a dry run. Each op is listed in the order
+// it would run, with the requests it would send. Sensitive values in the
+// request bodies are already redacted by vclusterops.
+func (c *CmdBase) printPlan(plan *vclusterops.VClusterPlan, logger vlog.Printer) {
+	if plan == nil {
+		return
+	}
+	if globals.result != nil {
+		globals.result.setPlan(plan)
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString("Dry run: the following operations would be run\n")
+	for i := range plan.Ops {
+		op := &plan.Ops[i]
+		fmt.Fprintf(&sb, "%d. [%s] %s", i+1, op.Status, op.Name)
+		if op.Description != "" {
+			fmt.Fprintf(&sb, ": %s", op.Description)
+		}
+		sb.WriteString("\n")
+		if op.Reason != "" {
+			fmt.Fprintf(&sb, "   reason: %s\n", op.Reason)
+		}
+		for _, request := range op.Requests {
+			fmt.Fprintf(&sb, "   %s %s\n", request.Method, request.URL)
+			if request.Body != "" {
+				fmt.Fprintf(&sb, "     body: %s\n", request.Body)
+			}
+		}
+	}
+	c.writeCmdOutputToFile(globals.file, []byte(sb.String()), logger)
+}
+
 // initCmdOutputFile returns the open file descriptor, that will
 // be used to write the command output, or stdout
 func (c *CmdBase) initCmdOutputFile() (*os.File, error) {
@@ -355,3 +617,70 @@ func (c *CmdBase) getCertFilesFromCertPaths(opt *vclusterops.DatabaseOptions) er
 	}
 	return nil
 }
+
+// journalContext returns a copy of ctx under which the completed ops are
+// saved to a journal, and the path of the journal. With --resume, the journal
+// of the failed run is reused and its ops are skipped.
+func (c *CmdBase) journalContext(ctx context.Context, cmdName string,
+	opt *vclusterops.DatabaseOptions) (context.Context, string) {
+	if opt.Plan {
+		return ctx, ""
+	}
+	if c.resumeJournal != "" {
+		return vclusterops.ContextWithJournal(ctx, c.resumeJournal, true), c.resumeJournal
+	}
+	logDir := os.TempDir()
+	if opt.LogPath != "" {
+		logDir = filepath.Dir(opt.LogPath)
+	}
+	journalPath := filepath.Join(logDir, fmt.Sprintf("vcluster_%s_%s.journal", cmdName, opt.DBName))
+	return vclusterops.ContextWithJournal(ctx, journalPath, false), journalPath
+}
+
+// finishJournal removes the journal of a command that succeeded, or whose
+// changes were all rolled back automatically. When the command failed, it
+// tells how to resume the command or undo its changes.
+func (c *CmdBase) finishJournal(vcc vclusterops.ClusterCommands, journalPath string, runErr error) {
+	if journalPath == "" {
+		return
+	}
+	var autoRollbackErr *vclusterops.AutoRollbackError
+	if errors.As(runErr, &autoRollbackErr) && autoRollbackErr.RollbackErr == nil {
+		runErr = nil
+	}
+	if runErr != nil {
+		if _, err := os.Stat(journalPath); err == nil {
+			vcc.PrintWarning("The completed operations were saved to %s. Run the same command with --%s %s to continue it,"+
+				" or with --%s %s to undo its changes", journalPath, resumeFlag, journalPath, rollbackFlag, journalPath)
+		}
+		return
+	}
+	err := os.Remove(journalPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		vcc.PrintWarning("fail to remove journal %s, details: %s", journalPath, err)
+	}
+}
+
+// rollbackFromJournal undoes the changes saved in the journal passed to
+// --rollback, and removes the journal once they are all undone
+func (c *CmdBase) rollbackFromJournal(ctx context.Context, vcc vclusterops.ClusterCommands,
+	opt *vclusterops.DatabaseOptions) error {
+	options := vclusterops.VRollbackOptionsFactory()
+	options.DatabaseOptions = *opt
+	options.JournalPath = c.rollbackJournal
+	err := vcc.VRollback(ctx, &options)
+	if err != nil {
+		vcc.LogError(err, "failed to roll back the operations", "journal", c.rollbackJournal)
+		return err
+	}
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+	err = os.Remove(c.rollbackJournal)
+	if err != nil {
+		vcc.PrintWarning("fail to remove journal %s, details: %s", c.rollbackJournal, err)
+	}
+	vcc.PrintInfo("Rolled back the operations saved in %s", c.rollbackJournal)
+	return nil
+}
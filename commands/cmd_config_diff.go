@@ -0,0 +1,374 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/util"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+// the fields of the config file that can differ from the database
+const (
+	// driftNode is reported for a node that is only in the config file, or
+	// only in the database
+	driftNode        = "node"
+	driftName        = "name"
+	driftAddress     = "address"
+	driftSubcluster  = "subcluster"
+	driftSandbox     = "sandbox"
+	driftCatalogPath = "catalog_path"
+	driftDataPath    = "data_path"
+	driftDepotPath   = "depot_path"
+	driftEonMode     = "eon_mode"
+)
+
+/* CmdConfigDiff
+ *
+ * A subcommand comparing the YAML config file
+ * with the database.
+ *
+ * Implements ClusterCommand interface
+ */
+type CmdConfigDiff struct {
+	diffOptions *vclusterops.VFetchNodeStateOptions
+	// rewrite the config file to match the database
+	fix bool
+
+	CmdBase
+}
+
+// configDrift is a field of the config file that does not match the database
+type configDrift struct {
+	// Node is the name of the node, "" for the database
+	Node   string `json:"node,omitempty"`
+	Field  string `json:"field"`
+	Config string `json:"config"`
+	Live   string `json:"database"`
+}
+
+func makeCmdConfigDiff() *cobra.Command {
+	newCmd := &CmdConfigDiff{}
+	opt := vclusterops.VFetchNodeStateOptionsFactory()
+	newCmd.diffOptions = &opt
+
+	cmd := makeBasicCobraCmd(
+		newCmd,
+		configDiffSubCmd,
+		"Compare the config file with the database",
+		`This subcommand compares the config file with the database, and reports the
+nodes that are only in one of them, and for the other nodes, the names, addresses,
+subclusters, sandboxes, and catalog, data and depot paths that do not match,
+as well as whether the database is in Eon mode.
+
+The paths are compared when the nodes are up. The hosts of the config file are
+used to reach the database, use --hosts when they are stale.
+
+The subcommand fails when the config file does not match the database, unless
+--fix is given. With --fix, the config file is rewritten to match the
+database, atomically so that it is never left half written. The timeouts,
+hooks and ports in the config file are kept.
+
+Examples:
+  # Compare the config file with the database
+  vcluster manage_config diff --config /opt/vertica/config/vertica_cluster.yaml
+
+  # Rewrite the config file, reaching the database through a host that
+  # is not in the config file
+  vcluster manage_config diff --hosts 10.20.30.44 --fix \
+    --config /opt/vertica/config/vertica_cluster.yaml
+`,
+		[]string{dbNameFlag, hostsFlag, passwordFlag, ipv6Flag, configFlag, outputFileFlag},
+	)
+
+	// local flags
+	newCmd.setLocalFlags(cmd)
+
+	return cmd
+}
+
+// setLocalFlags will set the local flags the command has
+func (c *CmdConfigDiff) setLocalFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&c.fix,
+		"fix",
+		false,
+		"Rewrite the config file to match the database",
+	)
+}
+
+func (c *CmdConfigDiff) Parse(inputArgv []string, logger vlog.Printer) error {
+	c.argv = inputArgv
+	logger.LogArgParse(&c.argv)
+
+	// for some options, we do not want to use their default values,
+	// if they are not provided in cli,
+	// reset the value of those options to nil
+	c.ResetUserInputOptions(&c.diffOptions.DatabaseOptions)
+	return c.validateParse(logger)
+}
+
+func (c *CmdConfigDiff) validateParse(logger vlog.Printer) error {
+	logger.Info("Called validateParse()", "command", configDiffSubCmd)
+	err := c.getCertFilesFromCertPaths(&c.diffOptions.DatabaseOptions)
+	if err != nil {
+		return err
+	}
+
+	err = c.ValidateParseBaseOptions(&c.diffOptions.DatabaseOptions)
+	if err != nil {
+		return err
+	}
+	return c.setDBPassword(&c.diffOptions.DatabaseOptions)
+}
+
+func (c *CmdConfigDiff) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
+	vcc.V(1).Info("Called method Run()")
+
+	dbConfig, err := readConfig()
+	if err != nil {
+		return err
+	}
+	// the catalog is read when the database is down
+	if c.diffOptions.CatalogPrefix == "" {
+		c.diffOptions.CatalogPrefix, c.diffOptions.DataPrefix, c.diffOptions.DepotPrefix = dbConfig.getPathPrefixes()
+	}
+
+	nodeStates, err := vcc.VFetchNodeState(ctx, c.diffOptions)
+	if len(nodeStates) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no nodes were fetched from the database %s", c.diffOptions.DBName)
+		}
+		vcc.PrintError("fail to fetch the nodes of the database: %s", err)
+		return err
+	}
+	// the nodes are all down when they could only be read from the catalog,
+	// so their paths are not compared
+	if err != nil {
+		vcc.PrintWarning("The states of the nodes could not be fetched from the database, details: %v", err)
+	}
+
+	if c.diffOptions.Plan {
+		c.printPlan(c.diffOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	liveEon, err := c.fetchEonMode(ctx, vcc)
+	if err != nil {
+		vcc.PrintError("fail to read the mode of the database from its catalog: %s", err)
+		return err
+	}
+	details := c.fetchDetails(ctx, vcc, nodeStates)
+	drifts, fixedConfig := diffConfig(dbConfig, liveEon, nodeStates, details)
+	table := renderConfigDrifts(drifts)
+	if globals.result != nil {
+		globals.result.setOutputValue(drifts)
+		globals.result.setOutputTable(table)
+	} else {
+		c.writeCmdOutputToFile(globals.file, table, vcc.GetLog())
+	}
+	if len(drifts) == 0 {
+		return nil
+	}
+	if !c.fix {
+		return fmt.Errorf("the config file does not match the database, use --fix to rewrite it")
+	}
+
+	err = fixedConfig.writeAtomically(dbOptions.ConfigPath)
+	if err != nil {
+		return err
+	}
+	vcc.PrintInfo("Rewrote config file %s to match database %s", dbOptions.ConfigPath, fixedConfig.Name)
+	return nil
+}
+
+// fetchEonMode reads from the catalog whether the database is in Eon mode
+func (c *CmdConfigDiff) fetchEonMode(ctx context.Context, vcc vclusterops.ClusterCommands) (bool, error) {
+	options := vclusterops.VRecoverConfigOptionsFactory()
+	options.DatabaseOptions = c.diffOptions.DatabaseOptions
+	// the config file is not written by the fetch
+	options.Overwrite = true
+	vdb, err := vcc.VFetchCoordinationDatabase(ctx, &options)
+	// the fetch fails without a depot path once it found the database in
+	// Eon mode, which is all we need
+	if err != nil && !vdb.IsEon {
+		return false, err
+	}
+	return vdb.IsEon, nil
+}
+
+// fetchDetails returns the details of the nodes that are up, by address. If
+// some nodes fail to return them, the others are asked one by one.
+func (c *CmdConfigDiff) fetchDetails(ctx context.Context, vcc vclusterops.ClusterCommands,
+	nodeStates []vclusterops.NodeInfo) map[string]*vclusterops.NodeDetails {
+	details := make(map[string]*vclusterops.NodeDetails)
+	var hosts []string
+	for i := range nodeStates {
+		if nodeStates[i].State == util.NodeUpState {
+			hosts = append(hosts, nodeStates[i].Address)
+		}
+	}
+	if len(hosts) == 0 {
+		return details
+	}
+	fetch := func(hosts []string) error {
+		options := vclusterops.VFetchNodesDetailsOptionsFactory()
+		options.DatabaseOptions = c.diffOptions.DatabaseOptions
+		options.RawHosts = hosts
+		options.Hosts = nil
+		nodesDetails, err := vcc.VFetchNodesDetails(ctx, &options)
+		for i := range nodesDetails {
+			details[nodesDetails[i].Address] = &nodesDetails[i]
+		}
+		return err
+	}
+	if err := fetch(hosts); err == nil || len(hosts) == 1 {
+		return details
+	}
+	for _, host := range hosts {
+		if err := fetch([]string{host}); err != nil {
+			vcc.PrintWarning("fail to fetch the details of the node on host %s, its paths are not compared: %s", host, err)
+		}
+	}
+	return details
+}
+
+// SetDatabaseOptions will assign a vclusterops.DatabaseOptions instance to the one in CmdConfigDiff
+func (c *CmdConfigDiff) SetDatabaseOptions(opt *vclusterops.DatabaseOptions) {
+	c.diffOptions.DatabaseOptions = *opt
+}
+
+// configDiffer compares the nodes of the config file with the ones of the
+// database, and builds the config file that matches the database
+type configDiffer struct {
+	drifts []configDrift
+	fixed  DatabaseConfig
+}
+
+// diffConfig returns the fields of dbConfig that do not match the nodes of
+// the database, in Eon mode if liveEon is set, and the config that matches
+// them. A node of the config file is matched with the node of the database of
+// the same name, or else at the same address. The paths of a node are only
+// compared when it returned its details.
+func diffConfig(dbConfig *DatabaseConfig, liveEon bool, nodeStates []vclusterops.NodeInfo,
+	details map[string]*vclusterops.NodeDetails) ([]configDrift, *DatabaseConfig) {
+	differ := configDiffer{drifts: []configDrift{}, fixed: *dbConfig}
+	differ.fixed.Nodes = nil
+	byName := make(map[string]*NodeConfig, len(dbConfig.Nodes))
+	byAddress := make(map[string]*NodeConfig, len(dbConfig.Nodes))
+	for _, node := range dbConfig.Nodes {
+		byName[node.Name] = node
+		byAddress[node.Address] = node
+	}
+
+	sort.Slice(nodeStates, func(i, j int) bool { return nodeStates[i].Name < nodeStates[j].Name })
+	matched := make(map[*NodeConfig]bool)
+	for i := range nodeStates {
+		nodeInfo := &nodeStates[i]
+		node, ok := byName[nodeInfo.Name]
+		if !ok {
+			node, ok = byAddress[nodeInfo.Address]
+		}
+		if ok && !matched[node] {
+			matched[node] = true
+		} else {
+			differ.add(nodeInfo.Name, driftNode, "", nodeInfo.Address)
+			node = nil
+		}
+		differ.diffNode(dbConfig, node, nodeInfo, details[nodeInfo.Address])
+	}
+	for _, node := range dbConfig.Nodes {
+		if !matched[node] {
+			differ.add(node.Name, driftNode, node.Address, "")
+		}
+	}
+	differ.add("", driftEonMode, strconv.FormatBool(dbConfig.IsEon), strconv.FormatBool(liveEon))
+	differ.fixed.IsEon = liveEon
+	return differ.drifts, &differ.fixed
+}
+
+// add records a drift when value in the config file differs from the one in
+// the database
+func (differ *configDiffer) add(nodeName, field, configValue, liveValue string) {
+	if configValue != liveValue {
+		differ.drifts = append(differ.drifts, configDrift{Node: nodeName, Field: field, Config: configValue, Live: liveValue})
+	}
+}
+
+// diffNode compares node, the node of the config file matched with nodeInfo,
+// with nodeInfo and its details, and adds the node that matches them to the
+// fixed config. node is nil when the node is not in the config file.
+func (differ *configDiffer) diffNode(dbConfig *DatabaseConfig, node *NodeConfig, nodeInfo *vclusterops.NodeInfo,
+	nodeDetails *vclusterops.NodeDetails) {
+	fixedNode := &NodeConfig{Name: nodeInfo.Name, Address: nodeInfo.Address, Subcluster: nodeInfo.Subcluster,
+		Sandbox: nodeInfo.Sandbox}
+	// the paths that are not known are taken from the config file
+	if node != nil {
+		fixedNode.CatalogPath, fixedNode.DataPath, fixedNode.DepotPath = node.CatalogPath, node.DataPath, node.DepotPath
+		fixedNode.NMAPort, fixedNode.HTTPSPort = node.NMAPort, node.HTTPSPort
+	} else {
+		fixedNode.CatalogPath, fixedNode.DataPath, fixedNode.DepotPath = dbConfig.getPathPrefixes()
+	}
+	if nodeInfo.CatalogPath != "" {
+		fixedNode.CatalogPath = util.GetPathPrefix(nodeInfo.CatalogPath)
+	}
+	if nodeDetails != nil {
+		// the node knows its own sandbox, which the main cluster may not
+		fixedNode.Sandbox = nodeDetails.SandboxName
+		if len(nodeDetails.DataPath) > 0 {
+			fixedNode.DataPath = util.GetPathPrefix(nodeDetails.DataPath[0])
+		}
+		if nodeDetails.DepotPath != "" {
+			fixedNode.DepotPath = util.GetPathPrefix(nodeDetails.DepotPath)
+		}
+	}
+	differ.fixed.Nodes = append(differ.fixed.Nodes, fixedNode)
+	if node == nil {
+		return
+	}
+
+	differ.add(nodeInfo.Name, driftName, node.Name, fixedNode.Name)
+	differ.add(nodeInfo.Name, driftAddress, node.Address, fixedNode.Address)
+	differ.add(nodeInfo.Name, driftSubcluster, node.Subcluster, fixedNode.Subcluster)
+	differ.add(nodeInfo.Name, driftSandbox, node.Sandbox, fixedNode.Sandbox)
+	differ.add(nodeInfo.Name, driftCatalogPath, node.CatalogPath, fixedNode.CatalogPath)
+	differ.add(nodeInfo.Name, driftDataPath, node.DataPath, fixedNode.DataPath)
+	differ.add(nodeInfo.Name, driftDepotPath, node.DepotPath, fixedNode.DepotPath)
+}
+
+// renderConfigDrifts renders the drifts as a table
+func renderConfigDrifts(drifts []configDrift) []byte {
+	if len(drifts) == 0 {
+		return []byte("The config file matches the database\n")
+	}
+	const padding = 2
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(w, "NODE\tFIELD\tCONFIG FILE\tDATABASE")
+	for _, drift := range drifts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", statusCell(drift.Node), drift.Field, statusCell(drift.Config), statusCell(drift.Live))
+	}
+	w.Flush()
+	return buf.Bytes()
+}
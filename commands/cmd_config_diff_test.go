@@ -0,0 +1,109 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/util"
+)
+
+func TestDiffConfig(t *testing.T) {
+	node := func(name, address, subcluster, sandbox string) *NodeConfig {
+		return &NodeConfig{Name: name, Address: address, Subcluster: subcluster, Sandbox: sandbox,
+			CatalogPath: "/catalog", DataPath: "/data", DepotPath: "/depot"}
+	}
+	dbConfig := &DatabaseConfig{Name: "test_db", IsEon: true, CommunalStorageLocation: "s3://bucket/test_db",
+		Timeouts: &TimeoutConfig{Request: 60}, Nodes: []*NodeConfig{
+			node("v_test_db_node0001", "192.168.1.101", "sc1", ""),
+			node("v_test_db_node0002", "192.168.1.102", "sc1", ""),
+			node("v_test_db_node0003", "192.168.1.103", "sc2", ""),
+			node("v_test_db_node0009", "192.168.1.109", "sc2", ""),
+		}}
+	dbConfig.Nodes[0].NMAPort = 5555
+	liveNode := func(name, address, subcluster, sandbox string) vclusterops.NodeInfo {
+		return vclusterops.NodeInfo{Name: name, Address: address, Subcluster: subcluster, Sandbox: sandbox,
+			State: util.NodeUpState, CatalogPath: "/catalog/test_db/" + name + "_catalog"}
+	}
+	nodeDetails := func(sandbox, dataPath string) *vclusterops.NodeDetails {
+		return &vclusterops.NodeDetails{NodeState: vclusterops.NodeState{SandboxName: sandbox,
+			DataPath: []string{dataPath + "/test_db/v_test_db_node_data"}, DepotPath: "/depot/test_db/v_test_db_node_depot"}}
+	}
+	// node0002 moved to another address, node0003 was sandboxed and
+	// node0004 was added, while node0009 is gone
+	nodeStates := []vclusterops.NodeInfo{
+		liveNode("v_test_db_node0004", "192.168.1.104", "sc2", ""),
+		liveNode("v_test_db_node0001", "192.168.1.101", "sc1", ""),
+		liveNode("v_test_db_node0002", "192.168.1.112", "sc1", ""),
+		liveNode("v_test_db_node0003", "192.168.1.103", "sc2", ""),
+	}
+	details := map[string]*vclusterops.NodeDetails{
+		"192.168.1.101": nodeDetails("", "/data"),
+		"192.168.1.103": nodeDetails("sand1", "/newdata"),
+	}
+	drifts, fixed := diffConfig(dbConfig, true, nodeStates, details)
+	assert.Equal(t, []configDrift{
+		{Node: "v_test_db_node0002", Field: driftAddress, Config: "192.168.1.102", Live: "192.168.1.112"},
+		{Node: "v_test_db_node0003", Field: driftSandbox, Config: "", Live: "sand1"},
+		{Node: "v_test_db_node0003", Field: driftDataPath, Config: "/data", Live: "/newdata"},
+		{Node: "v_test_db_node0004", Field: driftNode, Config: "", Live: "192.168.1.104"},
+		{Node: "v_test_db_node0009", Field: driftNode, Config: "192.168.1.109", Live: ""},
+	}, drifts)
+
+	// the fixed config matches the database, and keeps the rest of the config
+	assert.Equal(t, []string{"192.168.1.101", "192.168.1.112", "192.168.1.103", "192.168.1.104"}, fixed.getHosts())
+	assert.Equal(t, 5555, fixed.Nodes[0].NMAPort)
+	assert.Equal(t, "sand1", fixed.Nodes[2].Sandbox)
+	assert.Equal(t, "/newdata", fixed.Nodes[2].DataPath)
+	assert.Equal(t, "/catalog", fixed.Nodes[3].CatalogPath)
+	assert.Equal(t, dbConfig.Timeouts, fixed.Timeouts)
+	assert.Equal(t, dbConfig.CommunalStorageLocation, fixed.CommunalStorageLocation)
+	drifts, _ = diffConfig(fixed, true, nodeStates, details)
+	assert.Empty(t, drifts)
+
+	// the nodes of an Enterprise database are in the default subcluster,
+	// only the catalog tells that it is not in Eon mode
+	dbConfig.Nodes = dbConfig.Nodes[:1]
+	dbConfig.Nodes[0].Subcluster = "default_subcluster"
+	nodeStates = []vclusterops.NodeInfo{liveNode("v_test_db_node0001", "192.168.1.101", "default_subcluster", "")}
+	drifts, fixed = diffConfig(dbConfig, false, nodeStates, nil)
+	assert.Equal(t, []configDrift{{Field: driftEonMode, Config: "true", Live: "false"}}, drifts)
+	assert.False(t, fixed.IsEon)
+	drifts, _ = diffConfig(fixed, false, nodeStates, nil)
+	assert.Empty(t, drifts)
+}
+
+func TestWriteConfigAtomically(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), defConfigFileName)
+	assert.NoError(t, os.WriteFile(configPath, []byte("stale"), configFilePerm))
+
+	dbConfig := &DatabaseConfig{Name: "test_db", Nodes: []*NodeConfig{{Name: "v_test_db_node0001", Address: "192.168.1.101"}}}
+	assert.NoError(t, dbConfig.writeAtomically(configPath))
+	readBack, err := readConfigFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, dbConfig, readBack)
+	// the temporary file is renamed
+	entries, err := os.ReadDir(filepath.Dir(configPath))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// a directory that cannot hold the temporary file fails the write
+	assert.Error(t, dbConfig.writeAtomically(filepath.Join(configPath, "sub", defConfigFileName)))
+}
@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -103,12 +104,17 @@ func (c *CmdConfigRecover) validateParse(logger vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdConfigRecover) Run(vcc vclusterops.ClusterCommands) error {
-	vdb, err := vcc.VFetchCoordinationDatabase(c.recoverConfigOptions)
+func (c *CmdConfigRecover) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
+	vdb, err := vcc.VFetchCoordinationDatabase(ctx, c.recoverConfigOptions)
 	if err != nil {
 		vcc.LogError(err, "failed to recover the config file")
 		return err
 	}
+
+	if c.recoverConfigOptions.Plan {
+		c.printPlan(c.recoverConfigOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
 	// write db info to vcluster config file
 	err = writeConfig(&vdb)
 	if err != nil {
@@ -16,12 +16,14 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
 	"github.com/vertica/vcluster/vclusterops/vlog"
+	"gopkg.in/yaml.v3"
 )
 
 /* CmdConfigShow
@@ -38,6 +40,7 @@ type CmdConfigShow struct {
 
 func makeCmdConfigShow() *cobra.Command {
 	newCmd := &CmdConfigShow{}
+	newCmd.localOnly = true
 
 	cmd := makeBasicCobraCmd(
 		newCmd,
@@ -65,11 +68,19 @@ func (c *CmdConfigShow) Parse(inputArgv []string, logger vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdConfigShow) Run(_ vclusterops.ClusterCommands) error {
+func (c *CmdConfigShow) Run(ctx context.Context, _ vclusterops.ClusterCommands) error {
 	fileBytes, err := os.ReadFile(dbOptions.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("fail to read config file, details: %w", err)
 	}
+	if globals.result != nil {
+		var config any
+		if parseErr := yaml.Unmarshal(fileBytes, &config); parseErr != nil {
+			return fmt.Errorf("fail to parse config file, details: %w", parseErr)
+		}
+		globals.result.setOutputValue(config)
+		return nil
+	}
 	fmt.Printf("%s", string(fileBytes))
 
 	return nil
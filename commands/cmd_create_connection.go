@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -104,7 +105,7 @@ func (c *CmdCreateConnection) Parse(inputArgv []string, logger vlog.Printer) err
 	return nil
 }
 
-func (c *CmdCreateConnection) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdCreateConnection) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	// write target db info to vcluster connection file
@@ -112,7 +113,7 @@ func (c *CmdCreateConnection) Run(vcc vclusterops.ClusterCommands) error {
 	if err != nil {
 		return fmt.Errorf("fail to write connection file, details: %s", err)
 	}
-	fmt.Printf("Successfully write connection file in %s", globals.connFile)
+	printCmdMessage("Successfully write connection file in %s", globals.connFile)
 	return nil
 }
 
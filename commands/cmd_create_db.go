@@ -16,6 +16,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -97,7 +99,7 @@ Examples:
     --password 12345678
 `,
 		[]string{dbNameFlag, hostsFlag, catalogPathFlag, dataPathFlag, depotPathFlag,
-			communalStorageLocationFlag, passwordFlag, configFlag, ipv6Flag, configParamFlag},
+			communalStorageLocationFlag, passwordFlag, configFlag, ipv6Flag, configParamFlag, resumeFlag},
 	)
 	// local flags
 	newCmd.setLocalFlags(cmd)
@@ -258,13 +260,25 @@ func (c *CmdCreateDB) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.createDBOptions.DatabaseOptions)
 }
 
-func (c *CmdCreateDB) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdCreateDB) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
-	vdb, createError := vcc.VCreateDatabase(c.createDBOptions)
+	if c.rollbackJournal != "" {
+		return c.rollbackFromJournal(ctx, vcc, &c.createDBOptions.DatabaseOptions)
+	}
+
+	ctx, journalPath := c.journalContext(ctx, createDBSubCmd, &c.createDBOptions.DatabaseOptions)
+	vdb, createError := vcc.VCreateDatabase(ctx, c.createDBOptions)
+	c.finishJournal(vcc, journalPath, createError)
 	if createError != nil {
 		return createError
 	}
 
+	if c.createDBOptions.Plan {
+		c.printPlan(c.createDBOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	globals.result.addDatabaseNodes(&vdb)
 	// write db info to vcluster config file
 	err := writeConfig(&vdb)
 	if err != nil {
@@ -0,0 +1,184 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+/* CmdDoctor
+ *
+ * Implements ClusterCommand interface
+ */
+type CmdDoctor struct {
+	doctorOptions *vclusterops.VDoctorOptions
+
+	CmdBase
+}
+
+func makeCmdDoctor() *cobra.Command {
+	newCmd := &CmdDoctor{}
+
+	opt := vclusterops.VDoctorOptionsFactory()
+	newCmd.doctorOptions = &opt
+
+	cmd := makeBasicCobraCmd(
+		newCmd,
+		doctorSubCmd,
+		"Check that the hosts are ready for a new database",
+		`This subcommand checks, through the NMA of each host, that the hosts are
+ready for create_db, or for revive_db with --for-revive. Each check passes,
+warns, or fails, with a hint on how to fix it:
+
+  - the NMA is reachable on every host
+  - the hosts run the same Vertica version
+  - the addresses of the hosts are IPv6 ones if, and only if, --ipv6 is given
+  - the catalog, data and depot paths are writable, which is checked by
+    creating scratch directories under them and deleting them
+  - the communal storage is reachable, and holds the database only for a
+    revive
+  - the clocks of the hosts agree
+  - the certificates of the NMAs and the client certificate are valid
+  - the file systems of the catalog, data and depot paths have room for the
+    database
+
+The free disk space of the hosts is not reported by the NMA, so it is only
+checked on the hosts that are this machine, and skipped on the other ones.
+
+The subcommand fails when one of the checks fails.
+
+Examples:
+  # Check the hosts before creating an Eon database
+  vcluster doctor --db-name test_db \
+    --hosts 10.20.30.40,10.20.30.41,10.20.30.42 \
+    --catalog-path /data --data-path /data --depot-path /depot \
+    --communal-storage-location s3://bucket/test_db
+
+  # Check the hosts before reviving a database, as a JSON document
+  vcluster doctor --db-name test_db --for-revive \
+    --hosts 10.20.30.40,10.20.30.41,10.20.30.42 \
+    --communal-storage-location s3://bucket/test_db --output json
+`,
+		[]string{dbNameFlag, hostsFlag, catalogPathFlag, dataPathFlag, depotPathFlag, communalStorageLocationFlag,
+			configParamFlag, ipv6Flag, configFlag, outputFileFlag},
+	)
+
+	// local flags
+	newCmd.setLocalFlags(cmd)
+
+	// require db-name
+	markFlagsRequired(cmd, []string{dbNameFlag})
+
+	return cmd
+}
+
+// setLocalFlags will set the local flags the command has
+func (c *CmdDoctor) setLocalFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&c.doctorOptions.ForRevive,
+		"for-revive",
+		false,
+		"Check the hosts for revive_db rather than create_db: the communal storage must hold the database",
+	)
+}
+
+func (c *CmdDoctor) Parse(inputArgv []string, logger vlog.Printer) error {
+	c.argv = inputArgv
+	logger.LogMaskedArgParse(c.argv)
+
+	c.doctorOptions.IsEon = c.parser.Changed(depotPathFlag) || c.parser.Changed(communalStorageLocationFlag)
+	return c.validateParse(logger)
+}
+
+func (c *CmdDoctor) validateParse(logger vlog.Printer) error {
+	logger.Info("Called validateParse()", "command", doctorSubCmd)
+	err := c.ValidateParseBaseOptions(&c.doctorOptions.DatabaseOptions)
+	if err != nil {
+		return err
+	}
+
+	return c.getCertFilesFromCertPaths(&c.doctorOptions.DatabaseOptions)
+}
+
+func (c *CmdDoctor) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
+	vcc.V(1).Info("Called method Run()")
+
+	report, err := vcc.VDoctor(ctx, c.doctorOptions)
+	if err != nil {
+		vcc.PrintError("fail to check the hosts: %s", err)
+		return err
+	}
+
+	if c.doctorOptions.Plan {
+		c.printPlan(c.doctorOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	table := renderDoctorReport(report)
+	if globals.result != nil {
+		globals.result.setOutputValue(report)
+		globals.result.setOutputTable(table)
+	} else {
+		c.writeCmdOutputToFile(globals.file, table, vcc.GetLog())
+	}
+	if report.Status == vclusterops.DoctorFail {
+		return fmt.Errorf("the hosts failed some of the checks")
+	}
+	return nil
+}
+
+// SetDatabaseOptions will assign a vclusterops.DatabaseOptions instance to the one in CmdDoctor
+func (c *CmdDoctor) SetDatabaseOptions(opt *vclusterops.DatabaseOptions) {
+	c.doctorOptions.DatabaseOptions = *opt
+}
+
+// renderDoctorReport renders the report as a table of the checks, followed
+// by the hints of the checks that warn or fail
+func renderDoctorReport(report *vclusterops.DoctorReport) []byte {
+	const padding = 2
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tCHECK\tHOST\tDETAIL")
+	var hints []string
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Status, check.Name, statusCell(check.Host), check.Detail)
+		if check.Hint == "" || (check.Status != vclusterops.DoctorWarn && check.Status != vclusterops.DoctorFail) {
+			continue
+		}
+		if check.Host != "" {
+			hints = append(hints, fmt.Sprintf("- %s (%s): %s", check.Name, check.Host, check.Hint))
+		} else {
+			hints = append(hints, fmt.Sprintf("- %s: %s", check.Name, check.Hint))
+		}
+	}
+	w.Flush()
+
+	if len(hints) > 0 {
+		buf.WriteString("\nHINTS:\n")
+		for _, hint := range hints {
+			fmt.Fprintln(&buf, hint)
+		}
+	}
+	fmt.Fprintf(&buf, "\nRESULT: %s\n", report.Status)
+	return buf.Bytes()
+}
@@ -16,6 +16,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
 	"github.com/vertica/vcluster/vclusterops/vlog"
@@ -101,15 +103,20 @@ func (c *CmdDropDB) validateParse(logger vlog.Printer) error {
 	return c.ValidateParseBaseOptions(&c.dropDBOptions.DatabaseOptions)
 }
 
-func (c *CmdDropDB) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdDropDB) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
-	err := vcc.VDropDatabase(c.dropDBOptions)
+	err := vcc.VDropDatabase(ctx, c.dropDBOptions)
 	if err != nil {
 		vcc.LogError(err, "failed do drop the database")
 		return err
 	}
 
+	if c.dropDBOptions.Plan {
+		c.printPlan(c.dropDBOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	vcc.PrintInfo("Successfully dropped database %s", c.dropDBOptions.DBName)
 	// if the database is successfully dropped, the config file will be removed
 	// if failed to remove it, we will ask users to manually do it
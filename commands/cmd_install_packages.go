@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/spf13/cobra"
@@ -112,15 +113,20 @@ func (c *CmdInstallPackages) Analyze(_ vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdInstallPackages) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdInstallPackages) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	options := c.installPkgOpts
 
-	status, err := vcc.VInstallPackages(options)
+	status, err := vcc.VInstallPackages(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "failed to install the packages")
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	var bytes []byte
 	bytes, err = json.MarshalIndent(status, "", "  ")
 	if err != nil {
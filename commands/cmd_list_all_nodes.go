@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
@@ -97,10 +98,10 @@ func (c *CmdListAllNodes) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.fetchNodeStateOptions.DatabaseOptions)
 }
 
-func (c *CmdListAllNodes) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdListAllNodes) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
-	nodeStates, err := vcc.VFetchNodeState(c.fetchNodeStateOptions)
+	nodeStates, err := vcc.VFetchNodeState(ctx, c.fetchNodeStateOptions)
 	if err != nil {
 		// if all nodes are down, the nodeStates list is not empty
 		// for this case, we don't want to show errors but show DOWN for the nodes
@@ -110,6 +111,12 @@ func (c *CmdListAllNodes) Run(vcc vclusterops.ClusterCommands) error {
 		}
 	}
 
+	if c.fetchNodeStateOptions.Plan {
+		c.printPlan(c.fetchNodeStateOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	globals.result.addNodeStates(nodeStates)
 	bytes, err := c.marshalNoteStates(nodeStates)
 	if err != nil {
 		return err
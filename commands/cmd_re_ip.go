@@ -16,7 +16,7 @@
 package commands
 
 import (
-	"fmt"
+	"context"
 
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
@@ -114,7 +114,7 @@ func (c *CmdReIP) validateParse(logger vlog.Printer) error {
 	return c.reIPOptions.ReadReIPFile(c.reIPFilePath)
 }
 
-func (c *CmdReIP) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdReIP) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	options := c.reIPOptions
@@ -127,12 +127,17 @@ func (c *CmdReIP) Run(vcc vclusterops.ClusterCommands) error {
 		canUpdateConfig = false
 	}
 
-	err = vcc.VReIP(options)
+	err = vcc.VReIP(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "fail to re-ip")
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	vcc.PrintInfo("Re-ip is successfully completed")
 
 	// update config file after running re_ip
@@ -140,7 +145,7 @@ func (c *CmdReIP) Run(vcc vclusterops.ClusterCommands) error {
 		c.UpdateConfig(dbConfig)
 		err = dbConfig.write(options.ConfigPath)
 		if err != nil {
-			fmt.Printf("Warning: fail to update config file, details %v\n", err)
+			printCmdWarning("fail to update config file, details %v", err)
 		}
 	}
 
@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -133,16 +134,22 @@ func (c *CmdRemoveNode) parseHostToRemoveList() error {
 	return nil
 }
 
-func (c *CmdRemoveNode) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdRemoveNode) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	options := c.removeNodeOptions
 
-	vdb, err := vcc.VRemoveNode(options)
+	vdb, err := vcc.VRemoveNode(ctx, options)
 	if err != nil {
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	globals.result.addHosts(c.removeNodeOptions.HostsToRemove, removedNodeState)
 	// write db info to vcluster config file
 	err = writeConfig(&vdb)
 	if err != nil {
@@ -16,6 +16,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/vertica/vcluster/vclusterops"
@@ -123,16 +125,21 @@ func (c *CmdRemoveSubcluster) Analyze(_ vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdRemoveSubcluster) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdRemoveSubcluster) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
 	options := c.removeScOptions
 
-	vdb, err := vcc.VRemoveSubcluster(options)
+	vdb, err := vcc.VRemoveSubcluster(ctx, options)
 	if err != nil {
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	// write db info to vcluster config file
 	err = writeConfig(&vdb)
 	if err != nil {
@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -155,21 +156,27 @@ func (c *CmdRestartNodes) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.restartNodesOptions.DatabaseOptions)
 }
 
-func (c *CmdRestartNodes) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdRestartNodes) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
 	options := c.restartNodesOptions
 
 	// this is the instruction that will be used by both CLI and operator
-	err := vcc.VStartNodes(options)
+	err := vcc.VStartNodes(ctx, options)
 	if err != nil {
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	var hostToRestart []string
 	for _, ip := range options.Nodes {
 		hostToRestart = append(hostToRestart, ip)
 	}
+	globals.result.addHosts(hostToRestart, util.NodeUpState)
 	vcc.PrintInfo("Successfully restart hosts %s of the database %s", hostToRestart, options.DBName)
 
 	return nil
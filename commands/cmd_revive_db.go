@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/spf13/cobra"
@@ -165,20 +166,26 @@ func (c *CmdReviveDB) validateParse(logger vlog.Printer) error {
 	return c.ValidateParseBaseOptions(&c.reviveDBOptions.DatabaseOptions)
 }
 
-func (c *CmdReviveDB) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdReviveDB) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
-	dbInfo, vdb, err := vcc.VReviveDatabase(c.reviveDBOptions)
+	dbInfo, vdb, err := vcc.VReviveDatabase(ctx, c.reviveDBOptions)
 	if err != nil {
 		vcc.LogError(err, "fail to revive database", "DBName", c.reviveDBOptions.DBName)
 		return err
 	}
 
+	if c.reviveDBOptions.Plan {
+		c.printPlan(c.reviveDBOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	if c.reviveDBOptions.DisplayOnly {
 		c.writeCmdOutputToFile(globals.file, []byte(dbInfo), vcc.GetLog())
 		vcc.LogInfo("database details: ", "db-info", dbInfo)
 		return nil
 	}
 
+	globals.result.addDatabaseNodes(vdb)
 	// write db info to vcluster config file
 	err = writeConfig(vdb)
 	if err != nil {
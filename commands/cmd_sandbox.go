@@ -16,6 +16,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
 	"github.com/vertica/vcluster/vclusterops/vlog"
@@ -124,17 +126,22 @@ func (c *CmdSandboxSubcluster) Analyze(logger vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdSandboxSubcluster) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdSandboxSubcluster) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.PrintInfo("Running sandbox subcluster")
 	vcc.LogInfo("Calling method Run() for command " + sandboxSubCmd)
 
 	options := c.sbOptions
 
-	err := vcc.VSandbox(&options)
+	err := vcc.VSandbox(ctx, &options)
 	if err != nil {
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	defer vcc.PrintInfo("Successfully sandboxed subcluster " + c.sbOptions.SCName + " as " + c.sbOptions.SandboxName)
 	// Read and then update the sandbox information on config file
 	dbConfig, configErr := readConfig()
@@ -185,6 +185,13 @@ func (c *CmdScrutinize) setLocalFlags(cmd *cobra.Command) {
 		"Include information describing all UDX functions, "+
 			"which can be expensive to gather on Eon",
 	)
+	cmd.Flags().IntVar(
+		&c.sOptions.BatchSize,
+		"batch-size",
+		0,
+		"Number of hosts to stage and download the files of at a time, "+
+			"all of them at once if 0",
+	)
 }
 
 func (c *CmdScrutinize) Parse(inputArgv []string, logger vlog.Printer) error {
@@ -226,7 +233,7 @@ func (c *CmdScrutinize) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.sOptions.DatabaseOptions)
 }
 
-func (c *CmdScrutinize) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdScrutinize) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.PrintInfo("Running scrutinize") // TODO remove when no longer needed for tests
 	vcc.LogInfo("Calling method Run()")
 
@@ -248,11 +255,16 @@ func (c *CmdScrutinize) Run(vcc vclusterops.ClusterCommands) error {
 		return err
 	}
 
-	err = vcc.VScrutinize(&c.sOptions)
+	err = vcc.VScrutinize(ctx, &c.sOptions)
 	if err != nil {
 		vcc.LogError(err, "scrutinize run failed")
 		return err
 	}
+
+	if c.sOptions.Plan {
+		c.printPlan(c.sOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
 	vcc.PrintInfo("Successfully completed scrutinize run for the database %s", c.sOptions.DBName)
 	return err
 }
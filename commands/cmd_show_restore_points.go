@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/spf13/cobra"
@@ -155,16 +156,21 @@ func (c *CmdShowRestorePoints) Analyze(logger vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdShowRestorePoints) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdShowRestorePoints) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
 	options := c.showRestorePointsOptions
 
-	restorePoints, err := vcc.VShowRestorePoints(options)
+	restorePoints, err := vcc.VShowRestorePoints(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "fail to show restore points", "DBName", options.DBName)
 		return err
 	}
+
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
 	bytes, err := json.MarshalIndent(restorePoints, "", "  ")
 	if err != nil {
 		return err
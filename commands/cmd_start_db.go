@@ -16,6 +16,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -161,17 +163,23 @@ func (c *CmdStartDB) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.startDBOptions.DatabaseOptions)
 }
 
-func (c *CmdStartDB) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdStartDB) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
 	options := c.startDBOptions
 
-	vdb, err := vcc.VStartDatabase(options)
+	vdb, err := vcc.VStartDatabase(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "failed to start the database")
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	globals.result.addDatabaseNodes(vdb)
 	vcc.PrintInfo("Successfully start the database %s", options.DBName)
 
 	// for Eon database, update config file to fill nodes' subcluster information
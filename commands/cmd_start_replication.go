@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -225,16 +226,21 @@ func (c *CmdStartReplication) parseTargetPassword() error {
 	return nil
 }
 
-func (c *CmdStartReplication) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdStartReplication) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	options := c.startRepOptions
 
-	err := vcc.VReplicateDatabase(options)
+	err := vcc.VReplicateDatabase(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "fail to replicate to database", "targetDB", options.TargetDB)
 		return err
 	}
+
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
 	vcc.PrintInfo("Successfully replicate to database %s", options.TargetDB)
 	return nil
 }
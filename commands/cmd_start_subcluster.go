@@ -16,6 +16,8 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/vertica/vcluster/vclusterops"
@@ -120,16 +122,21 @@ func (c *CmdStartSubcluster) Analyze(_ vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdStartSubcluster) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdStartSubcluster) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.V(1).Info("Called method Run()")
 
 	options := c.startScOptions
 
-	err := vcc.VStartSubcluster(options)
+	err := vcc.VStartSubcluster(ctx, options)
 	if err != nil {
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	vcc.PrintInfo("Successfully started subcluster %s for database %s",
 		options.SubclusterToStart, options.DBName)
 
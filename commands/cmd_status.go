@@ -0,0 +1,193 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+/* CmdStatus
+ *
+ * Implements ClusterCommand interface
+ */
+type CmdStatus struct {
+	statusOptions *vclusterops.VClusterStatusOptions
+
+	CmdBase
+}
+
+func makeCmdStatus() *cobra.Command {
+	newCmd := &CmdStatus{}
+
+	opt := vclusterops.VClusterStatusOptionsFactory()
+	newCmd.statusOptions = &opt
+
+	cmd := makeBasicCobraCmd(
+		newCmd,
+		statusSubCmd,
+		"Show an overview of the database",
+		`This subcommand shows, for each subcluster of the database, whether it is
+primary or secondary and the sandbox it is in, and for each of its nodes, its
+state, version, number of shard subscriptions, when it went down if it is down,
+and how full the disks of its storage locations are.
+
+It also flags the anomalies it finds: the nodes that are down, the nodes that
+run different versions, the main cluster or a sandbox with no more than half of
+its primary nodes up, the storage locations whose disk is almost full, and the
+nodes that did not return their details.
+
+The overview is shown as a table. Use --output json for a JSON document that
+scripts can rely on.
+
+Examples:
+  # Show the status of the database with config file
+  vcluster status --password testpassword \
+    --config /opt/vertica/config/vertica_cluster.yaml
+
+  # Show the status of the database as a JSON document
+  vcluster status --db-name test_db --hosts 10.20.30.40 --output json
+`,
+		[]string{dbNameFlag, hostsFlag, passwordFlag, ipv6Flag, configFlag, outputFileFlag},
+	)
+
+	return cmd
+}
+
+func (c *CmdStatus) Parse(inputArgv []string, logger vlog.Printer) error {
+	c.argv = inputArgv
+	logger.LogArgParse(&c.argv)
+
+	// for some options, we do not want to use their default values,
+	// if they are not provided in cli,
+	// reset the value of those options to nil
+	c.ResetUserInputOptions(&c.statusOptions.DatabaseOptions)
+	return c.validateParse(logger)
+}
+
+func (c *CmdStatus) validateParse(logger vlog.Printer) error {
+	logger.Info("Called validateParse()", "command", statusSubCmd)
+	err := c.getCertFilesFromCertPaths(&c.statusOptions.DatabaseOptions)
+	if err != nil {
+		return err
+	}
+
+	err = c.ValidateParseBaseOptions(&c.statusOptions.DatabaseOptions)
+	if err != nil {
+		return err
+	}
+	return c.setDBPassword(&c.statusOptions.DatabaseOptions)
+}
+
+func (c *CmdStatus) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
+	vcc.V(1).Info("Called method Run()")
+
+	status, err := vcc.VClusterStatus(ctx, c.statusOptions)
+	if err != nil {
+		vcc.PrintError("fail to get the status of the database: %s", err)
+		return err
+	}
+
+	if c.statusOptions.Plan {
+		c.printPlan(c.statusOptions.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
+	table := renderClusterStatus(status)
+	if globals.result != nil {
+		globals.result.setOutputValue(status)
+		globals.result.setOutputTable(table)
+		return nil
+	}
+	c.writeCmdOutputToFile(globals.file, table, vcc.GetLog())
+	return nil
+}
+
+// SetDatabaseOptions will assign a vclusterops.DatabaseOptions instance to the one in CmdStatus
+func (c *CmdStatus) SetDatabaseOptions(opt *vclusterops.DatabaseOptions) {
+	c.statusOptions.DatabaseOptions = *opt
+}
+
+// renderClusterStatus renders the status as a table of the nodes, grouped by
+// subcluster, followed by the anomalies
+func renderClusterStatus(status *vclusterops.ClusterStatus) []byte {
+	const padding = 2
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "DATABASE: %s\n\n", status.Database)
+	w := tabwriter.NewWriter(&buf, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(w, "SUBCLUSTER\tROLE\tSANDBOX\tNODE\tADDRESS\tSTATE\tVERSION\tSHARDS\tDOWN SINCE\tSTORAGE")
+	for i := range status.Subclusters {
+		subcluster := &status.Subclusters[i]
+		role := "secondary"
+		if subcluster.IsPrimary {
+			role = "primary"
+		}
+		for j := range subcluster.Nodes {
+			node := &subcluster.Nodes[j]
+			shards := "-"
+			if node.ShardSubscriptions != nil {
+				shards = strconv.FormatUint(uint64(*node.ShardSubscriptions), 10)
+			}
+			var storage []string
+			for k := range node.StorageLocations {
+				location := &node.StorageLocations[k]
+				storage = append(storage, fmt.Sprintf("%s %s", location.UsageType, location.DiskPercent))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", statusCell(subcluster.Name), role,
+				statusCell(subcluster.Sandbox), node.Name, node.Address, node.State, statusCell(node.Version), shards,
+				statusCell(node.DownSince), statusCell(strings.Join(storage, "; ")))
+		}
+	}
+	w.Flush()
+
+	if len(status.Anomalies) == 0 {
+		buf.WriteString("\nNo anomalies found\n")
+		return buf.Bytes()
+	}
+	buf.WriteString("\nANOMALIES:\n")
+	for _, anomaly := range status.Anomalies {
+		var scope []string
+		if anomaly.Sandbox != "" {
+			scope = append(scope, "sandbox "+anomaly.Sandbox)
+		}
+		if anomaly.Node != "" {
+			scope = append(scope, anomaly.Node)
+		}
+		if len(scope) > 0 {
+			fmt.Fprintf(&buf, "- %s (%s): %s\n", anomaly.Kind, strings.Join(scope, ", "), anomaly.Detail)
+		} else {
+			fmt.Fprintf(&buf, "- %s: %s\n", anomaly.Kind, anomaly.Detail)
+		}
+	}
+	return buf.Bytes()
+}
+
+// statusCell returns value, or "-" when it is empty so that the columns of
+// the table stay aligned
+func statusCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -141,16 +142,22 @@ func (c *CmdStopDB) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.stopDBOptions.DatabaseOptions)
 }
 
-func (c *CmdStopDB) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdStopDB) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	options := c.stopDBOptions
 
-	err := vcc.VStopDatabase(options)
+	err := vcc.VStopDatabase(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "failed to stop the database")
 		return err
 	}
+
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+	globals.result.addHosts(options.Hosts, util.NodeDownState)
 	msg := fmt.Sprintf("Stopped a database with name %s", options.DBName)
 	if options.Sandbox != "" {
 		sandboxMsg := fmt.Sprintf(" on sandbox %s", options.Sandbox)
@@ -16,8 +16,11 @@
 package commands
 
 import (
+	"context"
+
 	"github.com/spf13/cobra"
 	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/util"
 	"github.com/vertica/vcluster/vclusterops/vlog"
 )
 
@@ -97,16 +100,22 @@ func (c *CmdStopNode) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.stopNodeOptions.DatabaseOptions)
 }
 
-func (c *CmdStopNode) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdStopNode) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	options := c.stopNodeOptions
 
-	err := vcc.VStopNode(options)
+	err := vcc.VStopNode(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "failed to stop the nodes", "Nodes", c.stopNodeOptions.StopHosts)
 		return err
 	}
+
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+	globals.result.addHosts(c.stopNodeOptions.StopHosts, util.NodeDownState)
 	vcc.PrintInfo("Successfully stopped the nodes %v", c.stopNodeOptions.StopHosts)
 	return nil
 }
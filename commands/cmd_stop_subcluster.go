@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/spf13/cobra"
@@ -142,16 +143,21 @@ func (c *CmdStopSubcluster) validateParse(logger vlog.Printer) error {
 	return c.setDBPassword(&c.stopSCOptions.DatabaseOptions)
 }
 
-func (c *CmdStopSubcluster) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdStopSubcluster) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.LogInfo("Called method Run()")
 
 	options := c.stopSCOptions
 
-	err := vcc.VStopSubcluster(options)
+	err := vcc.VStopSubcluster(ctx, options)
 	if err != nil {
 		vcc.LogError(err, "failed to stop the subcluster", "Subcluster", options.SCName)
 		return err
 	}
+
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
 	vcc.PrintInfo("Successfully stopped subcluster %s", options.SCName)
 	return nil
 }
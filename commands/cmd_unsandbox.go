@@ -16,6 +16,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -124,17 +125,22 @@ func (c *CmdUnsandboxSubcluster) Analyze(logger vlog.Printer) error {
 	return nil
 }
 
-func (c *CmdUnsandboxSubcluster) Run(vcc vclusterops.ClusterCommands) error {
+func (c *CmdUnsandboxSubcluster) Run(ctx context.Context, vcc vclusterops.ClusterCommands) error {
 	vcc.PrintInfo("Running unsandbox subcluster")
 	vcc.LogInfo("Calling method Run() for command " + unsandboxSubCmd)
 
 	options := c.usOptions
 
-	err := vcc.VUnsandbox(&options)
+	err := vcc.VUnsandbox(ctx, &options)
 	if err != nil {
 		return err
 	}
 
+	if options.Plan {
+		c.printPlan(options.GetPlan(), vcc.GetLog())
+		return nil
+	}
+
 	defer vcc.PrintInfo("Successfully unsandboxed subcluster " + c.usOptions.SCName)
 	// Read and then update the sandbox information on config file
 	dbConfig, configErr := c.resetSandboxInfo()
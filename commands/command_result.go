@@ -0,0 +1,484 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/util"
+	"golang.org/x/exp/maps"
+	"gopkg.in/yaml.v3"
+)
+
+// the formats of the result document of a command
+const (
+	outputFormatJSON  = "json"
+	outputFormatYAML  = "yaml"
+	outputFormatTable = "table"
+)
+
+// the status of a command in its result document
+const (
+	resultSucceeded = "succeeded"
+	resultFailed    = "failed"
+	resultPlanned   = "planned"
+)
+
+// the state of the nodes removed by a command, in its result document
+const removedNodeState = "REMOVED"
+
+// the type of the problems that are not a VProblem, as rfc7807 defines it for
+// the problems that have no more semantics than their title
+const blankProblemType = "about:blank"
+
+// commandResult is the document a command run with --output writes instead
+// of its usual output. Its fields are stable, so that scripts can rely on
+// them.
+type commandResult struct {
+	Command         string    `json:"command"`
+	Database        string    `json:"database,omitempty"`
+	Status          string    `json:"status"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	// Nodes are the nodes the command acted on, with their state once it
+	// completed
+	Nodes []resultNode `json:"nodes,omitempty"`
+	// Ops are the ops that completed, were skipped or failed, in the order
+	// they ended
+	Ops      []resultOp `json:"ops,omitempty"`
+	Warnings []string   `json:"warnings,omitempty"`
+	// Output is what the command prints otherwise, such as the node states
+	// of list_allnodes
+	Output any                       `json:"output,omitempty"`
+	Plan   *vclusterops.VClusterPlan `json:"plan,omitempty"`
+	// Error is the error of a failed command, shaped as an rfc7807 problem
+	Error *rfc7807.VProblem `json:"error,omitempty"`
+
+	mu sync.Mutex
+	// outputTable is how the command renders its output as a table, nil to
+	// render it from the document
+	outputTable []byte
+	// written is set once the document is written
+	written bool
+}
+
+type resultNode struct {
+	Name       string `json:"name,omitempty"`
+	Address    string `json:"address"`
+	Subcluster string `json:"subcluster,omitempty"`
+	State      string `json:"state,omitempty"`
+}
+
+type resultOp struct {
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// startCommandResult starts the result document of the command, if it is run
+// with --output
+func startCommandResult(command string) error {
+	globals.result = nil
+	switch globals.outputFormat {
+	case "":
+		return nil
+	case outputFormatJSON, outputFormatYAML, outputFormatTable:
+	default:
+		return fmt.Errorf("invalid value %q for --%s, it must be one of %s, %s or %s", globals.outputFormat,
+			outputFormatFlag, outputFormatJSON, outputFormatYAML, outputFormatTable)
+	}
+	globals.result = &commandResult{Command: command, StartTime: time.Now().UTC()}
+	return nil
+}
+
+// writeCommandResult ends the result document of the command with its error,
+// and writes it to the output file. It returns false if the command is not
+// run with --output.
+func writeCommandResult(err error) bool {
+	result := globals.result
+	if result == nil {
+		return false
+	}
+	if result.isWritten() {
+		return true
+	}
+	result.finish(err)
+	data, renderErr := result.render(globals.outputFormat)
+	if renderErr != nil {
+		fmt.Fprintf(os.Stderr, "fail to write the result of the command: %v\n", renderErr)
+		return false
+	}
+	f := globals.file
+	if f == nil {
+		f = os.Stdout
+	}
+	if _, writeErr := f.Write(data); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "fail to write the result of the command: %v\n", writeErr)
+	}
+	result.mu.Lock()
+	result.written = true
+	result.mu.Unlock()
+	return true
+}
+
+// printCmdMessage prints a message of the command to the console, unless the
+// command is run with --output, whose result document is its only output
+func printCmdMessage(msg string, v ...any) {
+	if globals.result != nil {
+		return
+	}
+	fmt.Printf(msg, v...)
+}
+
+// printCmdWarning prints a warning of the command to the console, or adds
+// it to the result document of the command run with --output
+func printCmdWarning(msg string, v ...any) {
+	if globals.result != nil {
+		globals.result.addWarning(fmt.Sprintf(msg, v...))
+		return
+	}
+	fmt.Printf("Warning: "+msg+"\n", v...)
+}
+
+func (r *commandResult) isWritten() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.written
+}
+
+// finish sets the end of the command and its status
+func (r *commandResult) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Database = dbOptions.DBName
+	r.EndTime = time.Now().UTC()
+	r.DurationSeconds = r.EndTime.Sub(r.StartTime).Seconds()
+	switch {
+	case err != nil:
+		r.Status = resultFailed
+		r.Error = makeResultProblem(err)
+	case r.Plan != nil:
+		r.Status = resultPlanned
+	default:
+		r.Status = resultSucceeded
+	}
+}
+
+// makeResultProblem returns the rfc7807 problem of err. The problem type
+// and title of a VProblem returned by a host are kept, the detail is the
+// whole error.
+func makeResultProblem(err error) *rfc7807.VProblem {
+	problem := &rfc7807.VProblem{
+		ProblemID: rfc7807.ProblemID{Type: blankProblemType, Title: "Command failed"},
+		Detail:    err.Error(),
+	}
+	var vproblem *rfc7807.VProblem
+	var lockedErr *vclusterops.DatabaseLockedError
+	var vetoErr *vclusterops.HookVetoError
+	var cancelErr *vclusterops.ClusterOpCancelledError
+	switch {
+	case errors.As(err, &vproblem):
+		problem.ProblemID = vproblem.ProblemID
+		problem.Host = vproblem.Host
+	case errors.As(err, &lockedErr):
+		problem.Title = "Database locked"
+	case errors.As(err, &vetoErr):
+		problem.Title = "Vetoed by a hook"
+	case errors.Is(err, context.DeadlineExceeded):
+		problem.Title = "Deadline exceeded"
+	case errors.As(err, &cancelErr):
+		problem.Title = "Command cancelled"
+	}
+	return problem
+}
+
+// setOutput sets the output of the command. Output that is JSON is kept as
+// is, other output is kept as a string.
+func (r *commandResult) setOutput(output []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if json.Valid(output) {
+		r.Output = json.RawMessage(output)
+	} else {
+		r.Output = string(output)
+	}
+}
+
+// setOutputValue sets the output of the command to value
+func (r *commandResult) setOutputValue(value any) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Output = value
+}
+
+// setOutputTable sets how the output of the command is rendered in the table
+// format
+func (r *commandResult) setOutputTable(table []byte) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outputTable = table
+}
+
+func (r *commandResult) setPlan(plan *vclusterops.VClusterPlan) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Plan = plan
+}
+
+func (r *commandResult) addWarning(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// addNodeStates adds the nodes of nodeStates
+func (r *commandResult) addNodeStates(nodeStates []vclusterops.NodeInfo) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range nodeStates {
+		node := &nodeStates[i]
+		r.Nodes = append(r.Nodes, resultNode{Name: node.Name, Address: node.Address,
+			Subcluster: node.Subcluster, State: node.State})
+	}
+}
+
+// addDatabaseNodes adds the nodes of vdb, in the order of their names
+func (r *commandResult) addDatabaseNodes(vdb *vclusterops.VCoordinationDatabase) {
+	if r == nil || vdb == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nodes := maps.Values(vdb.HostNodeMap)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, node := range nodes {
+		r.Nodes = append(r.Nodes, resultNode{Name: node.Name, Address: node.Address,
+			Subcluster: node.Subcluster, State: node.State})
+	}
+}
+
+// addHosts adds the nodes on hosts, which are all in state
+func (r *commandResult) addHosts(hosts []string, state string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, host := range hosts {
+		r.Nodes = append(r.Nodes, resultNode{Address: host, State: state})
+	}
+}
+
+// observer returns an observer that records the ops that ended, and passes
+// the events on to next
+func (r *commandResult) observer(next vclusterops.OpObserver) vclusterops.OpObserver {
+	if r == nil {
+		return next
+	}
+	return vclusterops.OpObserverFunc(func(event vclusterops.OpEvent) {
+		switch event.Type {
+		case vclusterops.OpEventFinished, vclusterops.OpEventSkipped, vclusterops.OpEventFailed:
+			r.mu.Lock()
+			r.Ops = append(r.Ops, resultOp{Name: event.OpName, Status: strings.TrimPrefix(string(event.Type), "op_"),
+				DurationSeconds: event.ElapsedSeconds, Error: event.Error})
+			r.mu.Unlock()
+		}
+		if next != nil {
+			next.OnOpEvent(event)
+		}
+	})
+}
+
+// render returns the document in format
+func (r *commandResult) render(format string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if format == outputFormatJSON {
+		return append(data, '\n'), nil
+	}
+	// JSON is YAML, so the document is decoded as YAML to keep the order and
+	// the names of its fields
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	clearYAMLStyle(&doc)
+	if format == outputFormatYAML {
+		return yaml.Marshal(&doc)
+	}
+	return r.renderTable(doc.Content[0]), nil
+}
+
+// clearYAMLStyle resets the flow and quoted styles of the nodes decoded from
+// JSON, so that they are written in the block style
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
+
+// renderTable renders the document as tables, for the console
+func (r *commandResult) renderTable(doc *yaml.Node) []byte {
+	const padding = 2
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, padding, ' ', 0)
+	fmt.Fprintf(w, "COMMAND:\t%s\n", r.Command)
+	if r.Database != "" {
+		fmt.Fprintf(w, "DATABASE:\t%s\n", r.Database)
+	}
+	fmt.Fprintf(w, "STATUS:\t%s\n", r.Status)
+	fmt.Fprintf(w, "DURATION:\t%s\n", time.Duration(r.DurationSeconds*float64(time.Second)).Round(time.Millisecond))
+	if r.Error != nil {
+		fmt.Fprintf(w, "ERROR:\t%s: %s\n", r.Error.Title, strings.ReplaceAll(r.Error.Detail, "\n", " "))
+	}
+	w.Flush()
+	for _, section := range []string{"nodes", "ops", "plan", "output"} {
+		value := yamlMappingValue(doc, section)
+		if value == nil {
+			continue
+		}
+		if section == "plan" {
+			value = yamlMappingValue(value, "ops")
+		}
+		fmt.Fprintf(&buf, "\n%s:\n", strings.ToUpper(section))
+		if section == "output" && r.outputTable != nil {
+			buf.Write(r.outputTable)
+			continue
+		}
+		writeYAMLTable(&buf, value)
+	}
+	if len(r.Warnings) > 0 {
+		buf.WriteString("\nWARNINGS:\n")
+		for _, warning := range r.Warnings {
+			fmt.Fprintf(&buf, "- %s\n", warning)
+		}
+	}
+	return buf.Bytes()
+}
+
+// yamlMappingValue returns the value of key in mapping, nil if it has none
+func yamlMappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// writeYAMLTable writes a list of objects as a table with a column for each
+// field, an object as a table of its fields, and anything else as is
+func writeYAMLTable(buf *bytes.Buffer, node *yaml.Node) {
+	const padding = 2
+	w := tabwriter.NewWriter(buf, 0, 0, padding, ' ', 0)
+	defer w.Flush()
+	switch {
+	case node == nil:
+	case node.Kind == yaml.SequenceNode && len(node.Content) > 0 && node.Content[0].Kind == yaml.MappingNode:
+		var columns []string
+		for _, item := range node.Content {
+			for i := 0; i+1 < len(item.Content); i += 2 {
+				if !util.StringInArray(item.Content[i].Value, columns) {
+					columns = append(columns, item.Content[i].Value)
+				}
+			}
+		}
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, item := range node.Content {
+			cells := make([]string, len(columns))
+			for i, column := range columns {
+				cells[i] = yamlCell(yamlMappingValue(item, column))
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+	case node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			fmt.Fprintf(w, "%s:\t%s\n", node.Content[i].Value, yamlCell(node.Content[i+1]))
+		}
+	default:
+		fmt.Fprintln(w, yamlCell(node))
+	}
+}
+
+// yamlCell returns the value of node in a table cell: a scalar as is, a list
+// of scalars joined with commas, and anything else as JSON
+func yamlCell(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return strings.ReplaceAll(node.Value, "\n", " ")
+	case yaml.SequenceNode:
+		var values []string
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				values = nil
+				break
+			}
+			values = append(values, item.Value)
+		}
+		if len(values) == len(node.Content) {
+			return strings.Join(values, ",")
+		}
+	}
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,97 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCommandResult(t *testing.T) {
+	defer func() { globals.outputFormat, globals.result, globals.file = "", nil, nil }()
+	globals.outputFormat = "xml"
+	assert.ErrorContains(t, startCommandResult(listAllNodesSubCmd), "invalid value \"xml\" for --output")
+	assert.Nil(t, globals.result)
+
+	globals.outputFormat = outputFormatJSON
+	assert.NoError(t, startCommandResult(listAllNodesSubCmd))
+	result := globals.result
+	events := 0
+	observer := result.observer(vclusterops.OpObserverFunc(func(_ vclusterops.OpEvent) { events++ }))
+	observer.OnOpEvent(vclusterops.OpEvent{Type: vclusterops.OpEventStarted, OpName: "HTTPSGetUpNodesOp"})
+	observer.OnOpEvent(vclusterops.OpEvent{Type: vclusterops.OpEventFinished, OpName: "HTTPSGetUpNodesOp",
+		ElapsedSeconds: 1.5})
+	assert.Equal(t, 2, events)
+	result.addWarning("host 192.168.1.102 is slow")
+	result.addNodeStates([]vclusterops.NodeInfo{{Name: "v_db_node0001", Address: "192.168.1.101", State: "UP"}})
+	result.setOutput([]byte(`[{"address":"192.168.1.101","state":"UP"}]`))
+
+	fileName := filepath.Join(t.TempDir(), "result.json")
+	f, err := os.Create(fileName)
+	assert.NoError(t, err)
+	globals.file = f
+	assert.True(t, writeCommandResult(nil))
+	// the document is only written once
+	assert.True(t, writeCommandResult(fmt.Errorf("some error")))
+	f.Close()
+	data, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	var doc map[string]any
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, listAllNodesSubCmd, doc["command"])
+	assert.Equal(t, resultSucceeded, doc["status"])
+	assert.Equal(t, []any{map[string]any{"name": "HTTPSGetUpNodesOp", "status": "finished", "duration_seconds": 1.5}},
+		doc["ops"])
+	assert.Equal(t, []any{"host 192.168.1.102 is slow"}, doc["warnings"])
+	assert.Equal(t, []any{map[string]any{"address": "192.168.1.101", "state": "UP"}}, doc["output"])
+	assert.NotContains(t, doc, "error")
+
+	// the error of a failed command is an rfc7807 problem
+	result = &commandResult{Command: stopDBSubCmd}
+	vproblem := rfc7807.New(rfc7807.CommunalStorageNotEmpty).WithDetail("access denied").WithHost("192.168.1.101")
+	result.finish(fmt.Errorf("fail to stop the database: %w", vproblem))
+	assert.Equal(t, resultFailed, result.Status)
+	assert.Equal(t, rfc7807.CommunalStorageNotEmpty, result.Error.ProblemID)
+	assert.Equal(t, "192.168.1.101", result.Error.Host)
+	assert.Contains(t, result.Error.Detail, "fail to stop the database")
+	result.finish(&vclusterops.DatabaseLockedError{Path: "vcluster_db.lock"})
+	assert.Equal(t, blankProblemType, result.Error.Type)
+	assert.Equal(t, "Database locked", result.Error.Title)
+
+	// the YAML document has the fields of the JSON one, in the same order
+	data, err = result.render(outputFormatYAML)
+	assert.NoError(t, err)
+	var node yaml.Node
+	assert.NoError(t, yaml.Unmarshal(data, &node))
+	assert.Equal(t, "command", node.Content[0].Content[0].Value)
+	assert.Equal(t, stopDBSubCmd, node.Content[0].Content[1].Value)
+	assert.Equal(t, "Database locked", yamlMappingValue(yamlMappingValue(node.Content[0], "error"), "title").Value)
+
+	// the table has a column for each field of the nodes
+	result.addHosts([]string{"192.168.1.101", "192.168.1.102"}, "DOWN")
+	data, err = result.render(outputFormatTable)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "STATUS:    failed\n")
+	assert.Contains(t, string(data), "NODES:\nADDRESS        STATE\n192.168.1.101  DOWN\n192.168.1.102  DOWN\n")
+}
@@ -0,0 +1,63 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+// jsonEventWriter is an observer of the op engine that writes each event on
+// its own line as a JSON object
+type jsonEventWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func makeJSONEventWriter(w io.Writer) *jsonEventWriter {
+	return &jsonEventWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *jsonEventWriter) OnOpEvent(event vclusterops.OpEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Encode adds the trailing newline. An event that cannot be written is
+	// dropped because it must not fail the operation it reports on.
+	if err := w.encoder.Encode(event); err != nil {
+		fmt.Fprintf(os.Stderr, "fail to write event %s of %s: %v\n", event.Type, event.OpName, err)
+	}
+}
+
+// openEventsFile opens the file the events are written to. It returns nil if
+// no events file was asked for, and stdout if the file name is a hyphen(`-`).
+func openEventsFile(fileName string) (*os.File, error) {
+	if fileName == "" {
+		return nil, nil
+	}
+	if fileName == "-" {
+		return os.Stdout, nil
+	}
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, outputFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open the events file %q: %w", fileName, err)
+	}
+	return f, nil
+}
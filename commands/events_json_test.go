@@ -0,0 +1,51 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+func TestJSONEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := makeJSONEventWriter(&buf)
+	writer.OnOpEvent(vclusterops.OpEvent{Type: vclusterops.OpEventStarted, OpName: "op1"})
+	writer.OnOpEvent(vclusterops.OpEvent{Type: vclusterops.OpEventRequestResult, OpName: "op1",
+		Host: "192.168.1.101", Status: "SUCCESS", StatusCode: 200})
+
+	// each event is a JSON object on its own line
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	var event vclusterops.OpEvent
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, vclusterops.OpEventRequestResult, event.Type)
+	assert.Equal(t, "192.168.1.101", event.Host)
+	assert.Equal(t, 200, event.StatusCode)
+
+	f, err := openEventsFile("")
+	assert.NoError(t, err)
+	assert.Nil(t, f)
+	f, err = openEventsFile("-")
+	assert.NoError(t, err)
+	assert.Equal(t, os.Stdout, f)
+}
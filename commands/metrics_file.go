@@ -0,0 +1,49 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+// writeMetricsFile writes the metrics of the command to fileName in the
+// Prometheus text format, for the textfile collector of the node exporter.
+// The file is replaced as a whole, so the collector never reads a partial
+// one.
+func writeMetricsFile(fileName string, metrics *vclusterops.Metrics) error {
+	if fileName == "" || metrics == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	err := metrics.WriteText(&buf)
+	if err != nil {
+		return fmt.Errorf("fail to format the metrics: %w", err)
+	}
+	tmpFileName := fileName + ".tmp"
+	err = os.WriteFile(tmpFileName, buf.Bytes(), outputFilePerm)
+	if err != nil {
+		return fmt.Errorf("fail to write the metrics file %q: %w", tmpFileName, err)
+	}
+	err = os.Rename(tmpFileName, fileName)
+	if err != nil {
+		return fmt.Errorf("fail to replace the metrics file %q: %w", fileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+func TestWriteMetricsFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "vcluster.prom")
+	assert.NoError(t, os.WriteFile(fileName, []byte("stale"), outputFilePerm))
+
+	assert.NoError(t, writeMetricsFile(fileName, vclusterops.NewMetrics()))
+	data, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	// the metric families without samples are not written
+	assert.Empty(t, string(data))
+	_, err = os.Stat(fileName + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	// nothing is written without a file name
+	assert.NoError(t, writeMetricsFile("", vclusterops.NewMetrics()))
+}
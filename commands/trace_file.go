@@ -0,0 +1,63 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/vertica/vcluster/vclusterops"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// setTracerProvider makes the spans of the command be written to fileName,
+// with the stdout exporter of OpenTelemetry, if a trace file was asked for.
+// A hyphen(`-`) stands for stdout. The spans are exported as they end, so
+// that the file has them even if the command fails. The returned function
+// shuts the tracer provider down and closes the file.
+func setTracerProvider(fileName string, options *vclusterops.DatabaseOptions,
+	vcc vclusterops.VClusterCommands) (closeTrace func(), err error) {
+	if fileName == "" {
+		return func() {}, nil
+	}
+	f := os.Stdout
+	if fileName != "-" {
+		f, err = os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, outputFilePerm)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open the trace file %q: %w", fileName, err)
+		}
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		closeFile(f)
+		return nil, fmt.Errorf("fail to create the trace exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "vcluster"))),
+	)
+	options.TracerProvider = provider
+	return func() {
+		if err := provider.Shutdown(context.Background()); err != nil {
+			vcc.PrintWarning("some spans could not be written to the trace file: %v", err)
+		}
+		closeFile(f)
+	}, nil
+}
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/vertica/vcluster/vclusterops"
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -49,6 +51,46 @@ type DatabaseConfig struct {
 	IsEon                   bool          `yaml:"eonMode" mapstructure:"eonMode"`
 	CommunalStorageLocation string        `yaml:"communalStorageLocation" mapstructure:"communalStorageLocation"`
 	Ipv6                    bool          `yaml:"ipv6" mapstructure:"ipv6"`
+	// Ports of the NMA and of the HTTPS service on the hosts, when they are not
+	// the default ones. The ports of a node take precedence.
+	NMAPort   int `yaml:"nmaPort,omitempty" mapstructure:"nmaPort"`
+	HTTPSPort int `yaml:"httpsPort,omitempty" mapstructure:"httpsPort"`
+	// Timeouts of the commands run on the database, when they are not the default ones
+	Timeouts *TimeoutConfig `yaml:"timeouts,omitempty" mapstructure:"timeouts"`
+	// Hooks run before and after the commands run on the database
+	Hooks []*HookConfig `yaml:"hooks,omitempty" mapstructure:"hooks"`
+}
+
+// TimeoutConfig contains the timeouts, in seconds, of the commands run on the
+// database. The timeouts passed on the command line take precedence.
+type TimeoutConfig struct {
+	// Request is the timeout of each request, for the operations that do not
+	// set their own. -1 means no timeout.
+	Request int `yaml:"request,omitempty" mapstructure:"request"`
+	// Ops are the timeouts by operation name. For a polling operation, it is
+	// the time the operation polls for.
+	Ops map[string]int `yaml:"ops,omitempty" mapstructure:"ops"`
+	// Deadline is the time a command has to complete
+	Deadline int `yaml:"deadline,omitempty" mapstructure:"deadline"`
+}
+
+// HookConfig contains a program run before or after the commands, or some
+// of their operations. The program reads the context of the hook, such as
+// the database, the subcluster and the hosts, as JSON on its standard input.
+// A pre hook that exits with a non-zero status vetoes the command or the
+// operation.
+type HookConfig struct {
+	Name string `yaml:"name" mapstructure:"name"`
+	// When is "pre" or "post"
+	When string `yaml:"when" mapstructure:"when"`
+	// Commands are the names of the commands, as in the audit log, such as
+	// stop_subcluster. The hook runs for all the commands if there are none.
+	Commands []string `yaml:"commands,omitempty" mapstructure:"commands"`
+	// Ops are the names of the operations the hook runs around, such as
+	// HTTPSDrainSubclusterOp. Without them, the hook runs around the command.
+	Ops []string `yaml:"ops,omitempty" mapstructure:"ops"`
+	// Run is the program and its arguments
+	Run []string `yaml:"run" mapstructure:"run"`
 }
 
 // NodeConfig contains node information in the database
@@ -60,6 +102,9 @@ type NodeConfig struct {
 	DataPath    string `yaml:"dataPath" mapstructure:"dataPath"`
 	DepotPath   string `yaml:"depotPath" mapstructure:"depotPath"`
 	Sandbox     string `yaml:"sandbox" mapstructure:"sandbox"` // Name of the sandbox the node belongs to
+	// Ports of the NMA and of the HTTPS service, when they are not the default ones
+	NMAPort   int `yaml:"nmaPort,omitempty" mapstructure:"nmaPort"`
+	HTTPSPort int `yaml:"httpsPort,omitempty" mapstructure:"httpsPort"`
 }
 
 // MakeDatabaseConfig() can create an instance of DatabaseConfig
@@ -150,7 +195,7 @@ func loadConfigToViper() error {
 	viper.SetConfigFile(dbOptions.ConfigPath)
 	err := viper.ReadInConfig()
 	if err != nil {
-		fmt.Printf("Warning: fail to read configuration file %q for viper: %v\n", dbOptions.ConfigPath, err)
+		printCmdWarning("fail to read configuration file %q for viper: %v", dbOptions.ConfigPath, err)
 		return nil
 	}
 
@@ -158,7 +203,7 @@ func loadConfigToViper() error {
 	dbConfig := MakeDatabaseConfig()
 	err = viper.Unmarshal(&dbConfig)
 	if err != nil {
-		fmt.Printf("Warning: fail to unmarshal configuration file into DatabaseConfig: %v\n", err)
+		printCmdWarning("fail to unmarshal configuration file into DatabaseConfig: %v", err)
 		return nil
 	}
 
@@ -182,6 +227,75 @@ func loadConfigToViper() error {
 	if !viper.IsSet(depotPathKey) {
 		viper.Set(depotPathKey, depotPrefix)
 	}
+	// the ports of the nodes override the ones of the command line, which
+	// override the ones of the database
+	dbOptions.HostPorts = dbConfig.getHostPorts()
+	globals.configPorts = vclusterops.HostPorts{NMAPort: dbConfig.NMAPort, HTTPSPort: dbConfig.HTTPSPort}
+	globals.configTimeouts = dbConfig.Timeouts
+	globals.configHooks = dbConfig.Hooks
+	return nil
+}
+
+// applyConfigTimeouts sets the timeouts read from the configuration file that
+// are not set on the command line
+func applyConfigTimeouts(flags *pflag.FlagSet) {
+	timeouts := globals.configTimeouts
+	if timeouts == nil {
+		return
+	}
+	if !flags.Changed(requestTimeoutFlag) {
+		dbOptions.RequestTimeout = timeouts.Request
+	}
+	if !flags.Changed(deadlineFlag) {
+		globals.deadline = timeouts.Deadline
+	}
+	if len(timeouts.Ops) == 0 {
+		return
+	}
+	// the op names are matched regardless of case, as viper lowercases the
+	// keys of the configuration file
+	opTimeouts := make(map[string]int, len(timeouts.Ops)+len(dbOptions.OpTimeouts))
+	for opName, timeout := range timeouts.Ops {
+		opTimeouts[strings.ToLower(opName)] = timeout
+	}
+	for opName, timeout := range dbOptions.OpTimeouts {
+		opTimeouts[strings.ToLower(opName)] = timeout
+	}
+	dbOptions.OpTimeouts = opTimeouts
+}
+
+// applyConfigPorts sets the ports of the database read from the
+// configuration file that are not set on the command line
+func applyConfigPorts(flags *pflag.FlagSet) {
+	if !flags.Changed(nmaPortFlag) && globals.configPorts.NMAPort != 0 {
+		dbOptions.NMAPort = globals.configPorts.NMAPort
+	}
+	if !flags.Changed(httpsPortFlag) && globals.configPorts.HTTPSPort != 0 {
+		dbOptions.HTTPSPort = globals.configPorts.HTTPSPort
+	}
+}
+
+// applyConfigHooks sets the hooks read from the configuration file
+func applyConfigHooks() error {
+	hooks := make([]vclusterops.Hook, 0, len(globals.configHooks))
+	for _, hookConfig := range globals.configHooks {
+		phase := vclusterops.HookPhase(hookConfig.When)
+		if phase != vclusterops.HookPhasePre && phase != vclusterops.HookPhasePost {
+			return fmt.Errorf("hook %q in the configuration file must run %q or %q, not %q",
+				hookConfig.Name, vclusterops.HookPhasePre, vclusterops.HookPhasePost, hookConfig.When)
+		}
+		if len(hookConfig.Run) == 0 {
+			return fmt.Errorf("hook %q in the configuration file has no program to run", hookConfig.Name)
+		}
+		hooks = append(hooks, vclusterops.Hook{
+			Name:     hookConfig.Name,
+			Phase:    phase,
+			Commands: hookConfig.Commands,
+			Ops:      hookConfig.Ops,
+			Run:      vclusterops.ExecHookRun(hookConfig.Run),
+		})
+	}
+	dbOptions.Hooks = hooks
 	return nil
 }
 
@@ -231,6 +345,10 @@ func readVDBToDBConfig(vdb *vclusterops.VCoordinationDatabase) (DatabaseConfig,
 		nodeConfig.Address = vnode.Address
 		nodeConfig.Subcluster = vnode.Subcluster
 		nodeConfig.Sandbox = vnode.Sandbox
+		// the ports are not in the catalog, we keep the ones read from the config file
+		ports := dbOptions.HostPorts[vnode.Address]
+		nodeConfig.NMAPort = ports.NMAPort
+		nodeConfig.HTTPSPort = ports.HTTPSPort
 
 		// VER-91869 will replace the path prefixes with full paths
 		if vdb.CatalogPrefix == "" {
@@ -255,6 +373,13 @@ func readVDBToDBConfig(vdb *vclusterops.VCoordinationDatabase) (DatabaseConfig,
 	dbConfig.CommunalStorageLocation = vdb.CommunalStorageLocation
 	dbConfig.Ipv6 = vdb.Ipv6
 	dbConfig.Name = vdb.Name
+	// the ports are not in the catalog, we keep the ones of the command line,
+	// such as the ones create_db was run with, or else the config file
+	dbConfig.NMAPort = dbOptions.NMAPort
+	dbConfig.HTTPSPort = dbOptions.HTTPSPort
+	// the timeouts are not in the catalog, we keep the ones read from the config file
+	dbConfig.Timeouts = globals.configTimeouts
+	dbConfig.Hooks = globals.configHooks
 
 	return dbConfig, nil
 }
@@ -262,8 +387,12 @@ func readVDBToDBConfig(vdb *vclusterops.VCoordinationDatabase) (DatabaseConfig,
 // read reads information from configFilePath to a DatabaseConfig object.
 // It returns any read error encountered.
 func readConfig() (dbConfig *DatabaseConfig, err error) {
-	configFilePath := dbOptions.ConfigPath
+	return readConfigFile(dbOptions.ConfigPath)
+}
 
+// readConfigFile reads the configuration file at configFilePath to a
+// DatabaseConfig object
+func readConfigFile(configFilePath string) (dbConfig *DatabaseConfig, err error) {
 	if configFilePath == "" {
 		return nil, fmt.Errorf("configuration file path is empty")
 	}
@@ -286,13 +415,9 @@ func readConfig() (dbConfig *DatabaseConfig, err error) {
 // work well(the order of keys cannot be customized) so we used yaml.Marshal()
 // and os.WriteFile() to write the config file.
 func (c *DatabaseConfig) write(configFilePath string) error {
-	var config Config
-	config.Version = currentConfigFileVersion
-	config.Database = *c
-
-	configBytes, err := yaml.Marshal(&config)
+	configBytes, err := c.marshal()
 	if err != nil {
-		return fmt.Errorf("fail to marshal configuration data, details: %w", err)
+		return err
 	}
 	err = os.WriteFile(configFilePath, configBytes, configFilePerm)
 	if err != nil {
@@ -302,6 +427,50 @@ func (c *DatabaseConfig) write(configFilePath string) error {
 	return nil
 }
 
+// writeAtomically writes configuration information to a temporary file next
+// to configFilePath, then renames it to configFilePath, so that the file is
+// either fully rewritten or left as it was
+func (c *DatabaseConfig) writeAtomically(configFilePath string) error {
+	configBytes, err := c.marshal()
+	if err != nil {
+		return err
+	}
+	tmpFile, err := os.CreateTemp(filepath.Dir(configFilePath), "."+filepath.Base(configFilePath)+".*")
+	if err != nil {
+		return fmt.Errorf("fail to create temporary configuration file, details: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(configBytes)
+	if err == nil {
+		err = tmpFile.Chmod(configFilePerm)
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, configFilePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("fail to write configuration file, details: %w", err)
+	}
+
+	return nil
+}
+
+// marshal returns the configuration file content of the database
+func (c *DatabaseConfig) marshal() ([]byte, error) {
+	var config Config
+	config.Version = currentConfigFileVersion
+	config.Database = *c
+
+	configBytes, err := yaml.Marshal(&config)
+	if err != nil {
+		return nil, fmt.Errorf("fail to marshal configuration data, details: %w", err)
+	}
+	return configBytes, nil
+}
+
 // getHosts returns host addresses of all nodes in database
 func (c *DatabaseConfig) getHosts() []string {
 	var hostList []string
@@ -313,6 +482,17 @@ func (c *DatabaseConfig) getHosts() []string {
 	return hostList
 }
 
+// getHostPorts returns the ports of the nodes that do not use the default ones
+func (c *DatabaseConfig) getHostPorts() map[string]vclusterops.HostPorts {
+	hostPorts := make(map[string]vclusterops.HostPorts)
+	for _, vnode := range c.Nodes {
+		if vnode.NMAPort != 0 || vnode.HTTPSPort != 0 {
+			hostPorts[vnode.Address] = vclusterops.HostPorts{NMAPort: vnode.NMAPort, HTTPSPort: vnode.HTTPSPort}
+		}
+	}
+	return hostPorts
+}
+
 // getPathPrefix returns catalog, data, and depot prefixes
 func (c *DatabaseConfig) getPathPrefixes() (catalogPrefix string,
 	dataPrefix string, depotPrefix string) {
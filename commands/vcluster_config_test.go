@@ -0,0 +1,64 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+func TestConfigPorts(t *testing.T) {
+	savedOptions, savedGlobals := dbOptions, globals
+	defer func() {
+		dbOptions, globals = savedOptions, savedGlobals
+		viper.Reset()
+	}()
+
+	// the ports create_db was run with are written to the config file
+	dbOptions = vclusterops.DatabaseOptionsFactory()
+	dbOptions.ConfigPath = filepath.Join(t.TempDir(), defConfigFileName)
+	dbOptions.NMAPort = 15554
+	dbOptions.HTTPSPort = 18443
+	dbOptions.HostPorts = map[string]vclusterops.HostPorts{"192.168.1.102": {HTTPSPort: 28443}}
+	vdb := vclusterops.VCoordinationDatabase{Name: "test_db", HostList: []string{"192.168.1.101", "192.168.1.102"}}
+	vdb.HostNodeMap = map[string]*vclusterops.VCoordinationNode{
+		"192.168.1.101": {Name: "v_test_db_node0001", Address: "192.168.1.101"},
+		"192.168.1.102": {Name: "v_test_db_node0002", Address: "192.168.1.102"},
+	}
+	dbConfig, err := readVDBToDBConfig(&vdb)
+	assert.NoError(t, err)
+	assert.NoError(t, dbConfig.writeAtomically(dbOptions.ConfigPath))
+
+	// and are read back by the next commands, unless they are passed
+	configPath := dbOptions.ConfigPath
+	dbOptions = vclusterops.DatabaseOptionsFactory()
+	dbOptions.ConfigPath = configPath
+	viper.Reset()
+	assert.NoError(t, loadConfigToViper())
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.IntVar(&dbOptions.NMAPort, nmaPortFlag, 0, "")
+	flags.IntVar(&dbOptions.HTTPSPort, httpsPortFlag, 0, "")
+	assert.NoError(t, flags.Parse([]string{"--" + httpsPortFlag, "38443"}))
+	applyConfigPorts(flags)
+	assert.Equal(t, 15554, dbOptions.NMAPort)
+	assert.Equal(t, 38443, dbOptions.HTTPSPort)
+	assert.Equal(t, map[string]vclusterops.HostPorts{"192.168.1.102": {HTTPSPort: 28443}}, dbOptions.HostPorts)
+}
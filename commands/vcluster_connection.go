@@ -26,7 +26,7 @@ func loadConnToViper() error {
 	viper.SetConfigFile(globals.connFile)
 	err := viper.MergeInConfig()
 	if err != nil {
-		fmt.Printf("Warning: fail to merge connection file %q for viper: %v\n", globals.connFile, err)
+		printCmdWarning("fail to merge connection file %q for viper: %v", globals.connFile, err)
 	}
 
 	return nil
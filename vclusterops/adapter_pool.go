@@ -17,12 +17,17 @@ package vclusterops
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"sort"
 	"time"
 
 	"github.com/theckman/yacspin"
 	"github.com/vertica/vcluster/vclusterops/vlog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type adapterPool struct {
@@ -31,28 +36,9 @@ type adapterPool struct {
 	connections map[string]adapter
 }
 
-var (
-	poolInstance adapterPool
-	once         sync.Once
-)
-
-// return a new instance of an adapterPool. The adapterPool cannot be shared
-// between Go routines. Otherwise, they will clobber each other state causing
-// HTTP request errors. It is the callers responsibility to ensure it doesn't
-// get shared.
-func getPoolInstance(logger vlog.Printer) adapterPool {
-	/* if once.Do(f) is called multiple times,
-	 * only the first call will invoke f,
-	 * even if f has a different value in each invocation.
-	 * Reference: https://pkg.go.dev/sync#Once
-	 */
-	once.Do(func() {
-		poolInstance = makeAdapterPool(logger)
-	})
-
-	return poolInstance
-}
-
+// makeAdapterPool returns a new adapterPool. Each dispatcher gets its own
+// pool, so concurrent ops never share the map of adapters. The connections
+// to the hosts are shared through the transport cache instead.
 func makeAdapterPool(logger vlog.Printer) adapterPool {
 	newAdapterPool := adapterPool{}
 	newAdapterPool.connections = make(map[string]adapter)
@@ -61,24 +47,48 @@ func makeAdapterPool(logger vlog.Printer) adapterPool {
 }
 
 type adapterToRequest struct {
+	host    string
 	adapter adapter
 	request hostHTTPRequest
+	// the span of the request
+	span trace.Span
 }
 
-func (pool *adapterPool) sendRequest(httpRequest *clusterHTTPRequest, spinner *yacspin.Spinner) error {
+func (pool *adapterPool) sendRequest(ctx context.Context, httpRequest *clusterHTTPRequest, spinner *yacspin.Spinner) error {
 	// build a collection of adapter to request
 	// we need this step as a host may not be in the pool
 	// in that case, we should not proceed
 	var adapterToRequestCollection []adapterToRequest
+	retryPolicy := requestRetryPolicy(ctx, httpRequest)
+	ports := portsFromContext(ctx)
+	timeouts := timeoutsFromContext(ctx)
+	replay := replayFromContext(ctx)
+	faults := faultsFromContext(ctx)
 	for host := range httpRequest.RequestCollection {
 		request := httpRequest.RequestCollection[host]
+		if request.RetryPolicy == nil {
+			request.RetryPolicy = retryPolicy
+		}
+		ports.setPort(host, &request)
+		timeouts.setTimeout(httpRequest, &request)
 		adpt, ok := pool.connections[host]
 		if !ok {
 			return fmt.Errorf("host %s is not found in the adapter pool", host)
 		}
-		ar := adapterToRequest{adapter: adpt, request: request}
+		if replay != nil {
+			adpt = &replayAdapter{bundle: replay, opName: httpRequest.Name, host: host,
+				singleHost: len(httpRequest.RequestCollection) == 1}
+		}
+		if len(faults) > 0 {
+			adpt = &faultAdapter{adapter: adpt, host: host, faults: faults, logger: pool.logger}
+		}
+		ar := adapterToRequest{host: host, adapter: adpt, request: request}
 		adapterToRequestCollection = append(adapterToRequestCollection, ar)
 	}
+	// the hosts are sorted so that the batches are always the same
+	sort.Slice(adapterToRequestCollection, func(i, j int) bool {
+		return adapterToRequestCollection[i].host < adapterToRequestCollection[j].host
+	})
 
 	hostCount := len(adapterToRequestCollection)
 
@@ -88,28 +98,36 @@ func (pool *adapterPool) sendRequest(httpRequest *clusterHTTPRequest, spinner *y
 	// only track the progress of HTTP requests for vcluster CLI
 	if pool.logger.ForCli {
 		// use context to check whether a step has completed
-		ctx, cancelCtx := context.WithCancel(context.Background())
-		go progressCheck(ctx, httpRequest.Name, pool.logger, spinner)
+		progressCtx, cancelCtx := context.WithCancel(ctx)
+		go progressCheck(progressCtx, httpRequest.Name, pool.logger, spinner)
 		// cancel the progress check context when the result channel is closed
 		defer cancelCtx()
 	}
 
-	for i := 0; i < len(adapterToRequestCollection); i++ {
-		ar := adapterToRequestCollection[i]
-		// send request to the hosts
-		// each goroutine will handle one request for one host
-		request := ar.request
-		go ar.adapter.sendRequest(&request, resultChannel)
+	// at most maxParallel requests are in flight at any time. A slow host
+	// only holds its own slot, the requests to the other hosts go on.
+	var inFlight chan struct{}
+	if maxParallel := requestMaxParallel(ctx, httpRequest); maxParallel > 0 && maxParallel < hostCount {
+		inFlight = make(chan struct{}, maxParallel)
+	}
+	// with batches, a batch is only sent once all the results of the
+	// previous one are in
+	batchSize := hostCount
+	if httpRequest.BatchSize > 0 && httpRequest.BatchSize < hostCount {
+		batchSize = httpRequest.BatchSize
 	}
 
-	// handle results
-	// we expect to receive the same number of results from the channel as the number of hosts
-	// before proceeding to the next steps
 	httpRequest.ResultCollection = make(map[string]hostHTTPResult)
-	for i := 0; i < hostCount; i++ {
-		result, ok := <-resultChannel
-		if ok {
-			httpRequest.ResultCollection[result.host] = result
+	for start := 0; start < hostCount; start += batchSize {
+		end := start + batchSize
+		if end > hostCount {
+			end = hostCount
+		}
+		batch := adapterToRequestCollection[start:end]
+		startRequests(ctx, httpRequest.Name, batch, inFlight, resultChannel)
+		err := collectResults(ctx, httpRequest, batch, resultChannel)
+		if err != nil {
+			return err
 		}
 	}
 	close(resultChannel)
@@ -117,6 +135,160 @@ func (pool *adapterPool) sendRequest(httpRequest *clusterHTTPRequest, spinner *y
 	return nil
 }
 
+// startRequests sends the requests of batch, each from its own goroutine.
+// When inFlight is not nil, a goroutine waits for a free slot in it before
+// sending its request.
+func startRequests(ctx context.Context, opName string, batch []adapterToRequest,
+	inFlight chan struct{}, resultChannel chan<- hostHTTPResult) {
+	for i := range batch {
+		ar := batch[i]
+		request := ar.request
+		sendOpEvent(ctx, &OpEvent{
+			Type:     OpEventRequestSent,
+			OpName:   opName,
+			Host:     ar.host,
+			Method:   request.Method,
+			Endpoint: request.Endpoint,
+		})
+		requestCtx := startRequestSpan(ctx, &batch[i])
+		go func() {
+			if inFlight != nil {
+				select {
+				case inFlight <- struct{}{}:
+					defer func() { <-inFlight }()
+				case <-ctx.Done():
+					resultChannel <- hostHTTPResult{host: ar.host, status: EXCEPTION, err: ctx.Err()}
+					return
+				}
+			}
+			ar.adapter.sendRequest(requestCtx, &request, resultChannel)
+		}()
+	}
+}
+
+// startRequestSpan starts the span of the request of ar, and returns the
+// context to send the request with
+func startRequestSpan(ctx context.Context, ar *adapterToRequest) context.Context {
+	request := &ar.request
+	attributes := []attribute.KeyValue{
+		attribute.String(spanAttrHost, ar.host),
+		attribute.String(spanAttrMethod, request.Method),
+		attribute.String(spanAttrEndpoint, request.Endpoint),
+	}
+	if request.Port != 0 {
+		attributes = append(attributes, attribute.Int(spanAttrPort, request.Port))
+	}
+	ctx, ar.span = startSpan(ctx, request.Method+" "+request.Endpoint, trace.SpanKindClient, attributes...)
+	return ctx
+}
+
+// collectResults waits for the results of batch and adds them to the result
+// collection of httpRequest, and to the replay bundle being recorded. A
+// request that timed out gets an error that names the op and the host. If
+// ctx is done first, the error lists the hosts that have not responded. When
+// replaying, a request the bundle does not have fails the op.
+func collectResults(ctx context.Context, httpRequest *clusterHTTPRequest, batch []adapterToRequest,
+	resultChannel <-chan hostHTTPResult) error {
+	pending := make(map[string]*hostHTTPRequest, len(batch))
+	spans := make(map[string]trace.Span, len(batch))
+	for i := range batch {
+		pending[batch[i].host] = &batch[i].request
+		spans[batch[i].host] = batch[i].span
+	}
+	recording := recordingFromContext(ctx)
+	for range batch {
+		select {
+		case <-ctx.Done():
+			// the requests still in flight are aborted by the same context.
+			// The result channel is buffered, so their goroutines can still
+			// write to it and exit. We must not close it here.
+			pendingHosts := maps.Keys(pending)
+			slices.Sort(pendingHosts)
+			for _, host := range pendingHosts {
+				endSpan(spans[host], ctx.Err())
+			}
+			return fmt.Errorf("no response from hosts %v: %w", pendingHosts, ctx.Err())
+		case result, ok := <-resultChannel:
+			if ok {
+				var diverged *ReplayDivergedError
+				if errors.As(result.err, &diverged) {
+					for host := range pending {
+						endSpan(spans[host], diverged)
+					}
+					return diverged
+				}
+				recording.record(httpRequest.Name, pending[result.host], &result)
+				delete(pending, result.host)
+				if result.isTimeout() && ctx.Err() == nil {
+					result.err = &RequestTimeoutError{
+						OpName:   httpRequest.Name,
+						Host:     result.host,
+						Endpoint: httpRequest.RequestCollection[result.host].Endpoint,
+						Timeout:  result.timeout,
+						Err:      result.err,
+					}
+				}
+				httpRequest.ResultCollection[result.host] = result
+				endRequestSpan(spans[result.host], &result)
+				sendRequestResultEvent(ctx, httpRequest.Name, &result)
+			}
+		}
+	}
+	return nil
+}
+
+type maxParallelContextKey struct{}
+
+// attachMaxParallel attaches the request concurrency limit of the options to
+// ctx, so that it applies to every op run under it
+func (opt *DatabaseOptions) attachMaxParallel(ctx context.Context) context.Context {
+	if opt.MaxParallel <= 0 || ctx.Value(maxParallelContextKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, maxParallelContextKey{}, opt.MaxParallel)
+}
+
+// requestMaxParallel returns the maximum number of requests of httpRequest
+// that can be in flight at the same time, 0 for no limit: the limit of
+// httpRequest, or else the one of the command options
+func requestMaxParallel(ctx context.Context, httpRequest *clusterHTTPRequest) int {
+	if httpRequest.MaxParallel > 0 {
+		return httpRequest.MaxParallel
+	}
+	maxParallel, _ := ctx.Value(maxParallelContextKey{}).(int)
+	return maxParallel
+}
+
+// requestRetryPolicy returns the retry policy of the requests of httpRequest
+// that do not set their own: the one of httpRequest, then the one of the
+// command options, then the default one
+func requestRetryPolicy(ctx context.Context, httpRequest *clusterHTTPRequest) *RetryPolicy {
+	if httpRequest.RetryPolicy != nil {
+		return httpRequest.RetryPolicy
+	}
+	if policy := retryPolicyFromContext(ctx); policy != nil {
+		return policy
+	}
+	policy := DefaultRetryPolicy()
+	return &policy
+}
+
+// sendRequestResultEvent lets the observer know that a host returned a result
+func sendRequestResultEvent(ctx context.Context, opName string, result *hostHTTPResult) {
+	event := OpEvent{
+		Type:       OpEventRequestResult,
+		OpName:     opName,
+		Host:       result.host,
+		Status:     result.status.String(),
+		StatusCode: result.statusCode,
+		Attempts:   result.attempts,
+	}
+	if result.err != nil {
+		event.Error = result.err.Error()
+	}
+	sendOpEvent(ctx, &event)
+}
+
 // progressCheck checks whether a step (operation) has been completed.
 // Elapsed time of the step in seconds will be displayed.
 func progressCheck(ctx context.Context, name string, logger vlog.Printer, spinner *yacspin.Spinner) {
@@ -0,0 +1,113 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+// mockConcurrencyAdapter records how many requests are in flight at once
+type mockConcurrencyAdapter struct {
+	host    string
+	tracker *concurrencyTracker
+}
+
+type concurrencyTracker struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	sent        []string
+}
+
+func (m *mockConcurrencyAdapter) sendRequest(_ context.Context, _ *hostHTTPRequest, resultChannel chan<- hostHTTPResult) {
+	m.tracker.mu.Lock()
+	m.tracker.inFlight++
+	if m.tracker.inFlight > m.tracker.maxInFlight {
+		m.tracker.maxInFlight = m.tracker.inFlight
+	}
+	m.tracker.sent = append(m.tracker.sent, m.host)
+	m.tracker.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	m.tracker.mu.Lock()
+	m.tracker.inFlight--
+	m.tracker.mu.Unlock()
+	resultChannel <- hostHTTPResult{host: m.host, status: SUCCESS, statusCode: http.StatusOK, attempts: 1}
+}
+
+func (m *mockConcurrencyAdapter) generateResult(_ *http.Response) hostHTTPResult {
+	return hostHTTPResult{}
+}
+
+func makeMockPool(hostCount int) (adapterPool, *clusterHTTPRequest, *concurrencyTracker) {
+	pool := makeAdapterPool(vlog.Printer{})
+	tracker := &concurrencyTracker{}
+	httpRequest := &clusterHTTPRequest{RequestCollection: make(map[string]hostHTTPRequest)}
+	for i := 0; i < hostCount; i++ {
+		host := fmt.Sprintf("host%02d", i)
+		pool.connections[host] = &mockConcurrencyAdapter{host: host, tracker: tracker}
+		httpRequest.RequestCollection[host] = hostHTTPRequest{Method: GetMethod}
+	}
+	return pool, httpRequest, tracker
+}
+
+func TestAdapterPoolMaxParallel(t *testing.T) {
+	// without a limit all the requests are sent at once
+	pool, httpRequest, tracker := makeMockPool(8)
+	err := pool.sendRequest(context.Background(), httpRequest, nil)
+	assert.NoError(t, err)
+	assert.Len(t, httpRequest.ResultCollection, 8)
+	assert.Equal(t, 8, tracker.maxInFlight)
+
+	// the limit of the options applies to every op
+	options := DatabaseOptionsFactory()
+	options.MaxParallel = 3
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, "")
+	pool, httpRequest, tracker = makeMockPool(8)
+	err = pool.sendRequest(ctx, httpRequest, nil)
+	assert.NoError(t, err)
+	assert.Len(t, httpRequest.ResultCollection, 8)
+	assert.Equal(t, 3, tracker.maxInFlight)
+
+	// the limit of the op takes precedence
+	pool, httpRequest, tracker = makeMockPool(8)
+	httpRequest.MaxParallel = 2
+	err = pool.sendRequest(ctx, httpRequest, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, tracker.maxInFlight)
+}
+
+func TestAdapterPoolBatches(t *testing.T) {
+	pool, httpRequest, tracker := makeMockPool(7)
+	httpRequest.BatchSize = 3
+	err := pool.sendRequest(context.Background(), httpRequest, nil)
+	assert.NoError(t, err)
+	assert.Len(t, httpRequest.ResultCollection, 7)
+	assert.Equal(t, 3, tracker.maxInFlight)
+	// a batch is only sent once the previous one is done
+	assert.ElementsMatch(t, []string{"host00", "host01", "host02"}, tracker.sent[:3])
+	assert.ElementsMatch(t, []string{"host03", "host04", "host05"}, tracker.sent[3:6])
+	assert.Equal(t, "host06", tracker.sent[6])
+}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -46,6 +47,15 @@ type VAddNodeOptions struct {
 	// Names of the existing nodes in the cluster. This option can be
 	// used to remove partially added nodes from catalog.
 	ExpectedNodeNames []string
+	// If true, a failure after the new nodes were created stops and drops
+	// them, and deletes their directories. The error is then an
+	// *AutoRollbackError that lists what was reverted.
+	AutoRollback bool
+
+	// the subcluster created by VAddSubcluster for the new nodes, dropped
+	// on rollback through the host it was added on
+	rollbackSubcluster     string
+	rollbackSubclusterHost string
 }
 
 func VAddNodeOptionsFactory() VAddNodeOptions {
@@ -119,15 +129,25 @@ func (o *VAddNodeOptions) validateAnalyzeOptions(logger vlog.Printer) error {
 
 // VAddNode adds one or more nodes to an existing database.
 // It returns a VCoordinationDatabase that contains catalog information and any error encountered.
-func (vcc VClusterCommands) VAddNode(options *VAddNodeOptions) (VCoordinationDatabase, error) {
+func (vcc VClusterCommands) VAddNode(ctx context.Context, options *VAddNodeOptions) (_ VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, options, commandAddNode)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+	// the subcluster created for the new nodes is dropped whatever the step
+	// that failed
+	defer func() {
+		if err != nil && options.rollbackSubcluster != "" {
+			err = vcc.rollbackAddNodeSubcluster(ctx, options, err)
+		}
+	}()
+
 	vdb := makeVCoordinationDatabase()
 
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return vdb, err
 	}
 
-	err = vcc.getVDBFromRunningDB(&vdb, &options.DatabaseOptions)
+	err = vcc.getVDBFromRunningDB(ctx, &vdb, &options.DatabaseOptions)
 	if err != nil {
 		return vdb, err
 	}
@@ -152,7 +172,7 @@ func (vcc VClusterCommands) VAddNode(options *VAddNodeOptions) (VCoordinationDat
 
 	// trim stale node information from catalog
 	// if NodeNames is provided
-	err = vcc.trimNodesInCatalog(&vdb, options)
+	err = vcc.trimNodesInCatalog(ctx, &vdb, options)
 	if err != nil {
 		return vdb, err
 	}
@@ -176,8 +196,12 @@ func (vcc VClusterCommands) VAddNode(options *VAddNodeOptions) (VCoordinationDat
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	if runError := clusterOpEngine.run(vcc.Log); runError != nil {
-		return vdb, fmt.Errorf("fail to complete add node operation, %w", runError)
+	if runError := clusterOpEngine.run(ctx, vcc.Log); runError != nil {
+		runError = fmt.Errorf("fail to complete add node operation, %w", runError)
+		if options.AutoRollback {
+			return vdb, vcc.rollbackAddNode(ctx, &vdb, options, instructions, runError)
+		}
+		return vdb, runError
 	}
 	return vdb, nil
 }
@@ -217,7 +241,7 @@ func (o *VAddNodeOptions) completeVDBSetting(vdb *VCoordinationDatabase) error {
 
 // trimNodesInCatalog removes failed node info from catalog
 // which can be used to remove partially added nodes
-func (vcc VClusterCommands) trimNodesInCatalog(vdb *VCoordinationDatabase,
+func (vcc VClusterCommands) trimNodesInCatalog(ctx context.Context, vdb *VCoordinationDatabase,
 	options *VAddNodeOptions) error {
 	if len(options.ExpectedNodeNames) == 0 {
 		vcc.Log.Info("ExpectedNodeNames is not set, skip trimming nodes", "ExpectedNodeNames", options.ExpectedNodeNames)
@@ -285,7 +309,7 @@ func (vcc VClusterCommands) trimNodesInCatalog(vdb *VCoordinationDatabase,
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	err := clusterOpEngine.run(vcc.Log)
+	err := clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		vcc.Log.Error(err, "fail to trim nodes from catalog, %v")
 		return err
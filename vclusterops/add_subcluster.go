@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -38,6 +39,11 @@ type VAddSubclusterOptions struct {
 	IsPrimary      bool
 	ControlSetSize int
 	CloneSC        string
+	// If true, the subcluster is dropped if the command fails after creating
+	// it. The embedded VAddNodeOptions are set to roll back as well, so that
+	// a failure to add nodes to the new subcluster drops the nodes and the
+	// subcluster.
+	AutoRollback bool
 	// part 3: add node info
 	VAddNodeOptions
 }
@@ -70,6 +76,10 @@ func (options *VAddSubclusterOptions) setDefaultValues() {
 	options.ControlSetSize = util.DefaultControlSetSize
 }
 
+func (options *VAddSubclusterOptions) subclusterName() string {
+	return options.SCName
+}
+
 func (options *VAddSubclusterOptions) validateRequiredOptions(logger vlog.Printer) error {
 	err := options.validateBaseOptions("db_add_subcluster", logger)
 	if err != nil {
@@ -179,7 +189,10 @@ func (options *VAddSubclusterOptions) validateAnalyzeOptions(vcc VClusterCommand
 
 // VAddSubcluster adds to a running database a new subcluster with provided options.
 // It returns any error encountered.
-func (vcc VClusterCommands) VAddSubcluster(options *VAddSubclusterOptions) error {
+func (vcc VClusterCommands) VAddSubcluster(ctx context.Context, options *VAddSubclusterOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandAddCluster)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -187,12 +200,12 @@ func (vcc VClusterCommands) VAddSubcluster(options *VAddSubclusterOptions) error
 	 */
 
 	// validate and analyze all options
-	err := options.validateAnalyzeOptions(vcc)
+	err = options.validateAnalyzeOptions(vcc)
 	if err != nil {
 		return err
 	}
 
-	instructions, err := vcc.produceAddSubclusterInstructions(options)
+	instructions, err := vcc.produceAddSubclusterInstructions(ctx, options)
 	if err != nil {
 		return fmt.Errorf("fail to produce instructions, %w", err)
 	}
@@ -202,14 +215,49 @@ func (vcc VClusterCommands) VAddSubcluster(options *VAddSubclusterOptions) error
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
-		return fmt.Errorf("fail to add subcluster %s, %w", options.SCName, runError)
+		runError = fmt.Errorf("fail to add subcluster %s, %w", options.SCName, runError)
+		if options.AutoRollback {
+			return vcc.rollbackAddSubcluster(ctx, options, instructions, runError)
+		}
+		return runError
 	}
 
+	if options.AutoRollback {
+		options.VAddNodeOptions.AutoRollback = true
+		options.VAddNodeOptions.rollbackSubcluster = options.SCName
+		options.VAddNodeOptions.rollbackSubclusterHost = addedSubclusterHost(instructions)
+	}
 	return nil
 }
 
+// rollbackAddSubcluster drops the subcluster if it was created before the
+// command failed. It returns runError as is when nothing was changed.
+func (vcc VClusterCommands) rollbackAddSubcluster(ctx context.Context, options *VAddSubclusterOptions,
+	instructions []clusterOp, runError error) error {
+	addedOnHost := addedSubclusterHost(instructions)
+	if addedOnHost == "" {
+		return runError
+	}
+	rollbackErr := &AutoRollbackError{Err: runError}
+	vcc.Log.PrintWarning("Rolling back subcluster %s", options.SCName)
+	rollbackErr.RollbackErr = vcc.rollbackNewSubcluster(ctx, &options.DatabaseOptions,
+		options.SCName, addedOnHost, rollbackErr)
+	return rollbackErr
+}
+
+// addedSubclusterHost returns the host the subcluster was added on, or an
+// empty string if it was not added
+func addedSubclusterHost(instructions []clusterOp) string {
+	for _, instruction := range instructions {
+		if op, ok := instruction.(*httpsAddSubclusterOp); ok {
+			return op.addedOnHost
+		}
+	}
+	return ""
+}
+
 // produceAddSubclusterInstructions will build a list of instructions to execute for
 // the add subcluster operation.
 //
@@ -222,12 +270,12 @@ func (vcc VClusterCommands) VAddSubcluster(options *VAddSubclusterOptions) error
 //     if the subcluster name already exists
 //   - Check if the new subcluster is created in database through HTTPS call
 //   - TODO: add new nodes to the subcluster
-func (vcc *VClusterCommands) produceAddSubclusterInstructions(options *VAddSubclusterOptions) ([]clusterOp, error) {
+func (vcc *VClusterCommands) produceAddSubclusterInstructions(ctx context.Context, options *VAddSubclusterOptions) ([]clusterOp, error) {
 	var instructions []clusterOp
 	vdb := makeVCoordinationDatabase()
 
 	// get cluster info
-	err := vcc.getClusterInfoFromRunningDB(&vdb, &options.DatabaseOptions)
+	err := vcc.getClusterInfoFromRunningDB(ctx, &vdb, &options.DatabaseOptions)
 	if err != nil {
 		return instructions, err
 	}
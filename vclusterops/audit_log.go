@@ -0,0 +1,377 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+)
+
+// the outcomes of the commands in the audit log
+const (
+	// AuditOutcomeStarted is the outcome of the entry written when a command
+	// starts its ops. A command that was killed has no later entry.
+	AuditOutcomeStarted   = "started"
+	AuditOutcomeSucceeded = "succeeded"
+	AuditOutcomeFailed    = "failed"
+	// AuditOutcomePlanned is the outcome of a command run in plan mode,
+	// which did not change the cluster
+	AuditOutcomePlanned = "planned"
+)
+
+const (
+	auditLogFilePerm = 0600
+	// the size of the first read from the end of the audit log to find its
+	// last entry
+	auditLogTailSize = 64 * 1024
+)
+
+// the options that are left out of the audit log. The certificates are not
+// secret, but would make each entry many times larger.
+var auditOmittedOptions = []string{"Cert", "CaCert"}
+
+// AuditEntry is a command recorded in the audit log. Each entry has the
+// hash of the entry before it, so that changing, removing or reordering
+// entries breaks the chain.
+type AuditEntry struct {
+	Seq int `json:"seq"`
+	// User is the OS user who ran the command, and SudoUser the one who
+	// ran it through sudo, if any
+	User     string   `json:"user"`
+	SudoUser string   `json:"sudo_user,omitempty"`
+	Command  string   `json:"command"`
+	Database string   `json:"database,omitempty"`
+	Hosts    []string `json:"hosts,omitempty"`
+	// Options are the options of the command, with the secrets redacted
+	Options   json.RawMessage `json:"options,omitempty"`
+	StartTime time.Time       `json:"start_time"`
+	EndTime   time.Time       `json:"end_time"`
+	Outcome   string          `json:"outcome"`
+	Error     string          `json:"error,omitempty"`
+	// StartSeq is the entry written when the command started its ops, 0 if
+	// it failed before
+	StartSeq int `json:"start_seq,omitempty"`
+	// PrevHash is the hash of the entry before, "" for the first one
+	PrevHash string `json:"prev_hash"`
+	// Hash is the SHA-256 of the entry, in hex, computed with Hash empty
+	Hash string `json:"hash"`
+}
+
+// AuditLogTamperedError is returned by VerifyAuditLog when an entry of the
+// audit log is not the one that was written
+type AuditLogTamperedError struct {
+	Path string
+	// Line is the line of the first entry that does not verify, from 1
+	Line   int
+	Reason string
+}
+
+func (e *AuditLogTamperedError) Error() string {
+	return fmt.Sprintf("the audit log %s was tampered with at line %d: %s", e.Path, e.Line, e.Reason)
+}
+
+// commandOptions is implemented by the options of every V* command, through
+// the DatabaseOptions they embed
+type commandOptions interface {
+	databaseOptions() *DatabaseOptions
+}
+
+func (opt *DatabaseOptions) databaseOptions() *DatabaseOptions {
+	return opt
+}
+
+// auditRun is the run of an outer command in the audit log. The command is
+// recorded once when it starts its ops, so that a command that is killed
+// leaves an entry, and again when it finishes, with its outcome.
+type auditRun struct {
+	path     string
+	options  commandOptions
+	command  *commandRun
+	startSeq int
+	started  bool
+}
+
+type auditRunContextKey struct{}
+
+// attachAudit attaches to ctx the run of the command in the audit log, if
+// there is one. Only the outer command is recorded, not the ones it runs
+// itself.
+func (vcc VClusterCommands) attachAudit(ctx context.Context, options commandOptions) context.Context {
+	command := commandRunFromContext(ctx)
+	if vcc.AuditLogPath == "" || command == nil || command.outer != nil {
+		return ctx
+	}
+	run := &auditRun{path: vcc.AuditLogPath, options: options, command: command}
+	return context.WithValue(ctx, auditRunContextKey{}, run)
+}
+
+func auditRunFromContext(ctx context.Context) *auditRun {
+	run, _ := ctx.Value(auditRunContextKey{}).(*auditRun)
+	return run
+}
+
+// auditCommandStart records the start of the command run under ctx, if it
+// has not been recorded yet. The command fails if it cannot be recorded,
+// before it changes anything.
+func auditCommandStart(ctx context.Context) error {
+	run := auditRunFromContext(ctx)
+	if run == nil || run.started {
+		return nil
+	}
+	run.started = true
+	entry := run.makeEntry()
+	entry.Outcome = AuditOutcomeStarted
+	if err := appendAuditEntry(run.path, &entry); err != nil {
+		return fmt.Errorf("the command could not be recorded in the audit log: %w", err)
+	}
+	run.startSeq = entry.Seq
+	return nil
+}
+
+// audit records the end of the command run under ctx, with its outcome. The
+// command has already run, so failing to record it is only warned about.
+func (vcc VClusterCommands) audit(ctx context.Context, err error) {
+	run := auditRunFromContext(ctx)
+	if run == nil || run.command != commandRunFromContext(ctx) {
+		return
+	}
+	entry := run.makeEntry()
+	entry.EndTime = time.Now().UTC()
+	entry.StartSeq = run.startSeq
+	entry.Outcome = AuditOutcomeSucceeded
+	if err != nil {
+		entry.Outcome = AuditOutcomeFailed
+		entry.Error = err.Error()
+	} else if isPlanMode(ctx) {
+		entry.Outcome = AuditOutcomePlanned
+	}
+	if auditErr := appendAuditEntry(run.path, &entry); auditErr != nil {
+		vcc.Log.PrintWarning("The end of the command could not be recorded in the audit log, details: %v", auditErr)
+	}
+}
+
+// makeEntry returns an entry of the command, without its outcome
+func (run *auditRun) makeEntry() AuditEntry {
+	opt := run.options.databaseOptions()
+	entry := AuditEntry{
+		SudoUser:  os.Getenv("SUDO_USER"),
+		Command:   run.command.command,
+		Database:  opt.DBName,
+		Hosts:     opt.Hosts,
+		Options:   sanitizeOptions(run.options),
+		StartTime: run.command.start.UTC(),
+	}
+	if len(entry.Hosts) == 0 {
+		entry.Hosts = opt.RawHosts
+	}
+	if user, err := util.GetCurrentUsername(); err == nil {
+		entry.User = user
+	}
+	return entry
+}
+
+// sanitizeOptions returns the options as JSON, with the secrets redacted
+func sanitizeOptions(options any) json.RawMessage {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil
+	}
+	for _, name := range auditOmittedOptions {
+		delete(fields, name)
+	}
+	data, err = json.Marshal(redactValue(fields))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// appendAuditEntry chains entry to the last one of the audit log at path and
+// appends it. The file is locked meanwhile, so that the commands run at the
+// same time do not break the chain.
+func appendAuditEntry(path string, entry *AuditEntry) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, auditLogFilePerm)
+	if err != nil {
+		return fmt.Errorf("fail to open the audit log %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("fail to lock the audit log %s: %w", path, err)
+	}
+	// the lock is released when the file is closed anyway
+	//nolint:errcheck
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	last, err := readLastAuditEntry(f)
+	if err != nil {
+		return err
+	}
+	entry.Seq = 1
+	if last != nil {
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+	}
+	entry.Hash, err = entry.computeHash()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readLastAuditEntry returns the last entry of the audit log, nil if it is
+// empty. Only the end of the file is read. A last line that is not an entry,
+// such as one torn by a crash, fails with an AuditLogTamperedError: nothing
+// can be chained to it.
+func readLastAuditEntry(f *os.File) (*AuditEntry, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("fail to read the audit log %s: %w", f.Name(), err)
+	}
+	size := info.Size()
+	for tailSize := int64(auditLogTailSize); ; tailSize *= 2 {
+		if tailSize > size {
+			tailSize = size
+		}
+		tail := make([]byte, tailSize)
+		if _, err := f.ReadAt(tail, size-tailSize); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("fail to read the audit log %s: %w", f.Name(), err)
+		}
+		tail = bytes.TrimRight(tail, "\n")
+		if len(tail) == 0 {
+			return nil, nil
+		}
+		start := bytes.LastIndexByte(tail, '\n')
+		if start < 0 && tailSize < size {
+			// the last line starts before the tail
+			continue
+		}
+		entry := &AuditEntry{}
+		if err := json.Unmarshal(tail[start+1:], entry); err != nil {
+			lineStart := size - tailSize + int64(start) + 1
+			return nil, &AuditLogTamperedError{Path: f.Name(), Line: countLines(f, lineStart) + 1,
+				Reason: fmt.Sprintf("the last line is not an audit entry: %v", err)}
+		}
+		return entry, nil
+	}
+}
+
+// countLines returns the number of lines in the first size bytes of f
+func countLines(f *os.File, size int64) int {
+	lines := 0
+	reader := io.NewSectionReader(f, 0, size)
+	chunk := make([]byte, auditLogTailSize)
+	for {
+		n, err := reader.Read(chunk)
+		lines += bytes.Count(chunk[:n], []byte{'\n'})
+		if err != nil {
+			return lines
+		}
+	}
+}
+
+// computeHash returns the hash of the entry, which covers the hash of the
+// entry before it
+func (entry *AuditEntry) computeHash() (string, error) {
+	unhashed := *entry
+	unhashed.Hash = ""
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return "", fmt.Errorf("fail to hash the audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ReadAuditLog returns the entries of the audit log at path, without
+// verifying them
+func ReadAuditLog(path string) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to open the audit log %s: %w", path, err)
+	}
+	defer f.Close()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<30)
+	for line := 1; scanner.Scan(); line++ {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return entries, &AuditLogTamperedError{Path: path, Line: line, Reason: fmt.Sprintf("not an audit entry: %v", err)}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("fail to read the audit log %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// VerifyAuditLog checks the hash chain of the audit log at path and
+// returns its entries. The first entry that was changed, inserted, removed
+// or moved fails the check with an AuditLogTamperedError. Removing entries
+// from the end of the log cannot be detected from the log alone, so the
+// last sequence number or hash should also be kept somewhere else.
+func VerifyAuditLog(path string) ([]AuditEntry, error) {
+	entries, err := ReadAuditLog(path)
+	if err != nil {
+		return entries, err
+	}
+	prevHash := ""
+	for i := range entries {
+		entry := &entries[i]
+		tampered := func(reason string, args ...any) error {
+			return &AuditLogTamperedError{Path: path, Line: i + 1, Reason: fmt.Sprintf(reason, args...)}
+		}
+		if entry.Seq != i+1 {
+			return entries[:i], tampered("expected entry %d, found entry %d", i+1, entry.Seq)
+		}
+		if entry.PrevHash != prevHash {
+			return entries[:i], tampered("entry %d does not follow the entry before it", entry.Seq)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return entries[:i], err
+		}
+		if entry.Hash != hash {
+			return entries[:i], tampered("entry %d does not match its hash", entry.Seq)
+		}
+		prevHash = entry.Hash
+	}
+	return entries, nil
+}
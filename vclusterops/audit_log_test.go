@@ -0,0 +1,132 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestAuditLog(t *testing.T) {
+	vcc := VClusterCommands{AuditLogPath: filepath.Join(t.TempDir(), "audit.log")}
+	password := "secret1"
+	options := VStopDatabaseOptionsFactory()
+	options.DBName = "test_db"
+	options.RawHosts = []string{"192.168.1.101", "192.168.1.102"}
+	options.Password = &password
+	options.Cert = "-----BEGIN CERTIFICATE-----"
+	options.Observer = OpObserverFunc(func(_ OpEvent) {})
+
+	ctx := vcc.setupContext(context.Background(), &options, commandStopDB)
+	// a command run by another one is not recorded
+	nestedOptions := VFetchNodeStateOptionsFactory()
+	vcc.finishCommand(vcc.setupContext(ctx, &nestedOptions, commandListAllNodes), &nestedOptions, nil)
+	vcc.finishCommand(ctx, &options, errors.New("fail to stop database"))
+	dropOptions := VDropDatabaseOptionsFactory()
+	dropOptions.DBName = "test_db"
+	vcc.finishCommand(vcc.setupContext(context.Background(), &dropOptions, commandDropDB), &dropOptions, nil)
+
+	entries, err := VerifyAuditLog(vcc.AuditLogPath)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	stop, drop := entries[0], entries[1]
+	assert.Equal(t, 1, stop.Seq)
+	assert.Equal(t, commandStopDB, stop.Command)
+	assert.Equal(t, "test_db", stop.Database)
+	assert.Equal(t, options.RawHosts, stop.Hosts)
+	assert.Equal(t, AuditOutcomeFailed, stop.Outcome)
+	assert.Equal(t, "fail to stop database", stop.Error)
+	assert.NotEmpty(t, stop.User)
+	assert.Contains(t, string(stop.Options), `"DBName":"test_db"`)
+	assert.NotContains(t, string(stop.Options), password)
+	assert.NotContains(t, string(stop.Options), "CERTIFICATE")
+	assert.Equal(t, 2, drop.Seq)
+	assert.Equal(t, AuditOutcomeSucceeded, drop.Outcome)
+	assert.Equal(t, stop.Hash, drop.PrevHash)
+
+	// an entry that was changed breaks the chain
+	data, err := os.ReadFile(vcc.AuditLogPath)
+	assert.NoError(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+	tamperedPath := filepath.Join(t.TempDir(), "tampered.log")
+	changed := strings.Replace(lines[0], `"outcome":"failed"`, `"outcome":"succeeded"`, 1)
+	assert.NoError(t, os.WriteFile(tamperedPath, []byte(changed+lines[1]), auditLogFilePerm))
+	_, err = VerifyAuditLog(tamperedPath)
+	var tamperedErr *AuditLogTamperedError
+	assert.True(t, errors.As(err, &tamperedErr))
+	assert.Equal(t, 1, tamperedErr.Line)
+	assert.Contains(t, err.Error(), "entry 1 does not match its hash")
+	// and so does an entry that was removed
+	assert.NoError(t, os.WriteFile(tamperedPath, []byte(lines[1]), auditLogFilePerm))
+	entries, err = VerifyAuditLog(tamperedPath)
+	assert.ErrorContains(t, err, "expected entry 1, found entry 2")
+	assert.Empty(t, entries)
+	// even if the sequence numbers are rewritten
+	renumbered := strings.Replace(lines[1], `"seq":2`, `"seq":1`, 1)
+	assert.NoError(t, os.WriteFile(tamperedPath, []byte(renumbered), auditLogFilePerm))
+	_, err = VerifyAuditLog(tamperedPath)
+	assert.ErrorContains(t, err, "entry 1 does not follow the entry before it")
+}
+
+func TestAuditCommandStart(t *testing.T) {
+	vcc := VClusterCommands{AuditLogPath: filepath.Join(t.TempDir(), "audit.log"), LockDir: t.TempDir()}
+	options := VDropDatabaseOptionsFactory()
+	options.DBName = "test_db"
+	op := makeMockOp(false)
+
+	// a command is recorded before its ops run, so that it leaves an entry
+	// even if it is killed
+	ctx := vcc.setupContext(context.Background(), &options, commandDropDB)
+	opEngine := makeClusterOpEngine([]clusterOp{&op}, &httpsCerts{})
+	assert.NoError(t, opEngine.run(ctx, vlog.Printer{}))
+	entries, err := VerifyAuditLog(vcc.AuditLogPath)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, AuditOutcomeStarted, entries[0].Outcome)
+	assert.True(t, entries[0].EndTime.IsZero())
+	vcc.finishCommand(ctx, &options, nil)
+	entries, err = VerifyAuditLog(vcc.AuditLogPath)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, AuditOutcomeSucceeded, entries[1].Outcome)
+	assert.Equal(t, 1, entries[1].StartSeq)
+
+	// a torn last line fails the next command before it runs its ops
+	f, err := os.OpenFile(vcc.AuditLogPath, os.O_WRONLY|os.O_APPEND, auditLogFilePerm)
+	assert.NoError(t, err)
+	_, err = f.WriteString(`{"seq":3,"user":"db`)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+	op = makeMockOp(false)
+	ctx = vcc.setupContext(context.Background(), &options, commandDropDB)
+	opEngine = makeClusterOpEngine([]clusterOp{&op}, &httpsCerts{})
+	err = opEngine.run(ctx, vlog.Printer{})
+	vcc.finishCommand(ctx, &options, err)
+	var tamperedErr *AuditLogTamperedError
+	if !assert.ErrorAs(t, err, &tamperedErr) {
+		return
+	}
+	assert.Equal(t, 3, tamperedErr.Line)
+	assert.Contains(t, err.Error(), "the last line is not an audit entry")
+	assert.False(t, op.calledPrepare)
+}
@@ -0,0 +1,262 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// AutoRollbackError is returned by VAddNode and VAddSubcluster when
+// AutoRollback is set and the command failed after it changed the cluster.
+// It lists exactly what was reverted. If the rollback failed too, RollbackErr
+// is set, and the changes that are not listed were left behind.
+type AutoRollbackError struct {
+	// Err is the failure that triggered the rollback
+	Err error
+	// RollbackErr is the failure of the rollback itself, nil if all the
+	// changes were reverted
+	RollbackErr error
+	// StoppedNodes are the new nodes that had started and were stopped
+	StoppedNodes []string
+	// DroppedNodes are the new nodes that were dropped from the catalog
+	DroppedNodes []string
+	// DeletedDirectoryHosts are the hosts where the directories prepared for
+	// the new nodes were deleted
+	DeletedDirectoryHosts []string
+	// DroppedSubcluster is the new subcluster, if it was dropped
+	DroppedSubcluster string
+}
+
+func (e *AutoRollbackError) Error() string {
+	var reverted []string
+	if len(e.StoppedNodes) > 0 {
+		reverted = append(reverted, fmt.Sprintf("stopped nodes %v", e.StoppedNodes))
+	}
+	if len(e.DroppedNodes) > 0 {
+		reverted = append(reverted, fmt.Sprintf("dropped nodes %v", e.DroppedNodes))
+	}
+	if len(e.DeletedDirectoryHosts) > 0 {
+		reverted = append(reverted, fmt.Sprintf("deleted the new directories on hosts %v", e.DeletedDirectoryHosts))
+	}
+	if e.DroppedSubcluster != "" {
+		reverted = append(reverted, fmt.Sprintf("dropped subcluster %s", e.DroppedSubcluster))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(e.Err.Error())
+	if len(reverted) > 0 {
+		fmt.Fprintf(&sb, "; rollback %s", strings.Join(reverted, ", "))
+	}
+	if e.RollbackErr != nil {
+		fmt.Fprintf(&sb, "; rollback failed, details: %v", e.RollbackErr)
+	} else {
+		sb.WriteString("; all the changes were rolled back")
+	}
+	return sb.String()
+}
+
+func (e *AutoRollbackError) Unwrap() error {
+	return e.Err
+}
+
+// rollbackAddNode reverts what a failed add_node run changed: the new nodes
+// that started are stopped, the ones in the catalog are dropped, and the
+// directories prepared for them are deleted. It returns runError as is when
+// nothing was changed.
+func (vcc VClusterCommands) rollbackAddNode(ctx context.Context, vdb *VCoordinationDatabase,
+	options *VAddNodeOptions, instructions []clusterOp, runError error) error {
+	var preparedHosts []string
+	createAttempted := false
+	for _, instruction := range instructions {
+		switch op := instruction.(type) {
+		case *nmaPrepareDirectoriesOp:
+			preparedHosts = op.preparedHosts
+		case *httpsCreateNodeOp:
+			createAttempted = len(op.clusterHTTPRequest.ResultCollection) > 0
+		}
+	}
+	if !createAttempted && len(preparedHosts) == 0 {
+		return runError
+	}
+
+	// the rollback ops are not part of the command, so they are not journaled
+	ctx = contextWithoutJournal(ctx)
+	rollbackErr := &AutoRollbackError{Err: runError}
+	vcc.Log.PrintWarning("Rolling back the nodes added to database %s", options.DBName)
+
+	// the response of the create node request may have been lost, so we check
+	// the catalog for the new nodes
+	newNodes := make(vHostNodeMap)
+	if createAttempted {
+		currentVDB := makeVCoordinationDatabase()
+		err := vcc.getVDBFromRunningDB(ctx, &currentVDB, &options.DatabaseOptions)
+		if err != nil {
+			rollbackErr.RollbackErr = fmt.Errorf("fail to find the nodes to roll back, %w", err)
+			return rollbackErr
+		}
+		for _, host := range options.NewHosts {
+			if vnode, ok := currentVDB.HostNodeMap[host]; ok {
+				newNodes[host] = vnode
+			}
+		}
+	}
+
+	err := vcc.rollbackNewNodes(ctx, vdb, options, newNodes, rollbackErr)
+	if err == nil {
+		err = vcc.rollbackNewDirectories(ctx, vdb, options, preparedHosts, rollbackErr)
+	}
+	rollbackErr.RollbackErr = err
+	return rollbackErr
+}
+
+// rollbackAddNodeSubcluster drops the subcluster VAddSubcluster created for
+// the nodes of a failed add_node. The subcluster is kept if the rollback of
+// the nodes failed, as some of them may still be in it.
+func (vcc VClusterCommands) rollbackAddNodeSubcluster(ctx context.Context, options *VAddNodeOptions, err error) error {
+	var rollbackErr *AutoRollbackError
+	if errors.As(err, &rollbackErr) {
+		if rollbackErr.RollbackErr != nil {
+			return err
+		}
+	} else {
+		rollbackErr = &AutoRollbackError{Err: err}
+		err = rollbackErr
+	}
+	vcc.Log.PrintWarning("Rolling back subcluster %s", options.rollbackSubcluster)
+	rollbackErr.RollbackErr = vcc.rollbackNewSubcluster(ctx, &options.DatabaseOptions,
+		options.rollbackSubcluster, options.rollbackSubclusterHost, rollbackErr)
+	return err
+}
+
+// rollbackNewNodes stops the new nodes that are up, then drops all of them
+// from the catalog one at a time
+func (vcc VClusterCommands) rollbackNewNodes(ctx context.Context, vdb *VCoordinationDatabase,
+	options *VAddNodeOptions, newNodes vHostNodeMap, rollbackErr *AutoRollbackError) error {
+	if len(newNodes) == 0 {
+		return nil
+	}
+	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
+	initiatorHost := []string{options.Initiator}
+	username := options.UserName
+	usePassword := options.usePassword
+	password := options.Password
+
+	stopNodes := make(map[string]string)
+	var stopHosts []string
+	for host, vnode := range newNodes {
+		if vnode.State == util.NodeUpState {
+			stopNodes[vnode.Name] = host
+			stopHosts = append(stopHosts, host)
+		}
+	}
+	if len(stopNodes) > 0 {
+		httpsStopNodeOp, err := makeHTTPSStopInputNodesOp(stopNodes, usePassword, username, password, nil)
+		if err != nil {
+			return err
+		}
+		httpsPollNodesDownOp, err := makeHTTPSPollNodeStateDownOp(stopHosts, usePassword, username, password)
+		if err != nil {
+			return err
+		}
+		clusterOpEngine := makeClusterOpEngine([]clusterOp{&httpsStopNodeOp, &httpsPollNodesDownOp}, &certs)
+		err = clusterOpEngine.run(ctx, vcc.Log)
+		if err != nil {
+			return fmt.Errorf("fail to stop the new nodes, %w", err)
+		}
+		for name := range stopNodes {
+			rollbackErr.StoppedNodes = append(rollbackErr.StoppedNodes, name)
+		}
+		slices.Sort(rollbackErr.StoppedNodes)
+	}
+
+	// nodes are dropped one at a time so we know exactly which ones are gone
+	newHosts := maps.Keys(newNodes)
+	slices.Sort(newHosts)
+	for _, host := range getSortedHosts(newHosts, newNodes) {
+		nodeName := newNodes[host].Name
+		httpsDropNodeOp, err := makeHTTPSDropNodeOp(nodeName, initiatorHost,
+			usePassword, username, password, vdb.IsEon)
+		if err != nil {
+			return err
+		}
+		clusterOpEngine := makeClusterOpEngine([]clusterOp{&httpsDropNodeOp}, &certs)
+		err = clusterOpEngine.run(ctx, vcc.Log)
+		if err != nil {
+			return fmt.Errorf("fail to drop node %s, %w", nodeName, err)
+		}
+		rollbackErr.DroppedNodes = append(rollbackErr.DroppedNodes, nodeName)
+	}
+
+	httpsReloadSpreadOp, err := makeHTTPSReloadSpreadOpWithInitiator(initiatorHost, usePassword, username, password)
+	if err != nil {
+		return err
+	}
+	clusterOpEngine := makeClusterOpEngine([]clusterOp{&httpsReloadSpreadOp}, &certs)
+	err = clusterOpEngine.run(ctx, vcc.Log)
+	if err != nil {
+		return fmt.Errorf("fail to reload spread after dropping the new nodes, %w", err)
+	}
+	return nil
+}
+
+// rollbackNewDirectories deletes the directories prepared for the new nodes.
+// Only the hosts where the directories were prepared are touched, so that
+// directories that existed before the command are left alone.
+func (vcc VClusterCommands) rollbackNewDirectories(ctx context.Context, vdb *VCoordinationDatabase,
+	options *VAddNodeOptions, preparedHosts []string, rollbackErr *AutoRollbackError) error {
+	if len(preparedHosts) == 0 {
+		return nil
+	}
+	vdbForDeleteDir := vdb.copy(preparedHosts)
+	nmaDeleteDirectoriesOp, err := makeNMADeleteDirectoriesOp(&vdbForDeleteDir, true /*force delete*/)
+	if err != nil {
+		return err
+	}
+	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
+	clusterOpEngine := makeClusterOpEngine([]clusterOp{&nmaDeleteDirectoriesOp}, &certs)
+	err = clusterOpEngine.run(ctx, vcc.Log)
+	if err != nil {
+		return fmt.Errorf("fail to delete the directories of the new nodes, %w", err)
+	}
+	rollbackErr.DeletedDirectoryHosts = slices.Clone(preparedHosts)
+	slices.Sort(rollbackErr.DeletedDirectoryHosts)
+	return nil
+}
+
+// rollbackNewSubcluster drops a subcluster created by the command
+func (vcc VClusterCommands) rollbackNewSubcluster(ctx context.Context, options *DatabaseOptions,
+	scName, initiator string, rollbackErr *AutoRollbackError) error {
+	httpsDropSubclusterOp, err := makeHTTPSDropSubclusterOp([]string{initiator}, scName,
+		options.usePassword, options.UserName, options.Password)
+	if err != nil {
+		return err
+	}
+	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
+	clusterOpEngine := makeClusterOpEngine([]clusterOp{&httpsDropSubclusterOp}, &certs)
+	err = clusterOpEngine.run(contextWithoutJournal(ctx), vcc.Log)
+	if err != nil {
+		return fmt.Errorf("fail to drop subcluster %s, %w", scName, err)
+	}
+	rollbackErr.DroppedSubcluster = scName
+	return nil
+}
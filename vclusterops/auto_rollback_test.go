@@ -0,0 +1,66 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoRollbackError(t *testing.T) {
+	runError := errors.New("fail to complete add node operation")
+	err := error(&AutoRollbackError{
+		Err:                   runError,
+		StoppedNodes:          []string{"v_db_node0004"},
+		DroppedNodes:          []string{"v_db_node0004", "v_db_node0005"},
+		DeletedDirectoryHosts: []string{"192.168.1.104", "192.168.1.105"},
+	})
+	assert.ErrorIs(t, err, runError)
+	assert.ErrorContains(t, err, "stopped nodes [v_db_node0004]")
+	assert.ErrorContains(t, err, "dropped nodes [v_db_node0004 v_db_node0005]")
+	assert.ErrorContains(t, err, "deleted the new directories on hosts [192.168.1.104 192.168.1.105]")
+	assert.ErrorContains(t, err, "all the changes were rolled back")
+
+	var rollbackErr *AutoRollbackError
+	assert.True(t, errors.As(err, &rollbackErr))
+
+	err = &AutoRollbackError{
+		Err:          runError,
+		DroppedNodes: []string{"v_db_node0004"},
+		RollbackErr:  errors.New("fail to drop node v_db_node0005"),
+	}
+	assert.ErrorContains(t, err, "dropped nodes [v_db_node0004]")
+	assert.ErrorContains(t, err, "rollback failed, details: fail to drop node v_db_node0005")
+	assert.NotContains(t, err.Error(), "all the changes were rolled back")
+}
+
+func TestRollbackAddNodeWithoutChanges(t *testing.T) {
+	vcc := VClusterCommands{}
+	vdb := makeVCoordinationDatabase()
+	options := VAddNodeOptionsFactory()
+	runError := errors.New("fail to complete add node operation")
+
+	// the directories were not prepared and no node was created, so there
+	// is nothing to roll back
+	prepareDirOp := nmaPrepareDirectoriesOp{}
+	createNodeOp := httpsCreateNodeOp{}
+	instructions := []clusterOp{&prepareDirOp, &createNodeOp}
+	err := vcc.rollbackAddNode(context.Background(), &vdb, &options, instructions, runError)
+	assert.Equal(t, runError, err)
+}
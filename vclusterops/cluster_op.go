@@ -21,6 +21,7 @@
 package vclusterops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -48,6 +49,18 @@ const (
 	EXCEPTION resultStatus = 2
 )
 
+func (status resultStatus) String() string {
+	switch status {
+	case SUCCESS:
+		return "SUCCESS"
+	case FAILURE:
+		return "FAILURE"
+	case EXCEPTION:
+		return "EXCEPTION"
+	}
+	return fmt.Sprintf("resultStatus(%d)", int(status))
+}
+
 const (
 	GetMethod    = "GET"
 	PutMethod    = "PUT"
@@ -83,7 +96,17 @@ type hostHTTPResult struct {
 	statusCode int
 	host       string
 	content    string
-	err        error // This is set if the http response ends in a failure scenario
+	err        error         // This is set if the http response ends in a failure scenario
+	attempts   int           // the number of times the request was sent
+	timeout    time.Duration // the timeout of each attempt, 0 for none
+	// the clock of the host when it responded, from the Date header, and the
+	// local clock when the response was received. They are zero when the
+	// host did not respond.
+	hostTime     time.Time
+	receivedTime time.Time
+	// when the certificate the host presented expires, zero if it presented
+	// none
+	certNotAfter time.Time
 }
 
 type httpsResponseStatus struct {
@@ -173,6 +196,7 @@ func (status resultStatus) getStatusString() string {
 // log* implemented by embedding OpBase, but overrideable
 type clusterOp interface {
 	getName() string
+	getDescription() string
 	setLogger(logger vlog.Printer)
 	setupSpinner()
 	startSpinner()
@@ -190,6 +214,9 @@ type clusterOp interface {
 	setupBasicInfo()
 	loadCertsIfNeeded(certs *httpsCerts, findCertsInOptions bool) error
 	isSkipExecute() bool
+	isReadOnly() bool
+	describePlan(ports *portConfig) PlannedOp
+	getDependencies() (dependencies []clusterOp, declared bool)
 }
 
 /* Cluster ops basic fields and functions
@@ -205,6 +232,9 @@ type opBase struct {
 	clusterHTTPRequest clusterHTTPRequest
 	skipExecute        bool // This can be set during prepare if we determine no work is needed
 	spinner            *yacspin.Spinner
+	// the ops that must complete before this one runs, when declared
+	dependencies         []clusterOp
+	dependenciesDeclared bool
 }
 
 type opResponseMap map[string]string
@@ -213,6 +243,23 @@ func (op *opBase) getName() string {
 	return op.name
 }
 
+func (op *opBase) getDescription() string {
+	return op.description
+}
+
+// setDependencies declares the ops that must complete before op runs, so
+// that the op engine can run it alongside the other ops. An op that declares
+// no dependencies can run as soon as the engine starts. An op that does not
+// declare them runs once all the ops before it have completed.
+func (op *opBase) setDependencies(ops ...clusterOp) {
+	op.dependencies = ops
+	op.dependenciesDeclared = true
+}
+
+func (op *opBase) getDependencies() (dependencies []clusterOp, declared bool) {
+	return op.dependencies, op.dependenciesDeclared
+}
+
 func (op *opBase) setLogger(logger vlog.Printer) {
 	op.logger = logger.WithName(op.name)
 }
@@ -328,11 +375,11 @@ func (op *opBase) stopFailSpinnerWithMessage(errMsg string, v ...any) {
 
 func (op *opBase) logResponse(host string, result hostHTTPResult) {
 	if result.err != nil {
-		op.logger.PrintError("[%s] result from host %s summary %s, details: %+v",
-			op.name, host, result.status.getStatusString(), result.err)
+		op.logger.PrintError("[%s] result from host %s summary %s after %d attempt(s), details: %+v",
+			op.name, host, result.status.getStatusString(), result.attempts, result.err)
 	} else {
 		op.logger.Log.Info("Request succeeded",
-			"op name", op.name, "host", host, "details", result)
+			"op name", op.name, "host", host, "attempts", result.attempts, "details", result)
 	}
 }
 
@@ -349,7 +396,7 @@ func (op *opBase) logFinalize() {
 }
 
 func (op *opBase) runExecute(execContext *opEngineExecContext) error {
-	err := execContext.dispatcher.sendRequest(&op.clusterHTTPRequest, op.spinner)
+	err := execContext.dispatcher.sendRequest(execContext.ctx, &op.clusterHTTPRequest, op.spinner)
 	if err != nil {
 		op.logger.Error(err, "Fail to dispatch request, detail", "dispatch request", op.clusterHTTPRequest)
 		return err
@@ -392,6 +439,22 @@ func (op *opBase) isSkipExecute() bool {
 	return op.skipExecute
 }
 
+// isReadOnly tells whether the op leaves the cluster unchanged, so that it can
+// run against a live cluster in plan mode. By default, an op is read-only
+// when all of its requests are GETs. Ops can override this when their method
+// does not tell the whole story.
+func (op *opBase) isReadOnly() bool {
+	if len(op.clusterHTTPRequest.RequestCollection) == 0 {
+		return false
+	}
+	for host := range op.clusterHTTPRequest.RequestCollection {
+		if op.clusterHTTPRequest.RequestCollection[host].Method != GetMethod {
+			return false
+		}
+	}
+	return true
+}
+
 // hasQuorum checks if we have enough working primary nodes to maintain data integrity
 // quorumCount = (1/2 * number of primary nodes) + 1
 func (op *opBase) hasQuorum(hostCount, primaryNodeCount uint) bool {
@@ -484,29 +547,32 @@ type ClusterCommands interface {
 	PrintWarning(msg string, v ...any)
 	PrintError(msg string, v ...any)
 
-	VAddNode(options *VAddNodeOptions) (VCoordinationDatabase, error)
-	VStopNode(options *VStopNodeOptions) error
-	VAddSubcluster(options *VAddSubclusterOptions) error
-	VCreateDatabase(options *VCreateDatabaseOptions) (VCoordinationDatabase, error)
-	VDropDatabase(options *VDropDatabaseOptions) error
-	VFetchNodeState(options *VFetchNodeStateOptions) ([]NodeInfo, error)
-	VInstallPackages(options *VInstallPackagesOptions) (*InstallPackageStatus, error)
-	VReIP(options *VReIPOptions) error
-	VRemoveNode(options *VRemoveNodeOptions) (VCoordinationDatabase, error)
-	VRemoveSubcluster(removeScOpt *VRemoveScOptions) (VCoordinationDatabase, error)
-	VReviveDatabase(options *VReviveDatabaseOptions) (dbInfo string, vdbPtr *VCoordinationDatabase, err error)
-	VSandbox(options *VSandboxOptions) error
-	VScrutinize(options *VScrutinizeOptions) error
-	VShowRestorePoints(options *VShowRestorePointsOptions) (restorePoints []RestorePoint, err error)
-	VStartDatabase(options *VStartDatabaseOptions) (vdbPtr *VCoordinationDatabase, err error)
-	VStartNodes(options *VStartNodesOptions) error
-	VStartSubcluster(startScOpt *VStartScOptions) error
-	VStopDatabase(options *VStopDatabaseOptions) error
-	VReplicateDatabase(options *VReplicationDatabaseOptions) error
-	VFetchCoordinationDatabase(options *VFetchCoordinationDatabaseOptions) (VCoordinationDatabase, error)
-	VUnsandbox(options *VUnsandboxOptions) error
-	VStopSubcluster(options *VStopSubclusterOptions) error
-	VFetchNodesDetails(options *VFetchNodesDetailsOptions) (NodesDetails, error)
+	VAddNode(ctx context.Context, options *VAddNodeOptions) (VCoordinationDatabase, error)
+	VStopNode(ctx context.Context, options *VStopNodeOptions) error
+	VAddSubcluster(ctx context.Context, options *VAddSubclusterOptions) error
+	VCreateDatabase(ctx context.Context, options *VCreateDatabaseOptions) (VCoordinationDatabase, error)
+	VDropDatabase(ctx context.Context, options *VDropDatabaseOptions) error
+	VFetchNodeState(ctx context.Context, options *VFetchNodeStateOptions) ([]NodeInfo, error)
+	VInstallPackages(ctx context.Context, options *VInstallPackagesOptions) (*InstallPackageStatus, error)
+	VReIP(ctx context.Context, options *VReIPOptions) error
+	VRemoveNode(ctx context.Context, options *VRemoveNodeOptions) (VCoordinationDatabase, error)
+	VRemoveSubcluster(ctx context.Context, removeScOpt *VRemoveScOptions) (VCoordinationDatabase, error)
+	VReviveDatabase(ctx context.Context, options *VReviveDatabaseOptions) (dbInfo string, vdbPtr *VCoordinationDatabase, err error)
+	VRollback(ctx context.Context, options *VRollbackOptions) error
+	VSandbox(ctx context.Context, options *VSandboxOptions) error
+	VScrutinize(ctx context.Context, options *VScrutinizeOptions) error
+	VShowRestorePoints(ctx context.Context, options *VShowRestorePointsOptions) (restorePoints []RestorePoint, err error)
+	VStartDatabase(ctx context.Context, options *VStartDatabaseOptions) (vdbPtr *VCoordinationDatabase, err error)
+	VStartNodes(ctx context.Context, options *VStartNodesOptions) error
+	VStartSubcluster(ctx context.Context, startScOpt *VStartScOptions) error
+	VStopDatabase(ctx context.Context, options *VStopDatabaseOptions) error
+	VReplicateDatabase(ctx context.Context, options *VReplicationDatabaseOptions) error
+	VFetchCoordinationDatabase(ctx context.Context, options *VFetchCoordinationDatabaseOptions) (VCoordinationDatabase, error)
+	VUnsandbox(ctx context.Context, options *VUnsandboxOptions) error
+	VStopSubcluster(ctx context.Context, options *VStopSubclusterOptions) error
+	VFetchNodesDetails(ctx context.Context, options *VFetchNodesDetailsOptions) (NodesDetails, error)
+	VClusterStatus(ctx context.Context, options *VClusterStatusOptions) (*ClusterStatus, error)
+	VDoctor(ctx context.Context, options *VDoctorOptions) (*DoctorReport, error)
 }
 
 type VClusterCommandsLogger struct {
@@ -545,4 +611,14 @@ func (vcc VClusterCommandsLogger) PrintError(msg string, v ...any) {
 // (e.g. create db, add node, etc.).
 type VClusterCommands struct {
 	VClusterCommandsLogger
+	// AuditLogPath, when set, is the file each command run is appended to,
+	// with who ran it, on which database and hosts, and how it ended. A
+	// command that cannot be recorded when it starts its ops fails.
+	AuditLogPath string
+	// LockDir is the directory of the lock files of the databases, the
+	// temporary directory of the OS when empty. The commands that change a
+	// database lock it while they run, with a lock file in this directory and
+	// a lease on the initiator, so that two of them cannot run on it at the
+	// same time, from this machine or from another one.
+	LockDir string
 }
@@ -16,15 +16,22 @@
 package vclusterops
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/vertica/vcluster/vclusterops/vlog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type VClusterOpEngine struct {
 	instructions []clusterOp
 	certs        *httpsCerts
 	execContext  *opEngineExecContext
+	// the number of the engine among the ones run under the journal
+	journalEngine int
 }
 
 func makeClusterOpEngine(instructions []clusterOp, certs *httpsCerts) VClusterOpEngine {
@@ -38,16 +45,39 @@ func (opEngine *VClusterOpEngine) shouldGetCertsFromOptions() bool {
 	return (opEngine.certs.key != "" && opEngine.certs.cert != "")
 }
 
-func (opEngine *VClusterOpEngine) run(logger vlog.Printer) error {
-	execContext := makeOpEngineExecContext(logger)
+func (opEngine *VClusterOpEngine) run(ctx context.Context, logger vlog.Printer) error {
+	execContext := makeOpEngineExecContext(ctx, logger)
 	opEngine.execContext = &execContext
 
+	journal := journalFromContext(ctx)
+	if err := journal.load(); err != nil {
+		return err
+	}
+	opEngine.journalEngine = journal.startEngine()
+
 	return opEngine.runWithExecContext(logger, &execContext)
 }
 
+// runWithExecContext runs the instructions. When some of them declare their
+// dependencies, they run alongside the others as soon as those have
+// completed. Otherwise they run in order. The context stored in execContext
+// is checked before each instruction so that a cancelled context stops the
+// engine without starting any more ops.
 func (opEngine *VClusterOpEngine) runWithExecContext(logger vlog.Printer, execContext *opEngineExecContext) error {
 	findCertsInOptions := opEngine.shouldGetCertsFromOptions()
 
+	if err := acquireDatabaseLock(execContext.ctx, logger); err != nil {
+		return err
+	}
+	if err := auditCommandStart(execContext.ctx); err != nil {
+		return err
+	}
+	if err := runPreCommandHooks(execContext.ctx, logger); err != nil {
+		return err
+	}
+	if opEngine.canRunInParallel(execContext.ctx) {
+		return opEngine.runInParallel(logger, execContext, findCertsInOptions)
+	}
 	for _, op := range opEngine.instructions {
 		err := opEngine.runInstruction(logger, execContext, op, findCertsInOptions)
 		if err != nil {
@@ -58,7 +88,106 @@ func (opEngine *VClusterOpEngine) runWithExecContext(logger vlog.Printer, execCo
 	return nil
 }
 
+// runInstruction runs one op. The op runs in a span of its own, which the
+// spans of its requests are children of.
 func (opEngine *VClusterOpEngine) runInstruction(
+	logger vlog.Printer, execContext *opEngineExecContext,
+	op clusterOp, findCertsInOptions bool) error {
+	ctx := execContext.ctx
+	spanCtx, span := startSpan(ctx, op.getName(), trace.SpanKindInternal,
+		attribute.String(spanAttrOp, op.getName()), attribute.String(spanAttrCommand, commandFromContext(ctx)))
+	execContext.ctx = spanCtx
+	err := runOpHooks(spanCtx, logger, HookPhasePre, op.getName(), nil)
+	if err == nil {
+		err = opEngine.runInstructionWithEvents(logger, execContext, op, findCertsInOptions)
+		_ = runOpHooks(spanCtx, logger, HookPhasePost, op.getName(), err)
+	}
+	execContext.ctx = ctx
+	endSpan(span, err)
+	return err
+}
+
+// runInstructionWithEvents runs one op and sends the events about its status
+// to the observer attached to the context, if there is one. When a journal is
+// attached to the context, the op is saved to it once completed.
+func (opEngine *VClusterOpEngine) runInstructionWithEvents(
+	logger vlog.Printer, execContext *opEngineExecContext,
+	op clusterOp, findCertsInOptions bool) error {
+	startTime := time.Now()
+	sendOpStatusEvent(execContext.ctx, op, OpEventStarted, startTime, nil)
+
+	journal := journalFromContext(execContext.ctx)
+	index := opEngine.instructionIndex(op)
+	entry, err := journal.entryFor(opEngine.journalEngine, index, op)
+	if err != nil {
+		sendOpStatusEvent(execContext.ctx, op, OpEventFailed, startTime, err)
+		return err
+	}
+	if entry != nil {
+		return opEngine.resumeInstruction(logger, execContext, op, findCertsInOptions, entry, startTime)
+	}
+
+	err = opEngine.runInstructionSteps(logger, execContext, op, findCertsInOptions)
+	if err != nil {
+		sendOpStatusEvent(execContext.ctx, op, OpEventFailed, startTime, err)
+		return err
+	}
+
+	// the op has already changed the cluster, so failing to save it must
+	// not fail the command
+	if err := journal.record(opEngine.journalEngine, index, op, execContext); err != nil {
+		logger.PrintWarning("[%s] could not be saved to the journal, details: %v", op.getName(), err)
+	}
+
+	eventType := OpEventFinished
+	if op.isSkipExecute() || (isPlanMode(execContext.ctx) && !canExecuteInPlanMode(op)) {
+		eventType = OpEventSkipped
+	}
+	sendOpStatusEvent(execContext.ctx, op, eventType, startTime, nil)
+	return nil
+}
+
+// resumeInstruction handles an op that the journal shows as completed by an
+// earlier run. An op that only read the cluster is run again to rebuild the
+// state that later ops and the V* command need. If it now fails, which is
+// what a pre-check does once the earlier run has made its changes, the state
+// saved in the journal is used instead. Any other op is skipped, and the state
+// saved after it is restored.
+func (opEngine *VClusterOpEngine) resumeInstruction(
+	logger vlog.Printer, execContext *opEngineExecContext,
+	op clusterOp, findCertsInOptions bool, entry *journalEntry, startTime time.Time) error {
+	if entry.ReadOnly {
+		err := opEngine.runInstructionSteps(logger, execContext, op, findCertsInOptions)
+		if err == nil {
+			sendOpStatusEvent(execContext.ctx, op, OpEventFinished, startTime, nil)
+			return nil
+		}
+		if execContext.ctx.Err() != nil {
+			sendOpStatusEvent(execContext.ctx, op, OpEventFailed, startTime, err)
+			return err
+		}
+		logger.PrintWarning("[%s] failed when run again to resume, using the state saved in the journal instead. Details: %v",
+			op.getName(), err)
+	} else {
+		logger.PrintInfo("[%s] was completed by an earlier run, skipping it", op.getName())
+	}
+	journalFromContext(execContext.ctx).restoreState(entry, execContext)
+	sendOpStatusEvent(execContext.ctx, op, OpEventSkipped, startTime, nil)
+	return nil
+}
+
+// instructionIndex returns the index of op in the instructions, which
+// identifies it in the journal
+func (opEngine *VClusterOpEngine) instructionIndex(op clusterOp) int {
+	for i, instruction := range opEngine.instructions {
+		if instruction == op {
+			return i
+		}
+	}
+	return -1
+}
+
+func (opEngine *VClusterOpEngine) runInstructionSteps(
 	logger vlog.Printer, execContext *opEngineExecContext,
 	op clusterOp, findCertsInOptions bool) error {
 	op.setLogger(logger)
@@ -66,12 +195,34 @@ func (opEngine *VClusterOpEngine) runInstruction(
 	op.setupSpinner()
 	defer op.cleanupSpinner()
 
+	if ctxErr := execContext.ctx.Err(); ctxErr != nil {
+		return &ClusterOpCancelledError{OpName: op.getName(), Err: ctxErr}
+	}
+
+	// in plan mode, only read-only ops are executed
+	plan := planFromContext(execContext.ctx)
+
 	op.logPrepare()
 	err := op.prepare(execContext)
 	if err != nil {
+		if ctxErr := execContext.ctx.Err(); ctxErr != nil {
+			return makeClusterOpCancelledError(op, err, ctxErr)
+		}
+		if plan != nil {
+			// the op may need state that only an earlier op, which was
+			// planned but not executed, would have set
+			plan.addOp(execContext.ctx, op, PlanOpUnprepared, err.Error())
+			return nil
+		}
 		return fmt.Errorf("prepare %s failed, details: %w", op.getName(), err)
 	}
 
+	if plan != nil && !op.isSkipExecute() && !canExecuteInPlanMode(op) {
+		plan.addOp(execContext.ctx, op, PlanOpPlanned, "")
+		logger.Info("op is planned but not executed", "name", op.getName())
+		return nil
+	}
+
 	if !op.isSkipExecute() {
 		// start the progress spinner
 		op.startSpinner()
@@ -91,6 +242,9 @@ func (opEngine *VClusterOpEngine) runInstruction(
 			// here we do not return an error as the spinner error does not
 			// affect the functionality
 			op.stopFailSpinner()
+			if ctxErr := execContext.ctx.Err(); ctxErr != nil {
+				return makeClusterOpCancelledError(op, err, ctxErr)
+			}
 			return fmt.Errorf("execute %s failed, details: %w", op.getName(), err)
 		}
 	}
@@ -101,7 +255,45 @@ func (opEngine *VClusterOpEngine) runInstruction(
 		return fmt.Errorf("finalize failed %w", err)
 	}
 
+	if plan != nil {
+		status := PlanOpExecuted
+		if op.isSkipExecute() {
+			status = PlanOpSkipped
+		}
+		plan.addOp(execContext.ctx, op, status, "")
+	}
+
 	logger.PrintInfo("[%s] is successfully completed", op.getName())
 
 	return nil
 }
+
+// ClusterOpCancelledError is returned by the op engine when the context passed
+// to a V* command is cancelled, or its deadline expires, while an op is running.
+// It wraps the context error so callers can still use errors.Is with
+// context.Canceled or context.DeadlineExceeded.
+type ClusterOpCancelledError struct {
+	OpName string
+	Err    error
+}
+
+// makeClusterOpCancelledError returns the error of op when the context was
+// done while it ran. The error of the op is kept when it wraps the context
+// error, as it tells more, such as the hosts that had not responded.
+func makeClusterOpCancelledError(op clusterOp, opErr, ctxErr error) *ClusterOpCancelledError {
+	if opErr == nil || !errors.Is(opErr, ctxErr) {
+		opErr = ctxErr
+	}
+	return &ClusterOpCancelledError{OpName: op.getName(), Err: opErr}
+}
+
+func (e *ClusterOpCancelledError) Error() string {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return fmt.Sprintf("operation %s did not complete before the deadline: %v", e.OpName, e.Err)
+	}
+	return fmt.Sprintf("operation %s was cancelled: %v", e.OpName, e.Err)
+}
+
+func (e *ClusterOpCancelledError) Unwrap() error {
+	return e.Err
+}
@@ -15,9 +15,16 @@
 
 package vclusterops
 
-import "github.com/vertica/vcluster/vclusterops/vlog"
+import (
+	"context"
+
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
 
 type opEngineExecContext struct {
+	// ctx is the context of the V* command that runs the op engine. It is
+	// checked between ops and passed down to the HTTP adapters and pollers.
+	ctx             context.Context
 	dispatcher      requestDispatcher
 	networkProfiles map[string]networkProfile
 	nmaVDatabase    nmaVDatabase
@@ -41,8 +48,9 @@ type opEngineExecContext struct {
 	hostsWithWrongAuth []string
 }
 
-func makeOpEngineExecContext(logger vlog.Printer) opEngineExecContext {
+func makeOpEngineExecContext(ctx context.Context, logger vlog.Printer) opEngineExecContext {
 	newOpEngineExecContext := opEngineExecContext{}
+	newOpEngineExecContext.ctx = ctx
 	newOpEngineExecContext.dispatcher = makeHTTPRequestDispatcher(logger)
 
 	return newOpEngineExecContext
@@ -0,0 +1,185 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+// opTurn is the turn of an op run in parallel to use the exec context. The
+// ops all read and write the same exec context, so only the op that has the
+// turn runs. It gives the turn up while its requests are in flight, or while
+// it waits to poll again, and the other ops run meanwhile. The context and
+// the dispatcher of the exec context are the ones of the op that has the
+// turn.
+type opTurn struct {
+	mu          *sync.Mutex
+	execContext *opEngineExecContext
+	ctx         context.Context
+	dispatcher  requestDispatcher
+}
+
+type opTurnContextKey struct{}
+
+func makeOpTurn(mu *sync.Mutex, execContext *opEngineExecContext, ctx context.Context,
+	logger vlog.Printer) *opTurn {
+	turn := &opTurn{mu: mu, execContext: execContext, dispatcher: makeHTTPRequestDispatcher(logger)}
+	turn.ctx = context.WithValue(ctx, opTurnContextKey{}, turn)
+	return turn
+}
+
+// opTurnFromContext returns the turn of the op run under ctx, nil if the ops
+// are not run in parallel
+func opTurnFromContext(ctx context.Context) *opTurn {
+	turn, _ := ctx.Value(opTurnContextKey{}).(*opTurn)
+	return turn
+}
+
+// acquire waits for the turn, and then sets the context and the dispatcher
+// of the op in the exec context
+func (turn *opTurn) acquire() {
+	if turn == nil {
+		return
+	}
+	turn.mu.Lock()
+	turn.execContext.ctx = turn.ctx
+	turn.execContext.dispatcher = turn.dispatcher
+}
+
+// release saves the context and the dispatcher of the op, and gives the turn
+// up. The op must not use the exec context until it acquires the turn again.
+func (turn *opTurn) release() {
+	if turn == nil {
+		return
+	}
+	turn.ctx = turn.execContext.ctx
+	turn.dispatcher = turn.execContext.dispatcher
+	turn.mu.Unlock()
+}
+
+// canRunInParallel is true if some instructions declare their dependencies.
+// The plan is in the order of the instructions, so in plan mode the
+// instructions always run in order.
+func (opEngine *VClusterOpEngine) canRunInParallel(ctx context.Context) bool {
+	if isPlanMode(ctx) {
+		return false
+	}
+	for _, op := range opEngine.instructions {
+		if _, declared := op.getDependencies(); declared {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyGraph returns the indexes of the instructions each instruction
+// waits for. A dependency that is not an instruction, such as an op that was
+// only added under some options, is ignored.
+func (opEngine *VClusterOpEngine) dependencyGraph() [][]int {
+	indexes := make(map[clusterOp]int, len(opEngine.instructions))
+	for i, op := range opEngine.instructions {
+		indexes[op] = i
+	}
+	graph := make([][]int, len(opEngine.instructions))
+	for i, op := range opEngine.instructions {
+		dependencies, declared := op.getDependencies()
+		if !declared {
+			for j := 0; j < i; j++ {
+				graph[i] = append(graph[i], j)
+			}
+			continue
+		}
+		for _, dependency := range dependencies {
+			if j, ok := indexes[dependency]; ok {
+				graph[i] = append(graph[i], j)
+			}
+		}
+	}
+	return graph
+}
+
+type parallelOpResult struct {
+	index int
+	err   error
+}
+
+// runInParallel runs each instruction once the ones it depends on have
+// completed, alongside the other instructions that can run. Once an op
+// fails, no more ops are started, and the error of the first op that failed
+// is returned after the running ones have completed.
+func (opEngine *VClusterOpEngine) runInParallel(logger vlog.Printer, execContext *opEngineExecContext,
+	findCertsInOptions bool) error {
+	graph := opEngine.dependencyGraph()
+	completed := make([]bool, len(graph))
+	started := make([]bool, len(graph))
+	results := make(chan parallelOpResult, len(graph))
+	ctx := execContext.ctx
+	dispatcher := execContext.dispatcher
+	mu := &sync.Mutex{}
+
+	running := 0
+	var firstErr error
+	for {
+		for i := range graph {
+			if firstErr != nil || started[i] || !dependenciesCompleted(graph[i], completed) {
+				continue
+			}
+			started[i] = true
+			running++
+			turn := makeOpTurn(mu, execContext, ctx, logger)
+			go func(i int, op clusterOp) {
+				turn.acquire()
+				err := opEngine.runInstruction(logger, execContext, op, findCertsInOptions)
+				turn.release()
+				results <- parallelOpResult{index: i, err: err}
+			}(i, opEngine.instructions[i])
+		}
+		if running == 0 {
+			break
+		}
+		result := <-results
+		running--
+		completed[result.index] = true
+		if result.err != nil && firstErr == nil {
+			firstErr = result.err
+		}
+	}
+
+	execContext.ctx = ctx
+	execContext.dispatcher = dispatcher
+	if firstErr != nil {
+		return firstErr
+	}
+	for i := range graph {
+		if !started[i] {
+			return fmt.Errorf("the dependencies of %s are not met by the ops before it", opEngine.instructions[i].getName())
+		}
+	}
+	return nil
+}
+
+func dependenciesCompleted(dependencies []int, completed []bool) bool {
+	for _, j := range dependencies {
+		if !completed[j] {
+			return false
+		}
+	}
+	return true
+}
@@ -16,8 +16,11 @@
 package vclusterops
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vertica/vcluster/vclusterops/vlog"
@@ -75,7 +78,7 @@ func TestSkipExecuteOp(t *testing.T) {
 	instructions := []clusterOp{&opWithSkipDisabled, &opWithSkipEnabled}
 	certs := httpsCerts{key: "key", cert: "cert", caCert: "ca-cert"}
 	opEngn := makeClusterOpEngine(instructions, &certs)
-	err := opEngn.run(vlog.Printer{})
+	err := opEngn.run(context.Background(), vlog.Printer{})
 	assert.Equal(t, nil, err)
 	assert.True(t, opWithSkipDisabled.calledPrepare)
 	assert.True(t, opWithSkipDisabled.calledExecute)
@@ -84,3 +87,95 @@ func TestSkipExecuteOp(t *testing.T) {
 	assert.False(t, opWithSkipEnabled.calledExecute)
 	assert.True(t, opWithSkipEnabled.calledFinalize)
 }
+
+func TestCancelledOpEngine(t *testing.T) {
+	op1 := makeMockOp(false)
+	op2 := makeMockOp(true)
+	instructions := []clusterOp{&op1, &op2}
+	certs := httpsCerts{}
+	opEngn := makeClusterOpEngine(instructions, &certs)
+
+	// a cancelled context should stop the engine before the first op runs
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := opEngn.run(ctx, vlog.Printer{})
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	var cancelErr *ClusterOpCancelledError
+	assert.True(t, errors.As(err, &cancelErr))
+	assert.Equal(t, op1.getName(), cancelErr.OpName)
+	assert.False(t, op1.calledPrepare)
+	assert.False(t, op2.calledPrepare)
+}
+
+// parallelMockOp runs its run func instead of the execute of mockOp
+type parallelMockOp struct {
+	mockOp
+	run func(execContext *opEngineExecContext) error
+}
+
+func makeParallelMockOp(name string, run func(execContext *opEngineExecContext) error) *parallelMockOp {
+	op := &parallelMockOp{mockOp: makeMockOp(false), run: run}
+	op.name = name
+	return op
+}
+
+func (m *parallelMockOp) execute(execContext *opEngineExecContext) error {
+	m.calledExecute = true
+	return m.run(execContext)
+}
+
+func TestParallelOpEngine(t *testing.T) {
+	var order []string
+	bDone := make(chan struct{})
+	// a waits for b, as if it was polling, which only completes if b runs
+	// while a waits
+	a := makeParallelMockOp("a", func(execContext *opEngineExecContext) error {
+		turn := opTurnFromContext(execContext.ctx)
+		turn.release()
+		defer turn.acquire()
+		select {
+		case <-bDone:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("b did not run alongside a")
+		}
+	})
+	b := makeParallelMockOp("b", func(_ *opEngineExecContext) error {
+		order = append(order, "b")
+		close(bDone)
+		return nil
+	})
+	c := makeParallelMockOp("c", func(_ *opEngineExecContext) error {
+		order = append(order, "c")
+		return nil
+	})
+	a.setDependencies()
+	b.setDependencies()
+	opEngn := makeClusterOpEngine([]clusterOp{a, b, c}, &httpsCerts{})
+	err := opEngn.run(context.Background(), vlog.Printer{})
+	assert.NoError(t, err)
+	// c does not declare its dependencies, so it waits for all the ops before it
+	assert.Equal(t, []string{"b", "c"}, order)
+
+	// once an op fails, the ops that depend on it do not run
+	failed := makeParallelMockOp("failed", func(_ *opEngineExecContext) error {
+		return errors.New("failed")
+	})
+	next := makeParallelMockOp("next", func(_ *opEngineExecContext) error { return nil })
+	failed.setDependencies()
+	next.setDependencies(failed)
+	opEngn = makeClusterOpEngine([]clusterOp{failed, next}, &httpsCerts{})
+	err = opEngn.run(context.Background(), vlog.Printer{})
+	assert.ErrorContains(t, err, "failed")
+	assert.False(t, next.calledPrepare)
+
+	// an op cannot depend on a later one
+	first := makeParallelMockOp("first", func(_ *opEngineExecContext) error { return nil })
+	second := makeParallelMockOp("second", func(_ *opEngineExecContext) error { return nil })
+	first.setDependencies(second)
+	second.setDependencies(first)
+	opEngn = makeClusterOpEngine([]clusterOp{first, second}, &httpsCerts{})
+	err = opEngn.run(context.Background(), vlog.Printer{})
+	assert.ErrorContains(t, err, "dependencies of first are not met")
+}
@@ -0,0 +1,133 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// OpEventType is the kind of progress an OpEvent reports
+type OpEventType string
+
+const (
+	// OpEventStarted is sent before an op is prepared
+	OpEventStarted OpEventType = "op_started"
+	// OpEventSkipped is sent when an op found during prepare that it has
+	// nothing to execute
+	OpEventSkipped OpEventType = "op_skipped"
+	// OpEventFinished is sent when an op completed successfully
+	OpEventFinished OpEventType = "op_finished"
+	// OpEventFailed is sent when an op failed, or was cancelled
+	OpEventFailed OpEventType = "op_failed"
+	// OpEventRequestSent is sent for each host an op sends a request to
+	OpEventRequestSent OpEventType = "request_sent"
+	// OpEventRequestResult is sent for each host that returned a result
+	OpEventRequestResult OpEventType = "request_result"
+	// OpEventPollIteration is sent each time a polling op checks the state
+	// it is waiting for
+	OpEventPollIteration OpEventType = "poll_iteration"
+)
+
+// OpEvent is one event of the op engine. Only the fields that apply to the
+// event type are set.
+type OpEvent struct {
+	Type        OpEventType `json:"type"`
+	Time        time.Time   `json:"time"`
+	OpName      string      `json:"op_name"`
+	Description string      `json:"description,omitempty"`
+	Host        string      `json:"host,omitempty"`
+	Method      string      `json:"method,omitempty"`
+	Endpoint    string      `json:"endpoint,omitempty"`
+	// Status is the result status of a request: SUCCESS, FAILURE or EXCEPTION
+	Status     string `json:"status,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	// Attempts is the number of times a request was sent, more than 1 when
+	// it was retried
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// ElapsedSeconds is the time spent in the op, or in polling, so far
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+	// PollCount is the number of times a polling op has checked the state
+	PollCount int `json:"poll_count,omitempty"`
+}
+
+// OpObserver receives the events of the op engines run by a V* command. The
+// ops that do not depend on each other run at the same time, so OnOpEvent
+// can be called from several goroutines at once. It must be safe for
+// concurrent use, and should return quickly.
+type OpObserver interface {
+	OnOpEvent(event OpEvent)
+}
+
+// OpObserverFunc lets a plain function be used as an OpObserver
+type OpObserverFunc func(event OpEvent)
+
+func (f OpObserverFunc) OnOpEvent(event OpEvent) {
+	f(event)
+}
+
+type observerContextKey struct{}
+
+// attachObserver attaches the observer of the options to ctx so that every op
+// engine, adapter pool and poller run under it can send events. An observer
+// already on ctx, set by an outer V* command, takes precedence.
+func (opt *DatabaseOptions) attachObserver(ctx context.Context) context.Context {
+	if opt.Observer == nil || observerFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, observerContextKey{}, opt.Observer)
+}
+
+func observerFromContext(ctx context.Context) OpObserver {
+	observer, _ := ctx.Value(observerContextKey{}).(OpObserver)
+	return observer
+}
+
+// sendOpEvent passes event to the observer attached to ctx, if there is one
+func sendOpEvent(ctx context.Context, event *OpEvent) {
+	observer := observerFromContext(ctx)
+	if observer == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	observer.OnOpEvent(*event)
+}
+
+// sendOpStatusEvent sends an event about the status of a whole op, and counts
+// the ops that ended in the metrics. startTime is when the op started, it is
+// ignored for OpEventStarted.
+func sendOpStatusEvent(ctx context.Context, op clusterOp, eventType OpEventType, startTime time.Time, err error) {
+	event := OpEvent{
+		Type:   eventType,
+		OpName: op.getName(),
+	}
+	if eventType == OpEventStarted {
+		event.Description = op.getDescription()
+	} else {
+		elapsed := time.Since(startTime)
+		event.ElapsedSeconds = elapsed.Seconds()
+		status := strings.TrimPrefix(string(eventType), "op_")
+		metricsFromContext(ctx).observeOp(commandFromContext(ctx), op.getName(), status, elapsed)
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	sendOpEvent(ctx, &event)
+}
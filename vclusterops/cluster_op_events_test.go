@@ -0,0 +1,76 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+type mockFailedOp struct {
+	mockOp
+}
+
+func (m *mockFailedOp) execute(_ *opEngineExecContext) error {
+	m.calledExecute = true
+	return errors.New("mock failure")
+}
+
+func TestOpEngineEvents(t *testing.T) {
+	var events []OpEvent
+	options := DatabaseOptionsFactory()
+	options.Observer = OpObserverFunc(func(event OpEvent) {
+		events = append(events, event)
+	})
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, "")
+
+	op1 := makeMockOp(false)
+	op2 := makeMockOp(true)
+	opEngn := makeClusterOpEngine([]clusterOp{&op1, &op2}, &httpsCerts{})
+	err := opEngn.run(ctx, vlog.Printer{})
+	assert.NoError(t, err)
+
+	expectedTypes := []OpEventType{OpEventStarted, OpEventFinished, OpEventStarted, OpEventSkipped}
+	assert.Len(t, events, len(expectedTypes))
+	for i, event := range events {
+		assert.Equal(t, expectedTypes[i], event.Type)
+		assert.False(t, event.Time.IsZero())
+	}
+	assert.Equal(t, op1.getName(), events[1].OpName)
+	assert.Equal(t, op2.getName(), events[3].OpName)
+
+	// a failed op reports the error
+	events = nil
+	failedOp := mockFailedOp{mockOp: makeMockOp(false)}
+	opEngn = makeClusterOpEngine([]clusterOp{&failedOp}, &httpsCerts{})
+	err = opEngn.run(ctx, vlog.Printer{})
+	assert.Error(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, OpEventFailed, events[1].Type)
+	assert.Contains(t, events[1].Error, "mock failure")
+
+	// the observer of an outer command is kept by nested commands
+	nestedOptions := DatabaseOptionsFactory()
+	nestedOptions.Observer = OpObserverFunc(func(_ OpEvent) {})
+	nestedCtx := VClusterCommands{}.setupContext(ctx, &nestedOptions, "")
+	events = nil
+	observerFromContext(nestedCtx).OnOpEvent(OpEvent{Type: OpEventStarted})
+	assert.Len(t, events, 1)
+}
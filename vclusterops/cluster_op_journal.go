@@ -0,0 +1,328 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	journalVersion  = 2
+	journalFilePerm = 0600
+)
+
+// opJournal is the on-disk record of the ops a command has completed. It is
+// saved after every op, so when a command fails the journal tells which ops
+// can be skipped to resume it, and which changes to undo to roll it back.
+// The entries are in the order the ops completed, which is not the order of
+// the instructions when the ops run in parallel.
+type opJournal struct {
+	Version int            `json:"version"`
+	Entries []journalEntry `json:"entries"`
+
+	path   string
+	resume bool
+	// number of op engines run under the journal so far
+	engines int
+	// when resuming, the latest entry whose state was restored, -1 if none
+	restored int
+	loadErr  error
+	// set once the journal could not be saved, to stop trying
+	saveFailed bool
+	once       sync.Once
+	mu         sync.Mutex
+}
+
+// journalEntry is one completed op
+type journalEntry struct {
+	OpName string `json:"op_name"`
+	// Engine and Index tell which op the entry is: the instruction at Index
+	// of the Engine-th op engine run under the journal
+	Engine      int       `json:"engine"`
+	Index       int       `json:"index"`
+	CompletedAt time.Time `json:"completed_at"`
+	// ReadOnly is true for ops that did not change the cluster, including
+	// the ones that had nothing to execute. They are run again on resume.
+	ReadOnly bool `json:"read_only"`
+	// State is the op engine state right after the op completed
+	State journalState `json:"state"`
+	// Rollback holds the requests that undo the changes of the op
+	Rollback []journalRequest `json:"rollback,omitempty"`
+}
+
+// journalState is the part of opEngineExecContext that later ops rely on
+type journalState struct {
+	UpHosts                       []string                  `json:"up_hosts,omitempty"`
+	NodesInfo                     []NodeInfo                `json:"nodes_info,omitempty"`
+	ScNodesInfo                   []NodeInfo                `json:"sc_nodes_info,omitempty"`
+	NetworkProfiles               map[string]networkProfile `json:"network_profiles,omitempty"`
+	NmaVDatabase                  nmaVDatabase              `json:"nma_vdatabase"`
+	UpScInfo                      map[string]string         `json:"up_sc_info,omitempty"`
+	UpHostsToSandboxes            map[string]string         `json:"up_hosts_to_sandboxes,omitempty"`
+	DefaultSCName                 string                    `json:"default_sc_name,omitempty"`
+	HostsWithLatestCatalog        []string                  `json:"hosts_with_latest_catalog,omitempty"`
+	PrimaryHostsWithLatestCatalog []string                  `json:"primary_hosts_with_latest_catalog,omitempty"`
+	StartupCommandMap             map[string][]string       `json:"startup_command_map,omitempty"`
+	DBInfo                        string                    `json:"db_info,omitempty"`
+}
+
+// journalRequest is an HTTP request saved in the journal to undo an op. The
+// password is never saved; requests that need one get it from the options
+// of the rollback.
+type journalRequest struct {
+	Host            string            `json:"host"`
+	Method          string            `json:"method"`
+	Endpoint        string            `json:"endpoint"`
+	IsNMACommand    bool              `json:"is_nma_command"`
+	QueryParams     map[string]string `json:"query_params,omitempty"`
+	RequestData     string            `json:"request_data,omitempty"`
+	UseHTTPPassword bool              `json:"use_http_password,omitempty"`
+	Username        string            `json:"username,omitempty"`
+}
+
+// compensatableOp is implemented by ops whose changes can be undone. The
+// requests are built after the op has executed, and saved in its journal
+// entry.
+type compensatableOp interface {
+	compensatingRequests() ([]journalRequest, error)
+}
+
+type journalContextKey struct{}
+
+// ContextWithJournal returns a copy of ctx that makes the op engines run
+// under it save each completed op to the journal file at path. If resume is
+// true, the ops already in the journal are skipped, and the ones after them
+// are added to it. Several V* commands run under the same context share the
+// journal, which is how a caller resumes a sequence of commands.
+func ContextWithJournal(ctx context.Context, path string, resume bool) context.Context {
+	journal := &opJournal{Version: journalVersion, path: path, resume: resume, restored: -1}
+	return context.WithValue(ctx, journalContextKey{}, journal)
+}
+
+// attachJournal attaches the journal of the options to ctx, unless ctx
+// already carries one
+func (opt *DatabaseOptions) attachJournal(ctx context.Context) context.Context {
+	if opt.JournalPath == "" || journalFromContext(ctx) != nil {
+		return ctx
+	}
+	return ContextWithJournal(ctx, opt.JournalPath, opt.ResumeFromJournal)
+}
+
+// contextWithoutJournal returns a copy of ctx under which the op engines do
+// not save their ops to the journal of ctx
+func contextWithoutJournal(ctx context.Context) context.Context {
+	if journalFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, journalContextKey{}, (*opJournal)(nil))
+}
+
+func journalFromContext(ctx context.Context) *opJournal {
+	journal, _ := ctx.Value(journalContextKey{}).(*opJournal)
+	return journal
+}
+
+// readJournal reads the journal file at path
+func readJournal(path string) (*opJournal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read journal %s: %w", path, err)
+	}
+	journal := &opJournal{path: path}
+	err = json.Unmarshal(data, journal)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse journal %s: %w", path, err)
+	}
+	if journal.Version != journalVersion {
+		return nil, fmt.Errorf("journal %s has version %d, only version %d is supported",
+			path, journal.Version, journalVersion)
+	}
+	return journal, nil
+}
+
+// load reads the journal file the first time it is called when resuming.
+// Otherwise the journal starts empty and the file is only written once the
+// first op completes.
+func (journal *opJournal) load() error {
+	if journal == nil || !journal.resume {
+		return nil
+	}
+	journal.once.Do(func() {
+		onDisk, err := readJournal(journal.path)
+		if err != nil {
+			journal.loadErr = err
+			return
+		}
+		journal.Entries = onDisk.Entries
+	})
+	return journal.loadErr
+}
+
+// startEngine returns the number of the op engine about to run, which the
+// entries of its ops are saved with
+func (journal *opJournal) startEngine() int {
+	if journal == nil {
+		return 0
+	}
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	engine := journal.engines
+	journal.engines++
+	return engine
+}
+
+// entryFor returns the journal entry of the op at index of the engine when
+// resuming, or nil if the op was not completed by the earlier run. The
+// engines must have the same instructions as in the run that wrote the
+// journal.
+func (journal *opJournal) entryFor(engine, index int, op clusterOp) (*journalEntry, error) {
+	if journal == nil || !journal.resume {
+		return nil, nil
+	}
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	for i := range journal.Entries {
+		entry := &journal.Entries[i]
+		if entry.Engine != engine || entry.Index != index {
+			continue
+		}
+		if entry.OpName != op.getName() {
+			return nil, fmt.Errorf("cannot resume from journal %s: op %d of engine %d is %s but the journal has %s",
+				journal.path, index, engine, op.getName(), entry.OpName)
+		}
+		return entry, nil
+	}
+	return nil, nil
+}
+
+// restoreState restores the state saved with entry, unless the state of an
+// entry completed later has already been restored. The ops run in parallel
+// are not resumed in the order they completed, and the state of a later
+// entry includes the changes of the earlier ones.
+func (journal *opJournal) restoreState(entry *journalEntry, execContext *opEngineExecContext) {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	for i := range journal.Entries {
+		if &journal.Entries[i] != entry {
+			continue
+		}
+		if i > journal.restored {
+			entry.State.restore(execContext)
+			journal.restored = i
+		}
+		return
+	}
+}
+
+// record adds the op at index of the engine, once completed, to the journal
+// and saves it. Only the first failure to save the journal is returned.
+func (journal *opJournal) record(engine, index int, op clusterOp, execContext *opEngineExecContext) error {
+	if journal == nil || journal.saveFailed || isPlanMode(execContext.ctx) {
+		return nil
+	}
+	entry := journalEntry{
+		OpName:      op.getName(),
+		Engine:      engine,
+		Index:       index,
+		CompletedAt: time.Now(),
+		ReadOnly:    op.isSkipExecute() || op.isReadOnly(),
+		State:       makeJournalState(execContext),
+	}
+	if compensatable, ok := op.(compensatableOp); ok && !op.isSkipExecute() {
+		requests, err := compensatable.compensatingRequests()
+		if err != nil {
+			return fmt.Errorf("fail to build the rollback requests of %s: %w", op.getName(), err)
+		}
+		entry.Rollback = requests
+	}
+
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+	journal.Entries = append(journal.Entries, entry)
+	err := journal.save()
+	journal.saveFailed = err != nil
+	return err
+}
+
+// save writes the journal to a temporary file that then replaces the old
+// one, so that a crash never leaves a truncated journal behind
+func (journal *opJournal) save() error {
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fail to marshal journal: %w", err)
+	}
+	tmpPath := journal.path + ".tmp"
+	err = os.WriteFile(tmpPath, data, journalFilePerm)
+	if err != nil {
+		return fmt.Errorf("fail to write journal %s: %w", tmpPath, err)
+	}
+	err = os.Rename(tmpPath, journal.path)
+	if err != nil {
+		return fmt.Errorf("fail to replace journal %s: %w", journal.path, err)
+	}
+	return nil
+}
+
+func makeJournalState(execContext *opEngineExecContext) journalState {
+	return journalState{
+		UpHosts:                       execContext.upHosts,
+		NodesInfo:                     execContext.nodesInfo,
+		ScNodesInfo:                   execContext.scNodesInfo,
+		NetworkProfiles:               execContext.networkProfiles,
+		NmaVDatabase:                  execContext.nmaVDatabase,
+		UpScInfo:                      execContext.upScInfo,
+		UpHostsToSandboxes:            execContext.upHostsToSandboxes,
+		DefaultSCName:                 execContext.defaultSCName,
+		HostsWithLatestCatalog:        execContext.hostsWithLatestCatalog,
+		PrimaryHostsWithLatestCatalog: execContext.primaryHostsWithLatestCatalog,
+		StartupCommandMap:             execContext.startupCommandMap,
+		DBInfo:                        execContext.dbInfo,
+	}
+}
+
+func (state *journalState) restore(execContext *opEngineExecContext) {
+	execContext.upHosts = state.UpHosts
+	execContext.nodesInfo = state.NodesInfo
+	execContext.scNodesInfo = state.ScNodesInfo
+	execContext.networkProfiles = state.NetworkProfiles
+	execContext.nmaVDatabase = state.NmaVDatabase
+	execContext.upScInfo = state.UpScInfo
+	execContext.upHostsToSandboxes = state.UpHostsToSandboxes
+	execContext.defaultSCName = state.DefaultSCName
+	execContext.hostsWithLatestCatalog = state.HostsWithLatestCatalog
+	execContext.primaryHostsWithLatestCatalog = state.PrimaryHostsWithLatestCatalog
+	execContext.startupCommandMap = state.StartupCommandMap
+	execContext.dbInfo = state.DBInfo
+}
+
+// makeJournalRequest saves request so it can be sent again by a rollback
+func makeJournalRequest(host string, request *hostHTTPRequest) journalRequest {
+	return journalRequest{
+		Host:            host,
+		Method:          request.Method,
+		Endpoint:        request.Endpoint,
+		IsNMACommand:    request.IsNMACommand,
+		QueryParams:     request.QueryParams,
+		RequestData:     request.RequestData,
+		UseHTTPPassword: request.Password != nil,
+		Username:        request.Username,
+	}
+}
@@ -0,0 +1,149 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+type mockCompensatableOp struct {
+	mockOp
+}
+
+func (m *mockCompensatableOp) compensatingRequests() ([]journalRequest, error) {
+	return []journalRequest{
+		{Host: "host1", Method: PostMethod, Endpoint: "nodes/node1/drop"},
+		{Host: "host2", Method: PostMethod, Endpoint: "nodes/node2/drop"},
+		{Host: "host1", Method: PostMethod, Endpoint: "nodes/node3/drop"},
+	}, nil
+}
+
+func TestJournalResume(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "test.journal")
+
+	readOnlyOp := mockReadOnlyOp{mockOp: makeMockOp(false)}
+	writeOp := mockCompensatableOp{mockOp: makeMockOp(false)}
+	opEngn := makeClusterOpEngine([]clusterOp{&readOnlyOp, &writeOp}, &httpsCerts{})
+	ctx := ContextWithJournal(context.Background(), journalPath, false)
+	err := opEngn.run(ctx, vlog.Printer{})
+	assert.NoError(t, err)
+
+	journal, err := readJournal(journalPath)
+	assert.NoError(t, err)
+	assert.Len(t, journal.Entries, 2)
+	assert.True(t, journal.Entries[0].ReadOnly)
+	assert.False(t, journal.Entries[1].ReadOnly)
+	assert.Len(t, journal.Entries[1].Rollback, 3)
+
+	// on resume, the read-only op runs again, the other op is skipped, and
+	// the new op is added to the journal
+	readOnlyOp = mockReadOnlyOp{mockOp: makeMockOp(false)}
+	writeOp = mockCompensatableOp{mockOp: makeMockOp(false)}
+	newOp := makeMockOp(false)
+	newOp.name = "new-op"
+	opEngn = makeClusterOpEngine([]clusterOp{&readOnlyOp, &writeOp, &newOp}, &httpsCerts{})
+	ctx = ContextWithJournal(context.Background(), journalPath, true)
+	err = opEngn.run(ctx, vlog.Printer{})
+	assert.NoError(t, err)
+	assert.True(t, readOnlyOp.calledExecute)
+	assert.False(t, writeOp.calledPrepare)
+	assert.True(t, newOp.calledExecute)
+
+	journal, err = readJournal(journalPath)
+	assert.NoError(t, err)
+	assert.Len(t, journal.Entries, 3)
+	assert.Equal(t, "new-op", journal.Entries[2].OpName)
+
+	// the ops must be the ones of the journal
+	otherOp := makeMockOp(false)
+	otherOp.name = "other-op"
+	opEngn = makeClusterOpEngine([]clusterOp{&otherOp}, &httpsCerts{})
+	ctx = ContextWithJournal(context.Background(), journalPath, true)
+	err = opEngn.run(ctx, vlog.Printer{})
+	assert.ErrorContains(t, err, "cannot resume from journal")
+	assert.False(t, otherOp.calledPrepare)
+}
+
+func TestJournalResumeInParallel(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "test.journal")
+
+	// a completes after b, which runs while a waits for it
+	makeOps := func() (a, b *parallelMockOp) {
+		bDone := make(chan struct{})
+		a = makeParallelMockOp("a", func(execContext *opEngineExecContext) error {
+			turn := opTurnFromContext(execContext.ctx)
+			turn.release()
+			defer turn.acquire()
+			<-bDone
+			return nil
+		})
+		b = makeParallelMockOp("b", func(_ *opEngineExecContext) error {
+			close(bDone)
+			return nil
+		})
+		a.setDependencies()
+		b.setDependencies()
+		return a, b
+	}
+	a, b := makeOps()
+	opEngn := makeClusterOpEngine([]clusterOp{a, b}, &httpsCerts{})
+	err := opEngn.run(ContextWithJournal(context.Background(), journalPath, false), vlog.Printer{})
+	assert.NoError(t, err)
+
+	journal, err := readJournal(journalPath)
+	assert.NoError(t, err)
+	assert.Len(t, journal.Entries, 2)
+	assert.Equal(t, "b", journal.Entries[0].OpName)
+	assert.Equal(t, 1, journal.Entries[0].Index)
+	assert.Equal(t, "a", journal.Entries[1].OpName)
+
+	// the entries are matched to the ops they were saved for, whatever the
+	// order they completed in
+	a, b = makeOps()
+	opEngn = makeClusterOpEngine([]clusterOp{a, b}, &httpsCerts{})
+	err = opEngn.run(ContextWithJournal(context.Background(), journalPath, true), vlog.Printer{})
+	assert.NoError(t, err)
+	assert.False(t, a.calledPrepare)
+	assert.False(t, b.calledPrepare)
+}
+
+func TestJournalRollbackInstructions(t *testing.T) {
+	op := mockCompensatableOp{}
+	requests, err := op.compensatingRequests()
+	assert.NoError(t, err)
+	entry := journalEntry{OpName: "HTTPSCreateNodeOp", Rollback: requests}
+
+	// host1 has two requests, so they are split into two ops
+	instructions := makeJournalRollbackInstructions(&entry, "dbadmin", nil)
+	assert.Len(t, instructions, 2)
+	first := instructions[0].(*journalRollbackOp)
+	second := instructions[1].(*journalRollbackOp)
+	assert.Equal(t, "RollbackHTTPSCreateNodeOp", first.getName())
+	assert.Len(t, first.requests, 2)
+	assert.Len(t, second.requests, 1)
+	assert.Equal(t, "nodes/node3/drop", second.requests[0].Endpoint)
+
+	// a request that needs the password fails to prepare without one
+	entry.Rollback[0].UseHTTPPassword = true
+	instructions = makeJournalRollbackInstructions(&entry, "dbadmin", nil)
+	err = instructions[0].prepare(&opEngineExecContext{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// PlannedOpStatus tells what the op engine did with an op in plan mode
+type PlannedOpStatus string
+
+const (
+	// PlanOpExecuted is for read-only ops that were run against the live cluster
+	PlanOpExecuted PlannedOpStatus = "executed"
+	// PlanOpPlanned is for ops that would have changed the cluster, so they were
+	// prepared but not executed
+	PlanOpPlanned PlannedOpStatus = "planned"
+	// PlanOpSkipped is for ops that found during prepare that there is no work to do
+	PlanOpSkipped PlannedOpStatus = "skipped"
+	// PlanOpUnprepared is for ops whose requests depend on the results of an
+	// earlier op that was not executed
+	PlanOpUnprepared PlannedOpStatus = "unprepared"
+)
+
+const redactedValue = "******"
+
+// VClusterPlan is the ordered list of ops that a command would run. It is
+// produced when DatabaseOptions.Plan is set.
+type VClusterPlan struct {
+	Ops []PlannedOp `json:"ops"`
+}
+
+// PlannedOp describes one op of a plan and the requests it sends
+type PlannedOp struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Status      PlannedOpStatus  `json:"status"`
+	Reason      string           `json:"reason,omitempty"`
+	Requests    []PlannedRequest `json:"requests,omitempty"`
+}
+
+// PlannedRequest is one HTTP request of a planned op. Sensitive values in
+// the body are redacted.
+type PlannedRequest struct {
+	Host   string `json:"host"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type planContextKey struct{}
+
+// startPlan attaches a plan to ctx if the options ask for plan mode. Every op
+// engine that runs under the returned context records its ops in that plan
+// and only executes the read-only ones. If ctx already carries a plan, for
+// instance when a V* command calls another one, the same plan is reused.
+func (opt *DatabaseOptions) startPlan(ctx context.Context) context.Context {
+	if plan := planFromContext(ctx); plan != nil {
+		opt.plan = plan
+		return ctx
+	}
+	if !opt.Plan {
+		return ctx
+	}
+	opt.plan = &VClusterPlan{}
+	return context.WithValue(ctx, planContextKey{}, opt.plan)
+}
+
+// GetPlan returns the plan built by the last command that ran with these
+// options in plan mode, or nil if the command did not run in plan mode.
+func (opt *DatabaseOptions) GetPlan() *VClusterPlan {
+	return opt.plan
+}
+
+func planFromContext(ctx context.Context) *VClusterPlan {
+	plan, _ := ctx.Value(planContextKey{}).(*VClusterPlan)
+	return plan
+}
+
+// isPlanMode returns true if the ops run under ctx should only be planned
+func isPlanMode(ctx context.Context) bool {
+	return planFromContext(ctx) != nil
+}
+
+// canExecuteInPlanMode returns true if the op can be run against a live
+// cluster without changing it. Pollers are excluded because they wait for
+// a state change that an earlier planned op would have caused.
+func canExecuteInPlanMode(op clusterOp) bool {
+	if _, isPoller := op.(statePoller); isPoller {
+		return false
+	}
+	return op.isReadOnly()
+}
+
+func (plan *VClusterPlan) addOp(ctx context.Context, op clusterOp, status PlannedOpStatus, reason string) {
+	plannedOp := op.describePlan(portsFromContext(ctx))
+	plannedOp.Status = status
+	plannedOp.Reason = reason
+	plan.Ops = append(plan.Ops, plannedOp)
+}
+
+// describePlan builds the plan entry of an op from the requests set up in prepare
+func (op *opBase) describePlan(ports *portConfig) PlannedOp {
+	plannedOp := PlannedOp{
+		Name:        op.name,
+		Description: op.description,
+	}
+	hosts := make([]string, 0, len(op.clusterHTTPRequest.RequestCollection))
+	for host := range op.clusterHTTPRequest.RequestCollection {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		request := op.clusterHTTPRequest.RequestCollection[host]
+		ports.setPort(host, &request)
+		plannedOp.Requests = append(plannedOp.Requests, PlannedRequest{
+			Host:   host,
+			Method: request.Method,
+			URL:    buildRequestURL(host, &request),
+			Body:   redactBody(request.RequestData),
+		})
+	}
+	return plannedOp
+}
+
+// redactBody masks the values of every key in a JSON request or response
+// body that may hold a password, a key or a credential. A body that is not
+// JSON is masked entirely because we cannot tell what it contains. The
+// numbers are kept as they are, even the ones too large for a float64.
+func redactBody(body string) string {
+	if body == "" {
+		return ""
+	}
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil || decoder.More() {
+		return redactedValue
+	}
+	redacted, err := json.Marshal(redactValue(data))
+	if err != nil {
+		return redactedValue
+	}
+	return string(redacted)
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, val := range v {
+			if isSensitiveKey(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(val)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return value
+}
+
+func isSensitiveKey(key string) bool {
+	sensitiveKeyParts := []string{"password", "secret", "key", "auth", "credential", "token", "security"}
+	keyLowerCase := strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(keyLowerCase, part) {
+			return true
+		}
+	}
+	return false
+}
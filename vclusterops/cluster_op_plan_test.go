@@ -0,0 +1,109 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+type mockReadOnlyOp struct {
+	mockOp
+}
+
+func (m *mockReadOnlyOp) isReadOnly() bool {
+	return true
+}
+
+func TestPlanModeOpEngine(t *testing.T) {
+	readOnlyOp := mockReadOnlyOp{mockOp: makeMockOp(false)}
+	writeOp := makeMockOp(false)
+	skippedOp := makeMockOp(true)
+	instructions := []clusterOp{&readOnlyOp, &writeOp, &skippedOp}
+	opEngn := makeClusterOpEngine(instructions, &httpsCerts{})
+
+	options := DatabaseOptionsFactory()
+	options.Plan = true
+	ctx := options.startPlan(context.Background())
+	err := opEngn.run(ctx, vlog.Printer{})
+	assert.NoError(t, err)
+
+	// only the read-only op is executed
+	assert.True(t, readOnlyOp.calledExecute)
+	assert.True(t, writeOp.calledPrepare)
+	assert.False(t, writeOp.calledExecute)
+	assert.False(t, writeOp.calledFinalize)
+	assert.False(t, skippedOp.calledExecute)
+
+	plan := options.GetPlan()
+	assert.Len(t, plan.Ops, len(instructions))
+	assert.Equal(t, PlanOpExecuted, plan.Ops[0].Status)
+	assert.Equal(t, PlanOpPlanned, plan.Ops[1].Status)
+	assert.Equal(t, PlanOpSkipped, plan.Ops[2].Status)
+	assert.Len(t, plan.Ops[1].Requests, 1)
+	assert.Equal(t, "host1", plan.Ops[1].Requests[0].Host)
+
+	// a nested command reuses the plan from the context
+	nestedOptions := DatabaseOptionsFactory()
+	nestedCtx := nestedOptions.startPlan(ctx)
+	assert.Equal(t, ctx, nestedCtx)
+	assert.Equal(t, plan, nestedOptions.GetPlan())
+
+	// without plan mode there is no plan
+	options = DatabaseOptionsFactory()
+	ctx = options.startPlan(context.Background())
+	assert.False(t, isPlanMode(ctx))
+	assert.Nil(t, options.GetPlan())
+}
+
+func TestPlanModeLocalChanges(t *testing.T) {
+	// a dry run of scrutinize does not create its output directory
+	id := filepath.Base(t.TempDir())
+	op, err := makeNMAGetScrutinizeTarOp(id, scrutinizeBatchNormal, []string{"host1"}, map[string]string{"host1": "v_db_node0001"})
+	assert.NoError(t, err)
+	opEngn := makeClusterOpEngine([]clusterOp{&op}, &httpsCerts{})
+	options := DatabaseOptionsFactory()
+	options.Plan = true
+	err = opEngn.run(options.startPlan(context.Background()), vlog.Printer{})
+	assert.NoError(t, err)
+	assert.Equal(t, PlanOpPlanned, options.GetPlan().Ops[0].Status)
+	_, err = os.Stat(filepath.Join(scrutinizeRemoteOutputPath, id))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRedactBody(t *testing.T) {
+	body := `{"db_name":"test_db","password":"secret1","parameters":{"awsauth":"id:key","path":"/data"},` +
+		`"users":[{"name":"dbadmin","AuthToken":"token1"}]}`
+	redacted := redactBody(body)
+	assert.Contains(t, redacted, `"db_name":"test_db"`)
+	assert.Contains(t, redacted, `"path":"/data"`)
+	assert.Contains(t, redacted, `"name":"dbadmin"`)
+	assert.NotContains(t, redacted, "secret1")
+	assert.NotContains(t, redacted, "id:key")
+	assert.NotContains(t, redacted, "token1")
+
+	// the numbers are not rounded
+	assert.Equal(t, `{"node_oid":45035996273704980}`, redactBody(`{"node_oid":45035996273704980}`))
+
+	// a body that is not JSON is masked entirely
+	assert.Equal(t, redactedValue, redactBody("user=dbadmin&password=secret1"))
+	assert.Equal(t, "", redactBody(""))
+}
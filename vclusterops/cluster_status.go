@@ -0,0 +1,306 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+	"golang.org/x/exp/maps"
+)
+
+// the kinds of the anomalies VClusterStatus flags
+const (
+	// AnomalyNodeDown is flagged for each node that is down
+	AnomalyNodeDown = "node_down"
+	// AnomalyMixedVersions is flagged when the nodes of the main cluster, or
+	// of a sandbox, run different versions
+	AnomalyMixedVersions = "mixed_versions"
+	// AnomalyPrimaryQuorumLost is flagged when no more than half of the
+	// primary nodes of the main cluster, or of a sandbox, are up
+	AnomalyPrimaryQuorumLost = "primary_quorum_lost"
+	// AnomalyStorageAlmostFull is flagged for each storage location whose
+	// disk is at least StorageAlmostFullPercent full
+	AnomalyStorageAlmostFull = "storage_almost_full"
+	// AnomalyDetailsUnavailable is flagged for each node that is not down
+	// but did not return its details
+	AnomalyDetailsUnavailable = "details_unavailable"
+)
+
+// StorageAlmostFullPercent is how full the disk of a storage location is,
+// in percent, when VClusterStatus flags it
+const StorageAlmostFullPercent = 90
+
+// ClusterStatus is the overview of a database returned by VClusterStatus
+type ClusterStatus struct {
+	Database string `json:"database"`
+	// Subclusters are sorted by sandbox, with the main cluster first, and
+	// then by name. An Enterprise database has a single subcluster with no
+	// name.
+	Subclusters []SubclusterStatus `json:"subclusters"`
+	Anomalies   []StatusAnomaly    `json:"anomalies"`
+}
+
+// SubclusterStatus is the status of a subcluster and of its nodes
+type SubclusterStatus struct {
+	Name      string `json:"name"`
+	IsPrimary bool   `json:"is_primary"`
+	// Sandbox is the sandbox the subcluster is in, "" for the main cluster
+	Sandbox string       `json:"sandbox"`
+	Nodes   []NodeStatus `json:"nodes"`
+}
+
+// NodeStatus is the status of a node. The details of a node, its shard
+// subscriptions and its storage locations, are only known when it returned
+// them.
+type NodeStatus struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	State     string `json:"state"`
+	Version   string `json:"version"`
+	DownSince string `json:"down_since,omitempty"`
+	// ShardSubscriptions is nil when the node did not return its details
+	ShardSubscriptions *uint             `json:"shard_subscriptions"`
+	StorageLocations   []StorageLocation `json:"storage_locations,omitempty"`
+}
+
+// StatusAnomaly is something VClusterStatus found wrong with the database
+type StatusAnomaly struct {
+	Kind string `json:"kind"`
+	// Sandbox is the sandbox the anomaly is in, "" for the main cluster
+	Sandbox string `json:"sandbox,omitempty"`
+	Node    string `json:"node,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+type VClusterStatusOptions struct {
+	DatabaseOptions
+}
+
+func VClusterStatusOptionsFactory() VClusterStatusOptions {
+	opt := VClusterStatusOptions{}
+	// set default values to the params
+	opt.setDefaultValues()
+
+	return opt
+}
+
+func (options *VClusterStatusOptions) setDefaultValues() {
+	options.DatabaseOptions.setDefaultValues()
+}
+
+func (options *VClusterStatusOptions) validateAnalyzeOptions(log vlog.Printer) (err error) {
+	err = options.validateBaseOptions(commandClusterStatus, log)
+	if err != nil {
+		return err
+	}
+	// resolve RawHosts to be IP addresses
+	if len(options.RawHosts) > 0 {
+		options.Hosts, err = util.ResolveRawHostsToAddresses(options.RawHosts, options.IPv6)
+	}
+	return err
+}
+
+// VClusterStatus returns an overview of the database: its subclusters, with
+// their role and sandbox, their nodes, with their state, version, shard
+// subscriptions and storage usage, and the anomalies found in them. The
+// nodes that are not down are asked for their details, and a node that does
+// not return them is only flagged, so that the status of a database in
+// trouble can still be shown.
+func (vcc VClusterCommands) VClusterStatus(ctx context.Context, options *VClusterStatusOptions) (status *ClusterStatus, err error) {
+	ctx = vcc.setupContext(ctx, options, commandClusterStatus)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
+	err = options.validateAnalyzeOptions(vcc.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchStateOptions := VFetchNodeStateOptionsFactory()
+	fetchStateOptions.DatabaseOptions = options.DatabaseOptions
+	fetchStateOptions.GetVersion = true
+	nodeStates, err := vcc.VFetchNodeState(ctx, &fetchStateOptions)
+	if len(nodeStates) == 0 {
+		return nil, fmt.Errorf("fail to fetch the states of the nodes: %w", err)
+	}
+	// the nodes are shown as down when their states could only be read
+	// from the catalog
+	if err != nil {
+		vcc.Log.PrintWarning("The states of the nodes could not be fetched from the database, details: %v", err)
+	}
+
+	var hosts []string
+	for i := range nodeStates {
+		if nodeStates[i].State != util.NodeDownState {
+			hosts = append(hosts, nodeStates[i].Address)
+		}
+	}
+	details := vcc.fetchStatusDetails(ctx, options, hosts)
+	return makeClusterStatus(options.DBName, nodeStates, details), nil
+}
+
+// fetchStatusDetails returns the details of the nodes on hosts, by host. If
+// some hosts fail to return them, the others are asked one by one.
+func (vcc VClusterCommands) fetchStatusDetails(ctx context.Context, options *VClusterStatusOptions,
+	hosts []string) map[string]*NodeDetails {
+	details := make(map[string]*NodeDetails, len(hosts))
+	if len(hosts) == 0 {
+		return details
+	}
+	fetch := func(hosts []string) error {
+		fetchDetailsOptions := VFetchNodesDetailsOptionsFactory()
+		fetchDetailsOptions.DatabaseOptions = options.DatabaseOptions
+		fetchDetailsOptions.RawHosts = hosts
+		fetchDetailsOptions.Hosts = nil
+		nodesDetails, err := vcc.VFetchNodesDetails(ctx, &fetchDetailsOptions)
+		for i := range nodesDetails {
+			details[nodesDetails[i].Address] = &nodesDetails[i]
+		}
+		return err
+	}
+	if err := fetch(hosts); err == nil || len(hosts) == 1 {
+		return details
+	}
+	for _, host := range hosts {
+		if err := fetch([]string{host}); err != nil {
+			vcc.Log.Info("fail to fetch the details of the node", "host", host, "details", err)
+		}
+	}
+	return details
+}
+
+// makeClusterStatus groups the nodes by subcluster, with their details, and
+// flags the anomalies
+func makeClusterStatus(dbName string, nodeStates []NodeInfo, details map[string]*NodeDetails) *ClusterStatus {
+	status := &ClusterStatus{Database: dbName, Anomalies: []StatusAnomaly{}}
+	type subclusterKey struct{ sandbox, name string }
+	subclusters := make(map[subclusterKey]*SubclusterStatus)
+	for i := range nodeStates {
+		nodeInfo := &nodeStates[i]
+		node := NodeStatus{Name: nodeInfo.Name, Address: nodeInfo.Address, State: nodeInfo.State,
+			Version: nodeInfo.Version, DownSince: nodeInfo.DownSince}
+		sandbox := nodeInfo.Sandbox
+		if nodeDetails, ok := details[nodeInfo.Address]; ok {
+			// the node knows its own state and sandbox, which the main
+			// cluster may not
+			node.State = nodeDetails.State
+			sandbox = nodeDetails.SandboxName
+			shardSubscriptions := nodeDetails.NumberShardSubscriptions
+			node.ShardSubscriptions = &shardSubscriptions
+			node.StorageLocations = nodeDetails.StorageLocList
+		} else if node.State != util.NodeDownState {
+			status.addAnomaly(AnomalyDetailsUnavailable, sandbox, node.Name, "the node did not return its details")
+		}
+		key := subclusterKey{sandbox: sandbox, name: nodeInfo.Subcluster}
+		subcluster, ok := subclusters[key]
+		if !ok {
+			subcluster = &SubclusterStatus{Name: nodeInfo.Subcluster, IsPrimary: nodeInfo.IsPrimary, Sandbox: sandbox}
+			subclusters[key] = subcluster
+		}
+		subcluster.Nodes = append(subcluster.Nodes, node)
+	}
+
+	keys := maps.Keys(subclusters)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].sandbox != keys[j].sandbox {
+			return keys[i].sandbox < keys[j].sandbox
+		}
+		return keys[i].name < keys[j].name
+	})
+	for _, key := range keys {
+		subcluster := subclusters[key]
+		sort.Slice(subcluster.Nodes, func(i, j int) bool { return subcluster.Nodes[i].Name < subcluster.Nodes[j].Name })
+		status.Subclusters = append(status.Subclusters, *subcluster)
+	}
+	status.flagAnomalies()
+	return status
+}
+
+func (status *ClusterStatus) addAnomaly(kind, sandbox, node, detail string) {
+	status.Anomalies = append(status.Anomalies, StatusAnomaly{Kind: kind, Sandbox: sandbox, Node: node, Detail: detail})
+}
+
+// flagAnomalies flags the nodes that are down or almost out of storage, and
+// checks the versions and the quorum of the main cluster and of each sandbox
+func (status *ClusterStatus) flagAnomalies() {
+	type clusterCounts struct {
+		versions               map[string]bool
+		primaries, upPrimaries int
+	}
+	var sandboxes []string
+	counts := make(map[string]*clusterCounts)
+	for i := range status.Subclusters {
+		subcluster := &status.Subclusters[i]
+		cluster, ok := counts[subcluster.Sandbox]
+		if !ok {
+			cluster = &clusterCounts{versions: make(map[string]bool)}
+			counts[subcluster.Sandbox] = cluster
+			sandboxes = append(sandboxes, subcluster.Sandbox)
+		}
+		for j := range subcluster.Nodes {
+			node := &subcluster.Nodes[j]
+			if node.Version != "" {
+				cluster.versions[node.Version] = true
+			}
+			if subcluster.IsPrimary {
+				cluster.primaries++
+				if node.State == util.NodeUpState {
+					cluster.upPrimaries++
+				}
+			}
+			if node.State == util.NodeDownState {
+				detail := "the node is down"
+				if node.DownSince != "" {
+					detail += " since " + node.DownSince
+				}
+				status.addAnomaly(AnomalyNodeDown, subcluster.Sandbox, node.Name, detail)
+			}
+			status.flagStorage(subcluster.Sandbox, node)
+		}
+	}
+	for _, sandbox := range sandboxes {
+		cluster := counts[sandbox]
+		if len(cluster.versions) > 1 {
+			versions := maps.Keys(cluster.versions)
+			sort.Strings(versions)
+			status.addAnomaly(AnomalyMixedVersions, sandbox, "",
+				fmt.Sprintf("the nodes run different versions: %s", strings.Join(versions, ", ")))
+		}
+		if cluster.primaries > 0 && cluster.upPrimaries*2 <= cluster.primaries {
+			status.addAnomaly(AnomalyPrimaryQuorumLost, sandbox, "",
+				fmt.Sprintf("only %d of the %d primary nodes are up, more than half of them must be",
+					cluster.upPrimaries, cluster.primaries))
+		}
+	}
+}
+
+// flagStorage flags the storage locations of node that are almost full
+func (status *ClusterStatus) flagStorage(sandbox string, node *NodeStatus) {
+	for i := range node.StorageLocations {
+		location := &node.StorageLocations[i]
+		percent, err := strconv.Atoi(strings.TrimSuffix(location.DiskPercent, "%"))
+		if err != nil || percent < StorageAlmostFullPercent {
+			continue
+		}
+		status.addAnomaly(AnomalyStorageAlmostFull, sandbox, node.Name,
+			fmt.Sprintf("the disk of storage location %s is %d%% full", location.Path, percent))
+	}
+}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -283,7 +284,10 @@ func (opt *VCreateDatabaseOptions) validateAnalyzeOptions(logger vlog.Printer) e
 	return opt.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VCreateDatabase(options *VCreateDatabaseOptions) (VCoordinationDatabase, error) {
+func (vcc VClusterCommands) VCreateDatabase(ctx context.Context, options *VCreateDatabaseOptions) (_ VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, options, commandCreateDB)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	vcc.Log.Info("starting VCreateDatabase")
 
 	/*
@@ -293,7 +297,7 @@ func (vcc VClusterCommands) VCreateDatabase(options *VCreateDatabaseOptions) (VC
 	 */
 	// Analyze to produce vdb info, for later create db use and for cache db info
 	vdb := makeVCoordinationDatabase()
-	err := vdb.setFromCreateDBOptions(options, vcc.Log)
+	err = vdb.setFromCreateDBOptions(options, vcc.Log)
 	if err != nil {
 		return vdb, err
 	}
@@ -309,7 +313,7 @@ func (vcc VClusterCommands) VCreateDatabase(options *VCreateDatabaseOptions) (VC
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		vcc.Log.Error(err, "fail to create database")
 		return vdb, err
@@ -412,6 +416,14 @@ func (vcc VClusterCommands) produceCreateDBBootstrapInstructions(
 		return instructions, err
 	}
 
+	// the checks do not depend on each other, and the network profiles only
+	// need the NMA to be up, so they run alongside the directory preparation
+	nmaHealthOp.setDependencies()
+	nmaVerticaVersionOp.setDependencies()
+	checkDBRunningOp.setDependencies()
+	nmaPrepareDirectoriesOp.setDependencies(&nmaHealthOp, &nmaVerticaVersionOp, &checkDBRunningOp)
+	nmaNetworkProfileOp.setDependencies(&nmaHealthOp)
+
 	instructions = append(instructions,
 		&nmaHealthOp,
 		&nmaVerticaVersionOp,
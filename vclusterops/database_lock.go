@@ -0,0 +1,447 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	lockFilePerm = 0644
+	// the time a lock is expected to be held for, when the command has no
+	// deadline
+	defaultLockDuration = 24 * time.Hour
+)
+
+// the commands run before the directory of the database exists on the hosts,
+// which only take the local lock
+var leaselessCommands = map[string]bool{
+	commandCreateDB: true,
+	commandReviveDB: true,
+}
+
+// the commands that do not change the cluster, which do not take the lock
+var readOnlyCommands = map[string]bool{
+	commandShowRestorePoints: true,
+	commandConfigRecover:     true,
+	commandFetchNodesDetails: true,
+	commandListAllNodes:      true,
+	commandClusterStatus:     true,
+	commandDoctor:            true,
+	VScrutinizeTypeName:      true,
+}
+
+// DatabaseLock tells who holds the lock on a database, and since when
+type DatabaseLock struct {
+	Database string `json:"database"`
+	// Owner is the OS user who runs the command that holds the lock
+	Owner   string    `json:"owner"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Start   time.Time `json:"start"`
+	// Expiry is when the command is expected to have completed, from its
+	// deadline if it has one. The local lock is released when the process
+	// that holds it exits, so a later expiry does not keep it. The lease on
+	// the initiator is only given up once it expires, if the command that
+	// holds it could not release it.
+	Expiry time.Time `json:"expiry"`
+}
+
+// DatabaseLockedError is returned by a command that changes the cluster when
+// another command that does holds the lock on the database
+type DatabaseLockedError struct {
+	// Path is the lock file, or the lease file on the initiator, prefixed
+	// with the host
+	Path   string
+	Holder DatabaseLock
+}
+
+func (e *DatabaseLockedError) Error() string {
+	holder := &e.Holder
+	if holder.PID == 0 {
+		return fmt.Sprintf("database %s is locked by another command, which holds %s", holder.Database, e.Path)
+	}
+	expiry := "expires"
+	if time.Now().After(holder.Expiry) {
+		expiry = "expired"
+	}
+	return fmt.Sprintf("database %s is locked by %s, running %s with PID %d on %s since %s. The lock %s at %s."+
+		" If that command is no longer running, force the unlock of the database",
+		holder.Database, holder.Owner, holder.Command, holder.PID, holder.Host,
+		holder.Start.Format(time.RFC3339), expiry, holder.Expiry.Format(time.RFC3339))
+}
+
+// databaseLock is the lock a command takes on its database. It is made of
+// an exclusive flock on a local lock file, which guards against the
+// commands run from this machine, and of a lease on the initiator, which
+// guards against the ones run from other machines. Both hold the
+// DatabaseLock of the command. The OS releases the flock of a process that
+// exits, so the local lock of a command that was killed does not have to be
+// broken, but its lease is only given up once it expires.
+type databaseLock struct {
+	path        string
+	forceUnlock bool
+	holder      DatabaseLock
+	command     *commandRun
+	file        *os.File
+	started     bool
+	// the error the lock could not be taken with, which the later op
+	// engines of the command fail with too
+	err     error
+	options *DatabaseOptions
+	// the initiator and the directory of the lease, set once it is held
+	leaseHost string
+	leaseDir  string
+}
+
+type databaseLockContextKey struct{}
+
+// attachLock attaches to ctx the lock the command takes on its database,
+// once it starts its ops. A command that does not change the cluster does
+// not take it, and neither does a command run by another one that holds it.
+func (vcc VClusterCommands) attachLock(ctx context.Context, options commandOptions) context.Context {
+	opt := options.databaseOptions()
+	run := commandRunFromContext(ctx)
+	if opt.DBName == "" || run == nil || readOnlyCommands[run.command] ||
+		ctx.Value(databaseLockContextKey{}) != nil {
+		return ctx
+	}
+	lockDir := vcc.LockDir
+	if lockDir == "" {
+		lockDir = os.TempDir()
+	}
+	lock := &databaseLock{
+		path:        filepath.Join(lockDir, fmt.Sprintf("vcluster_%s.lock", opt.DBName)),
+		forceUnlock: opt.ForceUnlock,
+		holder:      DatabaseLock{Database: opt.DBName, PID: os.Getpid(), Command: run.command},
+		command:     run,
+		options:     opt,
+	}
+	return context.WithValue(ctx, databaseLockContextKey{}, lock)
+}
+
+func databaseLockFromContext(ctx context.Context) *databaseLock {
+	lock, _ := ctx.Value(databaseLockContextKey{}).(*databaseLock)
+	if lock == nil || isPlanMode(ctx) || isReplaying(ctx) {
+		return nil
+	}
+	return lock
+}
+
+// acquireDatabaseLock takes the lock on the database of the command run under
+// ctx, if it has not taken it yet. The lease on the initiator is checked
+// before the local lock is taken, and then written. It fails with a
+// DatabaseLockedError if another command holds either of them. A lock file or
+// a lease that cannot be written does not stop the command, it only runs
+// without it.
+func acquireDatabaseLock(ctx context.Context, logger vlog.Printer) error {
+	lock := databaseLockFromContext(ctx)
+	if lock == nil {
+		return nil
+	}
+	if lock.started {
+		return lock.err
+	}
+	lock.started = true
+	lock.err = lock.acquireAll(ctx, logger)
+	return lock.err
+}
+
+// acquireAll takes the lease and the local lock
+func (lock *databaseLock) acquireAll(ctx context.Context, logger vlog.Printer) error {
+	lock.holder.Start = time.Now().UTC()
+	lock.holder.Expiry = lock.holder.Start.Add(defaultLockDuration)
+	if deadline, ok := ctx.Deadline(); ok {
+		lock.holder.Expiry = deadline.UTC()
+	}
+	lock.holder.Host, _ = os.Hostname()
+	lock.holder.Owner, _ = util.GetCurrentUsername()
+
+	leaseHost, leaseDir := lock.leaseLocation(logger)
+	if leaseHost != "" {
+		if err := lock.checkLease(ctx, logger, leaseHost, leaseDir); err != nil {
+			return err
+		}
+	}
+	err := lock.acquire(logger)
+	var lockedErr *DatabaseLockedError
+	if errors.As(err, &lockedErr) {
+		return err
+	}
+	if err != nil {
+		logger.PrintWarning("The database could not be locked, the command runs without the lock. Details: %v", err)
+	}
+	if leaseHost == "" {
+		return nil
+	}
+	err = lock.takeLease(ctx, logger, leaseHost, leaseDir)
+	if err != nil {
+		lock.releaseFile()
+	}
+	return err
+}
+
+// leaseLocation returns the initiator the lease is kept on, the first of the
+// hosts of the database, and the directory of the lease. There is no lease
+// when the command has no hosts, or runs before the directory exists.
+func (lock *databaseLock) leaseLocation(logger vlog.Printer) (host, dir string) {
+	opt := lock.options
+	if len(opt.Hosts) == 0 || leaselessCommands[lock.holder.Command] {
+		return "", ""
+	}
+	if opt.CatalogPrefix == "" {
+		logger.PrintWarning("The database is only locked on this machine, there is no catalog prefix to keep the lease in")
+		return "", ""
+	}
+	hosts := slices.Clone(opt.Hosts)
+	slices.Sort(hosts)
+	return hosts[0], filepath.Join(opt.CatalogPrefix, opt.DBName)
+}
+
+// checkLease fails with a DatabaseLockedError if another command holds the
+// lease, unless it has expired or the unlock is forced. A lease that cannot
+// be read does not stop the command.
+func (lock *databaseLock) checkLease(ctx context.Context, logger vlog.Printer, host, dir string) error {
+	holder, err := lock.readLease(ctx, logger, host, dir)
+	if err != nil {
+		logger.PrintWarning("The lease on database %s could not be read from %s. Details: %v", lock.holder.Database, host, err)
+		return nil
+	}
+	if holder == nil || holder.PID == 0 || time.Now().After(holder.Expiry) {
+		return nil
+	}
+	if lock.forceUnlock {
+		logger.PrintWarning("The lease of %s on database %s, held by PID %d on %s, was broken",
+			holder.Command, holder.Database, holder.PID, holder.Host)
+		return nil
+	}
+	return &DatabaseLockedError{Path: leasePath(host, dir), Holder: *holder}
+}
+
+// takeLease writes the lease of the command on the initiator, and reads it
+// back. The NMA cannot write the lease only if there is none, so of two
+// commands that write it at the same time, the one whose lease was
+// overwritten gives up. A lease that cannot be written does not stop the
+// command.
+func (lock *databaseLock) takeLease(ctx context.Context, logger vlog.Printer, host, dir string) error {
+	data, err := json.Marshal(&lock.holder)
+	if err != nil {
+		return err
+	}
+	writeOp := makeNMAWriteDatabaseLeaseOp(host, dir, string(data))
+	if err := lock.runLeaseOp(ctx, logger, &writeOp); err != nil {
+		logger.PrintWarning("The lease on database %s could not be written to %s, the command runs without it. Details: %v",
+			lock.holder.Database, host, err)
+		return nil
+	}
+	lock.leaseHost, lock.leaseDir = host, dir
+	holder, err := lock.readLease(ctx, logger, host, dir)
+	if err != nil || holder == nil || lock.isHolder(holder) {
+		return nil
+	}
+	lock.leaseHost, lock.leaseDir = "", ""
+	return &DatabaseLockedError{Path: leasePath(host, dir), Holder: *holder}
+}
+
+// isHolder is true if holder is the command of the lock
+func (lock *databaseLock) isHolder(holder *DatabaseLock) bool {
+	return holder.PID == lock.holder.PID && holder.Host == lock.holder.Host && holder.Start.Equal(lock.holder.Start)
+}
+
+// readLease returns the holder of the lease on the initiator, nil if there is
+// none
+func (lock *databaseLock) readLease(ctx context.Context, logger vlog.Printer, host, dir string) (*DatabaseLock, error) {
+	readOp := makeNMAReadDatabaseLeaseOp(host, dir)
+	if err := lock.runLeaseOp(ctx, logger, &readOp); err != nil {
+		return nil, err
+	}
+	if readOp.lease == "" {
+		return nil, nil
+	}
+	holder := &DatabaseLock{}
+	if err := json.Unmarshal([]byte(readOp.lease), holder); err != nil {
+		return nil, fmt.Errorf("fail to parse the lease %s: %w", leasePath(host, dir), err)
+	}
+	return holder, nil
+}
+
+// runLeaseOp runs an op on the lease outside of the instructions of the
+// command, with the certificates of the options. Like an instruction, it
+// runs in a span of its own.
+func (lock *databaseLock) runLeaseOp(ctx context.Context, logger vlog.Printer, op clusterOp) error {
+	opt := lock.options
+	certs := httpsCerts{key: opt.Key, cert: opt.Cert, caCert: opt.CaCert}
+	opEngine := makeClusterOpEngine([]clusterOp{op}, &certs)
+	spanCtx, span := startSpan(ctx, op.getName(), trace.SpanKindInternal,
+		attribute.String(spanAttrOp, op.getName()), attribute.String(spanAttrCommand, commandFromContext(ctx)))
+	execContext := makeOpEngineExecContext(spanCtx, logger)
+	err := opEngine.runInstructionSteps(logger, &execContext, op, opEngine.shouldGetCertsFromOptions())
+	endSpan(span, err)
+	return err
+}
+
+func leasePath(host, dir string) string {
+	return host + ":" + filepath.Join(dir, "vertica.conf")
+}
+
+func (lock *databaseLock) acquire(logger vlog.Printer) error {
+	if lock.forceUnlock {
+		holder, err := readDatabaseLock(lock.path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		// the holder keeps the flock on the file it opened, which is no
+		// longer the lock file
+		if err := os.Remove(lock.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("fail to remove the lock file %s: %w", lock.path, err)
+		}
+		if holder != nil && holder.PID != 0 {
+			logger.PrintWarning("The lock of %s on database %s, held by PID %d on %s, was broken",
+				holder.Command, holder.Database, holder.PID, holder.Host)
+		}
+	}
+	for {
+		f, err := os.OpenFile(lock.path, os.O_RDWR|os.O_CREATE, lockFilePerm)
+		if err != nil {
+			return fmt.Errorf("fail to open the lock file %s: %w", lock.path, err)
+		}
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			holder, readErr := readDatabaseLock(lock.path)
+			if readErr != nil || holder == nil {
+				holder = &DatabaseLock{Database: lock.holder.Database}
+			}
+			return &DatabaseLockedError{Path: lock.path, Holder: *holder}
+		}
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("fail to lock %s: %w", lock.path, err)
+		}
+		// the lock file may have been replaced by a forced unlock before the
+		// flock was taken, in which case the new one is locked instead
+		if sameFile(f, lock.path) {
+			lock.file = f
+			return lock.write()
+		}
+		f.Close()
+	}
+}
+
+func sameFile(f *os.File, path string) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	return err == nil && os.SameFile(info, pathInfo)
+}
+
+// write writes the holder of the lock to the lock file
+func (lock *databaseLock) write() error {
+	data, err := json.Marshal(&lock.holder)
+	if err != nil {
+		return err
+	}
+	if err := lock.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = lock.file.WriteAt(data, 0)
+	return err
+}
+
+// readDatabaseLock returns the holder written in the lock file, nil if there
+// is none
+func readDatabaseLock(path string) (*DatabaseLock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	holder := &DatabaseLock{}
+	if err := json.Unmarshal(data, holder); err != nil {
+		return nil, fmt.Errorf("fail to read the lock file %s: %w", path, err)
+	}
+	return holder, nil
+}
+
+// releaseDatabaseLock releases the lock taken by the command run under ctx.
+// The lease is emptied even when the command was cancelled, so that it does
+// not keep the database until it expires.
+func releaseDatabaseLock(ctx context.Context, logger vlog.Printer) {
+	lock := databaseLockFromContext(ctx)
+	if lock == nil || lock.command != commandRunFromContext(ctx) {
+		return
+	}
+	lock.releaseFile()
+	if lock.leaseHost == "" {
+		return
+	}
+	writeOp := makeNMAWriteDatabaseLeaseOp(lock.leaseHost, lock.leaseDir, "{}")
+	if err := lock.runLeaseOp(uncancelledContext{ctx}, logger, &writeOp); err != nil {
+		logger.PrintWarning("The lease on database %s could not be released on %s, it is kept until %s. Details: %v",
+			lock.holder.Database, lock.leaseHost, lock.holder.Expiry.Format(time.RFC3339), err)
+	}
+	lock.leaseHost, lock.leaseDir = "", ""
+}
+
+// releaseFile releases the local lock. The lock file is emptied rather than
+// removed, so that a command waiting on it does not lock a file that is gone.
+func (lock *databaseLock) releaseFile() {
+	if lock.file == nil {
+		return
+	}
+	// the flock is released when the file is closed anyway
+	_ = lock.file.Truncate(0)
+	lock.file.Close()
+	lock.file = nil
+}
+
+// uncancelledContext keeps the values of its context, but is never done
+type uncancelledContext struct {
+	context.Context
+}
+
+func (uncancelledContext) Deadline() (deadline time.Time, ok bool) {
+	return time.Time{}, false
+}
+
+func (uncancelledContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (uncancelledContext) Err() error {
+	return nil
+}
@@ -0,0 +1,90 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestDatabaseLock(t *testing.T) {
+	vcc := VClusterCommands{LockDir: t.TempDir()}
+	options := DatabaseOptionsFactory()
+	options.DBName = "test_db"
+	addCtx := vcc.setupContext(context.Background(), &options, commandAddNode)
+	assert.NoError(t, acquireDatabaseLock(addCtx, vlog.Printer{}))
+
+	// another command cannot run on the database meanwhile
+	removeCtx := vcc.setupContext(context.Background(), &options, commandRemoveNode)
+	err := acquireDatabaseLock(removeCtx, vlog.Printer{})
+	var lockedErr *DatabaseLockedError
+	assert.ErrorAs(t, err, &lockedErr)
+	assert.Equal(t, commandAddNode, lockedErr.Holder.Command)
+	assert.Equal(t, os.Getpid(), lockedErr.Holder.PID)
+	assert.ErrorContains(t, err, "database test_db is locked by")
+	assert.ErrorContains(t, err, "running db_add_node with PID")
+
+	// unless it does not change the cluster
+	listCtx := vcc.setupContext(context.Background(), &options, commandListAllNodes)
+	assert.NoError(t, acquireDatabaseLock(listCtx, vlog.Printer{}))
+	// or runs on another database
+	otherOptions := DatabaseOptionsFactory()
+	otherOptions.DBName = "other_db"
+	otherCtx := vcc.setupContext(context.Background(), &otherOptions, commandStopDB)
+	assert.NoError(t, acquireDatabaseLock(otherCtx, vlog.Printer{}))
+	releaseDatabaseLock(otherCtx, vlog.Printer{})
+
+	// the lock is released once the command completes
+	releaseDatabaseLock(addCtx, vlog.Printer{})
+	removeCtx = vcc.setupContext(context.Background(), &options, commandRemoveNode)
+	assert.NoError(t, acquireDatabaseLock(removeCtx, vlog.Printer{}))
+
+	// a lock that is held can be broken
+	options.ForceUnlock = true
+	stopCtx := vcc.setupContext(context.Background(), &options, commandStopDB)
+	assert.NoError(t, acquireDatabaseLock(stopCtx, vlog.Printer{}))
+	options.ForceUnlock = false
+	startCtx := vcc.setupContext(context.Background(), &options, commandStartDB)
+	err = acquireDatabaseLock(startCtx, vlog.Printer{})
+	assert.ErrorAs(t, err, &lockedErr)
+	assert.Equal(t, commandStopDB, lockedErr.Holder.Command)
+	// releasing the broken lock does not release the new one
+	releaseDatabaseLock(removeCtx, vlog.Printer{})
+	startCtx = vcc.setupContext(context.Background(), &options, commandStartDB)
+	assert.ErrorAs(t, acquireDatabaseLock(startCtx, vlog.Printer{}), &lockedErr)
+	releaseDatabaseLock(stopCtx, vlog.Printer{})
+}
+
+func TestDatabaseLockDefaultDir(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	vcc := VClusterCommands{}
+	options := DatabaseOptionsFactory()
+	options.DBName = "test_db"
+
+	// without a lock directory, the lock files are in the temporary one
+	addCtx := vcc.setupContext(context.Background(), &options, commandAddNode)
+	assert.NoError(t, acquireDatabaseLock(addCtx, vlog.Printer{}))
+	assert.FileExists(t, filepath.Join(os.TempDir(), "vcluster_test_db.lock"))
+	removeCtx := vcc.setupContext(context.Background(), &options, commandRemoveNode)
+	var lockedErr *DatabaseLockedError
+	assert.ErrorAs(t, acquireDatabaseLock(removeCtx, vlog.Printer{}), &lockedErr)
+	releaseDatabaseLock(addCtx, vlog.Printer{})
+}
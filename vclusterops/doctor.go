@@ -0,0 +1,622 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops/util"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// the statuses of the checks of VDoctor
+const (
+	DoctorPass = "pass"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+	// DoctorSkip is the status of a check that could not run, such as the
+	// checks of the hosts whose NMA is unreachable
+	DoctorSkip = "skip"
+)
+
+// the checks of VDoctor
+const (
+	DoctorCheckNMA               = "nma_reachable"
+	DoctorCheckVersions          = "vertica_versions"
+	DoctorCheckAddressFamily     = "address_family"
+	DoctorCheckDirectories       = "directories_writable"
+	DoctorCheckDiskSpace         = "free_disk_space"
+	DoctorCheckCommunalStorage   = "communal_storage"
+	DoctorCheckClockSkew         = "clock_skew"
+	DoctorCheckCertificates      = "certificates"
+	doctorScratchDirectoryPrefix = ".vcluster_doctor_"
+)
+
+const (
+	// DoctorClockSkewWarn and DoctorClockSkewFail are how far apart the
+	// clocks of the hosts can be before the clock skew check warns or fails.
+	// The clocks are read from the Date headers of the responses, to the
+	// second.
+	DoctorClockSkewWarn = 2 * time.Second
+	DoctorClockSkewFail = 30 * time.Second
+	// DoctorCertExpiryWarn is how soon a certificate can expire before the
+	// certificates check warns
+	DoctorCertExpiryWarn = 30 * 24 * time.Hour
+)
+
+// DoctorCheck is the outcome of a check of VDoctor, on a host or on all of
+// them
+type DoctorCheck struct {
+	Name string `json:"name"`
+	// Host is "" for the checks of all the hosts
+	Host   string `json:"host,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	// Hint tells how to fix a check that warns or fails
+	Hint string `json:"hint,omitempty"`
+}
+
+// DoctorReport is the report of VDoctor
+type DoctorReport struct {
+	// Status is the worst status of the checks: fail, warn, or pass. The
+	// checks that were skipped do not count.
+	Status string        `json:"status"`
+	Checks []DoctorCheck `json:"checks"`
+}
+
+type VDoctorOptions struct {
+	DatabaseOptions
+	// ForRevive checks the hosts before a revive_db rather than a create_db:
+	// the communal storage must then hold the database
+	ForRevive bool
+}
+
+func VDoctorOptionsFactory() VDoctorOptions {
+	opt := VDoctorOptions{}
+	// set default values to the params
+	opt.setDefaultValues()
+
+	return opt
+}
+
+func (options *VDoctorOptions) setDefaultValues() {
+	options.DatabaseOptions.setDefaultValues()
+}
+
+func (options *VDoctorOptions) validateAnalyzeOptions(log vlog.Printer) (err error) {
+	err = options.validateBaseOptions(commandDoctor, log)
+	if err != nil {
+		return err
+	}
+	for _, prefix := range []struct{ path, name string }{
+		{options.CatalogPrefix, "catalog path"}, {options.DataPrefix, "data path"}, {options.DepotPrefix, "depot path"},
+	} {
+		err = util.ValidateAbsPath(prefix.path, prefix.name)
+		if prefix.path != "" && err != nil {
+			return err
+		}
+	}
+	// resolve RawHosts to be IP addresses
+	if len(options.RawHosts) > 0 {
+		options.Hosts, err = util.ResolveRawHostsToAddresses(options.RawHosts, options.IPv6)
+	}
+	return err
+}
+
+// VDoctor checks that the hosts are ready for a create_db, or a revive_db,
+// with the NMA ops: that their NMA is reachable, that they run the same
+// Vertica version, that their addresses match IPv6, that the catalog, data
+// and depot paths are writable, that the communal storage is reachable, that
+// their clocks agree, and that the certificates are valid. The free disk
+// space is only checked on the hosts that are this machine. It changes
+// nothing on the hosts: the directories are checked by creating scratch
+// directories under the paths, which are then deleted.
+//
+// A check that fails does not fail the command. The error is only set when
+// the checks could not run, such as when the command is cancelled.
+func (vcc VClusterCommands) VDoctor(ctx context.Context, options *VDoctorOptions) (report *DoctorReport, err error) {
+	ctx = vcc.setupContext(ctx, options, commandDoctor)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
+	err = options.validateAnalyzeOptions(vcc.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	d := doctor{
+		vcc:     vcc,
+		ctx:     ctx,
+		options: options,
+		certs:   httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert},
+		report:  &DoctorReport{Checks: []DoctorCheck{}},
+		health:  make(map[string]hostHTTPResult),
+	}
+	hosts := d.checkNMA()
+	d.checkVersions(hosts)
+	d.checkAddressFamily(hosts)
+	d.checkDirectories(hosts)
+	d.checkDiskSpace()
+	d.checkCommunalStorage(hosts)
+	d.checkClockSkew()
+	d.checkCertificates()
+	if d.fatalErr != nil {
+		return nil, d.fatalErr
+	}
+
+	d.report.Status = DoctorPass
+	for i := range d.report.Checks {
+		switch d.report.Checks[i].Status {
+		case DoctorFail:
+			d.report.Status = DoctorFail
+		case DoctorWarn:
+			if d.report.Status == DoctorPass {
+				d.report.Status = DoctorWarn
+			}
+		}
+	}
+	return d.report, nil
+}
+
+// doctor runs the checks of VDoctor
+type doctor struct {
+	vcc     VClusterCommands
+	ctx     context.Context
+	options *VDoctorOptions
+	certs   httpsCerts
+	report  *DoctorReport
+	// health holds the responses of the NMA health endpoint, by host, from
+	// which the clocks and the certificates of the hosts are read
+	health map[string]hostHTTPResult
+	// fatalErr is the first error that stops the checks, such as a
+	// cancelled command or a hook veto
+	fatalErr error
+}
+
+func (d *doctor) add(name, host, status, detail, hint string) {
+	d.report.Checks = append(d.report.Checks, DoctorCheck{Name: name, Host: host, Status: status, Detail: detail, Hint: hint})
+}
+
+// runCheckOp runs op in its own op engine, so that a check that fails does
+// not stop the other ones. The errors about the hosts are reported by the
+// checks from the results of op.
+func (d *doctor) runCheckOp(op clusterOp) error {
+	if d.fatalErr != nil {
+		return d.fatalErr
+	}
+	clusterOpEngine := makeClusterOpEngine([]clusterOp{op}, &d.certs)
+	err := clusterOpEngine.run(d.ctx, d.vcc.Log)
+	var vetoErr *HookVetoError
+	if err != nil && (d.ctx.Err() != nil || errors.As(err, &vetoErr)) {
+		d.fatalErr = err
+	}
+	return err
+}
+
+// checkNMA checks the NMA of each host and returns the hosts whose NMA is
+// reachable
+func (d *doctor) checkNMA() (reachableHosts []string) {
+	op := makeNMAHealthOp(d.options.Hosts)
+	_ = d.runCheckOp(&op)
+	nmaPort := d.options.NMAPort
+	if nmaPort == 0 {
+		nmaPort = util.DefaultNMAPort
+	}
+	for _, host := range d.options.Hosts {
+		result, ok := op.clusterHTTPRequest.ResultCollection[host]
+		switch {
+		case !ok:
+			d.add(DoctorCheckNMA, host, DoctorSkip, "the check did not run", "")
+		case result.isPassing():
+			d.add(DoctorCheckNMA, host, DoctorPass, "the NMA is reachable", "")
+			d.health[host] = result
+			reachableHosts = append(reachableHosts, host)
+		default:
+			d.add(DoctorCheckNMA, host, DoctorFail, fmt.Sprintf("the NMA is not reachable: %v", result.err),
+				fmt.Sprintf("start the NMA on the host, and check that port %d is open to this machine", nmaPort))
+		}
+	}
+	return reachableHosts
+}
+
+// checkVersions checks that the hosts run the same Vertica version
+func (d *doctor) checkVersions(hosts []string) {
+	if len(hosts) == 0 {
+		d.add(DoctorCheckVersions, "", DoctorSkip, "no NMA is reachable", "")
+		return
+	}
+	op := makeNMACheckVerticaVersionOp(hosts, true /*sameVersion*/, d.options.IsEon)
+	_ = d.runCheckOp(&op)
+	if len(op.clusterHTTPRequest.ResultCollection) == 0 {
+		d.add(DoctorCheckVersions, "", DoctorSkip, "the check did not run", "")
+		return
+	}
+	versionHosts := make(map[string][]string)
+	for _, host := range hosts {
+		version := op.SCToHostVersionMap[DefaultSC][host]
+		if version == "" {
+			d.add(DoctorCheckVersions, host, DoctorFail, "the NMA did not report the Vertica version of the host",
+				"check that Vertica is installed on the host")
+			continue
+		}
+		versionHosts[version] = append(versionHosts[version], host)
+	}
+	versions := maps.Keys(versionHosts)
+	sort.Strings(versions)
+	switch len(versions) {
+	case 0:
+	case 1:
+		d.add(DoctorCheckVersions, "", DoctorPass, fmt.Sprintf("the hosts run %s", versions[0]), "")
+	default:
+		var details []string
+		for _, version := range versions {
+			details = append(details, fmt.Sprintf("%s on %s", version, strings.Join(versionHosts[version], ",")))
+		}
+		d.add(DoctorCheckVersions, "", DoctorFail, "the hosts run different versions: "+strings.Join(details, "; "),
+			"install the same Vertica version on all the hosts")
+	}
+}
+
+// checkAddressFamily checks that the networks of the hosts are IPv6 ones
+// if, and only if, IPv6 is set
+func (d *doctor) checkAddressFamily(hosts []string) {
+	op := makeNMANetworkProfileOp(hosts)
+	if len(hosts) > 0 {
+		_ = d.runCheckOp(&op)
+	}
+	family, otherFamily := "IPv4", "IPv6"
+	if d.options.IPv6 {
+		family, otherFamily = otherFamily, family
+	}
+	for _, host := range hosts {
+		result, ok := op.clusterHTTPRequest.ResultCollection[host]
+		if !ok {
+			d.add(DoctorCheckAddressFamily, host, DoctorSkip, "the check did not run", "")
+			continue
+		}
+		if !result.isPassing() {
+			d.add(DoctorCheckAddressFamily, host, DoctorFail, fmt.Sprintf("the NMA did not return the network profile: %v", result.err), "")
+			continue
+		}
+		profile, err := op.parseResponse(host, result.content)
+		if err != nil {
+			d.add(DoctorCheckAddressFamily, host, DoctorFail, fmt.Sprintf("the network profile is invalid: %v", err), "")
+			continue
+		}
+		ip := net.ParseIP(profile.Address)
+		if ip != nil && (ip.To4() == nil) == d.options.IPv6 {
+			d.add(DoctorCheckAddressFamily, host, DoctorPass,
+				fmt.Sprintf("the host is on an %s network, at %s on %s", family, profile.Address, profile.Name), "")
+			continue
+		}
+		d.add(DoctorCheckAddressFamily, host, DoctorFail,
+			fmt.Sprintf("the host is on the network of %s on %s, which is not an %s one", profile.Address, profile.Name, family),
+			fmt.Sprintf("use the %s addresses of the hosts, and set IPv6 only if they are IPv6 ones", otherFamily))
+	}
+}
+
+// checkDirectories checks that the catalog, data and depot paths are
+// writable, by creating scratch directories under them and deleting them
+func (d *doctor) checkDirectories(hosts []string) {
+	if d.options.CatalogPrefix == "" {
+		d.add(DoctorCheckDirectories, "", DoctorSkip, "no catalog path was given", "")
+		return
+	}
+	if len(hosts) == 0 {
+		d.add(DoctorCheckDirectories, "", DoctorSkip, "no NMA is reachable", "")
+		return
+	}
+	vdb := d.scratchDatabase(hosts)
+	prepareOp, err := makeNMAPrepareDirectoriesOp(vdb.HostNodeMap, true /*forceCleanup*/, false /*forRevive*/)
+	if err != nil {
+		d.add(DoctorCheckDirectories, "", DoctorFail, err.Error(), "")
+		return
+	}
+	_ = d.runCheckOp(&prepareOp)
+	paths := strings.Join(d.scratchPaths(&vdb), ", ")
+	for _, host := range hosts {
+		result, ok := prepareOp.clusterHTTPRequest.ResultCollection[host]
+		switch {
+		case !ok:
+			d.add(DoctorCheckDirectories, host, DoctorSkip, "the check did not run", "")
+		case result.isPassing():
+			d.add(DoctorCheckDirectories, host, DoctorPass, "the NMA can create directories under the paths", "")
+		default:
+			d.add(DoctorCheckDirectories, host, DoctorFail, fmt.Sprintf("the NMA cannot create %s: %v", paths, result.err),
+				"create the catalog, data and depot paths on the host, and give the database administrator write access to them")
+		}
+	}
+	if len(prepareOp.preparedHosts) == 0 {
+		return
+	}
+
+	vdb.HostList = prepareOp.preparedHosts
+	deleteOp, err := makeNMADeleteDirectoriesOp(&vdb, true /*forceDelete*/)
+	if err == nil {
+		err = d.runCheckOp(&deleteOp)
+	}
+	if err != nil {
+		d.add(DoctorCheckDirectories, "", DoctorWarn, fmt.Sprintf("the scratch directories could not all be deleted: %v", err),
+			fmt.Sprintf("delete %s on the hosts", paths))
+	}
+}
+
+// checkDiskSpace checks that the file systems of the catalog, data and depot
+// paths have room for the database. The NMA does not report the free disk
+// space of the hosts, so it is read with statfs, which only works for the
+// hosts that are this machine. The check is skipped on the other hosts.
+func (d *doctor) checkDiskSpace() {
+	var paths []string
+	for _, prefix := range []string{d.options.CatalogPrefix, d.options.DataPrefix, d.options.DepotPrefix} {
+		if prefix != "" && !slices.Contains(paths, prefix) {
+			paths = append(paths, prefix)
+		}
+	}
+	if len(paths) == 0 {
+		d.add(DoctorCheckDiskSpace, "", DoctorSkip, "no catalog, data or depot path was given", "")
+		return
+	}
+	const hint = "make room on the file systems of the catalog, data and depot paths"
+	for _, host := range d.options.Hosts {
+		if !isLocalAddress(host) {
+			d.add(DoctorCheckDiskSpace, host, DoctorSkip,
+				"the NMA does not report the free disk space of the host, it is only checked on this machine", hint)
+			continue
+		}
+		status := DoctorPass
+		var details []string
+		for _, path := range paths {
+			usedPercent, available, err := diskUsage(path)
+			if err != nil {
+				status = DoctorFail
+				details = append(details, fmt.Sprintf("cannot read the free space of %s: %v", path, err))
+				continue
+			}
+			switch {
+			case available == 0:
+				status = DoctorFail
+			case usedPercent >= StorageAlmostFullPercent && status == DoctorPass:
+				status = DoctorWarn
+			}
+			details = append(details, fmt.Sprintf("the disk of %s is %d%% full, with %.1f GiB free",
+				path, usedPercent, float64(available)/(1<<30)))
+		}
+		statusHint := hint
+		if status == DoctorPass {
+			statusHint = ""
+		}
+		d.add(DoctorCheckDiskSpace, host, status, strings.Join(details, ", "), statusHint)
+	}
+}
+
+// isLocalAddress is true if host is an address of this machine
+func isLocalAddress(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// diskUsage returns how full, in percent, the file system of path is, and
+// the bytes available on it, as df reports them. A path that does not exist
+// yet is on the file system of its closest parent that does.
+func diskUsage(path string) (usedPercent int, available uint64, err error) {
+	for {
+		_, err = os.Stat(path)
+		if err == nil || !errors.Is(err, os.ErrNotExist) || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	var stat syscall.Statfs_t
+	if err = syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	blockSize := uint64(stat.Bsize)
+	used := (stat.Blocks - stat.Bfree) * blockSize
+	available = stat.Bavail * blockSize
+	if used+available == 0 {
+		return 0, available, nil
+	}
+	// rounded up, as df does
+	return int((used*100 + used + available - 1) / (used + available)), available, nil
+}
+
+// scratchDatabase returns a database whose directories are the scratch
+// directories of the directories check
+func (d *doctor) scratchDatabase(hosts []string) VCoordinationDatabase {
+	vdb := makeVCoordinationDatabase()
+	vdb.Name = doctorScratchDirectoryPrefix + d.options.DBName
+	vdb.CatalogPrefix = d.options.CatalogPrefix
+	vdb.DataPrefix = d.options.DataPrefix
+	vdb.DepotPrefix = d.options.DepotPrefix
+	vdb.UseDepot = d.options.DepotPrefix != ""
+	vdb.HostList = hosts
+	vdb.HostNodeMap = makeVHostNodeMap()
+	for _, host := range hosts {
+		vnode := makeVCoordinationNode()
+		vnode.Address = host
+		vnode.CatalogPath = filepath.Join(vdb.CatalogPrefix, vdb.Name, "catalog")
+		if vdb.DataPrefix != "" {
+			vnode.StorageLocations = []string{filepath.Join(vdb.DataPrefix, vdb.Name, "data")}
+		}
+		if vdb.UseDepot {
+			vnode.DepotPath = filepath.Join(vdb.DepotPrefix, vdb.Name, "depot")
+		}
+		vdb.HostNodeMap[host] = &vnode
+	}
+	return vdb
+}
+
+// scratchPaths returns the roots of the scratch directories under the paths
+func (d *doctor) scratchPaths(vdb *VCoordinationDatabase) []string {
+	var paths []string
+	for _, prefix := range []string{vdb.CatalogPrefix, vdb.DataPrefix, vdb.DepotPrefix} {
+		path := filepath.Join(prefix, vdb.Name)
+		if prefix != "" && !util.StringInArray(path, paths) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// checkCommunalStorage checks that the communal storage is reachable, by
+// reading the description file of the database from it. It must be there
+// for a revive_db, and not for a create_db.
+func (d *doctor) checkCommunalStorage(hosts []string) {
+	location := d.options.CommunalStorageLocation
+	switch {
+	case location == "":
+		d.add(DoctorCheckCommunalStorage, "", DoctorSkip, "no communal storage location was given", "")
+		return
+	case len(hosts) == 0:
+		d.add(DoctorCheckCommunalStorage, "", DoctorSkip, "no NMA is reachable", "")
+		return
+	}
+	vdb := makeVCoordinationDatabase()
+	op, err := makeNMADownloadFileOpForRevive(hosts, d.options.getCurrConfigFilePath(), currConfigFileDestPath, catalogPath,
+		d.options.ConfigurationParameters, &vdb, true /*displayOnly*/, true /*ignoreClusterLease*/)
+	if err == nil {
+		err = d.runCheckOp(&op)
+	}
+	if len(op.clusterHTTPRequest.ResultCollection) == 0 && err == nil {
+		d.add(DoctorCheckCommunalStorage, "", DoctorSkip, "the check did not run", "")
+		return
+	}
+	problem := &rfc7807.VProblem{}
+	notFound := errors.As(err, &problem) && problem.ProblemID == rfc7807.UndefinedFile
+	switch {
+	case err == nil && d.options.ForRevive:
+		d.add(DoctorCheckCommunalStorage, "", DoctorPass,
+			fmt.Sprintf("database %s was found in %s", d.options.DBName, location), "")
+	case err == nil:
+		d.add(DoctorCheckCommunalStorage, "", DoctorFail,
+			fmt.Sprintf("%s already holds database %s", location, d.options.DBName),
+			"use another communal storage location, or revive the database with revive_db")
+	case notFound && d.options.ForRevive:
+		d.add(DoctorCheckCommunalStorage, "", DoctorFail,
+			fmt.Sprintf("database %s was not found in %s", d.options.DBName, location),
+			"check the communal storage location and the name of the database")
+	case notFound:
+		d.add(DoctorCheckCommunalStorage, "", DoctorPass,
+			fmt.Sprintf("%s is reachable and does not hold database %s", location, d.options.DBName), "")
+	default:
+		d.add(DoctorCheckCommunalStorage, "", DoctorFail, fmt.Sprintf("%s could not be read: %v", location, err),
+			"check the communal storage location, and the credentials in the configuration parameters")
+	}
+}
+
+// checkClockSkew checks how far apart the clocks of the hosts are. Each
+// clock is compared with the local one when the host responded, so the
+// latency of the network does not count.
+func (d *doctor) checkClockSkew() {
+	offsets := make(map[string]time.Duration, len(d.health))
+	for host := range d.health {
+		result := d.health[host]
+		if !result.hostTime.IsZero() {
+			offsets[host] = result.hostTime.Sub(result.receivedTime)
+		}
+	}
+	if len(offsets) < 2 {
+		d.add(DoctorCheckClockSkew, "", DoctorSkip, "the clocks of fewer than two hosts could be read", "")
+		return
+	}
+	hosts := maps.Keys(offsets)
+	sort.Slice(hosts, func(i, j int) bool { return offsets[hosts[i]] < offsets[hosts[j]] })
+	earliest, latest := hosts[0], hosts[len(hosts)-1]
+	skew := offsets[latest] - offsets[earliest]
+	detail := fmt.Sprintf("the clocks of the hosts are %s apart, %s being the earliest and %s the latest",
+		skew.Round(time.Second), earliest, latest)
+	const hint = "synchronize the clocks of the hosts with NTP or chrony"
+	switch {
+	case skew > DoctorClockSkewFail:
+		d.add(DoctorCheckClockSkew, "", DoctorFail, detail, hint)
+	case skew > DoctorClockSkewWarn:
+		d.add(DoctorCheckClockSkew, "", DoctorWarn, detail, hint)
+	default:
+		d.add(DoctorCheckClockSkew, "", DoctorPass, detail, "")
+	}
+}
+
+// checkCertificates checks the certificates the NMAs presented, and the
+// client certificate in the options
+func (d *doctor) checkCertificates() {
+	hosts := maps.Keys(d.health)
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		notAfter := d.health[host].certNotAfter
+		if notAfter.IsZero() {
+			d.add(DoctorCheckCertificates, host, DoctorSkip, "the NMA presented no certificate", "")
+			continue
+		}
+		d.checkExpiry(host, "the certificate of the NMA", notAfter, "renew the certificate of the NMA on the host")
+	}
+	if d.options.Cert == "" {
+		return
+	}
+	block, _ := pem.Decode([]byte(d.options.Cert))
+	if block == nil {
+		d.add(DoctorCheckCertificates, "", DoctorFail, "the client certificate is not in the PEM format", "")
+		return
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		d.add(DoctorCheckCertificates, "", DoctorFail, fmt.Sprintf("the client certificate is invalid: %v", err), "")
+		return
+	}
+	d.checkExpiry("", "the client certificate", cert.NotAfter, "renew the client certificate")
+}
+
+func (d *doctor) checkExpiry(host, what string, notAfter time.Time, hint string) {
+	expiry := notAfter.UTC().Format(time.RFC3339)
+	switch {
+	case time.Now().After(notAfter):
+		d.add(DoctorCheckCertificates, host, DoctorFail, fmt.Sprintf("%s expired on %s", what, expiry), hint)
+	case time.Until(notAfter) < DoctorCertExpiryWarn:
+		d.add(DoctorCheckCertificates, host, DoctorWarn, fmt.Sprintf("%s expires on %s", what, expiry), hint)
+	default:
+		d.add(DoctorCheckCertificates, host, DoctorPass, fmt.Sprintf("%s is valid until %s", what, expiry), "")
+	}
+}
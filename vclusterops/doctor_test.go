@@ -0,0 +1,45 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctorDiskSpace(t *testing.T) {
+	options := VDoctorOptionsFactory()
+	options.Hosts = []string{"127.0.0.1", "192.0.2.1"}
+	// the paths do not exist yet, so their closest parent is checked
+	options.CatalogPrefix = filepath.Join(t.TempDir(), "catalog")
+	d := doctor{options: &options, report: &DoctorReport{}}
+	d.checkDiskSpace()
+
+	assert.Len(t, d.report.Checks, 2)
+	local := d.report.Checks[0]
+	assert.Equal(t, DoctorCheckDiskSpace, local.Name)
+	assert.NotEqual(t, DoctorSkip, local.Status)
+	assert.Contains(t, local.Detail, "the disk of "+options.CatalogPrefix+" is")
+	// the free space of the other hosts is not reported by the NMA
+	assert.Equal(t, DoctorSkip, d.report.Checks[1].Status)
+
+	d = doctor{options: &VDoctorOptions{}, report: &DoctorReport{}}
+	d.checkDiskSpace()
+	assert.Equal(t, []DoctorCheck{{Name: DoctorCheckDiskSpace, Status: DoctorSkip,
+		Detail: "no catalog, data or depot path was given"}}, d.report.Checks)
+}
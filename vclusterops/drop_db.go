@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -56,7 +57,10 @@ func (options *VDropDatabaseOptions) validateAnalyzeOptions() error {
 	return options.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VDropDatabase(options *VDropDatabaseOptions) error {
+func (vcc VClusterCommands) VDropDatabase(ctx context.Context, options *VDropDatabaseOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandDropDB)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -66,7 +70,7 @@ func (vcc VClusterCommands) VDropDatabase(options *VDropDatabaseOptions) error {
 	// Analyze to produce vdb info for drop db use
 	vdb := makeVCoordinationDatabase()
 
-	err := options.validateAnalyzeOptions()
+	err = options.validateAnalyzeOptions()
 	if err != nil {
 		return err
 	}
@@ -87,7 +91,7 @@ func (vcc VClusterCommands) VDropDatabase(options *VDropDatabaseOptions) error {
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("fail to drop database: %w", runError)
 	}
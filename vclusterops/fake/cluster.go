@@ -0,0 +1,403 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+// Package fake provides an in-process fake of the NMA and of the HTTPS
+// service of the hosts of a Vertica cluster, to test the commands of
+// vclusterops end to end without a cluster.
+//
+// A Cluster runs local TLS servers for its hosts and emulates the endpoints
+// the ops call on a scriptable cluster state. The commands reach them when
+// the Cluster is set as the Dialer of their options, along with its
+// certificates:
+//
+//	cluster, err := fake.NewCluster("192.168.1.101", "192.168.1.102")
+//	...
+//	defer cluster.Close()
+//	options.Dialer = cluster
+//	certs := cluster.Certs()
+//	options.Key, options.Cert, options.CaCert = certs.Key, certs.Cert, certs.CaCert
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// the services the hosts run
+const (
+	NMA   = "nma"
+	HTTPS = "https"
+)
+
+// DefaultVerticaVersion is the version the hosts report until it is set
+const DefaultVerticaVersion = "v24.3.0"
+
+const defaultSubcluster = "default_subcluster"
+
+// downSinceLayout is the layout of the times the nodes went down, the one
+// of the nodes endpoints
+const downSinceLayout = "2006-01-02T15:04:05.999999-07"
+
+// shardSubscriptions is the number of shards each node of an Eon database
+// subscribes to
+const shardSubscriptions = 3
+
+// Node is the state of a node of the fake database
+type Node struct {
+	Name        string
+	Address     string
+	State       string
+	Subcluster  string
+	IsPrimary   bool
+	Sandbox     string
+	CatalogPath string
+	DataPath    string
+	DepotPath   string
+	// DownSince is when the node went down, "" when it is up
+	DownSince string
+}
+
+// setState sets the state of the node, and when it went down
+func (n *Node) setState(state string) {
+	if state == util.NodeDownState && n.State != util.NodeDownState {
+		n.DownSince = time.Now().Format(downSinceLayout)
+	} else if state != util.NodeDownState {
+		n.DownSince = ""
+	}
+	n.State = state
+}
+
+// Request is a request a host received
+type Request struct {
+	Host     string
+	Service  string
+	Method   string
+	Endpoint string
+	Query    string
+	// TraceParent is the W3C Trace Context header of the request, set when
+	// the command is traced
+	TraceParent string
+}
+
+// Cluster is a fake cluster. Its hosts all have an NMA, which is up unless
+// set down, and an HTTPS service, which is up when the node of the host is.
+// It is safe for concurrent use.
+type Cluster struct {
+	// NMAPort and HTTPSPort are the ports the hosts listen on, to set before
+	// the commands run. The connections to other ports are refused.
+	NMAPort   int
+	HTTPSPort int
+
+	mu       sync.Mutex
+	hosts    []string
+	servers  map[string]map[string]*httptest.Server
+	certs    Certs
+	handlers map[string]http.HandlerFunc
+	requests []Request
+	nmaDown  map[string]bool
+	versions map[string]string
+	disks    map[string]int
+	// configs are the contents of the config files of each host, by path
+	configs  map[string]map[string]string
+	database *database
+}
+
+// database is the state of the database created on a fake cluster
+type database struct {
+	name            string
+	communalStorage string
+	catalogVersion  int64
+	nodes           map[string]*Node
+	// addedSubclusters are the control set sizes of the secondary
+	// subclusters added by the endpoint, which have no nodes at first
+	addedSubclusters map[string]int
+}
+
+// NewCluster starts the servers of a fake cluster of the given hosts, which
+// must be IP addresses. There is no database on it until one is created.
+func NewCluster(hosts ...string) (*Cluster, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("a fake cluster needs at least one host")
+	}
+	ca, err := makeCertAuthority()
+	if err != nil {
+		return nil, fmt.Errorf("fail to generate the certificates of the fake cluster: %w", err)
+	}
+	tlsConfig, err := ca.serverTLSConfig(hosts)
+	if err != nil {
+		return nil, err
+	}
+	key, cert, err := ca.issue("vcluster fake client", nil)
+	if err != nil {
+		return nil, fmt.Errorf("fail to generate the client certificate of the fake cluster: %w", err)
+	}
+
+	c := &Cluster{
+		NMAPort:   util.DefaultNMAPort,
+		HTTPSPort: util.DefaultHTTPPort,
+		hosts:     slices.Clone(hosts),
+		servers:   make(map[string]map[string]*httptest.Server),
+		certs:     Certs{Key: key, Cert: cert, CaCert: ca.caPEM},
+		handlers:  make(map[string]http.HandlerFunc),
+		nmaDown:   make(map[string]bool),
+		versions:  make(map[string]string),
+		disks:     make(map[string]int),
+		configs:   make(map[string]map[string]string),
+	}
+	for _, host := range hosts {
+		c.servers[host] = make(map[string]*httptest.Server)
+		for _, service := range []string{NMA, HTTPS} {
+			server := httptest.NewUnstartedServer(c.handler(host, service))
+			server.TLS = tlsConfig.Clone()
+			server.StartTLS()
+			c.servers[host][service] = server
+		}
+		c.configs[host] = make(map[string]string)
+	}
+	return c, nil
+}
+
+// Close shuts the servers of the cluster down
+func (c *Cluster) Close() {
+	for _, services := range c.servers {
+		for _, server := range services {
+			server.Close()
+		}
+	}
+}
+
+// Certs returns the certificates to set in the options of the commands run
+// against the cluster
+func (c *Cluster) Certs() Certs {
+	return c.certs
+}
+
+// Hosts returns the hosts of the cluster
+func (c *Cluster) Hosts() []string {
+	return slices.Clone(c.hosts)
+}
+
+// DialContext connects to the server of a host of the cluster. It makes the
+// Cluster a vclusterops.Dialer. The connections to a service that is down
+// are refused.
+func (c *Cluster) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	server := c.serverFor(host, port)
+	c.mu.Unlock()
+	if server == nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, server.Listener.Addr().String())
+}
+
+// serverFor returns the server that listens on port of host, or nil if the
+// service is down
+func (c *Cluster) serverFor(host, port string) *httptest.Server {
+	services, ok := c.servers[host]
+	if !ok {
+		return nil
+	}
+	switch port {
+	case strconv.Itoa(c.NMAPort):
+		if c.nmaDown[host] {
+			return nil
+		}
+		return services[NMA]
+	case strconv.Itoa(c.HTTPSPort):
+		if !c.isUp(host) {
+			return nil
+		}
+		return services[HTTPS]
+	}
+	return nil
+}
+
+func (c *Cluster) isUp(host string) bool {
+	if c.database == nil {
+		return false
+	}
+	node, ok := c.database.nodes[host]
+	return ok && node.State == util.NodeUpState
+}
+
+// Handle makes the service of host answer the requests to endpoint with
+// handler instead of emulating it, to script failures. The endpoint is the
+// path without its leading slash, such as "v1/nodes". An empty host stands
+// for all the hosts.
+func (c *Cluster) Handle(host, service, method, endpoint string, handler http.HandlerFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[handlerKey(host, service, method, endpoint)] = handler
+}
+
+func handlerKey(host, service, method, endpoint string) string {
+	return strings.Join([]string{host, service, method, endpoint}, " ")
+}
+
+// Requests returns the requests the hosts received, in order
+func (c *Cluster) Requests() []Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.requests)
+}
+
+// SetNMADown makes the NMA of host refuse connections, or accept them again.
+// The connections open to an NMA that goes down are closed.
+func (c *Cluster) SetNMADown(host string, down bool) {
+	c.mu.Lock()
+	c.nmaDown[host] = down
+	server := c.servers[host][NMA]
+	c.mu.Unlock()
+	if down && server != nil {
+		server.CloseClientConnections()
+	}
+}
+
+// SetVerticaVersion sets the Vertica version host reports, such as "v24.3.0"
+func (c *Cluster) SetVerticaVersion(host, version string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.versions[host] = version
+}
+
+// SetDiskPercent sets how full, in percent, the disk of the data location of
+// host is
+func (c *Cluster) SetDiskPercent(host string, percent int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disks[host] = percent
+}
+
+// DatabaseName returns the name of the database, or "" if none was created
+func (c *Cluster) DatabaseName() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.database == nil {
+		return ""
+	}
+	return c.database.name
+}
+
+// Nodes returns the nodes of the database, sorted by name
+func (c *Cluster) Nodes() []Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodeList("", false)
+}
+
+// Subclusters returns the names of the subclusters of the database, sorted
+func (c *Cluster) Subclusters() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subclusters()
+}
+
+// Node returns the node on host
+func (c *Cluster) Node(host string) (Node, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	node, err := c.node(host)
+	if err != nil {
+		return Node{}, false
+	}
+	return *node, true
+}
+
+// SetNodeState sets the state of the node on host, such as "UP" or "DOWN".
+// The HTTPS service of a host is only up with its node.
+func (c *Cluster) SetNodeState(host, state string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	node, err := c.node(host)
+	if err != nil {
+		return err
+	}
+	node.setState(state)
+	return nil
+}
+
+// SetSubcluster moves the node on host to a subcluster, which is created if
+// it does not exist
+func (c *Cluster) SetSubcluster(host, subcluster string, isPrimary bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	node, err := c.node(host)
+	if err != nil {
+		return err
+	}
+	node.Subcluster = subcluster
+	node.IsPrimary = isPrimary
+	c.database.catalogVersion++
+	return nil
+}
+
+func (c *Cluster) node(host string) (*Node, error) {
+	if c.database == nil {
+		return nil, fmt.Errorf("there is no database on the fake cluster")
+	}
+	node, ok := c.database.nodes[host]
+	if !ok {
+		return nil, fmt.Errorf("there is no node on host %s", host)
+	}
+	return node, nil
+}
+
+// nodeList returns copies of the nodes of the database, sorted by name. If
+// bySandbox is true, only the nodes in sandbox are returned.
+func (c *Cluster) nodeList(sandbox string, bySandbox bool) []Node {
+	if c.database == nil {
+		return nil
+	}
+	nodes := make([]Node, 0, len(c.database.nodes))
+	for _, node := range c.database.nodes {
+		if !bySandbox || node.Sandbox == sandbox {
+			nodes = append(nodes, *node)
+		}
+	}
+	slices.SortFunc(nodes, func(a, b Node) int { return strings.Compare(a.Name, b.Name) })
+	return nodes
+}
+
+// subclusters returns the names of the subclusters, sorted
+func (c *Cluster) subclusters() []string {
+	names := make(map[string]bool)
+	for name := range c.database.addedSubclusters {
+		names[name] = true
+	}
+	for _, node := range c.database.nodes {
+		names[node.Subcluster] = true
+	}
+	subclusters := maps.Keys(names)
+	slices.Sort(subclusters)
+	return subclusters
+}
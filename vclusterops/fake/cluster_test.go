@@ -0,0 +1,426 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops"
+	"github.com/vertica/vcluster/vclusterops/util"
+)
+
+var testHosts = []string{"192.168.1.101", "192.168.1.102", "192.168.1.103"}
+
+// setOptions aims the options of a command at cluster
+func setOptions(cluster *Cluster, options *vclusterops.DatabaseOptions) {
+	certs := cluster.Certs()
+	options.DBName = "test_db"
+	options.RawHosts = cluster.Hosts()
+	options.CatalogPrefix = "/data"
+	options.DataPrefix = "/data"
+	options.UserName = "dbadmin"
+	options.Password = new(string)
+	options.Key, options.Cert, options.CaCert = certs.Key, certs.Cert, certs.CaCert
+	options.Dialer = cluster
+}
+
+func TestDatabaseLifecycle(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	_, err = vcc.VCreateDatabase(ctx, &createOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_db", cluster.DatabaseName())
+	nodes := cluster.Nodes()
+	assert.Len(t, nodes, len(testHosts))
+	for _, node := range nodes {
+		assert.Equal(t, util.NodeUpState, node.State)
+	}
+	node, ok := cluster.Node("192.168.1.102")
+	assert.True(t, ok)
+	assert.Equal(t, "/data/test_db/"+node.Name+"_catalog", node.CatalogPath)
+
+	stopOptions := vclusterops.VStopDatabaseOptionsFactory()
+	setOptions(cluster, &stopOptions.DatabaseOptions)
+	stopOptions.Metrics = vclusterops.NewMetrics()
+	err = vcc.VStopDatabase(ctx, &stopOptions)
+	assert.NoError(t, err)
+	for _, node := range cluster.Nodes() {
+		assert.Equal(t, util.NodeDownState, node.State)
+	}
+	// the ops and the requests of the command were counted
+	var text strings.Builder
+	assert.NoError(t, stopOptions.Metrics.WriteText(&text))
+	assert.Contains(t, text.String(), `vcluster_op_runs_total{command="stop_db",op="HTTPSStopDBOp",status="finished"} 1`)
+	assert.Contains(t, text.String(), `vcluster_http_requests_total{host="192.168.1.101",method="POST",status_code="200"}`)
+
+	startOptions := vclusterops.VStartDatabaseOptionsFactory()
+	setOptions(cluster, &startOptions.DatabaseOptions)
+	vdb, err := vcc.VStartDatabase(ctx, &startOptions)
+	assert.NoError(t, err)
+	assert.Len(t, vdb.HostNodeMap, len(testHosts))
+	for _, node := range cluster.Nodes() {
+		assert.Equal(t, util.NodeUpState, node.State)
+	}
+}
+
+func TestCreateInParallelWithJournal(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+
+	// the NMA health check only answers once the version check has reached
+	// the hosts, which only happens if the two ops run alongside each other
+	cluster.Handle("", NMA, http.MethodGet, "v1/health", func(w http.ResponseWriter, _ *http.Request) {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			for _, request := range cluster.Requests() {
+				if request.Endpoint == "v1/vertica/version" {
+					writeResponse(w, "", map[string]string{"healthy": "true"}, nil)
+					return
+				}
+			}
+		}
+		rfc7807.New(rfc7807.GenericHTTPInternalServerError).WithDetail("the version check did not run alongside").SendError(w)
+	})
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	createOptions.JournalPath = filepath.Join(t.TempDir(), "create_db.journal")
+	_, err = vcc.VCreateDatabase(context.Background(), &createOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, "test_db", cluster.DatabaseName())
+
+	// the journal has every op, those run in parallel included
+	data, err := os.ReadFile(createOptions.JournalPath)
+	assert.NoError(t, err)
+	var journal struct {
+		Entries []struct {
+			OpName string `json:"op_name"`
+		} `json:"entries"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &journal))
+	opNames := make([]string, 0, len(journal.Entries))
+	for _, entry := range journal.Entries {
+		opNames = append(opNames, entry.OpName)
+	}
+	assert.Contains(t, opNames, "NMAHealthOp")
+	assert.Contains(t, opNames, "NMACheckVerticaVersionOp")
+	assert.Contains(t, opNames, "NMANetworkProfileOp")
+}
+
+func TestSandbox(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	createOptions.IsEon = true
+	createOptions.CommunalStorageLocation = "s3://bucket/test_db"
+	createOptions.DepotPrefix = "/depot"
+	createOptions.ShardCount = 6
+	_, err = vcc.VCreateDatabase(ctx, &createOptions)
+	assert.NoError(t, err)
+	node, _ := cluster.Node("192.168.1.103")
+	assert.Equal(t, "/depot/test_db/"+node.Name+"_depot", node.DepotPath)
+
+	// the last node is moved to a secondary subcluster, which can be sandboxed
+	assert.NoError(t, cluster.SetSubcluster("192.168.1.103", "sc1", false))
+	sandboxOptions := vclusterops.VSandboxOptionsFactory()
+	setOptions(cluster, &sandboxOptions.DatabaseOptions)
+	sandboxOptions.SCName = "sc1"
+	sandboxOptions.SandboxName = "sand"
+	err = vcc.VSandbox(ctx, &sandboxOptions)
+	assert.NoError(t, err)
+	node, _ = cluster.Node("192.168.1.103")
+	assert.Equal(t, "sand", node.Sandbox)
+	assert.Equal(t, util.NodeUpState, node.State)
+
+	// a failure of the endpoint can be scripted
+	assert.NoError(t, cluster.SetSubcluster("192.168.1.102", "sc2", false))
+	cluster.Handle("", HTTPS, http.MethodPost, "v1/subclusters/sc2/sandbox", func(w http.ResponseWriter, _ *http.Request) {
+		rfc7807.New(rfc7807.InsufficientResources).WithDetail("out of memory").SendError(w)
+	})
+	sandboxOptions.SCName = "sc2"
+	sandboxOptions.SandboxName = "other"
+	err = vcc.VSandbox(ctx, &sandboxOptions)
+	assert.ErrorContains(t, err, "out of memory")
+	node, _ = cluster.Node("192.168.1.102")
+	assert.Empty(t, node.Sandbox)
+	var query string
+	for _, request := range cluster.Requests() {
+		if request.Endpoint == "v1/subclusters/sc2/sandbox" {
+			query = request.Query
+		}
+	}
+	assert.Equal(t, "sandbox=other", query)
+}
+
+func TestDatabaseLease(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	// two machines, which do not share their lock directory
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	otherVcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	_, err = vcc.VCreateDatabase(ctx, &createOptions)
+	assert.NoError(t, err)
+
+	// while a command runs on one machine, the lease on the initiator stops
+	// the commands run on the other one
+	otherOptions := vclusterops.VStopDatabaseOptionsFactory()
+	setOptions(cluster, &otherOptions.DatabaseOptions)
+	var otherErr error
+	stopOptions := vclusterops.VStopDatabaseOptionsFactory()
+	setOptions(cluster, &stopOptions.DatabaseOptions)
+	stopOptions.Hooks = []vclusterops.Hook{{Name: "other", Phase: vclusterops.HookPhasePre,
+		Run: func(_ context.Context, _ *vclusterops.HookContext) error {
+			otherErr = otherVcc.VStopDatabase(ctx, &otherOptions)
+			return nil
+		}}}
+	assert.NoError(t, vcc.VStopDatabase(ctx, &stopOptions))
+	var lockedErr *vclusterops.DatabaseLockedError
+	if assert.ErrorAs(t, otherErr, &lockedErr) {
+		assert.Equal(t, "192.168.1.101:/data/test_db/vertica.conf", lockedErr.Path)
+		assert.Equal(t, "stop_db", lockedErr.Holder.Command)
+	}
+
+	// the lease is released once the command completes
+	startOptions := vclusterops.VStartDatabaseOptionsFactory()
+	setOptions(cluster, &startOptions.DatabaseOptions)
+	_, err = otherVcc.VStartDatabase(ctx, &startOptions)
+	assert.NoError(t, err)
+
+	// a lease that is held can be broken
+	otherOptions.ForceUnlock = true
+	stopOptions.Hooks[0].Run = func(_ context.Context, _ *vclusterops.HookContext) error {
+		otherErr = otherVcc.VStopDatabase(ctx, &otherOptions)
+		return nil
+	}
+	_ = vcc.VStopDatabase(ctx, &stopOptions)
+	assert.NoError(t, otherErr)
+}
+
+func TestStartWithFaults(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	_, err = vcc.VCreateDatabase(ctx, &createOptions)
+	assert.NoError(t, err)
+	stopOptions := vclusterops.VStopDatabaseOptionsFactory()
+	setOptions(cluster, &stopOptions.DatabaseOptions)
+	assert.NoError(t, vcc.VStopDatabase(ctx, &stopOptions))
+
+	// a host that fails to return its catalog fails the start, before any
+	// node is started
+	startOptions := vclusterops.VStartDatabaseOptionsFactory()
+	setOptions(cluster, &startOptions.DatabaseOptions)
+	startOptions.Faults = []vclusterops.Fault{
+		{Kind: vclusterops.FaultServerError, Host: "192.168.1.103", Endpoint: "v1/catalog/database"},
+	}
+	_, err = vcc.VStartDatabase(ctx, &startOptions)
+	assert.ErrorContains(t, err, "fault injected by vcluster")
+	for _, node := range cluster.Nodes() {
+		assert.Equal(t, util.NodeDownState, node.State)
+	}
+
+	// a fault is only injected with its probability
+	startOptions.Faults[0].Probability = 0.000001
+	_, err = vcc.VStartDatabase(ctx, &startOptions)
+	assert.NoError(t, err)
+}
+
+func TestClusterStatus(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	createOptions.IsEon = true
+	createOptions.CommunalStorageLocation = "s3://bucket/test_db"
+	createOptions.DepotPrefix = "/depot"
+	createOptions.ShardCount = 6
+	_, err = vcc.VCreateDatabase(ctx, &createOptions)
+	assert.NoError(t, err)
+
+	statusOptions := vclusterops.VClusterStatusOptionsFactory()
+	setOptions(cluster, &statusOptions.DatabaseOptions)
+	status, err := vcc.VClusterStatus(ctx, &statusOptions)
+	assert.NoError(t, err)
+	assert.Empty(t, status.Anomalies)
+	assert.Len(t, status.Subclusters, 1)
+	assert.True(t, status.Subclusters[0].IsPrimary)
+	assert.Len(t, status.Subclusters[0].Nodes, len(testHosts))
+	node := status.Subclusters[0].Nodes[0]
+	assert.Equal(t, "192.168.1.101", node.Address)
+	assert.Equal(t, DefaultVerticaVersion, node.Version)
+	assert.Equal(t, uint(3), *node.ShardSubscriptions)
+	assert.Len(t, node.StorageLocations, 2)
+
+	// a secondary node of another version, a primary node down and a full
+	// disk are flagged
+	assert.NoError(t, cluster.SetSubcluster("192.168.1.103", "sc1", false))
+	cluster.SetVerticaVersion("192.168.1.103", "v24.4.0")
+	assert.NoError(t, cluster.SetNodeState("192.168.1.102", util.NodeDownState))
+	cluster.SetDiskPercent("192.168.1.101", 95)
+	status, err = vcc.VClusterStatus(ctx, &statusOptions)
+	assert.NoError(t, err)
+	assert.Len(t, status.Subclusters, 2)
+	assert.Equal(t, "sc1", status.Subclusters[1].Name)
+	assert.False(t, status.Subclusters[1].IsPrimary)
+	node = status.Subclusters[0].Nodes[1]
+	assert.Equal(t, util.NodeDownState, node.State)
+	assert.NotEmpty(t, node.DownSince)
+	assert.Nil(t, node.ShardSubscriptions)
+	var kinds []string
+	for _, anomaly := range status.Anomalies {
+		kinds = append(kinds, anomaly.Kind)
+	}
+	assert.ElementsMatch(t, []string{vclusterops.AnomalyNodeDown, vclusterops.AnomalyStorageAlmostFull,
+		vclusterops.AnomalyStorageAlmostFull, vclusterops.AnomalyMixedVersions, vclusterops.AnomalyPrimaryQuorumLost}, kinds)
+}
+
+func TestDoctor(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+
+	doctorOptions := vclusterops.VDoctorOptionsFactory()
+	setOptions(cluster, &doctorOptions.DatabaseOptions)
+	doctorOptions.DepotPrefix = "/depot"
+	doctorOptions.CommunalStorageLocation = "s3://bucket/test_db"
+	report, err := vcc.VDoctor(ctx, &doctorOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, vclusterops.DoctorWarn, report.Status)
+	statuses := doctorStatuses(report)
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckNMA+" 192.168.1.102"])
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckVersions])
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckAddressFamily+" 192.168.1.103"])
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckDirectories+" 192.168.1.101"])
+	// the hosts are not this machine, so their free space is not read
+	assert.Equal(t, vclusterops.DoctorSkip, statuses[vclusterops.DoctorCheckDiskSpace+" 192.168.1.101"])
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckCommunalStorage])
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckClockSkew])
+	// the certificates of the fake cluster are only valid for a day
+	assert.Equal(t, vclusterops.DoctorWarn, statuses[vclusterops.DoctorCheckCertificates+" 192.168.1.101"])
+	// the scratch directories were deleted
+	var deleted bool
+	for _, request := range cluster.Requests() {
+		deleted = deleted || request.Endpoint == "v1/directories/delete"
+	}
+	assert.True(t, deleted)
+
+	// a host without NMA, a host of another version, a host with no write
+	// access and a revive without a database all fail
+	cluster.SetNMADown("192.168.1.102", true)
+	cluster.SetVerticaVersion("192.168.1.103", "v24.4.0")
+	cluster.Handle("192.168.1.101", NMA, http.MethodPost, "v1/directories/prepare", func(w http.ResponseWriter, _ *http.Request) {
+		rfc7807.New(rfc7807.CreateDirectoryPermissionDenied).WithDetail("permission denied").SendError(w)
+	})
+	doctorOptions.ForRevive = true
+	report, err = vcc.VDoctor(ctx, &doctorOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, vclusterops.DoctorFail, report.Status)
+	statuses = doctorStatuses(report)
+	assert.Equal(t, vclusterops.DoctorFail, statuses[vclusterops.DoctorCheckNMA+" 192.168.1.102"])
+	assert.Equal(t, vclusterops.DoctorFail, statuses[vclusterops.DoctorCheckVersions])
+	assert.Equal(t, vclusterops.DoctorFail, statuses[vclusterops.DoctorCheckDirectories+" 192.168.1.101"])
+	assert.Equal(t, vclusterops.DoctorPass, statuses[vclusterops.DoctorCheckDirectories+" 192.168.1.103"])
+	assert.Equal(t, vclusterops.DoctorFail, statuses[vclusterops.DoctorCheckCommunalStorage])
+	assert.NotContains(t, statuses, vclusterops.DoctorCheckCertificates+" 192.168.1.102")
+}
+
+// doctorStatuses returns the statuses of the checks of report, by name and
+// host
+func doctorStatuses(report *vclusterops.DoctorReport) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[strings.TrimSpace(check.Name+" "+check.Host)] = check.Status
+	}
+	return statuses
+}
+
+func TestAddSubclusterRollback(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	ctx := context.Background()
+
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	createOptions.IsEon = true
+	createOptions.CommunalStorageLocation = "s3://bucket/test_db"
+	createOptions.DepotPrefix = "/depot"
+	createOptions.ShardCount = 6
+	_, err = vcc.VCreateDatabase(ctx, &createOptions)
+	assert.NoError(t, err)
+
+	addSubclusterOptions := vclusterops.VAddSubclusterOptionsFactory()
+	setOptions(cluster, &addSubclusterOptions.DatabaseOptions)
+	addSubclusterOptions.IsEon = true
+	addSubclusterOptions.SCName = "sc1"
+	addSubclusterOptions.AutoRollback = true
+	assert.NoError(t, vcc.VAddSubcluster(ctx, &addSubclusterOptions))
+	assert.Contains(t, cluster.Subclusters(), "sc1")
+
+	// the host already has a node, so add_node fails its requirements check
+	// before it changed anything, and the new subcluster is dropped
+	addNodeOptions := &addSubclusterOptions.VAddNodeOptions
+	addNodeOptions.DatabaseOptions = addSubclusterOptions.DatabaseOptions
+	addNodeOptions.SCName = addSubclusterOptions.SCName
+	addNodeOptions.NewHosts = []string{"192.168.1.103"}
+	_, err = vcc.VAddNode(ctx, addNodeOptions)
+	var rollbackErr *vclusterops.AutoRollbackError
+	if !assert.ErrorAs(t, err, &rollbackErr) {
+		return
+	}
+	assert.ErrorContains(t, err, "192.168.1.103")
+	assert.NoError(t, rollbackErr.RollbackErr)
+	assert.Equal(t, "sc1", rollbackErr.DroppedSubcluster)
+	assert.Empty(t, rollbackErr.DroppedNodes)
+	assert.NotContains(t, cluster.Subclusters(), "sc1")
+	assert.Len(t, cluster.Nodes(), len(testHosts))
+}
@@ -0,0 +1,104 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vertica/vcluster/rfc7807"
+)
+
+const endpointVersion = "v1/"
+
+// route emulates an endpoint on host. It returns the response body, which
+// is sent as is if it is a string and in JSON otherwise, or an error, which
+// is sent as a problem.
+type route func(host string, r *http.Request) (any, error)
+
+// handler returns the handler of the requests to service on host. The
+// handlers set with Handle take precedence over the emulated endpoints.
+func (c *Cluster) handler(host, service string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := strings.TrimPrefix(r.URL.Path, "/")
+		c.mu.Lock()
+		c.requests = append(c.requests, Request{Host: host, Service: service, Method: r.Method,
+			Endpoint: endpoint, Query: r.URL.RawQuery, TraceParent: r.Header.Get("traceparent")})
+		handler, ok := c.handlers[handlerKey(host, service, r.Method, endpoint)]
+		if !ok {
+			handler, ok = c.handlers[handlerKey("", service, r.Method, endpoint)]
+		}
+		c.mu.Unlock()
+		if ok {
+			handler(w, r)
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		// the node went down after the connection was made
+		if service == HTTPS && !c.isUp(host) {
+			panic(http.ErrAbortHandler)
+		}
+		var body any
+		var err error
+		if service == NMA {
+			body, err = c.routeNMA(r.Method, strings.TrimPrefix(endpoint, endpointVersion))(host, r)
+		} else {
+			body, err = c.routeHTTPS(r.Method, strings.TrimPrefix(endpoint, endpointVersion))(host, r)
+		}
+		writeResponse(w, host, body, err)
+	}
+}
+
+func writeResponse(w http.ResponseWriter, host string, body any, err error) {
+	if err != nil {
+		problem := &rfc7807.VProblem{}
+		if !errors.As(err, &problem) {
+			problem = rfc7807.New(rfc7807.GenericHTTPInternalServerError).WithDetail(err.Error())
+		}
+		problem.WithHost(host).SendError(w)
+		return
+	}
+	if content, ok := body.(string); ok {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(content))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func notFound(method, endpoint string) route {
+	return func(string, *http.Request) (any, error) {
+		return nil, rfc7807.New(rfc7807.BadRequest).
+			WithDetail(fmt.Sprintf("the fake cluster does not emulate %s %s", method, endpoint))
+	}
+}
+
+func badRequest(format string, args ...any) error {
+	return rfc7807.New(rfc7807.BadRequest).WithDetail(fmt.Sprintf(format, args...))
+}
+
+func decodeBody(r *http.Request, body any) error {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		return badRequest("invalid request body: %v", err)
+	}
+	return nil
+}
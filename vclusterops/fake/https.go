@@ -0,0 +1,399 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+	"golang.org/x/exp/slices"
+)
+
+// routeHTTPS returns the route of the HTTPS endpoint. It is only called when
+// the node of the host is up, so there is a database.
+func (c *Cluster) routeHTTPS(method, endpoint string) route {
+	routes := map[string]route{
+		"GET nodes":                  c.getNodes,
+		"GET node":                   c.getLocalNode,
+		"GET node/storage-locations": c.getStorageLocations,
+		"POST nodes":                 c.createNodes,
+		"GET cluster":                c.getCluster,
+		"POST cluster/shutdown":      c.shutdown,
+		"PUT cluster/k-safety":       c.markDesignKSafe,
+		"POST cluster/depot":         c.createDepot,
+		"POST cluster/catalog/sync":  c.syncCatalog,
+		"POST config/spread/reload": func(string, *http.Request) (any, error) {
+			return map[string]string{"detail": "Reloaded"}, nil
+		},
+		"GET startup/commands": c.startupCommands,
+		"POST packages":        installPackages,
+		"GET subclusters":      c.getSubclusters,
+	}
+	if r, ok := routes[method+" "+endpoint]; ok {
+		return r
+	}
+	if strings.HasPrefix(endpoint, "nodes/") && method == http.MethodGet {
+		return c.getNode(strings.TrimPrefix(endpoint, "nodes/"))
+	}
+	if subcluster, ok := strings.CutPrefix(endpoint, "subclusters/"); ok {
+		return c.routeSubcluster(method, endpoint, subcluster)
+	}
+	return notFound(method, endpoint)
+}
+
+// nodeState is a node as the nodes endpoints return it
+type nodeState struct {
+	Address       string   `json:"address"`
+	State         string   `json:"state"`
+	Database      string   `json:"database"`
+	CatalogPath   string   `json:"catalog_path"`
+	DepotPath     string   `json:"depot_path"`
+	DataPath      []string `json:"data_path"`
+	Subcluster    string   `json:"subcluster_name"`
+	IsPrimary     bool     `json:"is_primary"`
+	Name          string   `json:"name"`
+	Sandbox       string   `json:"sandbox_name"`
+	BuildInfo     string   `json:"build_info"`
+	IsControlNode bool     `json:"is_control_node"`
+	DownSince     *string  `json:"down_since"`
+	// ShardSubscriptions is the number of shards the node subscribes to
+	ShardSubscriptions int `json:"number_shard_subscriptions"`
+}
+
+func (c *Cluster) nodeState(node *Node) nodeState {
+	version, ok := c.versions[node.Address]
+	if !ok {
+		version = DefaultVerticaVersion
+	}
+	state := nodeState{
+		Address:       node.Address,
+		State:         node.State,
+		Database:      c.database.name,
+		CatalogPath:   path.Join(node.CatalogPath, catalogDirName),
+		DepotPath:     node.DepotPath,
+		DataPath:      []string{node.DataPath},
+		Subcluster:    node.Subcluster,
+		IsPrimary:     node.IsPrimary,
+		Name:          node.Name,
+		Sandbox:       node.Sandbox,
+		BuildInfo:     version + "-0123456789abcdef",
+		IsControlNode: true,
+	}
+	if node.DownSince != "" {
+		downSince := node.DownSince
+		state.DownSince = &downSince
+	}
+	if c.database.communalStorage != "" {
+		state.ShardSubscriptions = shardSubscriptions
+	}
+	return state
+}
+
+// getNodes returns the nodes the node on host sees: the ones of the main
+// cluster, or the ones of its sandbox
+func (c *Cluster) getNodes(host string, _ *http.Request) (any, error) {
+	nodeList := []nodeState{}
+	for _, node := range c.nodeList(c.database.nodes[host].Sandbox, true) {
+		nodeList = append(nodeList, c.nodeState(&node))
+	}
+	return map[string]any{"node_list": nodeList}, nil
+}
+
+// getLocalNode returns the node on host, as it sees itself
+func (c *Cluster) getLocalNode(host string, _ *http.Request) (any, error) {
+	return map[string]any{"node_list": []nodeState{c.nodeState(c.database.nodes[host])}}, nil
+}
+
+// getStorageLocations returns the data location of the node on host, with
+// its depot in Eon mode
+func (c *Cluster) getStorageLocations(host string, _ *http.Request) (any, error) {
+	type storageLocation struct {
+		Name        string `json:"name"`
+		UsageType   string `json:"location_usage_type"`
+		Path        string `json:"location_path"`
+		DiskPercent string `json:"disk_percent"`
+	}
+	node := c.database.nodes[host]
+	diskPercent := strconv.Itoa(c.disks[host]) + "%"
+	locations := []storageLocation{{Name: "__location_0_" + node.Name, UsageType: "DATA,TEMP",
+		Path: node.DataPath, DiskPercent: diskPercent}}
+	if node.DepotPath != "" {
+		locations = append(locations, storageLocation{Name: "__location_1_" + node.Name, UsageType: "DEPOT",
+			Path: node.DepotPath, DiskPercent: diskPercent})
+	}
+	return map[string]any{"storage_location_list": locations}, nil
+}
+
+func (c *Cluster) getNode(address string) route {
+	return func(string, *http.Request) (any, error) {
+		node, err := c.node(address)
+		if err != nil {
+			return nil, badRequest("%v", err)
+		}
+		return map[string]any{"node_list": []nodeState{c.nodeState(node)}}, nil
+	}
+}
+
+// createNodes adds the hosts to the database, with nodes that are down until
+// they are started. The prefixes of their paths include the database name.
+func (c *Cluster) createNodes(_ string, r *http.Request) (any, error) {
+	query := r.URL.Query()
+	subcluster := query.Get("subcluster")
+	if subcluster == "" {
+		subcluster = defaultSubcluster
+	}
+	names := make(map[string]string, len(c.database.nodes))
+	for _, node := range c.database.nodes {
+		names[node.Name] = node.Address
+	}
+	type createdNode struct {
+		Name        string `json:"name"`
+		CatalogPath string `json:"catalog_path"`
+	}
+	createdNodes := []createdNode{}
+	for _, host := range strings.Split(query.Get("hosts"), ",") {
+		if _, ok := c.servers[host]; !ok {
+			return nil, badRequest("host %s is not in the fake cluster", host)
+		}
+		if _, ok := c.database.nodes[host]; ok {
+			return nil, badRequest("host %s already has a node", host)
+		}
+		name, ok := util.GenVNodeName(names, c.database.name, len(c.servers))
+		if !ok {
+			return nil, badRequest("no node name is available for host %s", host)
+		}
+		names[name] = host
+		node := &Node{
+			Name:        name,
+			Address:     host,
+			State:       util.NodeDownState,
+			Subcluster:  subcluster,
+			IsPrimary:   subcluster == defaultSubcluster,
+			CatalogPath: path.Join(query.Get("catalog-prefix"), name+"_catalog"),
+			DataPath:    path.Join(query.Get("data-prefix"), name+"_data"),
+		}
+		c.database.nodes[host] = node
+		createdNodes = append(createdNodes, createdNode{Name: name, CatalogPath: node.CatalogPath})
+	}
+	c.database.catalogVersion++
+	return map[string]any{"created_nodes": createdNodes}, nil
+}
+
+func (c *Cluster) getCluster(string, *http.Request) (any, error) {
+	locations := []string{}
+	if c.database.communalStorage != "" {
+		locations = append(locations, c.database.communalStorage)
+	}
+	return map[string]any{
+		"is_eon":  c.database.communalStorage != "",
+		"db_name": c.database.name,
+		// the misspelling is the one of the endpoint
+		"commnual_storage_locations": locations,
+	}, nil
+}
+
+// shutdown stops the nodes of the main cluster, or of the sandbox of host
+func (c *Cluster) shutdown(host string, _ *http.Request) (any, error) {
+	sandbox := c.database.nodes[host].Sandbox
+	for _, node := range c.database.nodes {
+		if node.Sandbox == sandbox {
+			node.setState(util.NodeDownState)
+		}
+	}
+	return map[string]string{"detail": "Shutdown: moveout complete"}, nil
+}
+
+func (c *Cluster) markDesignKSafe(_ string, r *http.Request) (any, error) {
+	k, err := strconv.Atoi(r.URL.Query().Get("k"))
+	if err != nil || k < 0 || k > 1 {
+		return nil, badRequest("invalid k-safety %q", r.URL.Query().Get("k"))
+	}
+	return map[string]string{"detail": fmt.Sprintf("Marked design %d-safe", k)}, nil
+}
+
+// createDepot creates the depots of the nodes under the depot prefix
+func (c *Cluster) createDepot(_ string, r *http.Request) (any, error) {
+	prefix := r.URL.Query().Get("path")
+	if prefix == "" {
+		return nil, badRequest("the depot path is missing")
+	}
+	type depot struct {
+		Node          string `json:"node"`
+		DepotLocation string `json:"depot_location"`
+	}
+	depots := []depot{}
+	for _, node := range c.database.nodes {
+		node.DepotPath = path.Join(prefix, c.database.name, node.Name+"_depot")
+		depots = append(depots, depot{Node: node.Name, DepotLocation: node.DepotPath})
+	}
+	return map[string]any{"depots": depots}, nil
+}
+
+func (c *Cluster) syncCatalog(string, *http.Request) (any, error) {
+	return map[string]string{"new_truncation_version": strconv.FormatInt(c.database.catalogVersion, 10)}, nil
+}
+
+func (c *Cluster) startupCommands(string, *http.Request) (any, error) {
+	commands := make(map[string][]string, len(c.database.nodes))
+	for _, node := range c.database.nodes {
+		commands[node.Name] = c.startCommand(node)
+	}
+	return commands, nil
+}
+
+func installPackages(string, *http.Request) (any, error) {
+	return map[string]any{"packages": []map[string]string{
+		{"package_name": "ComplexTypes", "install_status": "Success"},
+	}}, nil
+}
+
+// subclusterState is a subcluster as the subclusters endpoints return it
+type subclusterState struct {
+	Name        string `json:"subcluster_name"`
+	IsSecondary bool   `json:"is_secondary"`
+	IsDefault   bool   `json:"is_default"`
+	Sandbox     string `json:"sandbox"`
+	CtlSetSize  int    `json:"control_set_size"`
+}
+
+func (c *Cluster) getSubclusters(string, *http.Request) (any, error) {
+	return map[string]any{"subcluster_list": c.subclusterStates()}, nil
+}
+
+func (c *Cluster) subclusterStates() []subclusterState {
+	subclusters := []subclusterState{}
+	for _, name := range c.subclusters() {
+		sc := subclusterState{Name: name, IsSecondary: true, IsDefault: name == defaultSubcluster, CtlSetSize: -1}
+		if size, ok := c.database.addedSubclusters[name]; ok {
+			sc.CtlSetSize = size
+		}
+		for _, node := range c.database.nodes {
+			if node.Subcluster == name {
+				sc.IsSecondary = !node.IsPrimary
+				sc.Sandbox = node.Sandbox
+			}
+		}
+		subclusters = append(subclusters, sc)
+	}
+	return subclusters
+}
+
+// routeSubcluster returns the route of an endpoint of a single subcluster
+func (c *Cluster) routeSubcluster(method, endpoint, subcluster string) route {
+	if method == http.MethodGet && !strings.Contains(subcluster, "/") {
+		return c.getSubcluster(subcluster)
+	}
+	if method != http.MethodPost {
+		return notFound(method, endpoint)
+	}
+	if name, ok := strings.CutSuffix(subcluster, "/sandbox"); ok {
+		return c.sandboxSubcluster(name)
+	}
+	if name, ok := strings.CutSuffix(subcluster, "/drop"); ok {
+		return c.dropSubcluster(name)
+	}
+	if !strings.Contains(subcluster, "/") {
+		return c.addSubcluster(subcluster)
+	}
+	return notFound(method, endpoint)
+}
+
+// addSubcluster adds a secondary subcluster without nodes
+func (c *Cluster) addSubcluster(subcluster string) route {
+	return func(_ string, r *http.Request) (any, error) {
+		// the control set size is the default one when it is not set
+		request := struct {
+			CtlSetSize int `json:"control_set_size"`
+		}{CtlSetSize: -1}
+		if err := decodeBody(r, &request); err != nil {
+			return nil, err
+		}
+		if slices.Contains(c.subclusters(), subcluster) {
+			return nil, badRequest("subcluster %s already exists", subcluster)
+		}
+		if c.database.addedSubclusters == nil {
+			c.database.addedSubclusters = make(map[string]int)
+		}
+		c.database.addedSubclusters[subcluster] = request.CtlSetSize
+		return map[string]string{"detail": ""}, nil
+	}
+}
+
+func (c *Cluster) getSubcluster(subcluster string) route {
+	return func(string, *http.Request) (any, error) {
+		for _, sc := range c.subclusterStates() {
+			if sc.Name == subcluster {
+				return sc, nil
+			}
+		}
+		return nil, badRequest("subcluster %s does not exist", subcluster)
+	}
+}
+
+// dropSubcluster drops a subcluster with its nodes
+func (c *Cluster) dropSubcluster(subcluster string) route {
+	return func(string, *http.Request) (any, error) {
+		if !slices.Contains(c.subclusters(), subcluster) {
+			return nil, badRequest("subcluster %s does not exist", subcluster)
+		}
+		if subcluster == defaultSubcluster {
+			return nil, badRequest("the default subcluster cannot be dropped")
+		}
+		delete(c.database.addedSubclusters, subcluster)
+		for host, node := range c.database.nodes {
+			if node.Subcluster == subcluster {
+				delete(c.database.nodes, host)
+			}
+		}
+		return map[string]string{"detail": ""}, nil
+	}
+}
+
+// sandboxSubcluster moves the nodes of a secondary subcluster to a sandbox.
+// They stay up.
+func (c *Cluster) sandboxSubcluster(subcluster string) route {
+	return func(_ string, r *http.Request) (any, error) {
+		sandbox := r.URL.Query().Get("sandbox")
+		if sandbox == "" {
+			return nil, badRequest("the sandbox name is missing")
+		}
+		var nodes []*Node
+		for _, node := range c.database.nodes {
+			if node.Subcluster == subcluster {
+				nodes = append(nodes, node)
+			}
+		}
+		if len(nodes) == 0 {
+			return nil, badRequest("subcluster %s does not exist", subcluster)
+		}
+		for _, node := range nodes {
+			if node.IsPrimary {
+				return nil, badRequest("subcluster %s is a primary subcluster, it cannot be sandboxed", subcluster)
+			}
+			if node.Sandbox != "" {
+				return nil, badRequest("subcluster %s is already in sandbox %s", subcluster, node.Sandbox)
+			}
+		}
+		for _, node := range nodes {
+			node.Sandbox = sandbox
+		}
+		return map[string]string{"detail": ""}, nil
+	}
+}
@@ -0,0 +1,296 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops/util"
+)
+
+const catalogDirName = "Catalog"
+
+// routeNMA returns the route of the NMA endpoint
+func (c *Cluster) routeNMA(method, endpoint string) route {
+	routes := map[string]route{
+		"GET health": func(string, *http.Request) (any, error) {
+			return map[string]string{"healthy": "true"}, nil
+		},
+		"GET vertica/version":        c.verticaVersion,
+		"POST directories/prepare":   c.prepareDirectories,
+		"POST directories/delete":    deleteDirectories,
+		"POST vertica/download-file": c.downloadFile,
+		"GET network-profiles":       networkProfile,
+		"POST catalog/bootstrap":     c.bootstrapCatalog,
+		"GET catalog/database":       c.readCatalog,
+		"GET nodes":                  c.nmaNodeInfo,
+		"POST nodes/start":           c.startNode,
+		"GET config/vertica":         c.downloadConfig("vertica"),
+		"GET config/spread":          c.downloadConfig("spread"),
+		"POST config/vertica":        c.uploadConfig("vertica"),
+		"POST config/spread":         c.uploadConfig("spread"),
+	}
+	if r, ok := routes[method+" "+endpoint]; ok {
+		return r
+	}
+	return notFound(method, endpoint)
+}
+
+func (c *Cluster) verticaVersion(host string, _ *http.Request) (any, error) {
+	version, ok := c.versions[host]
+	if !ok {
+		version = DefaultVerticaVersion
+	}
+	return map[string]string{"vertica_version": "Vertica Analytic Database " + version}, nil
+}
+
+func (c *Cluster) prepareDirectories(_ string, r *http.Request) (any, error) {
+	var request struct {
+		CatalogPath      string   `json:"catalog_path"`
+		DepotPath        string   `json:"depot_path"`
+		StorageLocations []string `json:"storage_locations"`
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	created := map[string]string{request.CatalogPath: "created"}
+	if request.DepotPath != "" {
+		created[request.DepotPath] = "created"
+	}
+	for _, location := range request.StorageLocations {
+		created[location] = "created"
+	}
+	return created, nil
+}
+
+func deleteDirectories(_ string, r *http.Request) (any, error) {
+	var request struct {
+		Directories []string `json:"directories"`
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	deleted := make(map[string]string, len(request.Directories))
+	for _, directory := range request.Directories {
+		deleted[directory] = "deleted"
+	}
+	return deleted, nil
+}
+
+// downloadFile returns the description file of the database, which is only
+// in the communal storage of an Eon database
+func (c *Cluster) downloadFile(_ string, r *http.Request) (any, error) {
+	var request struct {
+		SourceFilePath string `json:"source_file_path"`
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	if c.database == nil || c.database.communalStorage == "" ||
+		!strings.HasPrefix(request.SourceFilePath, c.database.communalStorage+"/") {
+		return nil, rfc7807.New(rfc7807.UndefinedFile).WithDetail(fmt.Sprintf("%s does not exist", request.SourceFilePath))
+	}
+	type descriptionNode struct {
+		Name        string `json:"name"`
+		Address     string `json:"address"`
+		CatalogPath string `json:"catalogPath"`
+		IsPrimary   bool   `json:"isPrimary"`
+	}
+	content := struct {
+		Nodes []descriptionNode `json:"Node"`
+	}{}
+	for _, node := range c.nodeList("", false) {
+		content.Nodes = append(content.Nodes, descriptionNode{Name: node.Name, Address: node.Address,
+			CatalogPath: path.Join(node.CatalogPath, catalogDirName), IsPrimary: node.IsPrimary})
+	}
+	fileContent, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"std_out": "Download successful", "file_content": string(fileContent)}, nil
+}
+
+// networkProfile returns the profile of a /24 network the host is on
+func networkProfile(host string, _ *http.Request) (any, error) {
+	ip := net.ParseIP(host).To4()
+	if ip == nil {
+		return nil, badRequest("the fake cluster only emulates the network profiles of IPv4 hosts")
+	}
+	return map[string]string{
+		"name":      "eth0",
+		"address":   host,
+		"subnet":    fmt.Sprintf("%d.%d.%d.0/24", ip[0], ip[1], ip[2]),
+		"netmask":   "255.255.255.0",
+		"broadcast": fmt.Sprintf("%d.%d.%d.255", ip[0], ip[1], ip[2]),
+	}, nil
+}
+
+// bootstrapCatalog creates the database with a first node, which is down
+// until it is started
+func (c *Cluster) bootstrapCatalog(host string, r *http.Request) (any, error) {
+	var request struct {
+		DBName          string `json:"db_name"`
+		NodeName        string `json:"node_name"`
+		CatalogPath     string `json:"catalog_path"`
+		StorageLocation string `json:"storage_location"`
+		CommunalStorage string `json:"communal_storage"`
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	if c.database != nil {
+		return nil, badRequest("database %s already exists", c.database.name)
+	}
+	c.database = &database{
+		name:            request.DBName,
+		communalStorage: request.CommunalStorage,
+		catalogVersion:  1,
+		nodes: map[string]*Node{host: {
+			Name:        request.NodeName,
+			Address:     host,
+			State:       util.NodeDownState,
+			Subcluster:  defaultSubcluster,
+			IsPrimary:   true,
+			CatalogPath: request.CatalogPath,
+			DataPath:    request.StorageLocation,
+		}},
+	}
+	c.configs[host][path.Join(request.CatalogPath, "vertica.conf")] = fmt.Sprintf("# vertica.conf of %s\n", request.NodeName)
+	c.configs[host][path.Join(request.CatalogPath, "spread.conf")] = fmt.Sprintf("# spread.conf of %s\n", request.DBName)
+	return map[string]string{"bootstrap_catalog_return_code": "0"}, nil
+}
+
+// readCatalog returns the catalog of the database as seen by the node on
+// host. All the nodes see the same catalog.
+func (c *Cluster) readCatalog(host string, r *http.Request) (any, error) {
+	node, err := c.node(host)
+	if err != nil {
+		return nil, badRequest("fail to read the catalog: %v", err)
+	}
+	if catalogPath := strings.TrimSuffix(r.URL.Query().Get("catalog_path"), "/"+catalogDirName); catalogPath != node.CatalogPath {
+		return nil, badRequest("there is no catalog at %s", catalogPath)
+	}
+
+	type subclusterDetails struct {
+		Name      string `json:"sc_name"`
+		IsPrimary bool   `json:"is_primary_sc"`
+		IsDefault bool   `json:"is_default"`
+		IsSandbox bool   `json:"sandbox"`
+	}
+	type catalogNode struct {
+		Address          string            `json:"address"`
+		CatalogPath      string            `json:"catalog_path"`
+		IsPrimary        bool              `json:"is_primary"`
+		Name             string            `json:"name"`
+		StartCommand     []string          `json:"start_command"`
+		StorageLocations []string          `json:"storage_locations"`
+		Subcluster       subclusterDetails `json:"sc_details"`
+	}
+	nodes := []catalogNode{}
+	for _, n := range c.nodeList("", false) {
+		nodes = append(nodes, catalogNode{
+			Address:          n.Address,
+			CatalogPath:      path.Join(n.CatalogPath, catalogDirName),
+			IsPrimary:        n.IsPrimary,
+			Name:             n.Name,
+			StartCommand:     c.startCommand(&n),
+			StorageLocations: []string{n.DataPath},
+			Subcluster: subclusterDetails{Name: n.Subcluster, IsPrimary: n.IsPrimary,
+				IsDefault: n.Subcluster == defaultSubcluster, IsSandbox: n.Sandbox != ""},
+		})
+	}
+	return map[string]any{
+		"name":                      c.database.name,
+		"versions":                  map[string]int64{"global": c.database.catalogVersion},
+		"nodes":                     nodes,
+		"control_mode":              "pt2pt",
+		"spread_encryption":         "",
+		"communal_storage_location": c.database.communalStorage,
+	}, nil
+}
+
+func (c *Cluster) startCommand(node *Node) []string {
+	return []string{"/opt/vertica/bin/vertica", "-D", node.CatalogPath, "-C", c.database.name,
+		"-n", node.Name, "-h", node.Address, "-p", strconv.Itoa(util.DefaultClientPort), "-P", "4803", "-Y", "ipv4"}
+}
+
+// nmaNodeInfo returns the node on host, read from its catalog
+func (c *Cluster) nmaNodeInfo(host string, r *http.Request) (any, error) {
+	node, err := c.node(host)
+	if err != nil || r.URL.Query().Get("db_name") != c.database.name {
+		return nil, badRequest("there is no node of database %s on host %s", r.URL.Query().Get("db_name"), host)
+	}
+	return map[string]any{
+		"name":             node.Name,
+		"catalog_path":     node.CatalogPath,
+		"Address":          node.Address,
+		"StorageLocations": []string{node.DataPath},
+		"DepotPath":        node.DepotPath,
+		"IsPrimary":        node.IsPrimary,
+	}, nil
+}
+
+// startNode starts the node on host. It is up at once.
+func (c *Cluster) startNode(host string, r *http.Request) (any, error) {
+	var request struct {
+		StartCommand []string `json:"start_command"`
+	}
+	if err := decodeBody(r, &request); err != nil {
+		return nil, err
+	}
+	node, err := c.node(host)
+	if err != nil {
+		return nil, badRequest("fail to start the node: %v", err)
+	}
+	if len(request.StartCommand) == 0 {
+		return nil, badRequest("the start command of node %s is empty", node.Name)
+	}
+	node.setState(util.NodeUpState)
+	return map[string]any{"dbLogPath": path.Join(node.CatalogPath, "dbLog"), "return_code": 0}, nil
+}
+
+func (c *Cluster) downloadConfig(name string) route {
+	return func(host string, r *http.Request) (any, error) {
+		filePath := path.Join(r.URL.Query().Get("catalog_path"), name+".conf")
+		content, ok := c.configs[host][filePath]
+		if !ok {
+			return nil, badRequest("there is no %s on host %s", filePath, host)
+		}
+		return content, nil
+	}
+}
+
+func (c *Cluster) uploadConfig(name string) route {
+	return func(host string, r *http.Request) (any, error) {
+		var request struct {
+			CatalogPath string `json:"catalog_path"`
+			Content     string `json:"content"`
+		}
+		if err := decodeBody(r, &request); err != nil {
+			return nil, err
+		}
+		filePath := path.Join(request.CatalogPath, name+".conf")
+		c.configs[host][filePath] = request.Content
+		return map[string]string{"destination": filePath}, nil
+	}
+}
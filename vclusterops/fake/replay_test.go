@@ -0,0 +1,104 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	bundlePath := filepath.Join(t.TempDir(), "replay.json")
+	password := "secret1"
+
+	// the commands run against the cluster, the last one fails
+	run := func(ctx context.Context, dialer vclusterops.Dialer, subcluster string) error {
+		createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+		setOptions(cluster, &createOptions.DatabaseOptions)
+		createOptions.Password = &password
+		createOptions.Dialer = dialer
+		createOptions.IsEon = true
+		createOptions.CommunalStorageLocation = "s3://bucket/test_db"
+		createOptions.DepotPrefix = "/depot"
+		createOptions.ShardCount = 6
+		if _, err := vcc.VCreateDatabase(ctx, &createOptions); err != nil {
+			return err
+		}
+		if dialer != nil {
+			assert.NoError(t, cluster.SetSubcluster("192.168.1.103", "sc1", false))
+		}
+		sandboxOptions := vclusterops.VSandboxOptionsFactory()
+		setOptions(cluster, &sandboxOptions.DatabaseOptions)
+		sandboxOptions.Password = &password
+		sandboxOptions.Dialer = dialer
+		sandboxOptions.SCName = subcluster
+		sandboxOptions.SandboxName = "sand"
+		return vcc.VSandbox(ctx, &sandboxOptions)
+	}
+	cluster.Handle("", HTTPS, http.MethodPost, "v1/subclusters/sc1/sandbox", func(w http.ResponseWriter, _ *http.Request) {
+		rfc7807.New(rfc7807.InsufficientResources).WithDetail("out of memory").SendError(w)
+	})
+	err = run(vclusterops.ContextWithRecording(context.Background(), bundlePath), cluster, "sc1")
+	assert.ErrorContains(t, err, "out of memory")
+	liveRequests := len(cluster.Requests())
+	// the bundle has no secret
+	data, err := os.ReadFile(bundlePath)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), password)
+	assert.NotContains(t, string(data), cluster.Certs().Key)
+	// the bundle has a header then a line per exchange, and its temporary
+	// file was renamed
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	assert.Equal(t, `{"version":2}`, lines[0])
+	for _, line := range lines[1:] {
+		assert.True(t, json.Valid([]byte(line)))
+	}
+	_, err = os.Stat(bundlePath + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	// the replay fails the same way, without sending anything
+	cluster.Close()
+	err = run(vclusterops.ContextWithReplay(context.Background(), bundlePath), nil, "sc1")
+	assert.ErrorContains(t, err, "out of memory")
+	assert.Len(t, cluster.Requests(), liveRequests)
+
+	// a replay that sends other requests than the recorded run stops there
+	err = run(vclusterops.ContextWithReplay(context.Background(), bundlePath), nil, "sc2")
+	var diverged *vclusterops.ReplayDivergedError
+	assert.True(t, errors.As(err, &diverged))
+	assert.Equal(t, "v1/subclusters/sc2/sandbox", diverged.Endpoint)
+
+	// the command options can replay a bundle too
+	options := vclusterops.VStopDatabaseOptionsFactory()
+	setOptions(cluster, &options.DatabaseOptions)
+	options.Dialer = nil
+	options.ReplayPath = bundlePath
+	err = vcc.VStopDatabase(context.Background(), &options)
+	assert.True(t, errors.As(err, &diverged))
+}
@@ -0,0 +1,108 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	cluster, err := NewCluster(testHosts...)
+	assert.NoError(t, err)
+	defer cluster.Close()
+	vcc := vclusterops.VClusterCommands{LockDir: t.TempDir()}
+	createOptions := vclusterops.VCreateDatabaseOptionsFactory()
+	setOptions(cluster, &createOptions.DatabaseOptions)
+	_, err = vcc.VCreateDatabase(context.Background(), &createOptions)
+	assert.NoError(t, err)
+	cluster.Handle("", HTTPS, http.MethodPost, "v1/cluster/shutdown", func(w http.ResponseWriter, _ *http.Request) {
+		rfc7807.New(rfc7807.InsufficientResources).WithDetail("out of memory").SendError(w)
+	})
+	sentBefore := len(cluster.Requests())
+
+	recorder := tracetest.NewSpanRecorder()
+	options := vclusterops.VStopDatabaseOptionsFactory()
+	setOptions(cluster, &options.DatabaseOptions)
+	options.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	err = vcc.VStopDatabase(context.Background(), &options)
+	assert.ErrorContains(t, err, "out of memory")
+
+	// the span of the command is the last to end
+	spans := recorder.Ended()
+	root := spans[len(spans)-1]
+	traceID := root.SpanContext().TraceID()
+	assert.Equal(t, "stop_db", root.Name())
+	assert.False(t, root.Parent().IsValid())
+	assert.Equal(t, codes.Error, root.Status().Code)
+	byID := make(map[trace.SpanID]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		assert.Equal(t, traceID, span.SpanContext().TraceID())
+		byID[span.SpanContext().SpanID()] = span
+	}
+	var shutdownSpans []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.SpanKind() != trace.SpanKindClient {
+			continue
+		}
+		// a request is a grandchild of the command, through its op
+		op := byID[span.Parent().SpanID()]
+		assert.Equal(t, root.SpanContext().SpanID(), op.Parent().SpanID())
+		assert.Equal(t, op.Name(), spanAttributes(op)["vcluster.op"].AsString())
+		if strings.HasSuffix(span.Name(), "cluster/shutdown") {
+			assert.Equal(t, "HTTPSStopDBOp", op.Name())
+			assert.Equal(t, codes.Error, op.Status().Code)
+			shutdownSpans = append(shutdownSpans, span)
+		}
+	}
+	assert.Len(t, shutdownSpans, 1)
+	shutdown := spanAttributes(shutdownSpans[0])
+	assert.Contains(t, testHosts, shutdown["server.address"].AsString())
+	assert.Equal(t, "v1/cluster/shutdown", shutdown["vcluster.endpoint"].AsString())
+	assert.Equal(t, int64(http.StatusInternalServerError), shutdown["http.response.status_code"].AsInt64())
+	assert.Equal(t, rfc7807.InsufficientResources.Type, shutdown["vcluster.problem.type"].AsString())
+
+	// each request carried its span to the host
+	for _, request := range cluster.Requests()[sentBefore:] {
+		assert.True(t, strings.HasPrefix(request.TraceParent, "00-"+traceID.String()+"-"), request.Endpoint)
+	}
+	var shutdownParent string
+	for _, request := range cluster.Requests() {
+		if request.Endpoint == "v1/cluster/shutdown" {
+			shutdownParent = request.TraceParent
+		}
+	}
+	assert.Equal(t, "00-"+traceID.String()+"-"+shutdownSpans[0].SpanContext().SpanID().String()+"-01", shutdownParent)
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
@@ -0,0 +1,308 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+// FaultKind is a failure the fault injector makes a host have
+type FaultKind string
+
+const (
+	// FaultSlowHost makes the host answer after the delay of the fault. The
+	// request times out if the delay is longer than its timeout.
+	FaultSlowHost FaultKind = "slow"
+	// FaultServerError makes the host answer with a 500 Internal Server Error
+	FaultServerError FaultKind = "error"
+	// FaultDroppedConnection makes the host close the connection without
+	// answering
+	FaultDroppedConnection FaultKind = "drop"
+	// FaultMalformedJSON makes the host answer 200 OK with a body that is not
+	// valid JSON
+	FaultMalformedJSON FaultKind = "malformed-json"
+)
+
+const malformedJSONBody = `{"detail": "the response was cut`
+
+// Fault is a failure injected into the requests of a command, to test how
+// the ops cope with it. The requests that get the fault are not sent. Each
+// attempt at sending a request draws the faults again, and the attempts that
+// fail are retried with the retry policy of the request.
+type Fault struct {
+	Kind FaultKind
+	// Host is the address of the host that has the fault, "" for all the
+	// hosts
+	Host string
+	// Endpoint is the pattern of the endpoints that have the fault, in the
+	// syntax of path.Match, such as "v1/nodes/*". "" stands for all the
+	// endpoints.
+	Endpoint string
+	// Probability is the chance, from 0 to 1, that a request the fault
+	// applies to gets it. 0 stands for 1: every request gets the fault.
+	Probability float64
+	// Delay is the time a slow host takes to answer
+	Delay time.Duration
+}
+
+// ParseFaults parses faults written as "key=value" pairs separated by
+// commas, one fault after the other separated by semicolons. The keys are
+// the fields of Fault in lower case, for example:
+//
+//	kind=slow,host=192.168.1.101,delay=30s;kind=error,endpoint=v1/nodes,probability=0.5
+func ParseFaults(spec string) ([]Fault, error) {
+	var faults []Fault
+	for _, faultSpec := range strings.Split(spec, ";") {
+		if strings.TrimSpace(faultSpec) == "" {
+			continue
+		}
+		fault, err := parseFault(faultSpec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fault %q: %w", faultSpec, err)
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+func parseFault(spec string) (fault Fault, err error) {
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return fault, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		switch key {
+		case "kind":
+			fault.Kind = FaultKind(value)
+		case "host":
+			fault.Host = value
+		case "endpoint":
+			fault.Endpoint = value
+		case "probability":
+			fault.Probability, err = strconv.ParseFloat(value, 64)
+		case "delay":
+			fault.Delay, err = time.ParseDuration(value)
+		default:
+			return fault, fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return fault, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return fault, fault.validate()
+}
+
+func (fault *Fault) validate() error {
+	switch fault.Kind {
+	case FaultSlowHost:
+		if fault.Delay <= 0 {
+			return fmt.Errorf("a slow host fault needs a delay")
+		}
+	case FaultServerError, FaultDroppedConnection, FaultMalformedJSON:
+	default:
+		return fmt.Errorf("unknown fault kind %q, must be one of %s, %s, %s or %s", fault.Kind,
+			FaultSlowHost, FaultServerError, FaultDroppedConnection, FaultMalformedJSON)
+	}
+	if fault.Probability < 0 || fault.Probability > 1 {
+		return fmt.Errorf("the probability of a fault must be between 0 and 1, got %g", fault.Probability)
+	}
+	if _, err := path.Match(fault.Endpoint, ""); err != nil {
+		return fmt.Errorf("invalid endpoint pattern %q: %w", fault.Endpoint, err)
+	}
+	return nil
+}
+
+func (opt *DatabaseOptions) validateFaults() error {
+	for i := range opt.Faults {
+		if err := opt.Faults[i].validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliesTo is true if the request to host gets the fault, drawn with the
+// probability of the fault
+func (fault *Fault) appliesTo(host string, request *hostHTTPRequest) bool {
+	if fault.Host != "" && fault.Host != host {
+		return false
+	}
+	if fault.Endpoint != "" {
+		if matched, _ := path.Match(fault.Endpoint, request.Endpoint); !matched {
+			return false
+		}
+	}
+	// the draws need no cryptographic randomness
+	//nolint:gosec
+	return fault.Probability == 0 || rand.Float64() < fault.Probability
+}
+
+type faultsContextKey struct{}
+
+// attachFaults attaches the faults of the options to ctx, so that they are
+// injected into the requests of every op run under it
+func (opt *DatabaseOptions) attachFaults(ctx context.Context) context.Context {
+	if len(opt.Faults) == 0 || ctx.Value(faultsContextKey{}) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, faultsContextKey{}, opt.Faults)
+}
+
+func faultsFromContext(ctx context.Context) []Fault {
+	faults, _ := ctx.Value(faultsContextKey{}).([]Fault)
+	return faults
+}
+
+// faultAdapter injects faults into the requests to a host. The requests
+// that get no fault are sent by the adapter it wraps.
+type faultAdapter struct {
+	adapter
+	host   string
+	faults []Fault
+	logger vlog.Printer
+}
+
+// sendRequest draws the faults again for each attempt at sending request.
+// An attempt that gets a fault is retried with the retry policy of the
+// request, as the HTTP adapter retries the real failures. Once an attempt
+// gets no fault, the wrapped adapter sends the request with the attempts
+// that are left.
+func (adapter *faultAdapter) sendRequest(ctx context.Context, request *hostHTTPRequest,
+	resultChannel chan<- hostHTTPResult) {
+	for attempt := 1; ; attempt++ {
+		fault := adapter.drawFault(request)
+		if fault == nil {
+			adapter.sendAttemptsLeft(ctx, request, attempt-1, resultChannel)
+			return
+		}
+		adapter.logger.Info("Injecting fault", "host", adapter.host, "endpoint", request.Endpoint,
+			"fault", fault.Kind, "attempt", attempt)
+		result, answered := adapter.injectFault(ctx, request, fault)
+		if !answered {
+			// the slow host answers once the delay has passed
+			adapter.sendAttemptsLeft(ctx, request, attempt-1, resultChannel)
+			return
+		}
+		result.attempts = attempt
+		if ctx.Err() != nil || !request.RetryPolicy.shouldRetry(request.Method, &result, attempt) {
+			resultChannel <- result
+			return
+		}
+		delay := request.RetryPolicy.backoff(attempt)
+		adapter.logger.Info("Retrying request", "host", adapter.host, "endpoint", request.Endpoint,
+			"attempt", attempt, "maxAttempts", request.RetryPolicy.MaxAttempts, "delay", delay.String(),
+			"statusCode", result.statusCode, "error", result.err)
+		if !waitBackoff(ctx, delay) {
+			resultChannel <- result
+			return
+		}
+	}
+}
+
+// drawFault returns the first fault that the request gets, or nil
+func (adapter *faultAdapter) drawFault(request *hostHTTPRequest) *Fault {
+	for i := range adapter.faults {
+		if adapter.faults[i].appliesTo(adapter.host, request) {
+			return &adapter.faults[i]
+		}
+	}
+	return nil
+}
+
+// injectFault makes the result of an attempt that gets fault. It returns
+// false if the request is to be sent, by a slow host that did not time out.
+func (adapter *faultAdapter) injectFault(ctx context.Context, request *hostHTTPRequest,
+	fault *Fault) (result hostHTTPResult, answered bool) {
+	switch fault.Kind {
+	case FaultSlowHost:
+		return adapter.waitSlowly(ctx, request, fault.Delay)
+	case FaultServerError:
+		problem := rfc7807.New(rfc7807.GenericHTTPInternalServerError).
+			WithDetail("fault injected by vcluster").WithHost(adapter.host)
+		body, _ := json.Marshal(problem)
+		return adapter.answer(http.StatusInternalServerError, string(body), rfc7807.GenerateErrorFromResponse(string(body))), true
+	case FaultMalformedJSON:
+		return adapter.answer(http.StatusOK, malformedJSONBody, nil), true
+	default:
+		err := fmt.Errorf("fail to send request %v on host %s, details %w", request.Endpoint, adapter.host,
+			io.ErrUnexpectedEOF)
+		return hostHTTPResult{host: adapter.host, status: EXCEPTION, err: err}, true
+	}
+}
+
+// sendAttemptsLeft sends request with the wrapped adapter, after failed
+// attempts got a fault. The retry policy of the request is cut by those
+// attempts, which are counted in the result.
+func (adapter *faultAdapter) sendAttemptsLeft(ctx context.Context, request *hostHTTPRequest, failed int,
+	resultChannel chan<- hostHTTPResult) {
+	if failed == 0 {
+		adapter.adapter.sendRequest(ctx, request, resultChannel)
+		return
+	}
+	retried := *request
+	policy := *request.RetryPolicy
+	policy.MaxAttempts -= failed
+	retried.RetryPolicy = &policy
+	results := make(chan hostHTTPResult, 1)
+	adapter.adapter.sendRequest(ctx, &retried, results)
+	result := <-results
+	result.attempts += failed
+	resultChannel <- result
+}
+
+// answer makes the result of a response the host did not send, with the
+// error of a failed one. The wrapped adapter does not read it, as it would
+// write the body of a download to the destination file.
+func (adapter *faultAdapter) answer(statusCode int, body string, err error) hostHTTPResult {
+	result := hostHTTPResult{host: adapter.host, status: SUCCESS, statusCode: statusCode, content: body}
+	if err != nil {
+		result.status = FAILURE
+		result.err = err
+	}
+	return result
+}
+
+// waitSlowly waits for delay to pass before request is sent. It returns the
+// result of the request if it times out, or ctx is done, first.
+func (adapter *faultAdapter) waitSlowly(ctx context.Context, request *hostHTTPRequest,
+	delay time.Duration) (result hostHTTPResult, answered bool) {
+	timeout := clientTimeout(request)
+	wait := delay
+	if timeout > 0 && timeout < delay {
+		wait = timeout
+	}
+	if !waitBackoff(ctx, wait) {
+		return hostHTTPResult{host: adapter.host, status: EXCEPTION, err: ctx.Err()}, true
+	}
+	if wait < delay {
+		err := fmt.Errorf("fail to send request %v on host %s, details %w", request.Endpoint, adapter.host,
+			&timeoutError{message: "Client.Timeout exceeded while awaiting headers"})
+		return hostHTTPResult{host: adapter.host, status: EXCEPTION, err: err, timeout: timeout}, true
+	}
+	return hostHTTPResult{}, false
+}
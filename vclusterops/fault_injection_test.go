@@ -0,0 +1,151 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/rfc7807"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("kind=slow,host=192.168.1.101,delay=30s; kind=error,endpoint=v1/nodes/*,probability=0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, []Fault{
+		{Kind: FaultSlowHost, Host: "192.168.1.101", Delay: 30 * time.Second},
+		{Kind: FaultServerError, Endpoint: "v1/nodes/*", Probability: 0.5},
+	}, faults)
+
+	_, err = ParseFaults("kind=slow")
+	assert.ErrorContains(t, err, "a slow host fault needs a delay")
+	_, err = ParseFaults("kind=flaky")
+	assert.ErrorContains(t, err, `unknown fault kind "flaky"`)
+	_, err = ParseFaults("kind=drop,probability=2")
+	assert.ErrorContains(t, err, "must be between 0 and 1")
+	_, err = ParseFaults("kind=drop,endpoint=[")
+	assert.ErrorContains(t, err, "invalid endpoint pattern")
+	_, err = ParseFaults("kind=drop,host")
+	assert.ErrorContains(t, err, "is not a key=value pair")
+}
+
+func TestFaultAdapter(t *testing.T) {
+	options := DatabaseOptionsFactory()
+	options.Faults = []Fault{
+		{Kind: FaultServerError, Host: "host00"},
+		{Kind: FaultMalformedJSON, Host: "host01"},
+		{Kind: FaultDroppedConnection, Host: "host02"},
+		{Kind: FaultSlowHost, Host: "host03", Delay: 10 * time.Millisecond},
+		{Kind: FaultSlowHost, Host: "host04", Delay: time.Hour},
+		// the request to host05 is not to this endpoint
+		{Kind: FaultDroppedConnection, Host: "host05", Endpoint: "v1/nodes/*"},
+	}
+	assert.NoError(t, options.validateFaults())
+	options.RetryPolicy = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond,
+		RetryableMethods: []string{GetMethod}}
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, "")
+	pool, httpRequest, tracker := makeMockPool(6)
+	for host, request := range httpRequest.RequestCollection {
+		request.Endpoint = "v1/nodes"
+		request.Timeout = 1
+		httpRequest.RequestCollection[host] = request
+	}
+	// the responses of the faults are not written to the file of a download
+	destFiles := map[string]string{}
+	for _, host := range []string{"host00", "host01"} {
+		destFiles[host] = filepath.Join(t.TempDir(), host+".tgz")
+		httpAdapter := makeHTTPDownloadAdapter(vlog.Printer{}, destFiles[host])
+		httpAdapter.host = host
+		pool.connections[host] = &httpAdapter
+	}
+	err := pool.sendRequest(ctx, httpRequest, nil)
+	assert.NoError(t, err)
+	result := func(host string) *hostHTTPResult {
+		r := httpRequest.ResultCollection[host]
+		return &r
+	}
+
+	// a server error
+	assert.Equal(t, http.StatusInternalServerError, result("host00").statusCode)
+	problem := &rfc7807.VProblem{}
+	assert.True(t, errors.As(result("host00").err, &problem))
+	assert.True(t, problem.IsInstanceOf(rfc7807.GenericHTTPInternalServerError))
+	// a body that is not JSON
+	assert.True(t, result("host01").isPassing())
+	assert.NotEmpty(t, result("host01").content)
+	assert.Error(t, json.Unmarshal([]byte(result("host01").content), &map[string]any{}))
+	for _, destFile := range destFiles {
+		assert.NoFileExists(t, destFile)
+	}
+	// a dropped connection, which is retried until the policy gives up
+	assert.True(t, result("host02").isException())
+	assert.ErrorContains(t, result("host02").err, "unexpected EOF")
+	assert.Equal(t, 3, result("host02").attempts)
+	// a server error is not retried
+	assert.Equal(t, 1, result("host00").attempts)
+	// a slow host answers, unless the request times out first
+	assert.True(t, result("host03").isPassing())
+	assert.True(t, result("host04").isTimeout())
+	var timeoutErr *RequestTimeoutError
+	assert.True(t, errors.As(result("host04").err, &timeoutErr))
+	assert.True(t, result("host05").isPassing())
+	// only the requests without a fault, or to a slow host, were sent
+	assert.ElementsMatch(t, []string{"host03", "host05"}, tracker.sent)
+}
+
+func TestFaultRetried(t *testing.T) {
+	const hostCount = 16
+	options := DatabaseOptionsFactory()
+	options.Faults = []Fault{{Kind: FaultDroppedConnection, Probability: 0.5}}
+	options.RetryPolicy = &RetryPolicy{MaxAttempts: 64, InitialBackoff: time.Microsecond,
+		RetryableMethods: []string{GetMethod}}
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, "")
+	pool, httpRequest, tracker := makeMockPool(hostCount)
+	err := pool.sendRequest(ctx, httpRequest, nil)
+	assert.NoError(t, err)
+
+	// each GET is retried until an attempt is not dropped, and is then sent
+	// once. With 16 hosts, some attempts are all but sure to be dropped.
+	attempts := 0
+	for host := range httpRequest.ResultCollection {
+		result := httpRequest.ResultCollection[host]
+		assert.True(t, result.isPassing(), host)
+		attempts += result.attempts
+	}
+	assert.Len(t, tracker.sent, hostCount)
+	assert.Greater(t, attempts, hostCount)
+
+	// a POST is never sent again
+	options.Faults[0].Probability = 0
+	ctx = VClusterCommands{}.setupContext(context.Background(), &options, "")
+	pool, httpRequest, tracker = makeMockPool(1)
+	request := httpRequest.RequestCollection["host00"]
+	request.Method = PostMethod
+	httpRequest.RequestCollection["host00"] = request
+	err = pool.sendRequest(ctx, httpRequest, nil)
+	assert.NoError(t, err)
+	result := httpRequest.ResultCollection["host00"]
+	assert.True(t, result.isException())
+	assert.Equal(t, 1, result.attempts)
+	assert.Empty(t, tracker.sent)
+}
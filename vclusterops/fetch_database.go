@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -72,7 +73,11 @@ func (opt *VFetchCoordinationDatabaseOptions) validateAnalyzeOptions(logger vlog
 	return opt.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VFetchCoordinationDatabase(options *VFetchCoordinationDatabaseOptions) (VCoordinationDatabase, error) {
+func (vcc VClusterCommands) VFetchCoordinationDatabase(ctx context.Context,
+	options *VFetchCoordinationDatabaseOptions) (_ VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, options, commandConfigRecover)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -81,7 +86,7 @@ func (vcc VClusterCommands) VFetchCoordinationDatabase(options *VFetchCoordinati
 
 	var vdb VCoordinationDatabase
 
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return vdb, err
 	}
@@ -104,7 +109,7 @@ func (vcc VClusterCommands) VFetchCoordinationDatabase(options *VFetchCoordinati
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 
 	// nmaVDB is an object obtained from the read catalog editor result
 	// we use nmaVDB data to complete vdb
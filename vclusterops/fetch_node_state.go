@@ -1,6 +1,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -54,23 +55,26 @@ func (options *VFetchNodeStateOptions) validateAnalyzeOptions(vcc VClusterComman
 
 // VFetchNodeState returns the node state (e.g., up or down) for each node in the cluster and any
 // error encountered.
-func (vcc VClusterCommands) VFetchNodeState(options *VFetchNodeStateOptions) ([]NodeInfo, error) {
+func (vcc VClusterCommands) VFetchNodeState(ctx context.Context, options *VFetchNodeStateOptions) (_ []NodeInfo, err error) {
+	ctx = vcc.setupContext(ctx, options, commandListAllNodes)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
 	 *   - Give the instructions to the VClusterOpEngine to run
 	 */
 
-	err := options.validateAnalyzeOptions(vcc)
+	err = options.validateAnalyzeOptions(vcc)
 	if err != nil {
 		return nil, err
 	}
 
 	// this vdb is used to fetch node version
 	var vdb VCoordinationDatabase
-	err = vcc.getVDBFromRunningDB(&vdb, &options.DatabaseOptions)
+	err = vcc.getVDBFromRunningDB(ctx, &vdb, &options.DatabaseOptions)
 	if err != nil {
-		return vcc.fetchNodeStateFromDownDB(options)
+		return vcc.fetchNodeStateFromDownDB(ctx, options)
 	}
 
 	// produce list_allnodes instructions
@@ -84,7 +88,7 @@ func (vcc VClusterCommands) VFetchNodeState(options *VFetchNodeStateOptions) ([]
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	nodeStates := clusterOpEngine.execContext.nodesInfo
 	if runError == nil {
 		// fill node version
@@ -119,17 +123,19 @@ func (vcc VClusterCommands) VFetchNodeState(options *VFetchNodeStateOptions) ([]
 	}
 
 	if upNodeCount == 0 {
-		return vcc.fetchNodeStateFromDownDB(options)
+		return vcc.fetchNodeStateFromDownDB(ctx, options)
 	}
 
 	return nodeStates, runError
 }
 
-func (vcc VClusterCommands) fetchNodeStateFromDownDB(options *VFetchNodeStateOptions) ([]NodeInfo, error) {
+func (vcc VClusterCommands) fetchNodeStateFromDownDB(ctx context.Context, options *VFetchNodeStateOptions) ([]NodeInfo, error) {
 	const msg = "Cannot get node information from running database. " +
 		"Try to get node information by reading catalog editor.\n" +
 		"The states of the nodes are shown as DOWN because we failed to fetch the node states."
-	fmt.Println(msg)
+	if vcc.Log.ForCli {
+		fmt.Println(msg)
+	}
 	vcc.Log.PrintInfo(msg)
 
 	var nodeStates []NodeInfo
@@ -137,7 +143,7 @@ func (vcc VClusterCommands) fetchNodeStateFromDownDB(options *VFetchNodeStateOpt
 	var fetchDatabaseOptions VFetchCoordinationDatabaseOptions
 	fetchDatabaseOptions.DatabaseOptions = options.DatabaseOptions
 	fetchDatabaseOptions.readOnly = true
-	vdb, err := vcc.VFetchCoordinationDatabase(&fetchDatabaseOptions)
+	vdb, err := vcc.VFetchCoordinationDatabase(ctx, &fetchDatabaseOptions)
 	if err != nil {
 		return nodeStates, err
 	}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -113,7 +114,11 @@ func (options *VFetchNodesDetailsOptions) validateAnalyzeOptions(log vlog.Printe
 }
 
 // VFetchNodesDetails can return nodes' details including node state and storage locations for the provided hosts
-func (vcc VClusterCommands) VFetchNodesDetails(options *VFetchNodesDetailsOptions) (nodesDetails NodesDetails, err error) {
+func (vcc VClusterCommands) VFetchNodesDetails(ctx context.Context,
+	options *VFetchNodesDetailsOptions) (nodesDetails NodesDetails, err error) {
+	ctx = vcc.setupContext(ctx, options, commandFetchNodesDetails)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Validate Options
 	 *   - Produce Instructions
@@ -136,7 +141,7 @@ func (vcc VClusterCommands) VFetchNodesDetails(options *VFetchNodesDetailsOption
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		return nodesDetails, fmt.Errorf("failed to fetch node details on hosts %v: %w", options.Hosts, err)
 	}
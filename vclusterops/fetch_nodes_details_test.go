@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,13 +27,13 @@ func TestRequiredOptions(t *testing.T) {
 	vcc := VClusterCommands{}
 
 	// dbName is required
-	nodesDetails, err := vcc.VFetchNodesDetails(&options)
+	nodesDetails, err := vcc.VFetchNodesDetails(context.Background(), &options)
 	assert.Empty(t, nodesDetails)
 	assert.ErrorContains(t, err, `must specify a database name`)
 
 	// hosts are required
 	options.DBName = "testDB"
-	nodesDetails, err = vcc.VFetchNodesDetails(&options)
+	nodesDetails, err = vcc.VFetchNodesDetails(context.Background(), &options)
 	assert.Empty(t, nodesDetails)
 	assert.ErrorContains(t, err, `must specify a host or host list`)
 }
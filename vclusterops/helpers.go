@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path"
@@ -110,6 +111,7 @@ type nodeStateInfo struct {
 	Sandbox          string   `json:"sandbox_name"`
 	Version          string   `json:"build_info"`
 	IsControlNode    bool     `json:"is_control_node"`
+	DownSince        string   `json:"down_since"`
 }
 
 func (node *nodeStateInfo) asNodeInfo() (n NodeInfo, err error) {
@@ -136,6 +138,7 @@ func (node *nodeStateInfo) asNodeInfoWithoutVer() (n NodeInfo) {
 	n.Subcluster = node.Subcluster
 	n.IsPrimary = node.IsPrimary
 	n.Sandbox = node.Sandbox
+	n.DownSince = node.DownSince
 	return
 }
 
@@ -155,17 +158,18 @@ func getInitiatorHost(primaryUpNodes, hostsToSkip []string) (string, error) {
 }
 
 // getVDBFromRunningDB will retrieve db configurations from a non-sandboxed host by calling https endpoints of a running db
-func (vcc VClusterCommands) getVDBFromRunningDB(vdb *VCoordinationDatabase, options *DatabaseOptions) error {
-	return vcc.getVDBFromRunningDBImpl(vdb, options, false, util.MainClusterSandbox)
+func (vcc VClusterCommands) getVDBFromRunningDB(ctx context.Context, vdb *VCoordinationDatabase, options *DatabaseOptions) error {
+	return vcc.getVDBFromRunningDBImpl(ctx, vdb, options, false, util.MainClusterSandbox)
 }
 
 // getVDBFromRunningDB will retrieve db configurations from any UP host by calling https endpoints of a running db
-func (vcc VClusterCommands) getVDBFromRunningDBIncludeSandbox(vdb *VCoordinationDatabase, options *DatabaseOptions, sandbox string) error {
-	return vcc.getVDBFromRunningDBImpl(vdb, options, true, sandbox)
+func (vcc VClusterCommands) getVDBFromRunningDBIncludeSandbox(ctx context.Context,
+	vdb *VCoordinationDatabase, options *DatabaseOptions, sandbox string) error {
+	return vcc.getVDBFromRunningDBImpl(ctx, vdb, options, true, sandbox)
 }
 
 // getVDBFromRunningDB will retrieve db configurations by calling https endpoints of a running db
-func (vcc VClusterCommands) getVDBFromRunningDBImpl(vdb *VCoordinationDatabase, options *DatabaseOptions,
+func (vcc VClusterCommands) getVDBFromRunningDBImpl(ctx context.Context, vdb *VCoordinationDatabase, options *DatabaseOptions,
 	allowUseSandboxRes bool, sandbox string) error {
 	err := options.setUsePassword(vcc.Log)
 	if err != nil {
@@ -198,7 +202,7 @@ func (vcc VClusterCommands) getVDBFromRunningDBImpl(vdb *VCoordinationDatabase,
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		return fmt.Errorf("fail to retrieve database configurations, %w", err)
 	}
@@ -207,7 +211,7 @@ func (vcc VClusterCommands) getVDBFromRunningDBImpl(vdb *VCoordinationDatabase,
 }
 
 // getClusterInfoFromRunningDB will retrieve db configurations by calling https endpoints of a running db
-func (vcc VClusterCommands) getClusterInfoFromRunningDB(vdb *VCoordinationDatabase, options *DatabaseOptions) error {
+func (vcc VClusterCommands) getClusterInfoFromRunningDB(ctx context.Context, vdb *VCoordinationDatabase, options *DatabaseOptions) error {
 	err := options.setUsePassword(vcc.Log)
 	if err != nil {
 		return fmt.Errorf("fail to set userPassword while retrieving cluster configurations, %w", err)
@@ -224,7 +228,7 @@ func (vcc VClusterCommands) getClusterInfoFromRunningDB(vdb *VCoordinationDataba
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		return fmt.Errorf("fail to retrieve cluster configurations, %w", err)
 	}
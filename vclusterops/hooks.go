@@ -0,0 +1,268 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/vertica/vcluster/vclusterops/vlog"
+	"golang.org/x/exp/slices"
+)
+
+// HookPhase tells whether a hook runs before or after what it hooks
+type HookPhase string
+
+const (
+	HookPhasePre  HookPhase = "pre"
+	HookPhasePost HookPhase = "post"
+)
+
+// the results of the commands and of the ops given to the post hooks
+const (
+	HookResultSucceeded = "succeeded"
+	HookResultFailed    = "failed"
+)
+
+// HookContext is what a hook is run with. The hooks run by ExecHookRun read
+// it as JSON on their standard input.
+type HookContext struct {
+	Phase HookPhase `json:"phase"`
+	// Command is the name of the V* command, as in the audit log, such as
+	// "stop_subcluster"
+	Command string `json:"command"`
+	// Op is the name of the op the hook runs around, "" for a hook of the
+	// command
+	Op         string   `json:"op,omitempty"`
+	Database   string   `json:"database,omitempty"`
+	Subcluster string   `json:"subcluster,omitempty"`
+	Hosts      []string `json:"hosts,omitempty"`
+	// Result and Error are only set for the post hooks
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Hook is run before or after the commands, or some of their ops, for the
+// automation of a site, such as taking the nodes of a subcluster out of a
+// load balancer before it is stopped. The hooks run in the order they are
+// given. A pre hook that returns an error vetoes the command or the op,
+// which then fails with a HookVetoError. The error of a post hook is only
+// logged, since what it hooks has already run.
+type Hook struct {
+	// Name identifies the hook in the logs and in the errors
+	Name  string
+	Phase HookPhase
+	// Commands are the names of the commands the hook runs for, all of
+	// them if empty
+	Commands []string
+	// Ops are the names of the ops the hook runs around, such as
+	// "HTTPSDrainSubclusterOp". Without ops, the hook runs around the command
+	// itself.
+	Ops []string
+	Run func(ctx context.Context, hookContext *HookContext) error
+}
+
+// HookVetoError is returned by a command when a pre hook vetoed it, or vetoed
+// one of its ops
+type HookVetoError struct {
+	Hook    string
+	Command string
+	// Op is "" when the command itself was vetoed
+	Op  string
+	Err error
+}
+
+func (e *HookVetoError) Error() string {
+	target := e.Command
+	if e.Op != "" {
+		target = fmt.Sprintf("%s of %s", e.Op, e.Command)
+	}
+	return fmt.Sprintf("hook %s vetoed %s: %v", e.Hook, target, e.Err)
+}
+
+func (e *HookVetoError) Unwrap() error {
+	return e.Err
+}
+
+// ExecHookRun returns the Run of a hook that runs the program in argv with
+// its arguments. The program reads the hook context as JSON on its standard
+// input. A non-zero exit status is an error, which vetoes the command or the
+// op if the hook is a pre hook.
+func ExecHookRun(argv []string) func(ctx context.Context, hookContext *HookContext) error {
+	return func(ctx context.Context, hookContext *HookContext) error {
+		if len(argv) == 0 {
+			return fmt.Errorf("the hook has no program to run")
+		}
+		input, err := json.Marshal(hookContext)
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Stdin = bytes.NewReader(input)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("%s failed: %w, output: %s", argv[0], err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+}
+
+// subclusterOptions is implemented by the options of the commands run on a
+// subcluster, which the hooks are given
+type subclusterOptions interface {
+	subclusterName() string
+}
+
+// hookRun holds the hooks of the outer command run under a context. The
+// hooks of the command run once, when it starts its first op engine, so that
+// they are not run for a command that fails to validate its options.
+type hookRun struct {
+	hooks   []Hook
+	options commandOptions
+	command *commandRun
+	started bool
+	vetoed  bool
+}
+
+type hookRunContextKey struct{}
+
+// attachHooks attaches the hooks of the options to ctx. Only the hooks of the
+// outer command are run, for the commands that it runs too.
+func attachHooks(ctx context.Context, options commandOptions) context.Context {
+	hooks := options.databaseOptions().Hooks
+	command := commandRunFromContext(ctx)
+	if len(hooks) == 0 || command == nil || hookRunFromContext(ctx) != nil {
+		return ctx
+	}
+	run := &hookRun{hooks: hooks, options: options, command: command}
+	return context.WithValue(ctx, hookRunContextKey{}, run)
+}
+
+func hookRunFromContext(ctx context.Context) *hookRun {
+	run, _ := ctx.Value(hookRunContextKey{}).(*hookRun)
+	if run == nil || isPlanMode(ctx) || isReplaying(ctx) {
+		// the hooks act on the site, so they are not run for a command
+		// that does not act on the cluster
+		return nil
+	}
+	return run
+}
+
+// makeHookContext returns the context of the hooks run in phase, around op,
+// or around the command if op is ""
+func (run *hookRun) makeHookContext(phase HookPhase, op string, err error) *HookContext {
+	opt := run.options.databaseOptions()
+	hookContext := &HookContext{
+		Phase:    phase,
+		Command:  run.command.command,
+		Op:       op,
+		Database: opt.DBName,
+		Hosts:    opt.Hosts,
+	}
+	if len(hookContext.Hosts) == 0 {
+		hookContext.Hosts = opt.RawHosts
+	}
+	if options, ok := run.options.(subclusterOptions); ok {
+		hookContext.Subcluster = options.subclusterName()
+	}
+	if phase == HookPhasePost {
+		hookContext.Result = HookResultSucceeded
+		if err != nil {
+			hookContext.Result = HookResultFailed
+			hookContext.Error = err.Error()
+		}
+	}
+	return hookContext
+}
+
+// runHooks runs the hooks of the phase around op, or around the command if
+// op is "". It returns the error of the first pre hook that fails, and logs
+// the errors of the post hooks. The other ops of the engine can go on
+// meanwhile.
+func (run *hookRun) runHooks(ctx context.Context, logger vlog.Printer, phase HookPhase, op string, err error) error {
+	var hooks []*Hook
+	for i := range run.hooks {
+		if run.matches(&run.hooks[i], phase, op) {
+			hooks = append(hooks, &run.hooks[i])
+		}
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	turn := opTurnFromContext(ctx)
+	turn.release()
+	defer turn.acquire()
+	for _, hook := range hooks {
+		hookErr := hook.Run(ctx, run.makeHookContext(phase, op, err))
+		if hookErr == nil {
+			continue
+		}
+		if phase == HookPhasePre {
+			return &HookVetoError{Hook: hook.Name, Command: run.command.command, Op: op, Err: hookErr}
+		}
+		logger.PrintWarning("The %s hook %s failed, details: %v", phase, hook.Name, hookErr)
+	}
+	return nil
+}
+
+func (run *hookRun) matches(hook *Hook, phase HookPhase, op string) bool {
+	if hook.Phase != phase {
+		return false
+	}
+	if len(hook.Commands) > 0 && !slices.Contains(hook.Commands, run.command.command) {
+		return false
+	}
+	if op == "" {
+		return len(hook.Ops) == 0
+	}
+	return slices.Contains(hook.Ops, op)
+}
+
+// runPreCommandHooks runs the pre hooks of the command run under ctx, if
+// they have not run yet
+func runPreCommandHooks(ctx context.Context, logger vlog.Printer) error {
+	run := hookRunFromContext(ctx)
+	if run == nil || run.started {
+		return nil
+	}
+	run.started = true
+	err := run.runHooks(ctx, logger, HookPhasePre, "", nil)
+	run.vetoed = err != nil
+	return err
+}
+
+// runPostCommandHooks runs the post hooks of the command run under ctx with
+// its error, if its pre hooks have run and have not vetoed it
+func (vcc VClusterCommands) runPostCommandHooks(ctx context.Context, err error) {
+	run := hookRunFromContext(ctx)
+	if run == nil || !run.started || run.vetoed || run.command != commandRunFromContext(ctx) {
+		return
+	}
+	_ = run.runHooks(ctx, vcc.Log, HookPhasePost, "", err)
+}
+
+// runOpHooks runs the hooks of the phase around op, as runHooks does
+func runOpHooks(ctx context.Context, logger vlog.Printer, phase HookPhase, op string, err error) error {
+	run := hookRunFromContext(ctx)
+	if run == nil {
+		return nil
+	}
+	return run.runHooks(ctx, logger, phase, op, err)
+}
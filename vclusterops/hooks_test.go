@@ -0,0 +1,105 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestHooks(t *testing.T) {
+	var contexts []HookContext
+	record := func(_ context.Context, hookContext *HookContext) error {
+		contexts = append(contexts, *hookContext)
+		return nil
+	}
+	options := VStopSubclusterOptionsFactory()
+	options.DBName = "test_db"
+	options.SCName = "sc1"
+	options.Hosts = []string{"192.168.1.101"}
+	options.Hooks = []Hook{
+		{Name: "drain", Phase: HookPhasePre, Commands: []string{commandStopCluster}, Run: record},
+		{Name: "other", Phase: HookPhasePre, Commands: []string{commandStartCluster}, Run: record},
+		{Name: "around-op", Phase: HookPhasePost, Ops: []string{"op"}, Run: record},
+		{Name: "done", Phase: HookPhasePost, Run: record},
+	}
+	vcc := VClusterCommands{VClusterCommandsLogger: VClusterCommandsLogger{Log: vlog.Printer{}}, LockDir: t.TempDir()}
+	ctx := vcc.setupContext(context.Background(), &options, commandStopCluster)
+	// the hooks of a nested command are the ones of the outer command
+	nestedOptions := DatabaseOptionsFactory()
+	nestedCtx := vcc.setupContext(ctx, &nestedOptions, commandListAllNodes)
+
+	op := makeMockOp(false)
+	op.name = "op"
+	opEngn := makeClusterOpEngine([]clusterOp{&op}, &httpsCerts{})
+	assert.NoError(t, opEngn.run(nestedCtx, vlog.Printer{}))
+	vcc.finishCommand(nestedCtx, &nestedOptions, nil)
+	// the pre hooks only run for the first op engine
+	assert.NoError(t, opEngn.run(ctx, vlog.Printer{}))
+	vcc.finishCommand(ctx, &options, errors.New("some error"))
+
+	assert.Len(t, contexts, 4)
+	assert.Equal(t, HookContext{Phase: HookPhasePre, Command: commandStopCluster, Database: "test_db",
+		Subcluster: "sc1", Hosts: options.Hosts}, contexts[0])
+	assert.Equal(t, "op", contexts[1].Op)
+	assert.Equal(t, HookResultSucceeded, contexts[1].Result)
+	assert.Equal(t, "op", contexts[2].Op)
+	assert.Equal(t, HookPhasePost, contexts[3].Phase)
+	assert.Equal(t, "", contexts[3].Op)
+	assert.Equal(t, HookResultFailed, contexts[3].Result)
+	assert.Equal(t, "some error", contexts[3].Error)
+
+	// a pre hook can veto an op, and then the op does not run
+	options.Hooks = []Hook{{Name: "veto", Phase: HookPhasePre, Ops: []string{"op"},
+		Run: func(_ context.Context, _ *HookContext) error { return errors.New("not now") }}}
+	ctx = vcc.setupContext(context.Background(), &options, commandStopCluster)
+	op = makeMockOp(false)
+	op.name = "op"
+	opEngn = makeClusterOpEngine([]clusterOp{&op}, &httpsCerts{})
+	err := opEngn.run(ctx, vlog.Printer{})
+	var vetoErr *HookVetoError
+	assert.ErrorAs(t, err, &vetoErr)
+	assert.Equal(t, "veto", vetoErr.Hook)
+	assert.Equal(t, "op", vetoErr.Op)
+	assert.ErrorContains(t, err, "hook veto vetoed op of stop_subcluster: not now")
+	assert.False(t, op.calledPrepare)
+}
+
+func TestExecHookRun(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.json")
+	hookContext := &HookContext{Phase: HookPhasePre, Command: commandStopCluster, Subcluster: "sc1"}
+	// the program reads the hook context on its standard input
+	run := ExecHookRun([]string{"sh", "-c", `cat > "$0"`, input})
+	assert.NoError(t, run(context.Background(), hookContext))
+	data, err := os.ReadFile(input)
+	assert.NoError(t, err)
+	var got HookContext
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, *hookContext, got)
+
+	// a non-zero exit status is an error, with the output of the program
+	run = ExecHookRun([]string{"sh", "-c", "echo draining failed; exit 3"})
+	err = run(context.Background(), hookContext)
+	assert.ErrorContains(t, err, "exit status 3")
+	assert.ErrorContains(t, err, "draining failed")
+}
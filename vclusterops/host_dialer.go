@@ -0,0 +1,45 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"net"
+)
+
+// Dialer opens the connections to the NMA and to the HTTPS service of the
+// hosts. *net.Dialer is one. A Dialer is identified by its address, so it
+// should be a pointer, and the same one should be reused across the
+// commands so that they share their connections.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type dialerContextKey struct{}
+
+// attachDialer attaches the dialer of the options to ctx, so that every
+// connection made under it goes through it
+func (opt *DatabaseOptions) attachDialer(ctx context.Context) context.Context {
+	if opt.Dialer == nil || dialerFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, dialerContextKey{}, opt.Dialer)
+}
+
+func dialerFromContext(ctx context.Context) Dialer {
+	dialer, _ := ctx.Value(dialerContextKey{}).(Dialer)
+	return dialer
+}
@@ -0,0 +1,105 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"fmt"
+)
+
+const maxPort = 65535
+
+// HostPorts are the ports of the NMA and of the HTTPS service on a host. A
+// zero port is not overridden.
+type HostPorts struct {
+	NMAPort   int
+	HTTPSPort int
+}
+
+func (ports *HostPorts) validate(name string) error {
+	if ports.NMAPort < 0 || ports.NMAPort > maxPort {
+		return fmt.Errorf("invalid NMA port %d for %s", ports.NMAPort, name)
+	}
+	if ports.HTTPSPort < 0 || ports.HTTPSPort > maxPort {
+		return fmt.Errorf("invalid HTTPS port %d for %s", ports.HTTPSPort, name)
+	}
+	return nil
+}
+
+// portConfig resolves the port of a request to a host: the port of the
+// host, then the one of the database, then the default one
+type portConfig struct {
+	dbPorts   HostPorts
+	hostPorts map[string]HostPorts
+}
+
+type portConfigContextKey struct{}
+
+// validatePorts checks that the port overrides are valid port numbers
+func (opt *DatabaseOptions) validatePorts() error {
+	dbPorts := HostPorts{NMAPort: opt.NMAPort, HTTPSPort: opt.HTTPSPort}
+	if err := dbPorts.validate("the database"); err != nil {
+		return err
+	}
+	for host, ports := range opt.HostPorts {
+		if err := ports.validate("host " + host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachPorts attaches the port overrides of the options to ctx, so that
+// every request sent under it goes to the right ports
+func (opt *DatabaseOptions) attachPorts(ctx context.Context) context.Context {
+	if (opt.NMAPort == 0 && opt.HTTPSPort == 0 && len(opt.HostPorts) == 0) || portsFromContext(ctx) != nil {
+		return ctx
+	}
+	ports := &portConfig{
+		dbPorts:   HostPorts{NMAPort: opt.NMAPort, HTTPSPort: opt.HTTPSPort},
+		hostPorts: opt.HostPorts,
+	}
+	return context.WithValue(ctx, portConfigContextKey{}, ports)
+}
+
+func portsFromContext(ctx context.Context) *portConfig {
+	ports, _ := ctx.Value(portConfigContextKey{}).(*portConfig)
+	return ports
+}
+
+// port returns the port request is sent to on host, or 0 for the default one
+func (ports *portConfig) port(host string, request *hostHTTPRequest) int {
+	if ports == nil {
+		return 0
+	}
+	choose := func(hostPorts HostPorts) int {
+		if request.IsNMACommand {
+			return hostPorts.NMAPort
+		}
+		return hostPorts.HTTPSPort
+	}
+	if port := choose(ports.hostPorts[host]); port != 0 {
+		return port
+	}
+	return choose(ports.dbPorts)
+}
+
+// setPort sets the port of a request that does not have one yet
+func (ports *portConfig) setPort(host string, request *hostHTTPRequest) {
+	if request.Port == 0 {
+		request.Port = ports.port(host, request)
+	}
+}
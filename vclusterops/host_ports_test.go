@@ -0,0 +1,65 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestPorts(t *testing.T) {
+	nmaRequest := hostHTTPRequest{}
+	nmaRequest.buildNMAEndpoint("health")
+	httpsRequest := hostHTTPRequest{}
+	httpsRequest.buildHTTPSEndpoint("nodes")
+
+	// without overrides the default ports are used
+	ctx := context.Background()
+	options := DatabaseOptionsFactory()
+	assert.Equal(t, ctx, options.attachPorts(ctx))
+	assert.Equal(t, "https://192.168.1.101:5554/v1/health", buildRequestURL("192.168.1.101", &nmaRequest))
+	assert.Equal(t, "https://192.168.1.101:8443/v1/nodes", buildRequestURL("192.168.1.101", &httpsRequest))
+
+	// the ports of a host take precedence over the ones of the database
+	options.NMAPort = 15554
+	options.HTTPSPort = 18443
+	options.HostPorts = map[string]HostPorts{"192.168.1.102": {HTTPSPort: 28443}}
+	ports := portsFromContext(options.attachPorts(ctx))
+	for host, expected := range map[string][]string{
+		"192.168.1.101": {"https://192.168.1.101:15554/v1/health", "https://192.168.1.101:18443/v1/nodes"},
+		"192.168.1.102": {"https://192.168.1.102:15554/v1/health", "https://192.168.1.102:28443/v1/nodes"},
+	} {
+		request := nmaRequest
+		ports.setPort(host, &request)
+		assert.Equal(t, expected[0], buildRequestURL(host, &request))
+		request = httpsRequest
+		ports.setPort(host, &request)
+		assert.Equal(t, expected[1], buildRequestURL(host, &request))
+	}
+
+	// the port set by an op is kept
+	request := httpsRequest
+	request.Port = 9443
+	ports.setPort("192.168.1.102", &request)
+	assert.Equal(t, 9443, request.Port)
+
+	// ports must be valid
+	assert.NoError(t, options.validatePorts())
+	options.HostPorts["192.168.1.103"] = HostPorts{NMAPort: 70000}
+	assert.ErrorContains(t, options.validatePorts(), "invalid NMA port 70000 for host 192.168.1.103")
+}
@@ -17,8 +17,10 @@ package vclusterops
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -74,8 +76,6 @@ type responseBodyDownloader struct {
 
 const (
 	certPathBase          = "/opt/vertica/config/https_certs"
-	nmaPort               = 5554
-	httpsPort             = 8443
 	defaultRequestTimeout = 300 // seconds
 )
 
@@ -85,23 +85,9 @@ type certificatePaths struct {
 	caFile   string
 }
 
-func (adapter *httpAdapter) sendRequest(request *hostHTTPRequest, resultChannel chan<- hostHTTPResult) {
-	// build query params
-	queryParams := buildQueryParamString(request.QueryParams)
-
+func (adapter *httpAdapter) sendRequest(ctx context.Context, request *hostHTTPRequest, resultChannel chan<- hostHTTPResult) {
 	// set up the request URL
-	var port int
-	if request.IsNMACommand {
-		port = nmaPort
-	} else {
-		port = httpsPort
-	}
-
-	requestURL := fmt.Sprintf("https://%s:%d/%s%s",
-		adapter.host,
-		port,
-		request.Endpoint,
-		queryParams)
+	requestURL := buildRequestURL(adapter.host, request)
 	adapter.logger.Info("Request URL", "URL", requestURL)
 
 	// whether use password (for HTTPS endpoints only)
@@ -112,12 +98,37 @@ func (adapter *httpAdapter) sendRequest(request *hostHTTPRequest, resultChannel
 	}
 
 	// HTTP client
-	client, err := adapter.setupHTTPClient(request, usePassword, resultChannel)
+	client, err := adapter.setupHTTPClient(request, usePassword, tlsVerificationFromContext(ctx),
+		dialerFromContext(ctx))
 	if err != nil {
 		resultChannel <- adapter.makeExceptionResult(err)
 		return
 	}
 
+	// send the request until it succeeds, or the retry policy gives up
+	for attempt := 1; ; attempt++ {
+		result := adapter.sendRequestOnce(ctx, client, request, requestURL, usePassword)
+		result.attempts = attempt
+		result.timeout = client.Timeout
+		if ctx.Err() != nil || !request.RetryPolicy.shouldRetry(request.Method, &result, attempt) {
+			resultChannel <- result
+			return
+		}
+
+		delay := request.RetryPolicy.backoff(attempt)
+		adapter.logger.Info("Retrying request", "URL", requestURL, "attempt", attempt,
+			"maxAttempts", request.RetryPolicy.MaxAttempts, "delay", delay.String(),
+			"statusCode", result.statusCode, "error", result.err)
+		if !waitBackoff(ctx, delay) {
+			resultChannel <- result
+			return
+		}
+	}
+}
+
+// sendRequestOnce makes one attempt at sending request and returns its result
+func (adapter *httpAdapter) sendRequestOnce(ctx context.Context, client *http.Client, request *hostHTTPRequest,
+	requestURL string, usePassword bool) hostHTTPResult {
 	// set up request body
 	var requestBody io.Reader
 	if request.RequestData == "" {
@@ -127,34 +138,46 @@ func (adapter *httpAdapter) sendRequest(request *hostHTTPRequest, resultChannel
 	}
 
 	// build HTTP request
-	req, err := http.NewRequest(request.Method, requestURL, requestBody)
+	req, err := http.NewRequestWithContext(ctx, request.Method, requestURL, requestBody)
 	if err != nil {
 		err = fmt.Errorf("fail to build request %v on host %s, details %w",
 			request.Endpoint, adapter.host, err)
-		resultChannel <- adapter.makeExceptionResult(err)
-		return
+		return adapter.makeExceptionResult(err)
 	}
-	// close the connection after sending the request (for clients)
-	req.Close = true
-
 	// set username and password
 	// which is only used for HTTPS endpoints
 	if usePassword {
 		req.SetBasicAuth(request.Username, *request.Password)
 	}
+	// the host can join the trace of the command
+	injectTraceContext(ctx, req.Header)
 
 	// send HTTP request
+	startTime := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		metricsFromContext(ctx).observeRequest(adapter.host, request.Method, 0, time.Since(startTime))
+		var verifyErr *tls.CertificateVerificationError
+		if errors.As(err, &verifyErr) {
+			err = &TLSVerificationError{Host: adapter.host, Err: verifyErr}
+		}
 		err = fmt.Errorf("fail to send request %v on host %s, details %w",
 			request.Endpoint, adapter.host, err)
-		resultChannel <- adapter.makeExceptionResult(err)
-		return
+		return adapter.makeExceptionResult(err)
 	}
 	defer resp.Body.Close()
 
 	// generate and return the result
-	resultChannel <- adapter.generateResult(resp)
+	result := adapter.generateResult(resp)
+	result.receivedTime = time.Now()
+	metricsFromContext(ctx).observeRequest(adapter.host, request.Method, resp.StatusCode, result.receivedTime.Sub(startTime))
+	if hostTime, dateErr := http.ParseTime(resp.Header.Get("Date")); dateErr == nil {
+		result.hostTime = hostTime
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		result.certNotAfter = resp.TLS.PeerCertificates[0].NotAfter
+	}
+	return result
 }
 
 func (adapter *httpAdapter) generateResult(resp *http.Response) hostHTTPResult {
@@ -313,42 +336,87 @@ func (adapter *httpAdapter) buildCertsFromMemory(key, cert, caCert string) (tls.
 		return certificate, nil, fmt.Errorf("fail to load HTTPS certificates, details %w", err)
 	}
 
+	// without a CA certificate, the system roots are used
+	if caCert == "" {
+		return certificate, nil, nil
+	}
 	caCertPool := x509.NewCertPool()
-	if caCert != "" {
-		ok := caCertPool.AppendCertsFromPEM([]byte(caCert))
-		if !ok {
-			return certificate, nil, fmt.Errorf("fail to load HTTPS CA certificates")
-		}
+	ok := caCertPool.AppendCertsFromPEM([]byte(caCert))
+	if !ok {
+		return certificate, nil, fmt.Errorf("fail to load HTTPS CA certificates")
 	}
 
 	return certificate, caCertPool, nil
 }
 
+// buildRootCAs returns the CA certificates that verify the certificate of the
+// host when a password is used: the CA certificate of the options, or else
+// the one of the certificate files, if any. If it returns nil, the system
+// roots are used.
+func (adapter *httpAdapter) buildRootCAs(request *hostHTTPRequest) (*x509.CertPool, error) {
+	var caCert []byte
+	if request.UseCertsInOptions {
+		caCert = []byte(request.Certs.caCert)
+	} else if certPaths, err := getCertFilePathsFn(); err == nil {
+		// the CA file is optional when a password is used
+		caCert, _ = os.ReadFile(certPaths.caFile)
+	}
+	if len(caCert) == 0 {
+		return nil, nil
+	}
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("fail to load HTTPS CA certificates")
+	}
+	return caCertPool, nil
+}
+
+// setupHTTPClient returns a client for request. The client itself is cheap,
+// it only holds the timeout of the request; its transport, which holds the
+// connections to the host, comes from the shared transport cache.
 func (adapter *httpAdapter) setupHTTPClient(
 	request *hostHTTPRequest,
 	usePassword bool,
-	_ chan<- hostHTTPResult) (*http.Client, error) {
-	var client *http.Client
+	verification *TLSVerification,
+	dialer Dialer) (*http.Client, error) {
+	key := transportKey(adapter.host, request, usePassword, verification, dialer)
+	transport, err := httpTransports.get(key, dialer, func() (*tls.Config, error) {
+		return adapter.buildTLSConfig(request, usePassword, verification)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Timeout:   clientTimeout(request),
+		Transport: transport,
+	}, nil
+}
 
-	// set up request timeout
-	requestTimeout := time.Duration(defaultRequestTimeout)
+// clientTimeout returns the timeout of the client that sends request, 0 for
+// no timeout
+func clientTimeout(request *hostHTTPRequest) time.Duration {
 	if request.Timeout > 0 {
-		requestTimeout = time.Duration(request.Timeout)
+		return time.Duration(request.Timeout) * time.Second
 	} else if request.Timeout == -1 {
-		requestTimeout = time.Duration(0) // a Timeout of zero means no timeout.
+		return 0 // a Timeout of zero means no timeout.
 	}
+	return defaultRequestTimeout * time.Second
+}
 
+// buildTLSConfig builds the TLS config of the connections to the host. The
+// certificate of the host is verified as told by verification.
+func (adapter *httpAdapter) buildTLSConfig(request *hostHTTPRequest, usePassword bool,
+	verification *TLSVerification) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
 	if usePassword {
-		// TODO: we have to use `InsecureSkipVerify: true` here,
-		//       as password is used
-		//nolint:gosec
-		client = &http.Client{
-			Timeout: time.Second * requestTimeout,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
-			},
+		// the password authenticates the client, so no certificate is
+		// presented, but the one of the host is still verified
+		if verification.verifiesChain() {
+			rootCAs, err := adapter.buildRootCAs(request)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = rootCAs
 		}
 	} else {
 		var cert tls.Certificate
@@ -360,25 +428,36 @@ func (adapter *httpAdapter) setupHTTPClient(
 			cert, caCertPool, err = adapter.buildCertsFromFile()
 		}
 		if err != nil {
-			return client, err
+			return nil, err
 		}
-		// for both http and nma, we have to use `InsecureSkipVerify: true` here
-		// because the certs are self signed at this time
-		// TODO: update the InsecureSkipVerify once we start to use non-self-signed certs
-
-		//nolint:gosec
-		client = &http.Client{
-			Timeout: time.Second * requestTimeout,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					Certificates:       []tls.Certificate{cert},
-					RootCAs:            caCertPool,
-					InsecureSkipVerify: true,
-				},
-			},
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	verification.apply(tlsConfig, adapter.host)
+	return tlsConfig, nil
+}
+
+// buildRequestURL builds the URL of a request sent to the NMA or the HTTPS
+// service on the given host
+func buildRequestURL(host string, request *hostHTTPRequest) string {
+	// build query params
+	queryParams := buildQueryParamString(request.QueryParams)
+
+	port := request.Port
+	if port == 0 {
+		if request.IsNMACommand {
+			port = util.DefaultNMAPort
+		} else {
+			port = util.DefaultHTTPPort
 		}
 	}
-	return client, nil
+
+	return fmt.Sprintf("https://%s:%d/%s%s",
+		host,
+		port,
+		request.Endpoint,
+		queryParams)
 }
 
 func buildQueryParamString(queryParams map[string]string) string {
@@ -25,10 +25,16 @@ type hostHTTPRequest struct {
 	// string pointer is used here as we need to check whether the password has been set
 	Password *string // optional, for HTTPS endpoints only
 	Timeout  int     // optional, set it if an Op needs longer time to complete
+	// optional, the port of the NMA or of the HTTPS service on the host. If
+	// 0, the port set in the command options or the default port is used.
+	Port int
 
 	// optional, for calling NMA/Vertica HTTPS endpoints. If Username/Password is set, that takes precedence over this for HTTPS calls.
 	UseCertsInOptions bool
 	Certs             httpsCerts
+	// optional, how the request is retried on transient errors. If nil, the
+	// policy of the clusterHTTPRequest is used.
+	RetryPolicy *RetryPolicy
 }
 
 type httpsCerts struct {
@@ -53,4 +59,16 @@ type clusterHTTPRequest struct {
 	ResultCollection  map[string]hostHTTPResult
 	SemVar            semVer
 	Name              string
+	// optional, the retry policy of the requests that do not set one. If
+	// nil, the policy of the command options or DefaultRetryPolicy is used.
+	RetryPolicy *RetryPolicy
+	// optional, the maximum number of requests in flight at the same time.
+	// If 0, the limit of the command options is used, if any.
+	MaxParallel int
+	// optional, when set the requests are sent in batches of this size, and
+	// a batch is only sent once all the requests of the previous one are done
+	BatchSize int
+	// set by a polling op whose timeout is overridden: the timeout then
+	// applies to the polling, not to each of its requests
+	isPolling bool
 }
@@ -16,6 +16,8 @@
 package vclusterops
 
 import (
+	"context"
+
 	"github.com/theckman/yacspin"
 	"github.com/vertica/vcluster/vclusterops/vlog"
 )
@@ -35,9 +37,8 @@ func makeHTTPRequestDispatcher(logger vlog.Printer) requestDispatcher {
 
 // set up the pool connection for each host
 func (dispatcher *requestDispatcher) setup(hosts []string) {
-	dispatcher.pool = getPoolInstance(dispatcher.logger)
+	dispatcher.pool = makeAdapterPool(dispatcher.logger)
 
-	dispatcher.pool.connections = make(map[string]adapter)
 	for _, host := range hosts {
 		adapter := makeHTTPAdapter(dispatcher.logger)
 		adapter.host = host
@@ -48,7 +49,7 @@ func (dispatcher *requestDispatcher) setup(hosts []string) {
 // set up the pool connection for each host to download a file
 func (dispatcher *requestDispatcher) setupForDownload(hosts []string,
 	hostToFilePathsMap map[string]string) {
-	dispatcher.pool = getPoolInstance(dispatcher.logger)
+	dispatcher.pool = makeAdapterPool(dispatcher.logger)
 
 	for _, host := range hosts {
 		adapter := makeHTTPDownloadAdapter(dispatcher.logger, hostToFilePathsMap[host])
@@ -57,7 +58,15 @@ func (dispatcher *requestDispatcher) setupForDownload(hosts []string,
 	}
 }
 
-func (dispatcher *requestDispatcher) sendRequest(httpRequest *clusterHTTPRequest, spinner *yacspin.Spinner) error {
+func (dispatcher *requestDispatcher) sendRequest(ctx context.Context, httpRequest *clusterHTTPRequest,
+	spinner *yacspin.Spinner) error {
 	dispatcher.logger.Info("HTTP request dispatcher's sendRequest is called")
-	return dispatcher.pool.sendRequest(httpRequest, spinner)
+	// the other ops of the engine can go on while the requests are in
+	// flight. The dispatcher is part of the exec context, which they can
+	// change meanwhile, so the pool is copied first.
+	pool := dispatcher.pool
+	turn := opTurnFromContext(ctx)
+	turn.release()
+	defer turn.acquire()
+	return pool.sendRequest(ctx, httpRequest, spinner)
 }
@@ -0,0 +1,158 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/vertica/vcluster/vclusterops/util"
+)
+
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+	defaultRetryMultiplier     = 2
+	defaultRetryJitter         = 0.2
+)
+
+// RetryPolicy tells how a request that failed with a transient error is sent
+// again. A request is retried when its connection was refused, or was reset
+// or closed before the response, or when its response has one of the
+// retryable status codes. A request that timed out is not retried, as the
+// host may still be processing it. Only idempotent methods are ever retried:
+// a POST is never sent twice, even if it is in RetryableMethods.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a request is sent, including the
+	// first one. 1 or less disables the retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry. It is multiplied by
+	// Multiplier before each of the next retries, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is the fraction, between 0 and 1, of each wait that is
+	// randomized, so the hosts are not retried all at once
+	Jitter float64
+	// RetryableStatusCodes are the response status codes that are retried
+	RetryableStatusCodes []int
+	// RetryableMethods are the HTTP methods that are retried
+	RetryableMethods []string
+}
+
+// DefaultRetryPolicy returns the policy used by the requests that do not set
+// one: GET requests are sent up to util.DefaultRetryCount times.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    util.DefaultRetryCount,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+		Multiplier:     defaultRetryMultiplier,
+		Jitter:         defaultRetryJitter,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryableMethods: []string{GetMethod},
+	}
+}
+
+type retryPolicyContextKey struct{}
+
+// attachRetryPolicy attaches the retry policy of the options to ctx, so that
+// every request sent under it uses the policy unless the op sets its own
+func (opt *DatabaseOptions) attachRetryPolicy(ctx context.Context) context.Context {
+	if opt.RetryPolicy == nil || retryPolicyFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, retryPolicyContextKey{}, opt.RetryPolicy)
+}
+
+func retryPolicyFromContext(ctx context.Context) *RetryPolicy {
+	policy, _ := ctx.Value(retryPolicyContextKey{}).(*RetryPolicy)
+	return policy
+}
+
+// isIdempotentMethod returns true for the HTTP methods that can be sent
+// more than once with the same effect as sending them once
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// shouldRetry returns true if a request sent attempt times, that got result,
+// should be sent again
+func (policy *RetryPolicy) shouldRetry(method string, result *hostHTTPResult, attempt int) bool {
+	if policy == nil || attempt >= policy.MaxAttempts || !isIdempotentMethod(method) ||
+		!util.StringInArray(method, policy.RetryableMethods) {
+		return false
+	}
+	if result.isException() {
+		return isTransientConnectionError(result.err)
+	}
+	for _, statusCode := range policy.RetryableStatusCodes {
+		if result.statusCode == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientConnectionError returns true if err tells that the connection to
+// the host was refused, or was reset or closed before the response. A
+// timeout, or a certificate that failed verification, is not transient.
+func isTransientConnectionError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns how long to wait after attempt before the next one
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := float64(policy.InitialBackoff) * math.Pow(math.Max(policy.Multiplier, 1), float64(attempt-1))
+	if policy.MaxBackoff > 0 {
+		delay = math.Min(delay, float64(policy.MaxBackoff))
+	}
+	if jitter := math.Min(math.Max(policy.Jitter, 0), 1); jitter > 0 {
+		// the jitter only spreads the retries of the hosts, it needs no
+		// cryptographic randomness
+		//nolint:gosec
+		delay = delay*(1-jitter) + delay*jitter*rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// waitBackoff waits for delay to pass. It returns false if ctx is done first.
+func waitBackoff(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
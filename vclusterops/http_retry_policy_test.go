@@ -0,0 +1,136 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	exception := hostHTTPResult{status: EXCEPTION, err: &url.Error{Op: "Get", URL: "https://192.168.1.101:8443/v1/nodes",
+		Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}}
+	unavailable := hostHTTPResult{status: FAILURE, statusCode: http.StatusServiceUnavailable}
+	notFound := hostHTTPResult{status: FAILURE, statusCode: http.StatusNotFound}
+
+	// GET requests are retried on connection failures and retryable status codes
+	assert.True(t, policy.shouldRetry(GetMethod, &exception, 1))
+	assert.True(t, policy.shouldRetry(GetMethod, &hostHTTPResult{status: EXCEPTION, err: io.EOF}, 1))
+	assert.True(t, policy.shouldRetry(GetMethod, &unavailable, 2))
+	assert.False(t, policy.shouldRetry(GetMethod, &notFound, 1))
+	// but not on timeouts, nor on other errors
+	timeout := hostHTTPResult{status: EXCEPTION, err: &RequestTimeoutError{Err: context.DeadlineExceeded}}
+	assert.False(t, policy.shouldRetry(GetMethod, &timeout, 1))
+	assert.False(t, policy.shouldRetry(GetMethod, &hostHTTPResult{status: EXCEPTION, err: errors.New("no route")}, 1))
+	// up to the max attempts
+	assert.False(t, policy.shouldRetry(GetMethod, &exception, policy.MaxAttempts))
+
+	// only GET requests are retried by default
+	assert.False(t, policy.shouldRetry(PutMethod, &exception, 1))
+
+	// a POST request is never retried
+	policy.RetryableMethods = []string{GetMethod, PutMethod, PostMethod}
+	assert.True(t, policy.shouldRetry(PutMethod, &exception, 1))
+	assert.False(t, policy.shouldRetry(PostMethod, &exception, 1))
+
+	// a nil policy does not retry
+	var noPolicy *RetryPolicy
+	assert.False(t, noPolicy.shouldRetry(GetMethod, &exception, 1))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, time.Second, policy.backoff(5))
+
+	// with jitter the wait stays between (1-jitter)*backoff and backoff
+	policy.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		delay := policy.backoff(2)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.LessOrEqual(t, delay, 200*time.Millisecond)
+	}
+}
+
+func TestRequestRetryPolicy(t *testing.T) {
+	httpRequest := clusterHTTPRequest{}
+	ctx := context.Background()
+	assert.Equal(t, DefaultRetryPolicy(), *requestRetryPolicy(ctx, &httpRequest))
+
+	// the policy of the options replaces the default one
+	options := DatabaseOptionsFactory()
+	options.RetryPolicy = &RetryPolicy{MaxAttempts: 5}
+	ctx = VClusterCommands{}.setupContext(ctx, &options, "")
+	assert.Equal(t, options.RetryPolicy, requestRetryPolicy(ctx, &httpRequest))
+
+	// the policy of the op takes precedence
+	httpRequest.RetryPolicy = &RetryPolicy{MaxAttempts: 1}
+	assert.Equal(t, httpRequest.RetryPolicy, requestRetryPolicy(ctx, &httpRequest))
+}
+
+func TestRetryTimedOutRequest(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	defer close(release)
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.NoError(t, err)
+	options := DatabaseOptionsFactory()
+	options.TLSVerification = TLSVerification{Insecure: true}
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, "")
+
+	// a GET that timed out may still be processed by the host, so it is not
+	// sent again
+	policy := DefaultRetryPolicy()
+	password := "password"
+	request := hostHTTPRequest{Method: GetMethod, Port: port, Password: &password, Timeout: 1, RetryPolicy: &policy}
+	adapter := makeHTTPAdapter(vlog.Printer{})
+	adapter.host = serverURL.Hostname()
+	resultChannel := make(chan hostHTTPResult, 1)
+	adapter.sendRequest(ctx, &request, resultChannel)
+	result := <-resultChannel
+	assert.True(t, result.isTimeout())
+	assert.Equal(t, 1, result.attempts)
+	assert.Equal(t, int32(1), requests.Load())
+}
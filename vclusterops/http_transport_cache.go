@@ -0,0 +1,164 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	transportMaxIdleConnsPerHost = 4
+	transportIdleConnTimeout     = 90 * time.Second
+	transportTLSHandshakeTimeout = 30 * time.Second
+	// the number of transports kept at most, the least recently used one
+	// is dropped beyond it
+	transportCacheSize = 256
+)
+
+// transportCache holds one keep-alive http.Transport per host and TLS
+// identity, so that the requests sent to a host, such as the iterations of a
+// poller, reuse the same TLS connections instead of doing a handshake each
+// time. It keeps at most maxSize transports, and drops the ones that have
+// not been used for longer than their connections stay idle. It is safe for
+// concurrent use, and so are the transports it returns.
+type transportCache struct {
+	mu         sync.Mutex
+	maxSize    int
+	transports map[string]*list.Element
+	// the transports, the most recently used first
+	lru *list.List
+}
+
+type cachedTransport struct {
+	key       string
+	transport *http.Transport
+	lastUsed  time.Time
+}
+
+// the transports are shared by all the commands run in the process
+var httpTransports = makeTransportCache(transportCacheSize)
+
+func makeTransportCache(maxSize int) *transportCache {
+	return &transportCache{maxSize: maxSize, transports: make(map[string]*list.Element), lru: list.New()}
+}
+
+// CloseIdleConnections closes the connections to the hosts that the commands
+// keep open to reuse them, and drops their transports. A process that runs
+// commands for a long time can call it once it is done with a cluster. The
+// commands that are still running open new connections as they need them.
+func CloseIdleConnections() {
+	httpTransports.close()
+}
+
+// transportKey identifies the transports that can be shared: the requests
+// to the same host with the same credentials, that verify the host the same
+// way, through the same dialer. The certificates are hashed so the key does
+// not hold them.
+func transportKey(host string, request *hostHTTPRequest, usePassword bool, verification *TLSVerification,
+	dialer Dialer) string {
+	var key string
+	switch {
+	case usePassword && request.UseCertsInOptions:
+		key = host + "|password|" + hashCerts(request.Certs.caCert)
+	case usePassword:
+		key = host + "|password"
+	case request.UseCertsInOptions:
+		key = host + "|certs|" + hashCerts(request.Certs.key, request.Certs.cert, request.Certs.caCert)
+	default:
+		key = host + "|cert-files"
+	}
+	key += "|" + verification.key(host)
+	if dialer != nil {
+		key += fmt.Sprintf("|dialer:%p", dialer)
+	}
+	return key
+}
+
+func hashCerts(certs ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(certs, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the transport for key. If there is none yet, one is created
+// with the TLS config built by buildTLSConfig. It opens its connections with
+// dialer, or with the default dialer if dialer is nil.
+func (cache *transportCache) get(key string, dialer Dialer,
+	buildTLSConfig func() (*tls.Config, error)) (*http.Transport, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	now := time.Now()
+	cache.evictIdle(now)
+	if elem, ok := cache.transports[key]; ok {
+		cached := elem.Value.(*cachedTransport)
+		cached.lastUsed = now
+		cache.lru.MoveToFront(elem)
+		return cached.transport, nil
+	}
+	tlsConfig, err := buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConnsPerHost: transportMaxIdleConnsPerHost,
+		IdleConnTimeout:     transportIdleConnTimeout,
+		TLSHandshakeTimeout: transportTLSHandshakeTimeout,
+	}
+	if dialer != nil {
+		transport.DialContext = dialer.DialContext
+	}
+	cache.transports[key] = cache.lru.PushFront(&cachedTransport{key: key, transport: transport, lastUsed: now})
+	if cache.lru.Len() > cache.maxSize {
+		cache.remove(cache.lru.Back())
+	}
+	return transport, nil
+}
+
+// evictIdle drops the transports that have not been used since their
+// connections went idle
+func (cache *transportCache) evictIdle(now time.Time) {
+	for elem := cache.lru.Back(); elem != nil; elem = cache.lru.Back() {
+		if now.Sub(elem.Value.(*cachedTransport).lastUsed) < transportIdleConnTimeout {
+			return
+		}
+		cache.remove(elem)
+	}
+}
+
+// remove drops the transport of elem and closes its idle connections. The
+// requests it is sending still complete.
+func (cache *transportCache) remove(elem *list.Element) {
+	cached := cache.lru.Remove(elem).(*cachedTransport)
+	delete(cache.transports, cached.key)
+	cached.transport.CloseIdleConnections()
+}
+
+// close drops all the transports
+func (cache *transportCache) close() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	for cache.lru.Len() > 0 {
+		cache.remove(cache.lru.Back())
+	}
+}
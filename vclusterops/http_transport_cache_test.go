@@ -0,0 +1,139 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestTransportCache(t *testing.T) {
+	cache := makeTransportCache(transportCacheSize)
+	builds := 0
+	buildTLSConfig := func() (*tls.Config, error) {
+		builds++
+		return &tls.Config{MinVersion: tls.VersionTLS12}, nil
+	}
+
+	request := hostHTTPRequest{UseCertsInOptions: true, Certs: httpsCerts{key: "key1", cert: "cert1"}}
+	key := transportKey("host1", &request, false, nil, nil)
+	transport, err := cache.get(key, nil, buildTLSConfig)
+	assert.NoError(t, err)
+	// the same host and credentials share the transport
+	sameTransport, err := cache.get(transportKey("host1", &request, false, nil, nil), nil, buildTLSConfig)
+	assert.NoError(t, err)
+	assert.Same(t, transport, sameTransport)
+	assert.Equal(t, 1, builds)
+
+	// other hosts, other certificates or a password get their own transport
+	otherRequest := hostHTTPRequest{UseCertsInOptions: true, Certs: httpsCerts{key: "key2", cert: "cert2"}}
+	for _, otherKey := range []string{
+		transportKey("host2", &request, false, nil, nil),
+		transportKey("host1", &otherRequest, false, nil, nil),
+		transportKey("host1", &request, true, nil, nil),
+	} {
+		assert.NotEqual(t, key, otherKey)
+		otherTransport, err := cache.get(otherKey, nil, buildTLSConfig)
+		assert.NoError(t, err)
+		assert.NotSame(t, transport, otherTransport)
+	}
+	assert.Equal(t, 4, builds)
+	assert.NotContains(t, key, "cert1")
+
+	// the connections made through a dialer are not shared with the others
+	dialer := &net.Dialer{}
+	dialerKey := transportKey("host1", &request, false, nil, dialer)
+	assert.NotEqual(t, key, dialerKey)
+	assert.Equal(t, dialerKey, transportKey("host1", &request, false, nil, dialer))
+	dialerTransport, err := cache.get(dialerKey, dialer, buildTLSConfig)
+	assert.NoError(t, err)
+	assert.NotNil(t, dialerTransport.DialContext)
+
+	// a failure to build the TLS config is not cached
+	_, err = cache.get("host3|cert-files", nil, func() (*tls.Config, error) {
+		return nil, errors.New("fail to load HTTPS certificates")
+	})
+	assert.Error(t, err)
+	_, err = cache.get("host3|cert-files", nil, buildTLSConfig)
+	assert.NoError(t, err)
+
+	// the cache is safe for concurrent use
+	var wg sync.WaitGroup
+	transports := make([]any, 10)
+	for i := range transports {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			transports[i], _ = cache.get("host4|password", nil, buildTLSConfig)
+		}(i)
+	}
+	wg.Wait()
+	for i := range transports {
+		assert.Same(t, transports[0], transports[i])
+	}
+}
+
+func TestTransportCacheEviction(t *testing.T) {
+	cache := makeTransportCache(2)
+	buildTLSConfig := func() (*tls.Config, error) {
+		return &tls.Config{MinVersion: tls.VersionTLS12}, nil
+	}
+	get := func(key string) *http.Transport {
+		transport, err := cache.get(key, nil, buildTLSConfig)
+		assert.NoError(t, err)
+		return transport
+	}
+
+	// beyond its size, the cache drops the least recently used transport
+	transport1 := get("host1|cert-files")
+	get("host2|cert-files")
+	assert.Same(t, transport1, get("host1|cert-files"))
+	get("host3|cert-files")
+	assert.Equal(t, 2, cache.lru.Len())
+	assert.Contains(t, cache.transports, "host1|cert-files")
+	assert.NotContains(t, cache.transports, "host2|cert-files")
+
+	// a transport whose connections have all gone idle is dropped
+	cache.transports["host1|cert-files"].Value.(*cachedTransport).lastUsed = time.Now().Add(-transportIdleConnTimeout)
+	get("host3|cert-files")
+	assert.NotContains(t, cache.transports, "host1|cert-files")
+	assert.Len(t, cache.transports, 1)
+	assert.NotSame(t, transport1, get("host1|cert-files"))
+
+	// and closing the cache drops them all
+	cache.close()
+	assert.Empty(t, cache.transports)
+	assert.Equal(t, 0, cache.lru.Len())
+}
+
+func TestDispatcherPoolsAreNotShared(t *testing.T) {
+	dispatcher1 := makeHTTPRequestDispatcher(vlog.Printer{})
+	dispatcher1.setup([]string{"host1"})
+	dispatcher2 := makeHTTPRequestDispatcher(vlog.Printer{})
+	dispatcher2.setup([]string{"host2"})
+	assert.Len(t, dispatcher1.pool.connections, 1)
+	assert.Contains(t, dispatcher1.pool.connections, "host1")
+	assert.Len(t, dispatcher2.pool.connections, 1)
+	assert.Contains(t, dispatcher2.pool.connections, "host2")
+}
@@ -30,6 +30,8 @@ type httpsAddSubclusterOp struct {
 	scName             string
 	isSecondary        bool
 	ctlSetSize         int
+	// the host that added the subcluster, used to drop it on rollback
+	addedOnHost string
 }
 
 func makeHTTPSAddSubclusterOp(useHTTPPassword bool, userName string, httpsPassword *string,
@@ -116,6 +118,21 @@ func (op *httpsAddSubclusterOp) execute(execContext *opEngineExecContext) error
 	return op.processResult(execContext)
 }
 
+// compensatingRequests drops the subcluster through the same initiator that
+// added it
+func (op *httpsAddSubclusterOp) compensatingRequests() ([]journalRequest, error) {
+	var requests []journalRequest
+	for host, addRequest := range op.clusterHTTPRequest.RequestCollection {
+		httpRequest := hostHTTPRequest{}
+		httpRequest.Method = PostMethod
+		httpRequest.buildHTTPSEndpoint("subclusters/" + op.scName + "/drop")
+		httpRequest.Username = addRequest.Username
+		httpRequest.Password = addRequest.Password
+		requests = append(requests, makeJournalRequest(host, &httpRequest))
+	}
+	return requests, nil
+}
+
 func (op *httpsAddSubclusterOp) processResult(_ *opEngineExecContext) error {
 	var allErrs error
 
@@ -143,6 +160,7 @@ func (op *httpsAddSubclusterOp) processResult(_ *opEngineExecContext) error {
 		if err != nil {
 			return fmt.Errorf(`[%s] fail to parse result on host %s, details: %w`, op.name, host, err)
 		}
+		op.addedOnHost = host
 
 		return nil
 	}
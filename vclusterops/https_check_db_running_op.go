@@ -421,6 +421,15 @@ func (op *httpsCheckRunningDBOp) execute(execContext *opEngineExecContext) error
 	return fmt.Errorf("unknown operation found in HTTPCheckRunningDBOp")
 }
 
+// isReadOnly returns false when the op waits for the database to go down,
+// since that only happens after an earlier op stopped it
+func (op *httpsCheckRunningDBOp) isReadOnly() bool {
+	if op.opType == StopDB || op.opType == StopSC {
+		return false
+	}
+	return op.opBase.isReadOnly()
+}
+
 func (op *httpsCheckRunningDBOp) pollForDBDown(execContext *opEngineExecContext) error {
 	// start the polling
 	startTime := time.Now()
@@ -442,9 +451,12 @@ func (op *httpsCheckRunningDBOp) pollForDBDown(execContext *opEngineExecContext)
 			break
 		}
 		if count > 0 {
-			time.Sleep(PollingInterval * time.Second)
+			if err = waitForNextPoll(execContext.ctx); err != nil {
+				return err
+			}
 		}
-		err = execContext.dispatcher.sendRequest(&op.clusterHTTPRequest, op.spinner)
+		sendPollIterationEvent(execContext.ctx, op.name, count+1, startTime)
+		err = execContext.dispatcher.sendRequest(execContext.ctx, &op.clusterHTTPRequest, op.spinner)
 		if err != nil {
 			return fmt.Errorf("fail to dispatch request %v: %w", op.clusterHTTPRequest, err)
 		}
@@ -470,7 +482,7 @@ func (op *httpsCheckRunningDBOp) pollForDBDown(execContext *opEngineExecContext)
 }
 
 func (op *httpsCheckRunningDBOp) checkDBConnection(execContext *opEngineExecContext) error {
-	err := execContext.dispatcher.sendRequest(&op.clusterHTTPRequest, op.spinner)
+	err := execContext.dispatcher.sendRequest(execContext.ctx, &op.clusterHTTPRequest, op.spinner)
 	if err != nil {
 		return fmt.Errorf("fail to dispatch request %v: %w", op.clusterHTTPRequest, err)
 	}
@@ -26,6 +26,8 @@ type httpsCreateNodeOp struct {
 	opBase
 	opHTTPSBase
 	RequestParams map[string]string
+	// names of the nodes created, used to drop them on rollback
+	createdNodes []string
 }
 
 func makeHTTPSCreateNodeOp(newNodeHosts []string, bootstrapHost []string,
@@ -103,6 +105,24 @@ func (op *httpsCreateNodeOp) finalize(_ *opEngineExecContext) error {
 
 type httpsCreateNodeResponse map[string][]map[string]string
 
+// compensatingRequests drops the created nodes from the catalog, through
+// the same host that created them
+func (op *httpsCreateNodeOp) compensatingRequests() ([]journalRequest, error) {
+	var requests []journalRequest
+	for host, createRequest := range op.clusterHTTPRequest.RequestCollection {
+		for _, nodeName := range op.createdNodes {
+			httpRequest := hostHTTPRequest{}
+			httpRequest.Method = PostMethod
+			httpRequest.buildHTTPSEndpoint("nodes/" + nodeName + "/drop")
+			httpRequest.QueryParams = map[string]string{"cascade": "false"}
+			httpRequest.Username = createRequest.Username
+			httpRequest.Password = createRequest.Password
+			requests = append(requests, makeJournalRequest(host, &httpRequest))
+		}
+	}
+	return requests, nil
+}
+
 func (op *httpsCreateNodeOp) processResult(_ *opEngineExecContext) error {
 	var allErrs error
 
@@ -120,10 +140,14 @@ func (op *httpsCreateNodeOp) processResult(_ *opEngineExecContext) error {
 				allErrs = errors.Join(allErrs, err)
 				continue
 			}
-			_, ok := responseObj["created_nodes"]
+			createdNodes, ok := responseObj["created_nodes"]
 			if !ok {
 				err = fmt.Errorf(`[%s] response does not contain field "created_nodes"`, op.name)
 				allErrs = errors.Join(allErrs, err)
+				continue
+			}
+			for _, node := range createdNodes {
+				op.createdNodes = append(op.createdNodes, node["name"])
 			}
 		} else {
 			allErrs = errors.Join(allErrs, result.err)
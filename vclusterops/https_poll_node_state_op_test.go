@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,7 +40,7 @@ func TestTimeoutErrorCase(t *testing.T) {
 	// default timeout value for the op
 	certs := httpsCerts{}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	err = clusterOpEngine.run(vlog.Printer{})
+	err = clusterOpEngine.run(context.Background(), vlog.Printer{})
 	// expect timeout error in http response
 	assert.ErrorContains(t, err, "[HTTPSPollNodeStateOp] cannot connect to host 192.0.2.1, please check if the host is still alive")
 
@@ -51,7 +52,7 @@ func TestTimeoutErrorCase(t *testing.T) {
 	httpsPollNodeStateOp.httpRequestTimeout = httpRequestTimeoutForTest
 	instructions = append(instructions, &httpsPollNodeStateOp)
 	clusterOpEngine = makeClusterOpEngine(instructions, &certs)
-	err = clusterOpEngine.run(vlog.Printer{})
+	err = clusterOpEngine.run(context.Background(), vlog.Printer{})
 	// no polling is done, directly error out
 	assert.ErrorContains(t, err, "reached polling timeout of 0 seconds")
 }
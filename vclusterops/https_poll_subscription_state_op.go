@@ -57,6 +57,15 @@ func (op *httpsPollSubscriptionStateOp) getPollingTimeout() int {
 	return util.Max(op.timeout, 0)
 }
 
+// setPollingTimeout overrides the polling timeout. This op must time out,
+// so a negative timeout is ignored.
+func (op *httpsPollSubscriptionStateOp) setPollingTimeout(timeout int) {
+	if timeout > 0 {
+		op.timeout = timeout
+	}
+	op.clusterHTTPRequest.isPolling = true
+}
+
 func (op *httpsPollSubscriptionStateOp) setupClusterHTTPRequest(hosts []string) error {
 	for _, host := range hosts {
 		httpRequest := hostHTTPRequest{}
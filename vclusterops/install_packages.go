@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -57,7 +58,10 @@ func (options *VInstallPackagesOptions) validateAnalyzeOptions(log vlog.Printer)
 	return options.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VInstallPackages(options *VInstallPackagesOptions) (*InstallPackageStatus, error) {
+func (vcc VClusterCommands) VInstallPackages(ctx context.Context, options *VInstallPackagesOptions) (_ *InstallPackageStatus, err error) {
+	ctx = vcc.setupContext(ctx, options, commandInstallPackages)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -65,7 +69,7 @@ func (vcc VClusterCommands) VInstallPackages(options *VInstallPackagesOptions) (
 	 */
 
 	// validate and analyze all options
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return nil, err
 	}
@@ -82,10 +86,14 @@ func (vcc VClusterCommands) VInstallPackages(options *VInstallPackagesOptions) (
 	clusterOpEngine := makeClusterOpEngine(instructions, &httpsCerts{})
 
 	// Give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return nil, fmt.Errorf("fail to install packages: %w", runError)
 	}
+	// in plan mode the packages were not installed, so there is no status
+	if isPlanMode(ctx) {
+		return status, nil
+	}
 	if len(status.Packages) == 0 {
 		return nil, fmt.Errorf("did not flow back the install package status")
 	}
@@ -0,0 +1,118 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"errors"
+	"fmt"
+)
+
+// journalRollbackOp sends the requests saved in a journal entry to undo the
+// changes of an op. It sends at most one request to each host.
+type journalRollbackOp struct {
+	opBase
+	opHTTPSBase
+	requests []journalRequest
+}
+
+func makeJournalRollbackOp(undoneOpName string, requests []journalRequest,
+	userName string, httpsPassword *string) journalRollbackOp {
+	op := journalRollbackOp{}
+	op.name = "Rollback" + undoneOpName
+	op.description = fmt.Sprintf("Undo the changes of %s", undoneOpName)
+	op.requests = requests
+	op.userName = userName
+	op.httpsPassword = httpsPassword
+	for i := range requests {
+		op.hosts = append(op.hosts, requests[i].Host)
+	}
+	return op
+}
+
+// makeJournalRollbackInstructions builds the ops that undo a journal entry.
+// Requests to the same host are split across ops.
+func makeJournalRollbackInstructions(entry *journalEntry, userName string, httpsPassword *string) []clusterOp {
+	var instructions []clusterOp
+	var batch []journalRequest
+	batchHosts := make(map[string]bool)
+	for _, request := range entry.Rollback {
+		if batchHosts[request.Host] {
+			op := makeJournalRollbackOp(entry.OpName, batch, userName, httpsPassword)
+			instructions = append(instructions, &op)
+			batch = nil
+			batchHosts = make(map[string]bool)
+		}
+		batch = append(batch, request)
+		batchHosts[request.Host] = true
+	}
+	if len(batch) > 0 {
+		op := makeJournalRollbackOp(entry.OpName, batch, userName, httpsPassword)
+		instructions = append(instructions, &op)
+	}
+	return instructions
+}
+
+func (op *journalRollbackOp) setupClusterHTTPRequest() error {
+	for i := range op.requests {
+		request := &op.requests[i]
+		httpRequest := hostHTTPRequest{}
+		httpRequest.Method = request.Method
+		httpRequest.Endpoint = request.Endpoint
+		httpRequest.IsNMACommand = request.IsNMACommand
+		httpRequest.QueryParams = request.QueryParams
+		httpRequest.RequestData = request.RequestData
+		if request.UseHTTPPassword {
+			if op.httpsPassword == nil {
+				return fmt.Errorf("[%s] a password is needed to send %s to host %s", op.name, request.Endpoint, request.Host)
+			}
+			httpRequest.Username = request.Username
+			if httpRequest.Username == "" {
+				httpRequest.Username = op.userName
+			}
+			httpRequest.Password = op.httpsPassword
+		}
+		op.clusterHTTPRequest.RequestCollection[request.Host] = httpRequest
+	}
+	return nil
+}
+
+func (op *journalRollbackOp) prepare(execContext *opEngineExecContext) error {
+	execContext.dispatcher.setup(op.hosts)
+	return op.setupClusterHTTPRequest()
+}
+
+func (op *journalRollbackOp) execute(execContext *opEngineExecContext) error {
+	if err := op.runExecute(execContext); err != nil {
+		return err
+	}
+
+	return op.processResult(execContext)
+}
+
+func (op *journalRollbackOp) finalize(_ *opEngineExecContext) error {
+	return nil
+}
+
+func (op *journalRollbackOp) processResult(_ *opEngineExecContext) error {
+	var allErrs error
+	for host, result := range op.clusterHTTPRequest.ResultCollection {
+		op.logResponse(host, result)
+		if !result.isPassing() {
+			allErrs = errors.Join(allErrs, result.err)
+		}
+	}
+	return allErrs
+}
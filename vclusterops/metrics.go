@@ -0,0 +1,170 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// the metric families of the ops and of their requests
+const (
+	MetricOpRuns              = "vcluster_op_runs_total"
+	MetricOpDuration          = "vcluster_op_duration_seconds"
+	MetricHTTPRequests        = "vcluster_http_requests_total"
+	MetricHTTPRequestDuration = "vcluster_http_request_duration_seconds"
+)
+
+// the upper bounds, in seconds, of the buckets of the duration histograms
+var (
+	opDurationBuckets      = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800}
+	requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+)
+
+// the label of the status of a request that got no response
+const requestStatusError = "error"
+
+// Metrics collects the metrics of the commands run with it in their
+// options:
+//   - vcluster_op_runs_total and vcluster_op_duration_seconds, the runs of
+//     the ops by command, op name and status: finished, failed or skipped
+//   - vcluster_http_requests_total and vcluster_http_request_duration_seconds,
+//     the requests sent to the hosts by host, method and status code, or
+//     "error" if there was no response. A retried request counts once per
+//     attempt.
+//
+// Metrics is a prometheus.Collector, which can be registered on a Prometheus
+// registry, and an http.Handler that serves the metrics on its own. It is
+// safe for concurrent use, and can be shared by many commands.
+type Metrics struct {
+	opRuns          *prometheus.CounterVec
+	opDuration      *prometheus.HistogramVec
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	// registry only holds the metrics, for Gather, WriteText and ServeHTTP
+	registry *prometheus.Registry
+}
+
+// NewMetrics returns a Metrics with no samples yet
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		opRuns: prometheus.NewCounterVec(prometheus.CounterOpts{Name: MetricOpRuns,
+			Help: "Number of op runs, by command, op and status."}, []string{"command", "op", "status"}),
+		opDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: MetricOpDuration,
+			Help: "Time spent in the ops, by command, op and status.", Buckets: opDurationBuckets},
+			[]string{"command", "op", "status"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{Name: MetricHTTPRequests,
+			Help: "Number of HTTP requests sent to the hosts, by host, method and status code."},
+			[]string{"host", "method", "status_code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: MetricHTTPRequestDuration,
+			Help: "Latency of the HTTP requests sent to the hosts, by host.", Buckets: requestDurationBuckets},
+			[]string{"host"}),
+		registry: prometheus.NewPedanticRegistry(),
+	}
+	m.registry.MustRegister(m)
+	return m
+}
+
+// Describe sends the descriptors of the metric families to ch
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.opRuns.Describe(ch)
+	m.opDuration.Describe(ch)
+	m.requests.Describe(ch)
+	m.requestDuration.Describe(ch)
+}
+
+// Collect sends the current metrics to ch
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.opRuns.Collect(ch)
+	m.opDuration.Collect(ch)
+	m.requests.Collect(ch)
+	m.requestDuration.Collect(ch)
+}
+
+// observeOp counts a run of an op that ended with status after duration
+func (m *Metrics) observeOp(command, opName, status string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.opRuns.WithLabelValues(command, opName, status).Inc()
+	m.opDuration.WithLabelValues(command, opName, status).Observe(duration.Seconds())
+}
+
+// observeRequest counts a request sent to host, which got a response with
+// statusCode, or no response if statusCode is 0
+func (m *Metrics) observeRequest(host, method string, statusCode int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	status := requestStatusError
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	m.requests.WithLabelValues(host, method, status).Inc()
+	m.requestDuration.WithLabelValues(host).Observe(duration.Seconds())
+}
+
+// Gather returns a snapshot of the metric families that have samples, sorted
+// by name, with their metrics sorted by label values
+func (m *Metrics) Gather() ([]*dto.MetricFamily, error) {
+	return m.registry.Gather()
+}
+
+// WriteText writes the metrics to w in the Prometheus text format, as read
+// by a Prometheus server or by the textfile collector of the node exporter
+func (m *Metrics) WriteText(w io.Writer) error {
+	families, err := m.Gather()
+	if err != nil {
+		return err
+	}
+	encoder := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the metrics in the format the scraper asks for, the
+// Prometheus text format by default
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+type metricsContextKey struct{}
+
+// attachMetrics attaches the metrics of the options to ctx, so that the ops
+// run under it and their requests are counted in them
+func (opt *DatabaseOptions) attachMetrics(ctx context.Context) context.Context {
+	if opt.Metrics == nil || metricsFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, metricsContextKey{}, opt.Metrics)
+}
+
+func metricsFromContext(ctx context.Context) *Metrics {
+	metrics, _ := ctx.Value(metricsContextKey{}).(*Metrics)
+	return metrics
+}
@@ -0,0 +1,118 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.observeOp("stop_db", "HTTPSStopDBOp", "finished", 2*time.Second)
+	metrics.observeOp("stop_db", "HTTPSStopDBOp", "finished", 40*time.Second)
+	metrics.observeRequest("192.168.1.101", "POST", 200, 20*time.Millisecond)
+	metrics.observeRequest("192.168.1.101", "POST", 0, 3*time.Second)
+
+	families, err := metrics.Gather()
+	assert.NoError(t, err)
+	assert.Len(t, families, 4)
+	assert.Equal(t, MetricHTTPRequestDuration, families[0].GetName())
+	assert.Equal(t, MetricHTTPRequests, families[1].GetName())
+	requests := families[1].GetMetric()
+	assert.Len(t, requests, 2)
+	assert.Equal(t, []string{"192.168.1.101", "POST", "200"}, labelValues(requests[0]))
+	assert.Equal(t, []string{"192.168.1.101", "POST", "error"}, labelValues(requests[1]))
+	assert.Equal(t, float64(1), requests[1].GetCounter().GetValue())
+	// the buckets are cumulative
+	opDuration := families[2].GetMetric()[0].GetHistogram()
+	assert.Equal(t, uint64(2), opDuration.GetSampleCount())
+	assert.Equal(t, float64(42), opDuration.GetSampleSum())
+	buckets := make(map[float64]uint64)
+	for _, bucket := range opDuration.GetBucket() {
+		buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+	}
+	assert.Equal(t, uint64(0), buckets[1])
+	assert.Equal(t, uint64(1), buckets[2.5])
+	assert.Equal(t, uint64(2), buckets[60])
+
+	var text strings.Builder
+	assert.NoError(t, metrics.WriteText(&text))
+	assert.Contains(t, text.String(), "# TYPE vcluster_op_runs_total counter\n"+
+		`vcluster_op_runs_total{command="stop_db",op="HTTPSStopDBOp",status="finished"} 2`+"\n")
+	assert.Contains(t, text.String(), "# TYPE vcluster_op_duration_seconds histogram\n")
+	assert.Contains(t, text.String(),
+		`vcluster_op_duration_seconds_bucket{command="stop_db",op="HTTPSStopDBOp",status="finished",le="30"} 1`+"\n"+
+			`vcluster_op_duration_seconds_bucket{command="stop_db",op="HTTPSStopDBOp",status="finished",le="60"} 2`)
+	assert.Contains(t, text.String(),
+		`vcluster_op_duration_seconds_bucket{command="stop_db",op="HTTPSStopDBOp",status="finished",le="+Inf"} 2`+"\n"+
+			`vcluster_op_duration_seconds_sum{command="stop_db",op="HTTPSStopDBOp",status="finished"} 42`+"\n"+
+			`vcluster_op_duration_seconds_count{command="stop_db",op="HTTPSStopDBOp",status="finished"} 2`+"\n")
+
+	// the metrics can be scraped
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, text.String(), recorder.Body.String())
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "version=0.0.4")
+
+	// the label values are escaped as in the Prometheus text format, in
+	// which only the backslash, the double quote and the line feed are
+	metrics.observeOp("drop_db", "op \\ \"é\"\n", "failed", time.Second)
+	text.Reset()
+	assert.NoError(t, metrics.WriteText(&text))
+	assert.Contains(t, text.String(), `vcluster_op_runs_total{command="drop_db",op="op \\ \"é\"\n",status="failed"} 1`)
+
+	// the metrics can be registered on another registry
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(metrics))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.opRuns.WithLabelValues("drop_db", "op \\ \"é\"\n", "failed")))
+}
+
+func labelValues(metric *dto.Metric) []string {
+	var values []string
+	for _, label := range metric.GetLabel() {
+		values = append(values, label.GetValue())
+	}
+	return values
+}
+
+func TestOpMetrics(t *testing.T) {
+	options := DatabaseOptionsFactory()
+	options.Metrics = NewMetrics()
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, commandStopDB)
+	// the command of a nested command is the outer one
+	nestedOptions := DatabaseOptionsFactory()
+	ctx = VClusterCommands{}.setupContext(ctx, &nestedOptions, commandStartDB)
+
+	op := makeNMAHealthOp([]string{"192.168.1.101"})
+	sendOpStatusEvent(ctx, &op, OpEventStarted, time.Now(), nil)
+	sendOpStatusEvent(ctx, &op, OpEventFailed, time.Now(), nil)
+	families, err := options.Metrics.Gather()
+	assert.NoError(t, err)
+	// only the families with samples are gathered
+	opRuns := families[1]
+	assert.Equal(t, MetricOpRuns, opRuns.GetName())
+	assert.Len(t, opRuns.GetMetric(), 1)
+	assert.Equal(t, []string{commandStopDB, op.getName(), "failed"}, labelValues(opRuns.GetMetric()[0]))
+}
@@ -15,9 +15,23 @@
 
 package vclusterops
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 type adapter interface {
-	sendRequest(*hostHTTPRequest, chan<- hostHTTPResult)
+	sendRequest(context.Context, *hostHTTPRequest, chan<- hostHTTPResult)
 	generateResult(*http.Response) hostHTTPResult
 }
+
+// timeoutError is the error of a request that timed out, for the results
+// that are made up rather than received, such as the replayed ones. It is a
+// net.Error, as the error of a request that really timed out.
+type timeoutError struct {
+	message string
+}
+
+func (e *timeoutError) Error() string   { return e.message }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
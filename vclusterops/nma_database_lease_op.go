@@ -0,0 +1,114 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// nmaDatabaseLeaseOp reads or writes the lease on a database, a file on the
+// initiator that holds the DatabaseLock of the command that holds it. The
+// NMA has no endpoint for other files than the config files of a catalog,
+// so the lease is kept with the vertica.conf endpoint in the directory of
+// the database, which is not the catalog of any node.
+type nmaDatabaseLeaseOp struct {
+	opBase
+	leaseDir string
+	// content is the lease to write, nil to read it
+	content *string
+	// lease is the lease read, "" if there is none
+	lease string
+}
+
+func makeNMAReadDatabaseLeaseOp(host, leaseDir string) nmaDatabaseLeaseOp {
+	op := nmaDatabaseLeaseOp{}
+	op.name = "NMAReadDatabaseLeaseOp"
+	op.description = "Read the lease on the database"
+	op.hosts = []string{host}
+	op.leaseDir = leaseDir
+	return op
+}
+
+func makeNMAWriteDatabaseLeaseOp(host, leaseDir, content string) nmaDatabaseLeaseOp {
+	op := nmaDatabaseLeaseOp{}
+	op.name = "NMAWriteDatabaseLeaseOp"
+	op.description = "Write the lease on the database"
+	op.hosts = []string{host}
+	op.leaseDir = leaseDir
+	op.content = &content
+	return op
+}
+
+func (op *nmaDatabaseLeaseOp) setupClusterHTTPRequest(hosts []string) error {
+	for _, host := range hosts {
+		httpRequest := hostHTTPRequest{}
+		httpRequest.buildNMAEndpoint(verticaConf)
+		if op.content == nil {
+			httpRequest.Method = GetMethod
+			httpRequest.QueryParams = map[string]string{"catalog_path": op.leaseDir}
+		} else {
+			httpRequest.Method = PostMethod
+			dataBytes, err := json.Marshal(uploadConfigRequestData{CatalogPath: op.leaseDir, Content: *op.content})
+			if err != nil {
+				return fmt.Errorf("[%s] fail to marshal request data to JSON string, detail %w", op.name, err)
+			}
+			httpRequest.RequestData = string(dataBytes)
+		}
+		op.clusterHTTPRequest.RequestCollection[host] = httpRequest
+	}
+
+	return nil
+}
+
+func (op *nmaDatabaseLeaseOp) prepare(execContext *opEngineExecContext) error {
+	execContext.dispatcher.setup(op.hosts)
+
+	return op.setupClusterHTTPRequest(op.hosts)
+}
+
+func (op *nmaDatabaseLeaseOp) execute(execContext *opEngineExecContext) error {
+	if err := op.runExecute(execContext); err != nil {
+		return err
+	}
+
+	return op.processResult(execContext)
+}
+
+func (op *nmaDatabaseLeaseOp) finalize(_ *opEngineExecContext) error {
+	return nil
+}
+
+func (op *nmaDatabaseLeaseOp) processResult(_ *opEngineExecContext) error {
+	for host, result := range op.clusterHTTPRequest.ResultCollection {
+		op.logResponse(host, result)
+		switch {
+		case result.isPassing() && op.content == nil:
+			op.lease = result.content
+		case result.isPassing():
+			if _, err := op.parseAndCheckMapResponse(host, result.content); err != nil {
+				return err
+			}
+		// the NMA answers with an error when there is no lease file yet
+		case result.isFailing() && op.content == nil:
+			op.lease = ""
+		default:
+			return result.err
+		}
+	}
+
+	return nil
+}
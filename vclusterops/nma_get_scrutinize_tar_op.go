@@ -46,14 +46,15 @@ func makeNMAGetScrutinizeTarOp(
 
 	// the caller is responsible for making sure hosts and maps match up exactly
 	err := validateHostMaps(hosts, hostNodeNameMap)
-	if err != nil {
-		return op, err
-	}
-
-	err = op.createOutputDir()
 	return op, err
 }
 
+// isReadOnly returns false because the NMA builds the tarball on the host
+// before sending it back
+func (op *nmaGetScrutinizeTarOp) isReadOnly() bool {
+	return false
+}
+
 // useSingleHost indicates that the tarball should only be retrieved from the first
 // up node
 func (op *nmaGetScrutinizeTarOp) useSingleHost() {
@@ -113,6 +114,10 @@ func (op *nmaGetScrutinizeTarOp) prepare(execContext *opEngineExecContext) error
 }
 
 func (op *nmaGetScrutinizeTarOp) execute(execContext *opEngineExecContext) error {
+	// the directory is only created when the op runs, not in a dry run
+	if err := op.createOutputDir(); err != nil {
+		return err
+	}
 	if err := op.runExecute(execContext); err != nil {
 		return err
 	}
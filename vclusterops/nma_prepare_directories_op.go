@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
 type nmaPrepareDirectoriesOp struct {
@@ -28,6 +29,8 @@ type nmaPrepareDirectoriesOp struct {
 	hostRequestBodyMap map[string]string
 	forceCleanup       bool
 	forRevive          bool
+	// hosts where the directories were prepared, used to delete them on rollback
+	preparedHosts []string
 }
 
 type prepareDirectoriesRequestData struct {
@@ -83,6 +86,39 @@ func (op *nmaPrepareDirectoriesOp) setupRequestBody(hostNodeMap vHostNodeMap) er
 	return nil
 }
 
+// compensatingRequests deletes the directories the op created. The user
+// storage locations are left alone because they may have existed before.
+func (op *nmaPrepareDirectoriesOp) compensatingRequests() ([]journalRequest, error) {
+	hosts := slices.Clone(op.hosts)
+	slices.Sort(hosts)
+	requests := make([]journalRequest, 0, len(hosts))
+	for _, host := range hosts {
+		prepareDirData := prepareDirectoriesRequestData{}
+		err := json.Unmarshal([]byte(op.hostRequestBodyMap[host]), &prepareDirData)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] fail to parse request data of host %s, detail %w", op.name, host, err)
+		}
+
+		deleteData := deleteDirParams{ForceDelete: true}
+		deleteData.Directories = append(deleteData.Directories, prepareDirData.CatalogPath)
+		if prepareDirData.DepotPath != "" {
+			deleteData.Directories = append(deleteData.Directories, prepareDirData.DepotPath)
+		}
+		deleteData.Directories = append(deleteData.Directories, prepareDirData.StorageLocations...)
+		dataBytes, err := json.Marshal(deleteData)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] fail to marshal request data to JSON string, detail %w", op.name, err)
+		}
+
+		httpRequest := hostHTTPRequest{}
+		httpRequest.Method = PostMethod
+		httpRequest.buildNMAEndpoint("directories/delete")
+		httpRequest.RequestData = string(dataBytes)
+		requests = append(requests, makeJournalRequest(host, &httpRequest))
+	}
+	return requests, nil
+}
+
 func (op *nmaPrepareDirectoriesOp) setupClusterHTTPRequest(hosts []string) error {
 	for _, host := range hosts {
 		httpRequest := hostHTTPRequest{}
@@ -128,7 +164,9 @@ func (op *nmaPrepareDirectoriesOp) processResult(_ *opEngineExecContext) error {
 			_, err := op.parseAndCheckMapResponse(host, result.content)
 			if err != nil {
 				allErrs = errors.Join(allErrs, err)
+				continue
 			}
+			op.preparedHosts = append(op.preparedHosts, host)
 		} else {
 			allErrs = errors.Join(allErrs, result.err)
 		}
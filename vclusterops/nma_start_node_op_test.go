@@ -1,6 +1,7 @@
 package vclusterops
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -22,7 +23,7 @@ func TestStartNodeOp(t *testing.T) {
 	certs := httpsCerts{}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
-	execContext := makeOpEngineExecContext(vl)
+	execContext := makeOpEngineExecContext(context.Background(), vl)
 	clusterOpEngine.execContext = &execContext
 	execContext.nmaVDatabase = nmaVDatabase{}
 	execContext.nmaVDatabase.HostNodeMap = make(map[string]*nmaVNode)
@@ -27,6 +27,9 @@ type NodeInfo struct {
 	Sandbox     string `json:"sandbox"`
 	IsPrimary   bool   `json:"is_primary"`
 	Version     string `json:"version"`
+	// DownSince is when a node that is down went down, as the database
+	// reports it
+	DownSince string `json:"down_since,omitempty"`
 }
 
 // NodeInfo does not contain Eon specific information
@@ -0,0 +1,128 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// noTimeout is the timeout, in seconds, of a request or of a polling op that
+// never times out
+const noTimeout = -1
+
+// RequestTimeoutError is the error of a request to a host that got no
+// response within its timeout
+type RequestTimeoutError struct {
+	OpName   string
+	Host     string
+	Endpoint string
+	Timeout  time.Duration
+	Err      error
+}
+
+func (e *RequestTimeoutError) Error() string {
+	return fmt.Sprintf("[%s] request %s to host %s timed out after %s, details: %v",
+		e.OpName, e.Endpoint, e.Host, e.Timeout, e.Err)
+}
+
+func (e *RequestTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// timeoutConfig resolves the timeout of a request: the timeout of its op,
+// then the one the op sets itself, then the request timeout of the options,
+// then defaultRequestTimeout. The op timeouts are keyed by the lowercase op
+// name, so that they can be read from case-insensitive configuration.
+type timeoutConfig struct {
+	requestTimeout int
+	opTimeouts     map[string]int
+}
+
+type timeoutConfigContextKey struct{}
+
+func validateTimeout(timeout int, name string) error {
+	if timeout < noTimeout {
+		return fmt.Errorf("invalid timeout %d for %s, it must be a number of seconds, or %d for no timeout",
+			timeout, name, noTimeout)
+	}
+	return nil
+}
+
+// validateTimeouts checks that the timeouts are numbers of seconds, or -1 for
+// no timeout. An op timeout of 0 is rejected as it would be ignored.
+func (opt *DatabaseOptions) validateTimeouts() error {
+	if err := validateTimeout(opt.RequestTimeout, "the requests"); err != nil {
+		return err
+	}
+	for opName, timeout := range opt.OpTimeouts {
+		if timeout == 0 {
+			return fmt.Errorf("invalid timeout 0 for op %s, it must be a number of seconds, or %d for no timeout",
+				opName, noTimeout)
+		}
+		if err := validateTimeout(timeout, "op "+opName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTimeouts attaches the timeouts of the options to ctx, so that they
+// apply to every request sent under it
+func (opt *DatabaseOptions) attachTimeouts(ctx context.Context) context.Context {
+	if (opt.RequestTimeout == 0 && len(opt.OpTimeouts) == 0) || timeoutsFromContext(ctx) != nil {
+		return ctx
+	}
+	timeouts := &timeoutConfig{
+		requestTimeout: opt.RequestTimeout,
+		opTimeouts:     make(map[string]int, len(opt.OpTimeouts)),
+	}
+	for opName, timeout := range opt.OpTimeouts {
+		timeouts.opTimeouts[strings.ToLower(opName)] = timeout
+	}
+	return context.WithValue(ctx, timeoutConfigContextKey{}, timeouts)
+}
+
+func timeoutsFromContext(ctx context.Context) *timeoutConfig {
+	timeouts, _ := ctx.Value(timeoutConfigContextKey{}).(*timeoutConfig)
+	return timeouts
+}
+
+// opTimeout returns the timeout set for the op named opName, if any
+func (timeouts *timeoutConfig) opTimeout(opName string) (int, bool) {
+	if timeouts == nil {
+		return 0, false
+	}
+	timeout, ok := timeouts.opTimeouts[strings.ToLower(opName)]
+	return timeout, ok
+}
+
+// setTimeout sets the timeout of a request of httpRequest. The timeout of a
+// polling op is the one of the polling, so it is not applied to its requests.
+func (timeouts *timeoutConfig) setTimeout(httpRequest *clusterHTTPRequest, request *hostHTTPRequest) {
+	if timeouts == nil {
+		return
+	}
+	if timeout, ok := timeouts.opTimeout(httpRequest.Name); ok && !httpRequest.isPolling {
+		request.Timeout = timeout
+		return
+	}
+	if request.Timeout == 0 {
+		request.Timeout = timeouts.requestTimeout
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+// mockTimeoutAdapter times out on slow hosts, and never responds on hung
+// ones until the context is done
+type mockTimeoutAdapter struct {
+	host string
+	slow bool
+	hung bool
+}
+
+func (m *mockTimeoutAdapter) sendRequest(ctx context.Context, request *hostHTTPRequest, resultChannel chan<- hostHTTPResult) {
+	switch {
+	case m.hung:
+		<-ctx.Done()
+		resultChannel <- hostHTTPResult{host: m.host, status: EXCEPTION, err: ctx.Err()}
+	case m.slow:
+		resultChannel <- hostHTTPResult{host: m.host, status: EXCEPTION, err: os.ErrDeadlineExceeded,
+			timeout: time.Duration(request.Timeout) * time.Second}
+	default:
+		resultChannel <- hostHTTPResult{host: m.host, status: SUCCESS, statusCode: http.StatusOK}
+	}
+}
+
+func (m *mockTimeoutAdapter) generateResult(_ *http.Response) hostHTTPResult {
+	return hostHTTPResult{}
+}
+
+// mockPoller never reaches the state it polls for
+type mockPoller struct {
+	timeout int
+}
+
+func (m *mockPoller) getName() string                         { return "MockPollOp" }
+func (m *mockPoller) getPollingTimeout() int                  { return m.timeout }
+func (m *mockPoller) setPollingTimeout(timeout int)           { m.timeout = timeout }
+func (m *mockPoller) shouldStopPolling() (bool, error)        { return false, nil }
+func (m *mockPoller) runExecute(_ *opEngineExecContext) error { return nil }
+
+func TestRequestTimeouts(t *testing.T) {
+	options := DatabaseOptionsFactory()
+	options.RequestTimeout = 60
+	options.OpTimeouts = map[string]int{"NMADownloadFileOp": 900, "HTTPSPollNodeStateOp": 600}
+	assert.NoError(t, options.validateTimeouts())
+	timeouts := timeoutsFromContext(VClusterCommands{}.setupContext(context.Background(), &options, ""))
+
+	// the global timeout only applies to the requests without one
+	request := hostHTTPRequest{}
+	timeouts.setTimeout(&clusterHTTPRequest{Name: "NMAHealthOp"}, &request)
+	assert.Equal(t, 60, request.Timeout)
+	request = hostHTTPRequest{Timeout: 30}
+	timeouts.setTimeout(&clusterHTTPRequest{Name: "NMAHealthOp"}, &request)
+	assert.Equal(t, 30, request.Timeout)
+
+	// the op timeout takes precedence, whatever the case of the op name
+	request = hostHTTPRequest{Timeout: 30}
+	timeouts.setTimeout(&clusterHTTPRequest{Name: "nmadownloadfileop"}, &request)
+	assert.Equal(t, 900, request.Timeout)
+
+	// the timeout of a polling op is the time it polls for
+	request = hostHTTPRequest{Timeout: 30}
+	timeouts.setTimeout(&clusterHTTPRequest{Name: "HTTPSPollNodeStateOp", isPolling: true}, &request)
+	assert.Equal(t, 30, request.Timeout)
+	op, err := makeHTTPSPollNodeStateOp([]string{"host1"}, false, "", nil)
+	assert.NoError(t, err)
+	timeout, ok := timeouts.opTimeout(op.getName())
+	assert.True(t, ok)
+	op.setPollingTimeout(timeout)
+	assert.Equal(t, 600, op.getPollingTimeout())
+	assert.True(t, op.clusterHTTPRequest.isPolling)
+
+	// invalid timeouts
+	options.OpTimeouts = map[string]int{"NMADownloadFileOp": 0}
+	assert.ErrorContains(t, options.validateTimeouts(), "invalid timeout 0 for op NMADownloadFileOp")
+	options.OpTimeouts = nil
+	options.RequestTimeout = -2
+	assert.ErrorContains(t, options.validateTimeouts(), "invalid timeout -2")
+}
+
+func TestRequestTimeoutErrors(t *testing.T) {
+	pool := makeAdapterPool(vlog.Printer{})
+	httpRequest := &clusterHTTPRequest{Name: "NMAHealthOp", RequestCollection: make(map[string]hostHTTPRequest)}
+	for _, host := range []string{"host1", "host2"} {
+		pool.connections[host] = &mockTimeoutAdapter{host: host, slow: host == "host2"}
+		httpRequest.RequestCollection[host] = hostHTTPRequest{Method: GetMethod, Endpoint: "v1/health"}
+	}
+	options := DatabaseOptionsFactory()
+	options.OpTimeouts = map[string]int{"NMAHealthOp": 5}
+	err := pool.sendRequest(VClusterCommands{}.setupContext(context.Background(), &options, ""), httpRequest, nil)
+	assert.NoError(t, err)
+
+	// the error of the request that timed out names the op and the host
+	result := httpRequest.ResultCollection["host2"]
+	assert.True(t, result.isTimeout())
+	var timeoutErr *RequestTimeoutError
+	assert.True(t, errors.As(result.err, &timeoutErr))
+	assert.Equal(t, "NMAHealthOp", timeoutErr.OpName)
+	assert.Equal(t, "host2", timeoutErr.Host)
+	assert.Equal(t, 5*time.Second, timeoutErr.Timeout)
+	assert.ErrorContains(t, result.err, "[NMAHealthOp] request v1/health to host host2 timed out after 5s")
+	assert.NoError(t, httpRequest.ResultCollection["host1"].err)
+
+	// when the deadline expires first, the hosts that did not respond are named
+	pool.connections["host2"] = &mockTimeoutAdapter{host: "host2", hung: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = pool.sendRequest(ctx, httpRequest, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "no response from hosts [host2]")
+}
+
+func TestPollingTimeouts(t *testing.T) {
+	options := DatabaseOptionsFactory()
+	options.OpTimeouts = map[string]int{"MockPollOp": 1}
+	execContext := makeOpEngineExecContext(VClusterCommands{}.setupContext(context.Background(), &options, ""), vlog.Printer{})
+	poller := &mockPoller{}
+	assert.ErrorContains(t, pollState(poller, &execContext), "[MockPollOp] reached polling timeout of 1 seconds")
+
+	// a poller must time out, so it keeps its own timeout instead of no timeout
+	options.OpTimeouts = map[string]int{"MockPollOp": -1}
+	assert.NoError(t, options.validateTimeouts())
+	// the deadline stops a poller that would poll forever
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	execContext = makeOpEngineExecContext(VClusterCommands{}.setupContext(ctx, &options, ""), vlog.Printer{})
+	poller = &mockPoller{}
+	assert.ErrorContains(t, pollState(poller, &execContext), "[MockPollOp] reached polling timeout of 0 seconds")
+}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -59,7 +60,7 @@ func (opt *VReIPOptions) validateParseOptions(logger vlog.Printer) error {
 		return util.ValidateCommunalStorageLocation(opt.CommunalStorageLocation)
 	}
 
-	return opt.validateBaseOptions("re_ip", logger)
+	return opt.validateBaseOptions(commandReIP, logger)
 }
 
 func (opt *VReIPOptions) analyzeOptions() error {
@@ -120,14 +121,17 @@ func (opt *VReIPOptions) validateAnalyzeOptions(logger vlog.Printer) error {
 
 // VReIP changes the node address, control address, and control broadcast for a node.
 // It returns any error encountered.
-func (vcc VClusterCommands) VReIP(options *VReIPOptions) error {
+func (vcc VClusterCommands) VReIP(ctx context.Context, options *VReIPOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandReIP)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
 	 *   - Give the instructions to the VClusterOpEngine to run
 	 */
 
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
@@ -140,7 +144,7 @@ func (vcc VClusterCommands) VReIP(options *VReIPOptions) error {
 		const warningMsg = " for an Eon database, re_ip after revive_db could fail " +
 			"because we cannot retrieve the correct database information"
 		if options.CommunalStorageLocation != "" {
-			vdb, e := options.getVDBWhenDBIsDown(vcc)
+			vdb, e := options.getVDBWhenDBIsDown(ctx, vcc)
 			if e != nil {
 				// show a warning message if we cannot get VDB from a down database
 				vcc.Log.PrintWarning("failed to retrieve the communal storage location" + warningMsg)
@@ -165,7 +169,7 @@ func (vcc VClusterCommands) VReIP(options *VReIPOptions) error {
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("fail to re-ip: %w", runError)
 	}
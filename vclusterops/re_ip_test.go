@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -73,7 +74,7 @@ func TestTrimReIPList(t *testing.T) {
 	// build a stub exec context
 	log := vlog.Printer{}
 	var op nmaReIPOp
-	execContext := makeOpEngineExecContext(log)
+	execContext := makeOpEngineExecContext(context.Background(), log)
 
 	// build a stub NmaVDatabase
 	nmaVDB := nmaVDatabase{}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -103,16 +104,19 @@ func (o *VRemoveNodeOptions) validateAnalyzeOptions(log vlog.Printer) error {
 	return o.setUsePassword(log)
 }
 
-func (vcc VClusterCommands) VRemoveNode(options *VRemoveNodeOptions) (VCoordinationDatabase, error) {
+func (vcc VClusterCommands) VRemoveNode(ctx context.Context, options *VRemoveNodeOptions) (_ VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, options, commandRemoveNode)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	vdb := makeVCoordinationDatabase()
 
 	// validate and analyze options
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return vdb, err
 	}
 
-	err = vcc.getVDBFromRunningDB(&vdb, &options.DatabaseOptions)
+	err = vcc.getVDBFromRunningDB(ctx, &vdb, &options.DatabaseOptions)
 	if err != nil {
 		return vdb, err
 	}
@@ -136,18 +140,19 @@ func (vcc VClusterCommands) VRemoveNode(options *VRemoveNodeOptions) (VCoordinat
 	var hostsNotInCatalog []string
 	options.HostsToRemove, hostsNotInCatalog = vdb.containNodes(options.HostsToRemove)
 
-	vdb, err = vcc.removeNodesInCatalog(options, &vdb)
+	vdb, err = vcc.removeNodesInCatalog(ctx, options, &vdb)
 	if err != nil || len(hostsNotInCatalog) == 0 {
 		return vdb, err
 	}
 
-	return vcc.handleRemoveNodeForHostsNotInCatalog(&vdb, options, hostsNotInCatalog)
+	return vcc.handleRemoveNodeForHostsNotInCatalog(ctx, &vdb, options, hostsNotInCatalog)
 }
 
 // removeNodesInCatalog will perform the steps to remove nodes. The node list in
 // options.HostsToRemove has already been verified that each node is in the
 // catalog.
-func (vcc VClusterCommands) removeNodesInCatalog(options *VRemoveNodeOptions, vdb *VCoordinationDatabase) (VCoordinationDatabase, error) {
+func (vcc VClusterCommands) removeNodesInCatalog(ctx context.Context,
+	options *VRemoveNodeOptions, vdb *VCoordinationDatabase) (VCoordinationDatabase, error) {
 	if len(options.HostsToRemove) == 0 {
 		vcc.Log.Info("Exit early because there are no hosts to remove")
 		return *vdb, nil
@@ -168,12 +173,12 @@ func (vcc VClusterCommands) removeNodesInCatalog(options *VRemoveNodeOptions, vd
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	if runError := clusterOpEngine.run(vcc.Log); runError != nil {
+	if runError := clusterOpEngine.run(ctx, vcc.Log); runError != nil {
 		// If the machines of the to-be-removed nodes crashed or get killed,
 		// the run error may be ignored.
 		// Here we check whether the to-be-removed nodes are still in the catalog.
 		// If they have been removed from catalog, we let remove_node succeed.
-		if vcc.findRemovedNodesInCatalog(options, remainingHosts) {
+		if vcc.findRemovedNodesInCatalog(ctx, options, remainingHosts) {
 			return *vdb, fmt.Errorf("fail to complete remove node operation, %w", runError)
 		}
 		// If the target nodes have already been removed from catalog,
@@ -189,7 +194,8 @@ func (vcc VClusterCommands) removeNodesInCatalog(options *VRemoveNodeOptions, vd
 // handleRemoveNodeForHostsNotInCatalog will build and execute a list of
 // instructions to do remove of hosts that aren't present in the catalog. We
 // will do basic cleanup logic for this needed by the operator.
-func (vcc VClusterCommands) handleRemoveNodeForHostsNotInCatalog(vdb *VCoordinationDatabase, options *VRemoveNodeOptions,
+func (vcc VClusterCommands) handleRemoveNodeForHostsNotInCatalog(ctx context.Context,
+	vdb *VCoordinationDatabase, options *VRemoveNodeOptions,
 	missingHosts []string) (VCoordinationDatabase, error) {
 	vcc.Log.Info("Doing cleanup of hosts missing from database", "hostsNotInCatalog", missingHosts)
 
@@ -199,7 +205,7 @@ func (vcc VClusterCommands) handleRemoveNodeForHostsNotInCatalog(vdb *VCoordinat
 	instructions := []clusterOp{&nmaGetNodesInfoOp}
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	opEng := makeClusterOpEngine(instructions, &certs)
-	err := opEng.run(vcc.Log)
+	err := opEng.run(ctx, vcc.Log)
 	if err != nil {
 		return *vdb, fmt.Errorf("failed to get node info for missing hosts: %w", err)
 	}
@@ -220,7 +226,7 @@ func (vcc VClusterCommands) handleRemoveNodeForHostsNotInCatalog(vdb *VCoordinat
 	}
 	instructions = []clusterOp{&nmaDeleteDirectoriesOp}
 	opEng = makeClusterOpEngine(instructions, &certs)
-	err = opEng.run(vcc.Log)
+	err = opEng.run(ctx, vcc.Log)
 	if err != nil {
 		return *vdb, fmt.Errorf("failed to delete directories for missing hosts: %w", err)
 	}
@@ -506,7 +512,7 @@ func (o *VRemoveNodeOptions) setInitiator(primaryUpNodes []string) error {
 
 // findRemovedNodesInCatalog checks whether the to-be-removed nodes are still in catalog.
 // Return true if they are still in catalog.
-func (vcc VClusterCommands) findRemovedNodesInCatalog(options *VRemoveNodeOptions,
+func (vcc VClusterCommands) findRemovedNodesInCatalog(ctx context.Context, options *VRemoveNodeOptions,
 	remainingHosts []string) bool {
 	fetchNodeStateOpt := VFetchNodeStateOptionsFactory()
 	fetchNodeStateOpt.DBName = options.DBName
@@ -516,7 +522,7 @@ func (vcc VClusterCommands) findRemovedNodesInCatalog(options *VRemoveNodeOption
 	fetchNodeStateOpt.Password = options.Password
 
 	var nodesInformation nodesInfo
-	res, err := vcc.VFetchNodeState(&fetchNodeStateOpt)
+	res, err := vcc.VFetchNodeState(ctx, &fetchNodeStateOpt)
 	if err != nil {
 		vcc.Log.PrintWarning("Fail to fetch states of the nodes, detail: %v", err)
 		return false
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -44,6 +45,10 @@ func (o *VRemoveScOptions) setDefaultValues() {
 	o.DatabaseOptions.setDefaultValues()
 }
 
+func (o *VRemoveScOptions) subclusterName() string {
+	return o.SubclusterToRemove
+}
+
 func (o *VRemoveScOptions) validateRequiredOptions(logger vlog.Printer) error {
 	err := o.validateBaseOptions("db_remove_subcluster", logger)
 	if err != nil {
@@ -120,18 +125,21 @@ func (o *VRemoveScOptions) validateAnalyzeOptions(logger vlog.Printer) error {
 //  1. Pre-check: check the subcluster name and get nodes for the subcluster.
 //  2. Removes nodes: Optional. If there are any nodes still associated with the subcluster, runs VRemoveNode.
 //  3. Drop the subcluster: Remove the subcluster name from the database catalog.
-func (vcc VClusterCommands) VRemoveSubcluster(removeScOpt *VRemoveScOptions) (VCoordinationDatabase, error) {
+func (vcc VClusterCommands) VRemoveSubcluster(ctx context.Context, removeScOpt *VRemoveScOptions) (_ VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, removeScOpt, commandRemoveCluster)
+	defer func() { vcc.finishCommand(ctx, removeScOpt, err) }()
+
 	vdb := makeVCoordinationDatabase()
 
 	// validate and analyze options
-	err := removeScOpt.validateAnalyzeOptions(vcc.Log)
+	err = removeScOpt.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return vdb, err
 	}
 
 	// pre-check: should not remove the default subcluster
 	vcc.PrintInfo("Performing db_remove_subcluster pre-checks")
-	hostsToRemove, err := vcc.removeScPreCheck(&vdb, removeScOpt)
+	hostsToRemove, err := vcc.removeScPreCheck(ctx, &vdb, removeScOpt)
 	if err != nil {
 		return vdb, err
 	}
@@ -158,7 +166,7 @@ func (vcc VClusterCommands) VRemoveSubcluster(removeScOpt *VRemoveScOptions) (VC
 
 		vcc.Log.PrintInfo("Removing nodes %q from subcluster %s",
 			hostsToRemove, removeScOpt.SubclusterToRemove)
-		vdb, err = vcc.VRemoveNode(&removeNodeOpt)
+		vdb, err = vcc.VRemoveNode(ctx, &removeNodeOpt)
 		if err != nil {
 			return vdb, err
 		}
@@ -166,7 +174,7 @@ func (vcc VClusterCommands) VRemoveSubcluster(removeScOpt *VRemoveScOptions) (VC
 
 	// drop subcluster (i.e., remove the sc name from catalog)
 	vcc.Log.PrintInfo("Removing the subcluster name from catalog")
-	err = vcc.dropSubcluster(&vdb, removeScOpt)
+	err = vcc.dropSubcluster(ctx, &vdb, removeScOpt)
 	if err != nil {
 		return vdb, err
 	}
@@ -189,12 +197,12 @@ func (e *removeDefaultSubclusterError) Error() string {
 // for a successful remove_node:
 //   - Get cluster and nodes info (check if the target DB is Eon and get to-be-removed node list)
 //   - Get the subcluster info (check if the target sc exists and if it is the default sc)
-func (vcc VClusterCommands) removeScPreCheck(vdb *VCoordinationDatabase, options *VRemoveScOptions) ([]string, error) {
+func (vcc VClusterCommands) removeScPreCheck(ctx context.Context, vdb *VCoordinationDatabase, options *VRemoveScOptions) ([]string, error) {
 	var hostsToRemove []string
 	const preCheckErrMsg = "while performing db_remove_subcluster pre-checks"
 
 	// get cluster and nodes info
-	err := vcc.getVDBFromRunningDB(vdb, &options.DatabaseOptions)
+	err := vcc.getVDBFromRunningDB(ctx, vdb, &options.DatabaseOptions)
 	if err != nil {
 		return hostsToRemove, err
 	}
@@ -229,7 +237,7 @@ func (vcc VClusterCommands) removeScPreCheck(vdb *VCoordinationDatabase, options
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		// VER-88585 will improve this rfc error flow
 		if strings.Contains(err.Error(), "does not exist in the database") {
@@ -272,7 +280,7 @@ func (o *VRemoveScOptions) completeVDBSetting(vdb *VCoordinationDatabase) error
 	return nil
 }
 
-func (vcc VClusterCommands) dropSubcluster(vdb *VCoordinationDatabase, options *VRemoveScOptions) error {
+func (vcc VClusterCommands) dropSubcluster(ctx context.Context, vdb *VCoordinationDatabase, options *VRemoveScOptions) error {
 	dropScErrMsg := fmt.Sprintf("fail to drop subcluster %s", options.SubclusterToRemove)
 
 	// the initiator is a list of one primary up host
@@ -296,7 +304,7 @@ func (vcc VClusterCommands) dropSubcluster(vdb *VCoordinationDatabase, options *
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		vcc.Log.Error(err, "fail to drop subcluster, details: %v", dropScErrMsg)
 		return err
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -112,7 +113,10 @@ func (opt *VReplicationDatabaseOptions) validateAnalyzeOptions(logger vlog.Print
 }
 
 // VReplicateDatabase can copy all table data and metadata from this cluster to another
-func (vcc VClusterCommands) VReplicateDatabase(options *VReplicationDatabaseOptions) error {
+func (vcc VClusterCommands) VReplicateDatabase(ctx context.Context, options *VReplicationDatabaseOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandReplicationStart)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -120,7 +124,7 @@ func (vcc VClusterCommands) VReplicateDatabase(options *VReplicationDatabaseOpti
 	 */
 
 	// validate and analyze options
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
@@ -136,7 +140,7 @@ func (vcc VClusterCommands) VReplicateDatabase(options *VReplicationDatabaseOpti
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		if strings.Contains(runError.Error(), "EnableConnectCredentialForwarding is false") {
 			runError = fmt.Errorf("target database authentication failed, need to do one of the following things: " +
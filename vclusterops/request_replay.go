@@ -0,0 +1,472 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vertica/vcluster/rfc7807"
+	"golang.org/x/exp/slices"
+)
+
+const replayBundleVersion = 2
+
+// the kinds of errors a replay bundle tells apart, so that the replayed
+// results fail the same way as the recorded ones
+const (
+	replayErrorProblem = "problem"
+	replayErrorTimeout = "timeout"
+)
+
+// replayBundle is the on-disk record of the requests the ops of a command
+// sent and of the results the hosts returned. A bundle recorded where a
+// command failed can be replayed elsewhere, without the cluster, to run the
+// ops through the same results. The file is in JSON lines: a header, then
+// one exchange per line, in the order they were recorded.
+type replayBundle struct {
+	path string
+	// when recording, the temporary file each exchange is appended to, until
+	// the command ends and the file is renamed to path
+	file *os.File
+	// set once the bundle has been saved to path
+	saved bool
+	// set once the bundle could not be recorded, to stop trying
+	saveFailed bool
+	saveErr    error
+	// when replaying, the exchanges not replayed yet by op name and host
+	queues  map[string][]replayExchange
+	loadErr error
+	once    sync.Once
+	mu      sync.Mutex
+}
+
+// replayBundleHeader is the first line of a replay bundle
+type replayBundleHeader struct {
+	Version int `json:"version"`
+}
+
+// replayExchange is one request sent to a host and its result. The values
+// that may hold a password, a key or a credential are redacted.
+type replayExchange struct {
+	OpName       string            `json:"op_name"`
+	Host         string            `json:"host"`
+	Method       string            `json:"method"`
+	Endpoint     string            `json:"endpoint"`
+	IsNMACommand bool              `json:"is_nma_command"`
+	QueryParams  map[string]string `json:"query_params,omitempty"`
+	RequestData  string            `json:"request_data,omitempty"`
+	Status       string            `json:"status"`
+	StatusCode   int               `json:"status_code,omitempty"`
+	Content      string            `json:"content,omitempty"`
+	Error        string            `json:"error,omitempty"`
+	ErrorKind    string            `json:"error_kind,omitempty"`
+	Attempts     int               `json:"attempts,omitempty"`
+	Timeout      time.Duration     `json:"timeout,omitempty"`
+}
+
+// ReplayDivergedError is returned when a command replayed from a bundle
+// sends a request that the recorded run did not send at that point
+type ReplayDivergedError struct {
+	Path     string
+	OpName   string
+	Host     string
+	Method   string
+	Endpoint string
+	// Expected is the request of the bundle, as "<method> <endpoint>", or
+	// "" if the bundle has no more requests of the op to the host
+	Expected string
+}
+
+func (e *ReplayDivergedError) Error() string {
+	if e.Expected == "" {
+		return fmt.Sprintf("replay bundle %s has no more requests of %s to host %s, but the op sent %s %s",
+			e.Path, e.OpName, e.Host, e.Method, e.Endpoint)
+	}
+	return fmt.Sprintf("replay bundle %s has %s as the next request of %s to host %s, but the op sent %s %s",
+		e.Path, e.Expected, e.OpName, e.Host, e.Method, e.Endpoint)
+}
+
+// exhausted is true when the recorded run sent no more requests of the op
+// to the host
+func (e *ReplayDivergedError) exhausted() bool {
+	return e.Expected == ""
+}
+
+type recordingContextKey struct{}
+type replayContextKey struct{}
+
+// ContextWithRecording returns a copy of ctx that makes the op engines run
+// under it save every request they send and its result to the replay bundle
+// at path. Several V* commands run under the same context share the bundle.
+func ContextWithRecording(ctx context.Context, path string) context.Context {
+	bundle := &replayBundle{path: path}
+	return context.WithValue(ctx, recordingContextKey{}, bundle)
+}
+
+// ContextWithReplay returns a copy of ctx under which the op engines do not
+// send their requests, but get the results saved in the replay bundle at
+// path instead. The results of an op are replayed host by host, in the
+// order they were recorded, and the polling ops do not wait between polls.
+func ContextWithReplay(ctx context.Context, path string) context.Context {
+	bundle := &replayBundle{path: path}
+	return context.WithValue(ctx, replayContextKey{}, bundle)
+}
+
+// attachReplayBundle attaches the bundle the options record to, or replay
+// from, to ctx, unless ctx already carries one
+func (opt *DatabaseOptions) attachReplayBundle(ctx context.Context) context.Context {
+	if recordingFromContext(ctx) != nil || replayFromContext(ctx) != nil {
+		return ctx
+	}
+	if opt.ReplayPath != "" {
+		return ContextWithReplay(ctx, opt.ReplayPath)
+	}
+	if opt.RecordPath != "" {
+		return ContextWithRecording(ctx, opt.RecordPath)
+	}
+	return ctx
+}
+
+func (opt *DatabaseOptions) validateReplay() error {
+	if opt.RecordPath != "" && opt.ReplayPath != "" {
+		return fmt.Errorf("cannot record a command while it is replayed")
+	}
+	return nil
+}
+
+func recordingFromContext(ctx context.Context) *replayBundle {
+	bundle, _ := ctx.Value(recordingContextKey{}).(*replayBundle)
+	return bundle
+}
+
+func replayFromContext(ctx context.Context) *replayBundle {
+	bundle, _ := ctx.Value(replayContextKey{}).(*replayBundle)
+	return bundle
+}
+
+// record appends a request and its result to the bundle being recorded.
+// The bundle is best effort: a failure to record it does not fail the
+// command.
+func (bundle *replayBundle) record(opName string, request *hostHTTPRequest, result *hostHTTPResult) {
+	if bundle == nil {
+		return
+	}
+	exchange := replayExchange{
+		OpName:       opName,
+		Host:         result.host,
+		Method:       request.Method,
+		Endpoint:     request.Endpoint,
+		IsNMACommand: request.IsNMACommand,
+		QueryParams:  redactQueryParams(request.QueryParams),
+		RequestData:  redactBody(request.RequestData),
+		Status:       result.status.String(),
+		StatusCode:   result.statusCode,
+		Content:      redactBody(result.content),
+		Attempts:     result.attempts,
+		Timeout:      result.timeout,
+	}
+	if result.err != nil {
+		exchange.Error = result.err.Error()
+		problem := &rfc7807.VProblem{}
+		if errors.As(result.err, &problem) {
+			exchange.ErrorKind = replayErrorProblem
+		} else if result.isTimeout() {
+			exchange.ErrorKind = replayErrorTimeout
+		}
+	}
+	data, err := json.Marshal(&exchange)
+	if err != nil {
+		return
+	}
+
+	bundle.mu.Lock()
+	defer bundle.mu.Unlock()
+	if bundle.saveFailed {
+		return
+	}
+	if bundle.file == nil {
+		err = bundle.openTempFile()
+	}
+	if err == nil {
+		_, err = bundle.file.Write(append(data, '\n'))
+	}
+	bundle.setSaveErr(err)
+}
+
+func (bundle *replayBundle) tempPath() string {
+	return bundle.path + ".tmp"
+}
+
+// openTempFile starts the temporary file the exchanges are appended to. It
+// starts with the bundle saved by an earlier command of the recording, if
+// any, or else with the header.
+func (bundle *replayBundle) openTempFile() error {
+	f, err := os.OpenFile(bundle.tempPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, journalFilePerm)
+	if err != nil {
+		return fmt.Errorf("fail to create replay bundle %s: %w", bundle.tempPath(), err)
+	}
+	if bundle.saved {
+		err = copyFile(f, bundle.path)
+	} else {
+		err = json.NewEncoder(f).Encode(replayBundleHeader{Version: replayBundleVersion})
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("fail to write replay bundle %s: %w", bundle.tempPath(), err)
+	}
+	bundle.file = f
+	return nil
+}
+
+func copyFile(dst *os.File, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// setSaveErr stops the recording once the bundle could not be written, and
+// keeps the error for save to report
+func (bundle *replayBundle) setSaveErr(err error) {
+	if err == nil {
+		return
+	}
+	bundle.saveFailed = true
+	bundle.saveErr = err
+	if bundle.file != nil {
+		bundle.file.Close()
+		bundle.file = nil
+	}
+}
+
+// save renames the temporary file to the bundle, once the command ends, so
+// that the bundle is never a truncated file. The commands that run next
+// under the same recording add their exchanges to it.
+func (bundle *replayBundle) save() error {
+	if bundle == nil {
+		return nil
+	}
+	bundle.mu.Lock()
+	defer bundle.mu.Unlock()
+	if bundle.file == nil {
+		return bundle.saveErr
+	}
+	err := bundle.file.Sync()
+	if closeErr := bundle.file.Close(); err == nil {
+		err = closeErr
+	}
+	bundle.file = nil
+	if err == nil {
+		err = os.Rename(bundle.tempPath(), bundle.path)
+	}
+	if err != nil {
+		bundle.setSaveErr(fmt.Errorf("fail to save replay bundle %s: %w", bundle.path, err))
+		return bundle.saveErr
+	}
+	bundle.saved = true
+	return nil
+}
+
+// saveRecording saves the bundle recorded under ctx at the end of the outer
+// command. The command has already run, so failing to save the bundle is
+// only warned about.
+func (vcc VClusterCommands) saveRecording(ctx context.Context) {
+	if run := commandRunFromContext(ctx); run == nil || run.outer != nil {
+		return
+	}
+	if err := recordingFromContext(ctx).save(); err != nil {
+		vcc.Log.PrintWarning("The replay bundle could not be recorded, details: %v", err)
+	}
+}
+
+// load reads the bundle file the first time it is called
+func (bundle *replayBundle) load() error {
+	bundle.once.Do(func() {
+		f, err := os.Open(bundle.path)
+		if err != nil {
+			bundle.loadErr = fmt.Errorf("fail to read replay bundle %s: %w", bundle.path, err)
+			return
+		}
+		defer f.Close()
+		decoder := json.NewDecoder(f)
+		var header replayBundleHeader
+		if err = decoder.Decode(&header); err != nil {
+			bundle.loadErr = fmt.Errorf("fail to parse replay bundle %s: %w", bundle.path, err)
+			return
+		}
+		if header.Version != replayBundleVersion {
+			bundle.loadErr = fmt.Errorf("replay bundle %s has version %d, only version %d is supported",
+				bundle.path, header.Version, replayBundleVersion)
+			return
+		}
+		bundle.queues = make(map[string][]replayExchange)
+		for {
+			var exchange replayExchange
+			err = decoder.Decode(&exchange)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				bundle.loadErr = fmt.Errorf("fail to parse replay bundle %s: %w", bundle.path, err)
+				return
+			}
+			key := replayQueueKey(exchange.OpName, exchange.Host)
+			bundle.queues[key] = append(bundle.queues[key], exchange)
+		}
+	})
+	return bundle.loadErr
+}
+
+func replayQueueKey(opName, host string) string {
+	return opName + " " + host
+}
+
+// next returns the result of the next recorded request of the op to host,
+// which must be the same request as the one the op sends now. The ops pick
+// their initiator among the up hosts in no fixed order, so if the op sends
+// its request to a single host, it can get the result of another host when
+// host has none.
+func (bundle *replayBundle) next(opName, host string, request *hostHTTPRequest,
+	singleHost bool) (hostHTTPResult, error) {
+	if err := bundle.load(); err != nil {
+		return hostHTTPResult{}, err
+	}
+	bundle.mu.Lock()
+	defer bundle.mu.Unlock()
+	diverged := &ReplayDivergedError{Path: bundle.path, OpName: opName, Host: host,
+		Method: request.Method, Endpoint: request.Endpoint}
+	key := replayQueueKey(opName, host)
+	queue := bundle.queues[key]
+	if len(queue) == 0 && singleHost {
+		key = bundle.otherHostKey(opName, request)
+		queue = bundle.queues[key]
+	}
+	if len(queue) == 0 {
+		return hostHTTPResult{}, diverged
+	}
+	exchange := queue[0]
+	if exchange.Method != request.Method || exchange.Endpoint != request.Endpoint {
+		diverged.Expected = exchange.Method + " " + exchange.Endpoint
+		return hostHTTPResult{}, diverged
+	}
+	bundle.queues[key] = queue[1:]
+	result := exchange.result()
+	result.host = host
+	return result, nil
+}
+
+// otherHostKey returns the key of the first queue, by host, whose next
+// exchange is the request of the op, or "" if there is none
+func (bundle *replayBundle) otherHostKey(opName string, request *hostHTTPRequest) string {
+	var keys []string
+	for key, queue := range bundle.queues {
+		if len(queue) > 0 && queue[0].OpName == opName &&
+			queue[0].Method == request.Method && queue[0].Endpoint == request.Endpoint {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	slices.Sort(keys)
+	return keys[0]
+}
+
+// result rebuilds the result of the host from the exchange
+func (exchange *replayExchange) result() hostHTTPResult {
+	result := hostHTTPResult{
+		status:     FAILURE,
+		statusCode: exchange.StatusCode,
+		host:       exchange.Host,
+		content:    exchange.Content,
+		attempts:   exchange.Attempts,
+		timeout:    exchange.Timeout,
+	}
+	for _, status := range []resultStatus{SUCCESS, FAILURE, EXCEPTION} {
+		if status.String() == exchange.Status {
+			result.status = status
+		}
+	}
+	if exchange.Error == "" {
+		return result
+	}
+	switch exchange.ErrorKind {
+	case replayErrorProblem:
+		result.err = rfc7807.GenerateErrorFromResponse(exchange.Content)
+	case replayErrorTimeout:
+		result.err = &timeoutError{message: exchange.Error}
+	default:
+		result.err = errors.New(exchange.Error)
+	}
+	return result
+}
+
+// replayAdapter is the adapter of a host when replaying: it returns the
+// results of the bundle instead of sending the requests
+type replayAdapter struct {
+	bundle *replayBundle
+	opName string
+	host   string
+	// true if the op sends its request to this host only
+	singleHost bool
+}
+
+func (adapter *replayAdapter) sendRequest(_ context.Context, request *hostHTTPRequest,
+	resultChannel chan<- hostHTTPResult) {
+	result, err := adapter.bundle.next(adapter.opName, adapter.host, request, adapter.singleHost)
+	if err != nil {
+		result = hostHTTPResult{status: EXCEPTION, host: adapter.host, err: err}
+	}
+	resultChannel <- result
+}
+
+func (adapter *replayAdapter) generateResult(*http.Response) hostHTTPResult {
+	return hostHTTPResult{status: EXCEPTION, host: adapter.host,
+		err: fmt.Errorf("a replayed request has no response")}
+}
+
+// redactQueryParams masks the values of the query parameters that may hold
+// a password, a key or a credential
+func redactQueryParams(params map[string]string) map[string]string {
+	if len(params) == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, len(params))
+	for key, value := range params {
+		if isSensitiveKey(key) {
+			value = redactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// isReplaying is true when the requests of the op engines run under ctx are
+// replayed from a bundle
+func isReplaying(ctx context.Context) bool {
+	return replayFromContext(ctx) != nil
+}
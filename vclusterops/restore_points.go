@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -142,7 +143,11 @@ func (opt *VShowRestorePointsOptions) validateAnalyzeOptions(logger vlog.Printer
 }
 
 // VShowRestorePoints can query the restore points from an archive
-func (vcc VClusterCommands) VShowRestorePoints(options *VShowRestorePointsOptions) (restorePoints []RestorePoint, err error) {
+func (vcc VClusterCommands) VShowRestorePoints(ctx context.Context,
+	options *VShowRestorePointsOptions) (restorePoints []RestorePoint, err error) {
+	ctx = vcc.setupContext(ctx, options, commandShowRestorePoints)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -166,7 +171,7 @@ func (vcc VClusterCommands) VShowRestorePoints(options *VShowRestorePointsOption
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return restorePoints, fmt.Errorf("fail to show restore points: %w", runError)
 	}
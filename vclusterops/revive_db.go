@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strconv"
@@ -190,7 +191,11 @@ func (options *VReviveDatabaseOptions) validateAnalyzeOptions() error {
 
 // VReviveDatabase revives a database that was terminated but whose communal storage data still exists.
 // It returns the database information retrieved from communal storage and any error encountered.
-func (vcc VClusterCommands) VReviveDatabase(options *VReviveDatabaseOptions) (dbInfo string, vdbPtr *VCoordinationDatabase, err error) {
+func (vcc VClusterCommands) VReviveDatabase(ctx context.Context,
+	options *VReviveDatabaseOptions) (dbInfo string, vdbPtr *VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, options, commandReviveDB)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Validate options
 	 *   - Run VClusterOpEngine to get terminated database info
@@ -215,7 +220,7 @@ func (vcc VClusterCommands) VReviveDatabase(options *VReviveDatabaseOptions) (db
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	// feed the pre-revive db instructions to the VClusterOpEngine
 	clusterOpEngine := makeClusterOpEngine(preReviveDBInstructions, &certs)
-	err = clusterOpEngine.run(vcc.GetLog())
+	err = clusterOpEngine.run(ctx, vcc.GetLog())
 	if err != nil {
 		return dbInfo, nil, fmt.Errorf("fail to collect the information of database in revive_db %w", err)
 	}
@@ -233,7 +238,7 @@ func (vcc VClusterCommands) VReviveDatabase(options *VReviveDatabaseOptions) (db
 
 		// feed the restore db specific instructions to the VClusterOpEngine
 		clusterOpEngine = makeClusterOpEngine(restoreDBSpecificInstructions, &certs)
-		runErr := clusterOpEngine.run(vcc.GetLog())
+		runErr := clusterOpEngine.run(ctx, vcc.GetLog())
 		if runErr != nil {
 			return dbInfo, &vdb, fmt.Errorf("fail to collect the restore-specific information of database in revive_db %w", runErr)
 		}
@@ -252,7 +257,7 @@ func (vcc VClusterCommands) VReviveDatabase(options *VReviveDatabaseOptions) (db
 
 	// feed revive db instructions to the VClusterOpEngine
 	clusterOpEngine = makeClusterOpEngine(reviveDBInstructions, &certs)
-	err = clusterOpEngine.run(vcc.GetLog())
+	err = clusterOpEngine.run(ctx, vcc.GetLog())
 	if err != nil {
 		return dbInfo, &vdb, fmt.Errorf("fail to revive database %w", err)
 	}
@@ -289,6 +294,8 @@ func (vcc VClusterCommands) producePreReviveDBInstructions(options *VReviveDatab
 	if err != nil {
 		return instructions, err
 	}
+	nmaHealthOp.setDependencies()
+	checkDBRunningOp.setDependencies()
 	instructions = append(instructions,
 		&nmaHealthOp,
 		&checkDBRunningOp,
@@ -412,6 +419,9 @@ func (vcc VClusterCommands) produceReviveDBInstructions(options *VReviveDatabase
 	nmaLoadRemoteCatalogOp := makeNMALoadRemoteCatalogOp(oldHosts, options.ConfigurationParameters,
 		&newVDB, options.LoadCatalogTimeout, &options.RestorePoint)
 
+	// the network profiles do not depend on the directories
+	nmaPrepareDirectoriesOp.setDependencies()
+	nmaNetworkProfileOp.setDependencies()
 	instructions = append(instructions,
 		&nmaPrepareDirectoriesOp,
 		&nmaNetworkProfileOp,
@@ -0,0 +1,79 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"fmt"
+)
+
+// VRollbackOptions represents the available options for VRollback. The
+// journal to roll back is given by DatabaseOptions.JournalPath.
+type VRollbackOptions struct {
+	DatabaseOptions
+}
+
+func VRollbackOptionsFactory() VRollbackOptions {
+	opt := VRollbackOptions{}
+	// set default values to the params
+	opt.setDefaultValues()
+
+	return opt
+}
+
+// VRollback undoes the changes of the ops saved in a journal, starting from
+// the last one. Each op is removed from the journal once it is undone, so a
+// rollback that fails part way can be run again. Ops that did not change the
+// cluster, or whose changes cannot be undone, are removed without sending
+// anything.
+func (vcc VClusterCommands) VRollback(ctx context.Context, options *VRollbackOptions) (err error) {
+	if options.JournalPath == "" {
+		return fmt.Errorf("must specify the journal to roll back")
+	}
+	ctx = vcc.setupContext(ctx, options, commandRollback)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+	// the journal is read below rather than attached to the context, so the
+	// rollback ops are not added to it
+	ctx = contextWithoutJournal(ctx)
+
+	journal, err := readJournal(options.JournalPath)
+	if err != nil {
+		return err
+	}
+
+	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
+	for i := len(journal.Entries) - 1; i >= 0; i-- {
+		entry := &journal.Entries[i]
+		instructions := makeJournalRollbackInstructions(entry, options.UserName, options.Password)
+		if len(instructions) > 0 {
+			clusterOpEngine := makeClusterOpEngine(instructions, &certs)
+			err = clusterOpEngine.run(ctx, vcc.Log)
+			if err != nil {
+				return fmt.Errorf("fail to roll back %s, %w", entry.OpName, err)
+			}
+		}
+		if isPlanMode(ctx) {
+			continue
+		}
+		journal.Entries = journal.Entries[:i]
+		err = journal.save()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
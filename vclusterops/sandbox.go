@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -40,6 +41,10 @@ func (options *VSandboxOptions) setDefaultValues() {
 	options.DatabaseOptions.setDefaultValues()
 }
 
+func (options *VSandboxOptions) subclusterName() string {
+	return options.SCName
+}
+
 func (options *VSandboxOptions) validateRequiredOptions(logger vlog.Printer) error {
 	err := options.validateBaseOptions("sandbox_subcluster", logger)
 	if err != nil {
@@ -150,20 +155,23 @@ func (vcc *VClusterCommands) produceSandboxSubclusterInstructions(options *VSand
 	return instructions, nil
 }
 
-func (vcc VClusterCommands) VSandbox(options *VSandboxOptions) error {
+func (vcc VClusterCommands) VSandbox(ctx context.Context, options *VSandboxOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandSandboxSC)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	vcc.Log.V(0).Info("VSandbox method called", "options", options)
-	return runSandboxCmd(vcc, options)
+	return runSandboxCmd(ctx, vcc, options)
 }
 
 // sandboxInterface is an interface that will be used by runSandboxCmd().
 // The purpose of this interface is to avoid code duplication.
 type sandboxInterface interface {
 	ValidateAnalyzeOptions(vcc VClusterCommands) error
-	runCommand(vcc VClusterCommands) error
+	runCommand(ctx context.Context, vcc VClusterCommands) error
 }
 
 // runCommand will produce instructions and run them
-func (options *VSandboxOptions) runCommand(vcc VClusterCommands) error {
+func (options *VSandboxOptions) runCommand(ctx context.Context, vcc VClusterCommands) error {
 	// make instructions
 	instructions, err := vcc.produceSandboxSubclusterInstructions(options)
 	if err != nil {
@@ -175,7 +183,7 @@ func (options *VSandboxOptions) runCommand(vcc VClusterCommands) error {
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// run the engine
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("fail to sandbox subcluster %s, %w", options.SCName, runError)
 	}
@@ -184,7 +192,7 @@ func (options *VSandboxOptions) runCommand(vcc VClusterCommands) error {
 
 // runSandboxCmd is a help function to run sandbox/unsandbox command.
 // It can avoid code duplication between VSandbox and VUnsandbox.
-func runSandboxCmd(vcc VClusterCommands, i sandboxInterface) error {
+func runSandboxCmd(ctx context.Context, vcc VClusterCommands, i sandboxInterface) error {
 	// check required options
 	err := i.ValidateAnalyzeOptions(vcc)
 	if err != nil {
@@ -192,5 +200,5 @@ func runSandboxCmd(vcc VClusterCommands, i sandboxInterface) error {
 		return err
 	}
 
-	return i.runCommand(vcc)
+	return i.runCommand(ctx, vcc)
 }
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -57,6 +58,9 @@ type VScrutinizeOptions struct {
 	LogAgeOldestTime            string
 	LogAgeNewestTime            string
 	LogAgeHours                 int // max log age from input
+	// BatchSize is the number of hosts the files are staged on and
+	// downloaded from at a time, 0 for all of them at once
+	BatchSize int
 
 	timeFormats    []util.TimeFormat // generated by factory
 	logAgeMaxHours int               // calculated from exported log age options
@@ -148,6 +152,10 @@ func (options *VScrutinizeOptions) validateRequiredOptions(logger vlog.Printer)
 		return err
 	}
 
+	if options.BatchSize < 0 {
+		return fmt.Errorf("invalid batch size %d, must not be negative", options.BatchSize)
+	}
+
 	// RawHosts is already required by the cmd parser, so no need to check here
 	// check if catalog prefix in user input is correct
 	return options.validateCatalogPath()
@@ -185,9 +193,12 @@ func (options *VScrutinizeOptions) ValidateAnalyzeOptions(logger vlog.Printer) e
 	return options.analyzeOptions(logger)
 }
 
-func (vcc VClusterCommands) VScrutinize(options *VScrutinizeOptions) error {
+func (vcc VClusterCommands) VScrutinize(ctx context.Context, options *VScrutinizeOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, VScrutinizeTypeName)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	// check required options (including those that can come from cluster config)
-	err := options.ValidateAnalyzeOptions(vcc.Log)
+	err = options.ValidateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		vcc.Log.Error(err, "validation of scrutinize arguments failed")
 		return err
@@ -197,7 +208,7 @@ func (vcc VClusterCommands) VScrutinize(options *VScrutinizeOptions) error {
 	// 1. slice of nodes with NMA running
 	// 2. host -> node info map
 	vdb := makeVCoordinationDatabase()
-	err = options.getVDBForScrutinize(vcc.Log, &vdb)
+	err = options.getVDBForScrutinize(ctx, vcc.Log, &vdb)
 	if err != nil {
 		vcc.Log.Error(err, "failed to retrieve cluster info for scrutinize")
 		return err
@@ -211,12 +222,17 @@ func (vcc VClusterCommands) VScrutinize(options *VScrutinizeOptions) error {
 		vcc.Log.Error(err, "failed to produce instructions for scrutinize")
 		return err
 	}
-	err = options.runClusterOpEngine(vcc.Log, instructions)
+	err = options.runClusterOpEngine(ctx, vcc.Log, instructions)
 	if err != nil {
 		vcc.Log.Error(err, "failed to run scrutinize operations")
 		return err
 	}
 
+	// in plan mode no files were staged, so there is nothing to tar
+	if isPlanMode(ctx) {
+		return nil
+	}
+
 	// add vcluster log to output
 	options.stageVclusterLog(options.ID, vcc.Log)
 
@@ -272,11 +288,11 @@ func tarAndRemoveDirectory(tarballName, id string, log vlog.Printer) (err error)
 
 // getVDBForScrutinize populates an empty coordinator database with the minimum
 // required information for further scrutinize operations.
-func (options *VScrutinizeOptions) getVDBForScrutinize(logger vlog.Printer,
+func (options *VScrutinizeOptions) getVDBForScrutinize(ctx context.Context, logger vlog.Printer,
 	vdb *VCoordinationDatabase) error {
 	// get nodes where NMA is running and only use those for NMA ops
 	getHealthyNodesOp := makeNMAGetHealthyNodesOp(options.Hosts, vdb)
-	err := options.runClusterOpEngine(logger, []clusterOp{&getHealthyNodesOp})
+	err := options.runClusterOpEngine(ctx, logger, []clusterOp{&getHealthyNodesOp})
 	if err != nil {
 		return err
 	}
@@ -284,7 +300,7 @@ func (options *VScrutinizeOptions) getVDBForScrutinize(logger vlog.Printer,
 	// get map of host to node name and fully qualified catalog path
 	getNodesInfoOp := makeNMAGetNodesInfoOp(vdb.HostList, options.DBName,
 		options.CatalogPrefix, true /* ignore internal errors */, vdb)
-	err = options.runClusterOpEngine(logger, []clusterOp{&getNodesInfoOp})
+	err = options.runClusterOpEngine(ctx, logger, []clusterOp{&getNodesInfoOp})
 	if err != nil {
 		return err
 	}
@@ -407,9 +423,20 @@ func (vcc VClusterCommands) produceScrutinizeInstructions(options *VScrutinizeOp
 	getSystemTablesTarballOp.useSingleHost()
 	instructions = append(instructions, &getSystemTablesTarballOp)
 
+	setScrutinizeBatchSize(instructions, options.BatchSize)
 	return instructions, nil
 }
 
+// setScrutinizeBatchSize makes the ops that stage or download the files of
+// every host send their requests to batchSize hosts at a time
+func setScrutinizeBatchSize(instructions []clusterOp, batchSize int) {
+	for _, op := range instructions {
+		if batchedOp, ok := op.(interface{ setBatchSize(int) }); ok {
+			batchedOp.setBatchSize(batchSize)
+		}
+	}
+}
+
 func getNodeInfoForScrutinize(hosts []string, vdb *VCoordinationDatabase,
 ) (hostNodeNameMap, hostCatPathMap map[string]string, err error) {
 	hostNodeNameMap = make(map[string]string)
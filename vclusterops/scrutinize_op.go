@@ -34,6 +34,14 @@ type scrutinizeOpBase struct {
 	hostNodeNameMap    map[string]string // must correspond to host list exactly!
 	hostCatPathMap     map[string]string // must correspond to host list exactly, if non-nil
 	hostRequestBodyMap map[string]string // should be nil if not used
+	batchSize          int               // hosts the requests are sent to at a time, 0 for all of them
+}
+
+// setBatchSize makes the op send its requests to batchSize hosts at a time,
+// so that staging or downloading the files of a large cluster does not
+// saturate the initiator and the network
+func (op *scrutinizeOpBase) setBatchSize(batchSize int) {
+	op.batchSize = batchSize
 }
 
 func (op *scrutinizeOpBase) setupClusterHTTPRequest(hosts []string) error {
@@ -48,6 +56,7 @@ func (op *scrutinizeOpBase) setupClusterHTTPRequest(hosts []string) error {
 		}
 		op.clusterHTTPRequest.RequestCollection[host] = httpRequest
 	}
+	op.clusterHTTPRequest.BatchSize = op.batchSize
 
 	return nil
 }
@@ -17,6 +17,7 @@ package vclusterops
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -148,3 +149,29 @@ func TestSetLogAgeRange(t *testing.T) {
 	assert.ErrorContains(t, err, "invalid time range: max log age cannot be less than min log age")
 	assert.Contains(t, logBuf.String(), "invalid log age range")
 }
+
+func TestScrutinizeBatchSize(t *testing.T) {
+	hosts := []string{"host00", "host01", "host02", "host03", "host04"}
+	hostNodeNameMap := make(map[string]string)
+	hostCatPathMap := make(map[string]string)
+	for i, host := range hosts {
+		hostNodeNameMap[host] = fmt.Sprintf("v_test_db_node%04d", i+1)
+		hostCatPathMap[host] = "/catalog/test_db/" + hostNodeNameMap[host] + "_catalog"
+	}
+	op, err := makeNMAStageDCTablesOp("VerticaScrutinize.20240101000000", hosts, hostNodeNameMap, hostCatPathMap)
+	assert.NoError(t, err)
+	getUpNodesOp, err := makeHTTPSGetUpNodesOp("test_db", hosts, false, "", nil, ScrutinizeCmd)
+	assert.NoError(t, err)
+	setScrutinizeBatchSize([]clusterOp{&getUpNodesOp, &op}, 2)
+
+	// the files of the hosts are staged two hosts at a time
+	execContext := makeOpEngineExecContext(context.Background(), vlog.Printer{})
+	op.setupBasicInfo()
+	assert.NoError(t, op.prepare(&execContext))
+	pool, _, tracker := makeMockPool(len(hosts))
+	execContext.dispatcher.pool = pool
+	assert.NoError(t, op.execute(&execContext))
+	assert.Len(t, op.clusterHTTPRequest.ResultCollection, len(hosts))
+	assert.Equal(t, 2, tracker.maxInFlight)
+	assert.ElementsMatch(t, hosts[:2], tracker.sent[:2])
+}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -101,7 +102,10 @@ func (options *VStartDatabaseOptions) validateAnalyzeOptions(logger vlog.Printer
 	return options.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VStartDatabase(options *VStartDatabaseOptions) (vdbPtr *VCoordinationDatabase, err error) {
+func (vcc VClusterCommands) VStartDatabase(ctx context.Context, options *VStartDatabaseOptions) (vdbPtr *VCoordinationDatabase, err error) {
+	ctx = vcc.setupContext(ctx, options, commandStartDB)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create VClusterOpEngine
@@ -124,7 +128,7 @@ func (vcc VClusterCommands) VStartDatabase(options *VStartDatabaseOptions) (vdbP
 		const warningMsg = " for an Eon database, start_db after revive_db could fail " +
 			"because we cannot retrieve the correct database information"
 		if options.CommunalStorageLocation != "" {
-			vdbNew, e := options.getVDBWhenDBIsDown(vcc)
+			vdbNew, e := options.getVDBWhenDBIsDown(ctx, vcc)
 			if e != nil {
 				// show a warning message if we cannot get VDB from a down database
 				vcc.Log.PrintWarning("failed to retrieve the communal storage location" + warningMsg)
@@ -141,7 +145,7 @@ func (vcc VClusterCommands) VStartDatabase(options *VStartDatabaseOptions) (vdbP
 	}
 
 	// start_db pre-checks and get basic info
-	err = vcc.runStartDBPrecheck(options, &vdb)
+	err = vcc.runStartDBPrecheck(ctx, options, &vdb)
 	if err != nil {
 		return nil, err
 	}
@@ -157,14 +161,19 @@ func (vcc VClusterCommands) VStartDatabase(options *VStartDatabaseOptions) (vdbP
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return nil, fmt.Errorf("fail to start database: %w", runError)
 	}
 
+	// in plan mode the database was not started, so there is nothing to read back
+	if isPlanMode(ctx) {
+		return &vdb, nil
+	}
+
 	// get vdb info from the running database
 	var updatedVDB VCoordinationDatabase
-	err = vcc.getVDBFromRunningDBIncludeSandbox(&updatedVDB, &options.DatabaseOptions, AnySandbox)
+	err = vcc.getVDBFromRunningDBIncludeSandbox(ctx, &updatedVDB, &options.DatabaseOptions, AnySandbox)
 	if err != nil {
 		return nil, err
 	}
@@ -172,7 +181,7 @@ func (vcc VClusterCommands) VStartDatabase(options *VStartDatabaseOptions) (vdbP
 	return &updatedVDB, nil
 }
 
-func (vcc VClusterCommands) runStartDBPrecheck(options *VStartDatabaseOptions, vdb *VCoordinationDatabase) error {
+func (vcc VClusterCommands) runStartDBPrecheck(ctx context.Context, options *VStartDatabaseOptions, vdb *VCoordinationDatabase) error {
 	// pre-instruction to perform basic checks and get basic information
 	preInstructions, err := vcc.produceStartDBPreCheck(options, vdb, options.TrimHostList)
 	if err != nil {
@@ -182,7 +191,7 @@ func (vcc VClusterCommands) runStartDBPrecheck(options *VStartDatabaseOptions, v
 	// create a VClusterOpEngine for pre-check, and add certs to the engine
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(preInstructions, &certs)
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("fail to start database pre-checks: %w", runError)
 	}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -73,7 +74,7 @@ func (options *VStartNodesOptions) setDefaultValues() {
 }
 
 func (options *VStartNodesOptions) validateParseOptions(logger vlog.Printer) error {
-	return options.validateBaseOptions("restart_node", logger)
+	return options.validateBaseOptions(commandRestartNode, logger)
 }
 
 // analyzeOptions will modify some options based on what is chosen
@@ -141,7 +142,10 @@ func (vcc VClusterCommands) startNodePreCheck(vdb *VCoordinationDatabase, option
 // node's IP in the Vertica catalog. If cluster quorum is already lost, use
 // VStartDatabase. It will skip any nodes given that no longer exist in the
 // catalog.
-func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
+func (vcc VClusterCommands) VStartNodes(ctx context.Context, options *VStartNodesOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandRestartNode)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -149,7 +153,7 @@ func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
 	 */
 
 	// validate and analyze options
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
@@ -158,7 +162,7 @@ func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
 	// if VStartNodes is called from VStartSubcluster, we can reuse the vdb from VStartSubcluster
 	vdb := makeVCoordinationDatabase()
 	if options.vdb == nil {
-		err = vcc.getVDBFromRunningDBIncludeSandbox(&vdb, &options.DatabaseOptions, AnySandbox)
+		err = vcc.getVDBFromRunningDBIncludeSandbox(ctx, &vdb, &options.DatabaseOptions, AnySandbox)
 		if err != nil {
 			return err
 		}
@@ -179,7 +183,7 @@ func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
 	}
 
 	// sandboxes may have different catalog from the main cluster, update the vdb build from the sandbox of the nodes to restart
-	err = vcc.getVDBFromRunningDBIncludeSandbox(&vdb, &options.DatabaseOptions, restartNodeInfo.Sandbox)
+	err = vcc.getVDBFromRunningDBIncludeSandbox(ctx, &vdb, &options.DatabaseOptions, restartNodeInfo.Sandbox)
 	if err != nil {
 		if restartNodeInfo.Sandbox != util.MainClusterSandbox {
 			return errors.Join(err, fmt.Errorf("hint: make sure there is at least one UP node in the sandbox %s", restartNodeInfo.Sandbox))
@@ -197,7 +201,9 @@ func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
 	// we will early stop as there is no need to start them
 	if !restartNodeInfo.hasDownNodeNoNeedToReIP && len(restartNodeInfo.ReIPList) == 0 {
 		const msg = "The provided nodes are either not in catalog or already up. There is nothing to start."
-		fmt.Println(msg)
+		if vcc.Log.ForCli {
+			fmt.Println(msg)
+		}
 		vcc.Log.Info(msg)
 		return nil
 	}
@@ -210,7 +216,9 @@ func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
 	// given a list of nodes that aren't in the catalog any longer.
 	if len(restartNodeInfo.HostsToStart) == 0 {
 		const msg = "None of the nodes provided are in the catalog. There is nothing to start."
-		fmt.Println(msg)
+		if vcc.Log.ForCli {
+			fmt.Println(msg)
+		}
 		vcc.Log.Info(msg)
 		return nil
 	}
@@ -226,7 +234,7 @@ func (vcc VClusterCommands) VStartNodes(options *VStartNodesOptions) error {
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		return fmt.Errorf("fail to restart node, %w", err)
 	}
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -44,8 +45,12 @@ func (o *VStartScOptions) setDefaultValues() {
 	o.VStartNodesOptions.setDefaultValues()
 }
 
+func (o *VStartScOptions) subclusterName() string {
+	return o.SubclusterToStart
+}
+
 func (o *VStartScOptions) validateRequiredOptions(logger vlog.Printer) error {
-	err := o.validateBaseOptions("start_subcluster", logger)
+	err := o.validateBaseOptions(commandStartCluster, logger)
 	if err != nil {
 		return err
 	}
@@ -101,15 +106,18 @@ func (o *VStartScOptions) validateAnalyzeOptions(logger vlog.Printer) error {
 // VStartSubcluster has two major phases:
 //  1. Pre-check: check the subcluster name and get nodes for the subcluster.
 //  2. Start nodes: Optional. If there are any down nodes in the subcluster, runs VStartNodes.
-func (vcc VClusterCommands) VStartSubcluster(options *VStartScOptions) error {
-	err := options.validateAnalyzeOptions(vcc.Log)
+func (vcc VClusterCommands) VStartSubcluster(ctx context.Context, options *VStartScOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandStartCluster)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
 
 	// retrieve database information to execute the command so we do not always rely on some user input
 	vdb := makeVCoordinationDatabase()
-	err = vcc.getVDBFromRunningDBIncludeSandbox(&vdb, &options.DatabaseOptions, AnySandbox)
+	err = vcc.getVDBFromRunningDBIncludeSandbox(ctx, &vdb, &options.DatabaseOptions, AnySandbox)
 	if err != nil {
 		return err
 	}
@@ -135,6 +143,8 @@ func (vcc VClusterCommands) VStartSubcluster(options *VStartScOptions) error {
 	startNodesOptions.StatePollingTimeout = options.StatePollingTimeout
 	startNodesOptions.vdb = &vdb
 
-	fmt.Printf("Starting nodes %v in subcluster %s\n", maps.Keys(nodesToStart), options.SubclusterToStart)
-	return vcc.VStartNodes(&startNodesOptions)
+	if vcc.Log.ForCli {
+		fmt.Printf("Starting nodes %v in subcluster %s\n", maps.Keys(nodesToStart), options.SubclusterToStart)
+	}
+	return vcc.VStartNodes(ctx, &startNodesOptions)
 }
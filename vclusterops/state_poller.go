@@ -16,6 +16,8 @@
 package vclusterops
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -30,7 +32,9 @@ const (
 )
 
 type statePoller interface {
+	getName() string
 	getPollingTimeout() int
+	setPollingTimeout(timeout int)
 	shouldStopPolling() (bool, error)
 	runExecute(execContext *opEngineExecContext) error
 }
@@ -39,6 +43,11 @@ type statePoller interface {
 // If poller.getPollingTimeout() returns a value < 0, pollState will poll forever.
 func pollState(poller statePoller, execContext *opEngineExecContext) error {
 	startTime := time.Now()
+	// the timeout set for the op in the command options is the time it polls
+	// for. A poller must time out, so no timeout is ignored.
+	if opTimeout, ok := timeoutsFromContext(execContext.ctx).opTimeout(poller.getName()); ok && opTimeout >= 0 {
+		poller.setPollingTimeout(opTimeout)
+	}
 	timeout := poller.getPollingTimeout()
 	duration := time.Duration(timeout) * time.Second
 	count := 0
@@ -53,7 +62,9 @@ func pollState(poller statePoller, execContext *opEngineExecContext) error {
 		}
 
 		if count > 0 {
-			time.Sleep(PollingInterval * time.Second)
+			if err := waitForNextPoll(execContext.ctx); err != nil {
+				return err
+			}
 		}
 
 		shouldStopPoll, err := poller.shouldStopPolling()
@@ -65,12 +76,50 @@ func pollState(poller statePoller, execContext *opEngineExecContext) error {
 			return nil
 		}
 
+		sendPollIterationEvent(execContext.ctx, poller.getName(), count+1, startTime)
 		if err := poller.runExecute(execContext); err != nil {
+			// when replaying, the recorded run polled until the timeout
+			var diverged *ReplayDivergedError
+			if errors.As(err, &diverged) && diverged.exhausted() {
+				break
+			}
 			return err
 		}
 
 		count++
 	}
 
-	return fmt.Errorf("reached polling timeout of %d seconds", timeout)
+	return fmt.Errorf("[%s] reached polling timeout of %d seconds", poller.getName(), timeout)
+}
+
+// sendPollIterationEvent lets the observer know that a poller is checking the
+// state it waits for. count starts at 1 for the first check.
+func sendPollIterationEvent(ctx context.Context, opName string, count int, startTime time.Time) {
+	sendOpEvent(ctx, &OpEvent{
+		Type:           OpEventPollIteration,
+		OpName:         opName,
+		PollCount:      count,
+		ElapsedSeconds: time.Since(startTime).Seconds(),
+	})
+}
+
+// waitForNextPoll sleeps for PollingInterval seconds, or not at all when
+// replaying. It returns early with the context error if ctx is cancelled
+// while waiting. The other ops of the engine can go on meanwhile.
+func waitForNextPoll(ctx context.Context) error {
+	if isReplaying(ctx) {
+		return ctx.Err()
+	}
+	turn := opTurnFromContext(ctx)
+	turn.release()
+	defer turn.acquire()
+	timer := time.NewTimer(PollingInterval * time.Second)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -119,7 +120,10 @@ func (options *VStopDatabaseOptions) validateAnalyzeOptions(log vlog.Printer) er
 	return options.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VStopDatabase(options *VStopDatabaseOptions) error {
+func (vcc VClusterCommands) VStopDatabase(ctx context.Context, options *VStopDatabaseOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandStopDB)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Produce Instructions
 	 *   - Create a VClusterOpEngine
@@ -127,14 +131,14 @@ func (vcc VClusterCommands) VStopDatabase(options *VStopDatabaseOptions) error {
 	 */
 
 	// validate and analyze all options
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
 
 	// get vdb and check requirements
 	vdb := makeVCoordinationDatabase()
-	err = vcc.getVDBFromRunningDBIncludeSandbox(&vdb, &options.DatabaseOptions, AnySandbox)
+	err = vcc.getVDBFromRunningDBIncludeSandbox(ctx, &vdb, &options.DatabaseOptions, AnySandbox)
 	if err != nil {
 		vcc.LogError(err, "failed to get vdb from running db")
 	} else {
@@ -155,7 +159,7 @@ func (vcc VClusterCommands) VStopDatabase(options *VStopDatabaseOptions) error {
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("fail to stop database: %w", runError)
 	}
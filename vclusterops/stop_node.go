@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -44,7 +45,7 @@ func (o *VStopNodeOptions) setDefaultValues() {
 
 func (o *VStopNodeOptions) validateParseOptions(logger vlog.Printer) error {
 	// validate required parameters
-	return o.validateBaseOptions("stop_node", logger)
+	return o.validateBaseOptions(commandStopNode, logger)
 }
 
 // analyzeOptions will modify some options based on what is chosen
@@ -78,15 +79,18 @@ func (o *VStopNodeOptions) validateAnalyzeOptions(logger vlog.Printer) error {
 
 // VStopNode stops a host in an existing database.
 // It returns any error encountered.
-func (vcc VClusterCommands) VStopNode(options *VStopNodeOptions) error {
+func (vcc VClusterCommands) VStopNode(ctx context.Context, options *VStopNodeOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandStopNode)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	vdb := makeVCoordinationDatabase()
 
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
 
-	err = vcc.getVDBFromRunningDB(&vdb, &options.DatabaseOptions)
+	err = vcc.getVDBFromRunningDB(ctx, &vdb, &options.DatabaseOptions)
 	if err != nil {
 		return err
 	}
@@ -107,7 +111,7 @@ func (vcc VClusterCommands) VStopNode(options *VStopNodeOptions) error {
 
 	certs := httpsCerts{key: options.Key, cert: options.Cert, caCert: options.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
-	if runError := clusterOpEngine.run(vcc.Log); runError != nil {
+	if runError := clusterOpEngine.run(ctx, vcc.Log); runError != nil {
 		return fmt.Errorf("fail to complete stop node operation, %w", runError)
 	}
 	return nil
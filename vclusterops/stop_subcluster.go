@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/vclusterops/util"
@@ -45,6 +46,10 @@ func (options *VStopSubclusterOptions) setDefaultValues() {
 	options.DrainSeconds = util.DefaultDrainSeconds
 }
 
+func (options *VStopSubclusterOptions) subclusterName() string {
+	return options.SCName
+}
+
 func (options *VStopSubclusterOptions) validateRequiredOptions(log vlog.Printer) error {
 	err := options.validateBaseOptions(commandStopCluster, log)
 	if err != nil {
@@ -109,7 +114,10 @@ func (options *VStopSubclusterOptions) validateAnalyzeOptions(log vlog.Printer)
 	return options.analyzeOptions()
 }
 
-func (vcc VClusterCommands) VStopSubcluster(options *VStopSubclusterOptions) error {
+func (vcc VClusterCommands) VStopSubcluster(ctx context.Context, options *VStopSubclusterOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandStopCluster)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	/*
 	 *   - Validate Options
 	 *   - Produce Instructions
@@ -118,7 +126,7 @@ func (vcc VClusterCommands) VStopSubcluster(options *VStopSubclusterOptions) err
 	 */
 
 	// validate and analyze all options
-	err := options.validateAnalyzeOptions(vcc.Log)
+	err = options.validateAnalyzeOptions(vcc.Log)
 	if err != nil {
 		return err
 	}
@@ -133,7 +141,7 @@ func (vcc VClusterCommands) VStopSubcluster(options *VStopSubclusterOptions) err
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("failed to stop subcluster %s: %w", options.SCName, runError)
 	}
@@ -0,0 +1,166 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// TLSVerification tells how the certificates presented by the NMA and by the
+// HTTPS service are verified. By default, the certificate of a host must be
+// signed by the CA certificate of the command options, or by the one of the
+// certificate files, or else by a system root, and it must be valid for the
+// host address.
+type TLSVerification struct {
+	// Insecure skips the verification of the certificates, which leaves the
+	// connections open to man-in-the-middle attacks
+	Insecure bool
+	// ServerNames overrides, by host address, the name the certificate of the
+	// host must be valid for. It is also sent as the TLS server name.
+	ServerNames map[string]string
+	// PinnedFingerprints are the SHA-256 fingerprints, in hex, of the
+	// certificates the hosts may present. When set, a host is trusted if its
+	// certificate has one of them, whoever signed it and whatever names it
+	// is valid for, which suits self-signed certificates.
+	PinnedFingerprints []string
+}
+
+// TLSVerificationError is the error of a request to a host whose certificate
+// could not be verified
+type TLSVerificationError struct {
+	Host string
+	Err  error
+}
+
+func (e *TLSVerificationError) Error() string {
+	return fmt.Sprintf("fail to verify the TLS certificate of host %s: %v. Check that the CA certificate signed it,"+
+		" or set the name it is valid for, or pin its fingerprint", e.Host, e.Err)
+}
+
+func (e *TLSVerificationError) Unwrap() error {
+	return e.Err
+}
+
+type tlsVerificationContextKey struct{}
+
+// normalizeFingerprint lowercases a fingerprint and removes the colons that
+// tools such as openssl put between its bytes
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+}
+
+// validateTLSVerification checks that the pinned fingerprints are SHA-256
+// fingerprints, and that the verification is not both skipped and pinned
+func (opt *DatabaseOptions) validateTLSVerification() error {
+	if opt.TLSVerification.Insecure && len(opt.TLSVerification.PinnedFingerprints) > 0 {
+		return fmt.Errorf("cannot pin certificate fingerprints when the TLS verification is skipped")
+	}
+	for _, fingerprint := range opt.TLSVerification.PinnedFingerprints {
+		sum, err := hex.DecodeString(normalizeFingerprint(fingerprint))
+		if err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("invalid pinned fingerprint %q, it must be the hex SHA-256 fingerprint of a certificate",
+				fingerprint)
+		}
+	}
+	return nil
+}
+
+// attachTLSVerification attaches the TLS verification of the options to ctx,
+// so that it applies to every connection made under it
+func (opt *DatabaseOptions) attachTLSVerification(ctx context.Context) context.Context {
+	verification := &opt.TLSVerification
+	if (!verification.Insecure && len(verification.ServerNames) == 0 && len(verification.PinnedFingerprints) == 0) ||
+		tlsVerificationFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tlsVerificationContextKey{}, verification)
+}
+
+func tlsVerificationFromContext(ctx context.Context) *TLSVerification {
+	verification, _ := ctx.Value(tlsVerificationContextKey{}).(*TLSVerification)
+	return verification
+}
+
+// key identifies the verification of the connections to host, so that the
+// transports that verify the host differently are not shared
+func (verification *TLSVerification) key(host string) string {
+	switch {
+	case verification == nil:
+		return "verify"
+	case verification.Insecure:
+		return "insecure"
+	case len(verification.PinnedFingerprints) > 0:
+		pins := make([]string, len(verification.PinnedFingerprints))
+		for i, fingerprint := range verification.PinnedFingerprints {
+			pins[i] = normalizeFingerprint(fingerprint)
+		}
+		slices.Sort(pins)
+		return "pinned:" + strings.Join(pins, ",")
+	default:
+		return "verify:" + verification.ServerNames[host]
+	}
+}
+
+// verifiesChain returns true if the certificates of the hosts are verified
+// against the CA certificates
+func (verification *TLSVerification) verifiesChain() bool {
+	return verification == nil || (!verification.Insecure && len(verification.PinnedFingerprints) == 0)
+}
+
+// apply sets up tlsConfig to verify the certificate of host
+func (verification *TLSVerification) apply(tlsConfig *tls.Config, host string) {
+	switch {
+	case verification == nil:
+		return
+	case verification.Insecure:
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec
+	case len(verification.PinnedFingerprints) > 0:
+		// the chain and the names are not verified, the pin replaces them
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec
+		tlsConfig.VerifyPeerCertificate = verification.verifyPin(host)
+	default:
+		tlsConfig.ServerName = verification.ServerNames[host]
+	}
+}
+
+// verifyPin returns a function that checks that the certificate of host is
+// one of the pinned ones
+func (verification *TLSVerification) verifyPin(host string) func([][]byte, [][]*x509.Certificate) error {
+	pins := make(map[string]bool, len(verification.PinnedFingerprints))
+	for _, fingerprint := range verification.PinnedFingerprints {
+		pins[normalizeFingerprint(fingerprint)] = true
+	}
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return &TLSVerificationError{Host: host, Err: fmt.Errorf("no certificate was presented")}
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		fingerprint := hex.EncodeToString(sum[:])
+		if !pins[fingerprint] {
+			return &TLSVerificationError{Host: host,
+				Err: fmt.Errorf("the certificate has SHA-256 fingerprint %s, which is not pinned", fingerprint)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/vclusterops/vlog"
+)
+
+func TestTLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+	port, err := strconv.Atoi(serverURL.Port())
+	assert.NoError(t, err)
+	// the certificate of the test server is valid for 127.0.0.1 and example.com
+	host := serverURL.Hostname()
+	serverCert := server.Certificate()
+	caCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverCert.Raw}))
+	sum := sha256.Sum256(serverCert.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	send := func(verification TLSVerification, caCert string) *hostHTTPResult {
+		options := DatabaseOptionsFactory()
+		options.TLSVerification = verification
+		assert.NoError(t, options.validateTLSVerification())
+		ctx := VClusterCommands{}.setupContext(context.Background(), &options, "")
+
+		password := "password"
+		request := hostHTTPRequest{Method: GetMethod, Port: port, Password: &password,
+			UseCertsInOptions: true, Certs: httpsCerts{caCert: caCert}}
+		adapter := makeHTTPAdapter(vlog.Printer{})
+		adapter.host = host
+		resultChannel := make(chan hostHTTPResult, 1)
+		adapter.sendRequest(ctx, &request, resultChannel)
+		result := <-resultChannel
+		return &result
+	}
+	assertVerificationFails := func(result *hostHTTPResult, message string) {
+		var verifyErr *TLSVerificationError
+		assert.True(t, errors.As(result.err, &verifyErr))
+		assert.ErrorContains(t, result.err, message)
+		// a verification failure is not retried
+		assert.Equal(t, 1, result.attempts)
+	}
+
+	// the certificate is verified against the CA certificate
+	assert.True(t, send(TLSVerification{}, caCert).isPassing())
+	assertVerificationFails(send(TLSVerification{}, ""), "fail to verify the TLS certificate of host "+host)
+
+	// the name the certificate must be valid for can be overridden
+	assert.True(t, send(TLSVerification{ServerNames: map[string]string{host: "example.com"}}, caCert).isPassing())
+	assertVerificationFails(send(TLSVerification{ServerNames: map[string]string{host: "other.example.org"}}, caCert),
+		"other.example.org")
+
+	// a pinned certificate is trusted without a CA certificate
+	assert.True(t, send(TLSVerification{PinnedFingerprints: []string{fingerprint}}, "").isPassing())
+	wrongFingerprint := hex.EncodeToString(make([]byte, sha256.Size))
+	assertVerificationFails(send(TLSVerification{PinnedFingerprints: []string{wrongFingerprint}}, ""),
+		"has SHA-256 fingerprint "+fingerprint+", which is not pinned")
+
+	// the verification can only be skipped explicitly
+	assert.True(t, send(TLSVerification{Insecure: true}, "").isPassing())
+
+	// invalid verifications
+	options := DatabaseOptionsFactory()
+	options.TLSVerification = TLSVerification{Insecure: true, PinnedFingerprints: []string{fingerprint}}
+	assert.ErrorContains(t, options.validateTLSVerification(), "cannot pin certificate fingerprints")
+	options.TLSVerification = TLSVerification{PinnedFingerprints: []string{"ab:cd"}}
+	assert.ErrorContains(t, options.validateTLSVerification(), "invalid pinned fingerprint")
+}
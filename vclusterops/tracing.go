@@ -0,0 +1,112 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/vertica/vcluster/rfc7807"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the attributes of the spans, named after the OpenTelemetry semantic
+// conventions where there is one
+const (
+	spanAttrCommand     = "vcluster.command"
+	spanAttrDBName      = "db.name"
+	spanAttrOp          = "vcluster.op"
+	spanAttrHost        = "server.address"
+	spanAttrPort        = "server.port"
+	spanAttrMethod      = "http.request.method"
+	spanAttrEndpoint    = "vcluster.endpoint"
+	spanAttrStatusCode  = "http.response.status_code"
+	spanAttrProblemType = "vcluster.problem.type"
+	spanAttrAttempts    = "vcluster.request.attempts"
+	tracerName          = "github.com/vertica/vcluster/vclusterops"
+)
+
+type tracerProviderContextKey struct{}
+
+// startCommandSpan starts the span of command. A command has a span, with a
+// child span for each op it runs, which has a child span for each request
+// the op sends to a host. The span of the command is a child of the span of
+// ctx, if there is one, which is the span of the caller or of the command
+// that runs this one.
+func (opt *DatabaseOptions) startCommandSpan(ctx context.Context, command string) context.Context {
+	if opt.TracerProvider != nil && ctx.Value(tracerProviderContextKey{}) == nil {
+		ctx = context.WithValue(ctx, tracerProviderContextKey{}, opt.TracerProvider)
+	}
+	attributes := []attribute.KeyValue{attribute.String(spanAttrCommand, command)}
+	if opt.DBName != "" {
+		attributes = append(attributes, attribute.String(spanAttrDBName, opt.DBName))
+	}
+	ctx, _ = startSpan(ctx, command, trace.SpanKindInternal, attributes...)
+	return ctx
+}
+
+// startSpan starts a span as a child of the span of ctx, with the tracer
+// provider of the command, or the global one of OpenTelemetry if the
+// command has none, and returns a context that has it
+func startSpan(ctx context.Context, name string, kind trace.SpanKind,
+	attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	provider, _ := ctx.Value(tracerProviderContextKey{}).(trace.TracerProvider)
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// endCommandSpan ends the span that setupContext started for the command
+func endCommandSpan(ctx context.Context, err error) {
+	endSpan(trace.SpanFromContext(ctx), err)
+}
+
+// endSpan ends span, with an error status if err is not nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endRequestSpan ends the span of a request to a host with its result
+func endRequestSpan(span trace.Span, result *hostHTTPResult) {
+	if result.statusCode != 0 {
+		span.SetAttributes(attribute.Int(spanAttrStatusCode, result.statusCode))
+	}
+	if result.attempts > 0 {
+		span.SetAttributes(attribute.Int(spanAttrAttempts, result.attempts))
+	}
+	problem := &rfc7807.VProblem{}
+	if errors.As(result.err, &problem) {
+		span.SetAttributes(attribute.String(spanAttrProblemType, problem.Type))
+	}
+	endSpan(span, result.err)
+}
+
+// injectTraceContext sets the traceparent header of a request to the span
+// of ctx, with the W3C Trace Context propagator, so that the host can join
+// the trace. A span that is not sampled, or no span, sets no header.
+func injectTraceContext(ctx context.Context, header http.Header) {
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,106 @@
+/*
+ (c) Copyright [2023-2024] Open Text.
+ Licensed under the Apache License, Version 2.0 (the "License");
+ You may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+ http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package vclusterops
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vertica/vcluster/rfc7807"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+func TestCommandSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	options := DatabaseOptionsFactory()
+	options.DBName = "test_db"
+	options.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, commandStopDB)
+	// a nested command is a child of the outer one, with its tracer provider
+	nestedOptions := DatabaseOptionsFactory()
+	nestedCtx := VClusterCommands{}.setupContext(ctx, &nestedOptions, commandStartDB)
+	requestCtx, span := startSpan(nestedCtx, "POST v1/cluster/shutdown", trace.SpanKindClient,
+		attribute.String(spanAttrHost, "192.168.1.101"))
+	header := http.Header{}
+	injectTraceContext(requestCtx, header)
+	spanContext := span.SpanContext()
+	assert.Equal(t, "00-"+spanContext.TraceID().String()+"-"+spanContext.SpanID().String()+"-01", header.Get("traceparent"))
+	problem := rfc7807.New(rfc7807.GenericHTTPInternalServerError).WithHost("192.168.1.101")
+	endRequestSpan(span, &hostHTTPResult{statusCode: 500, attempts: 2, err: problem})
+	endCommandSpan(nestedCtx, nil)
+	endCommandSpan(ctx, nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	request, nested, root := spans[0], spans[1], spans[2]
+	assert.Equal(t, commandStopDB, root.Name())
+	assert.False(t, root.Parent().IsValid())
+	assert.Equal(t, root.SpanContext().SpanID(), nested.Parent().SpanID())
+	assert.Equal(t, nested.SpanContext().SpanID(), request.Parent().SpanID())
+	assert.Equal(t, root.SpanContext().TraceID(), request.SpanContext().TraceID())
+	assert.Equal(t, trace.SpanKindClient, request.SpanKind())
+	assert.Equal(t, codes.Error, request.Status().Code)
+	assert.Equal(t, codes.Unset, root.Status().Code)
+
+	attributes := spanAttributes(request)
+	assert.Equal(t, "192.168.1.101", attributes[spanAttrHost].AsString())
+	assert.Equal(t, int64(500), attributes[spanAttrStatusCode].AsInt64())
+	assert.Equal(t, int64(2), attributes[spanAttrAttempts].AsInt64())
+	assert.Equal(t, rfc7807.GenericHTTPInternalServerError.Type, attributes[spanAttrProblemType].AsString())
+	assert.Equal(t, "test_db", spanAttributes(root)[spanAttrDBName].AsString())
+}
+
+func TestCallerSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, caller := provider.Tracer("caller").Start(context.Background(), "reconcile")
+	options := DatabaseOptionsFactory()
+	options.TracerProvider = provider
+	commandCtx := VClusterCommands{}.setupContext(ctx, &options, commandStopDB)
+	endCommandSpan(commandCtx, nil)
+	caller.End()
+
+	// the span of the command is a child of the span of the caller
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, caller.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, caller.SpanContext().TraceID(), spans[0].SpanContext().TraceID())
+}
+
+func TestNoTracing(t *testing.T) {
+	options := DatabaseOptionsFactory()
+	ctx := VClusterCommands{}.setupContext(context.Background(), &options, commandStopDB)
+	assert.False(t, trace.SpanFromContext(ctx).IsRecording())
+	// a request sent without a span has no traceparent header
+	header := http.Header{}
+	injectTraceContext(ctx, header)
+	assert.Empty(t, header)
+	endCommandSpan(ctx, nil)
+}
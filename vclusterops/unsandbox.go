@@ -16,6 +16,7 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/vertica/vcluster/rfc7807"
@@ -45,6 +46,10 @@ func (options *VUnsandboxOptions) setDefaultValues() {
 	options.RestartSC = true
 }
 
+func (options *VUnsandboxOptions) subclusterName() string {
+	return options.SCName
+}
+
 func (options *VUnsandboxOptions) validateRequiredOptions(logger vlog.Printer) error {
 	err := options.validateBaseOptions("unsandbox_subcluster", logger)
 	if err != nil {
@@ -103,8 +108,8 @@ func (e *SubclusterNotSandboxedError) Error() string {
 // for a successful unsandbox_subcluster
 // - Get cluster and nodes info (check if the DB is Eon)
 // - Get the subcluster info (check if the target subcluster is sandboxed)
-func (vcc *VClusterCommands) unsandboxPreCheck(vdb *VCoordinationDatabase, options *VUnsandboxOptions) error {
-	err := vcc.getVDBFromRunningDB(vdb, &options.DatabaseOptions)
+func (vcc *VClusterCommands) unsandboxPreCheck(ctx context.Context, vdb *VCoordinationDatabase, options *VUnsandboxOptions) error {
+	err := vcc.getVDBFromRunningDB(ctx, vdb, &options.DatabaseOptions)
 	if err != nil {
 		return err
 	}
@@ -264,15 +269,18 @@ func (vcc *VClusterCommands) produceUnsandboxSCInstructions(options *VUnsandboxO
 	return instructions, nil
 }
 
-func (vcc VClusterCommands) VUnsandbox(options *VUnsandboxOptions) error {
+func (vcc VClusterCommands) VUnsandbox(ctx context.Context, options *VUnsandboxOptions) (err error) {
+	ctx = vcc.setupContext(ctx, options, commandUnsandboxSC)
+	defer func() { vcc.finishCommand(ctx, options, err) }()
+
 	vcc.Log.V(0).Info("VUnsandbox method called", "options", options)
-	return runSandboxCmd(vcc, options)
+	return runSandboxCmd(ctx, vcc, options)
 }
 
 // runCommand will produce instructions and run them
-func (options *VUnsandboxOptions) runCommand(vcc VClusterCommands) error {
+func (options *VUnsandboxOptions) runCommand(ctx context.Context, vcc VClusterCommands) error {
 	vdb := makeVCoordinationDatabase()
-	err := vcc.unsandboxPreCheck(&vdb, options)
+	err := vcc.unsandboxPreCheck(ctx, &vdb, options)
 	if err != nil {
 		return err
 	}
@@ -287,7 +295,7 @@ func (options *VUnsandboxOptions) runCommand(vcc VClusterCommands) error {
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// run the engine
-	runError := clusterOpEngine.run(vcc.Log)
+	runError := clusterOpEngine.run(ctx, vcc.Log)
 	if runError != nil {
 		return fmt.Errorf("fail to unsandbox subcluster %s, %w", options.SCName, runError)
 	}
@@ -16,12 +16,15 @@
 package vclusterops
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/vertica/vcluster/vclusterops/util"
 	"github.com/vertica/vcluster/vclusterops/vlog"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slices"
 )
 
@@ -73,6 +76,74 @@ type DatabaseOptions struct {
 	LogPath string
 	// whether use password
 	usePassword bool
+	// Plan, when set, makes the command run only the ops that are safe to run
+	// against a live cluster. The other ops are prepared but not executed, and
+	// GetPlan returns the ops with the requests they would have sent.
+	Plan bool
+	// the plan built by the command in plan mode
+	plan *VClusterPlan
+	// Observer, when set, receives the events of every op the command runs
+	Observer OpObserver `json:"-"`
+	// JournalPath, when set, is the file where each op completed by the
+	// command is saved, so that the command can be resumed or rolled back
+	// if it fails
+	JournalPath string
+	// ResumeFromJournal makes the command skip the ops already saved in the
+	// journal at JournalPath
+	ResumeFromJournal bool
+	// RetryPolicy, when set, replaces DefaultRetryPolicy for the requests of
+	// the ops that do not set their own policy
+	RetryPolicy *RetryPolicy
+	// NMAPort and HTTPSPort, when set, replace the default ports of the NMA
+	// and of the HTTPS service on all the hosts
+	NMAPort   int
+	HTTPSPort int
+	// HostPorts overrides the ports of some hosts, by host address. They
+	// take precedence over NMAPort and HTTPSPort.
+	HostPorts map[string]HostPorts
+	// MaxParallel, when greater than 0, is the maximum number of requests an
+	// op sends at the same time, to protect the initiator and the network on
+	// large clusters
+	MaxParallel int
+	// RequestTimeout, when set, replaces the default timeout, in seconds, of
+	// the requests of the ops that do not set their own. -1 means no timeout.
+	// An overall deadline is set with the context passed to the command.
+	RequestTimeout int
+	// OpTimeouts are timeouts in seconds by op name, such as
+	// "NMADownloadFileOp". They take precedence over the timeout an op sets
+	// for its requests. The timeout of a polling op, such as
+	// "HTTPSPollNodeStateOp", is the time it polls for. The polling ops must
+	// time out, so -1 is ignored for them.
+	OpTimeouts map[string]int
+	// TLSVerification tells how the certificates of the hosts are verified.
+	// By default, they must be signed by the CA certificate.
+	TLSVerification TLSVerification
+	// Dialer, when set, opens the connections to the NMA and to the HTTPS
+	// service of the hosts, such as the one of a fake.Cluster in tests
+	Dialer Dialer `json:"-"`
+	// RecordPath, when set, is the replay bundle where every request the
+	// command sends and its result are saved, with the secrets redacted
+	RecordPath string
+	// ReplayPath, when set, is a replay bundle recorded by an earlier run.
+	// The command gets the results of its requests from it instead of
+	// sending them to the hosts.
+	ReplayPath string
+	// Faults, when set, are injected into the requests of the command, to
+	// test how it copes with the failures of the hosts
+	Faults []Fault
+	// Metrics, when set, counts the ops of the command and the requests
+	// they send
+	Metrics *Metrics `json:"-"`
+	// TracerProvider, when set, traces the command, its ops and their
+	// requests to the hosts. Without one, the global tracer provider of
+	// OpenTelemetry is used.
+	TracerProvider trace.TracerProvider `json:"-"`
+	// Hooks are run before and after the command, or some of its ops. A pre
+	// hook can veto the command or the op.
+	Hooks []Hook `json:"-"`
+	// ForceUnlock breaks the lock or the lease another command holds on the
+	// database, when that command is no longer running
+	ForceUnlock bool
 }
 
 const (
@@ -101,8 +172,55 @@ const (
 	commandConfigRecover     = "manage_config_recover"
 	commandReplicationStart  = "replication_start"
 	commandFetchNodesDetails = "fetch_nodes_details"
+	commandListAllNodes      = "list_allnodes"
+	commandReIP              = "re_ip"
+	commandReviveDB          = "revive_db"
+	commandRestartNode       = "restart_node"
+	commandStartCluster      = "start_subcluster"
+	commandStopNode          = "stop_node"
+	commandRollback          = "rollback"
+	commandClusterStatus     = "status"
+	commandDoctor            = "doctor"
 )
 
+type commandContextKey struct{}
+
+// commandRun is a run of a V* command, within the run of the outer command
+// that runs it, if any
+type commandRun struct {
+	command string
+	start   time.Time
+	outer   *commandRun
+}
+
+// contextWithCommand attaches a run of the V* command to ctx
+func contextWithCommand(ctx context.Context, command string) context.Context {
+	if command == "" {
+		return ctx
+	}
+	run := &commandRun{command: command, start: time.Now(), outer: commandRunFromContext(ctx)}
+	return context.WithValue(ctx, commandContextKey{}, run)
+}
+
+func commandRunFromContext(ctx context.Context) *commandRun {
+	run, _ := ctx.Value(commandContextKey{}).(*commandRun)
+	return run
+}
+
+// commandFromContext returns the name of the V* command run under ctx, such
+// as "start_db". For a command run by another one, it is the name of the
+// outer command.
+func commandFromContext(ctx context.Context) string {
+	run := commandRunFromContext(ctx)
+	if run == nil {
+		return ""
+	}
+	for run.outer != nil {
+		run = run.outer
+	}
+	return run.command
+}
+
 func DatabaseOptionsFactory() DatabaseOptions {
 	opt := DatabaseOptions{}
 	// set default values to the params
@@ -139,9 +257,39 @@ func (opt *DatabaseOptions) validateBaseOptions(commandName string, log vlog.Pri
 		return err
 	}
 
+	// ports
+	err = opt.validatePorts()
+	if err != nil {
+		return err
+	}
+
+	// timeouts
+	err = opt.validateTimeouts()
+	if err != nil {
+		return err
+	}
+
+	// TLS verification
+	err = opt.validateTLSVerification()
+	if err != nil {
+		return err
+	}
+
+	// record and replay
+	err = opt.validateReplay()
+	if err != nil {
+		return err
+	}
+
+	// injected faults
+	err = opt.validateFaults()
+	if err != nil {
+		return err
+	}
+
 	// config directory
 	// VER-91801: remove this condition once re_ip supports the config file
-	if !slices.Contains([]string{"re_ip"}, commandName) {
+	if !slices.Contains([]string{commandReIP}, commandName) {
 		err = opt.validateConfigDir(commandName)
 		if err != nil {
 			return err
@@ -275,7 +423,7 @@ func (opt *DatabaseOptions) normalizePaths() {
 }
 
 // getVDBWhenDBIsDown can retrieve db configurations from NMA /nodes endpoint and cluster_config.json when db is down
-func (opt *DatabaseOptions) getVDBWhenDBIsDown(vcc VClusterCommands) (vdb VCoordinationDatabase, err error) {
+func (opt *DatabaseOptions) getVDBWhenDBIsDown(ctx context.Context, vcc VClusterCommands) (vdb VCoordinationDatabase, err error) {
 	/*
 	 *   1. Get node names for input hosts from NMA /nodes.
 	 *   2. Get other node information for input hosts from cluster_config.json.
@@ -302,7 +450,7 @@ func (opt *DatabaseOptions) getVDBWhenDBIsDown(vcc VClusterCommands) (vdb VCoord
 
 	certs := httpsCerts{key: opt.Key, cert: opt.Cert, caCert: opt.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions1, &certs)
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		vcc.Log.PrintError("fail to retrieve node names from NMA /nodes: %v", err)
 		return vdb, err
@@ -320,7 +468,7 @@ func (opt *DatabaseOptions) getVDBWhenDBIsDown(vcc VClusterCommands) (vdb VCoord
 	instructions2 = append(instructions2, &nmaDownLoadFileOp)
 
 	clusterOpEngine = makeClusterOpEngine(instructions2, &certs)
-	err = clusterOpEngine.run(vcc.Log)
+	err = clusterOpEngine.run(ctx, vcc.Log)
 	if err != nil {
 		vcc.Log.PrintError("fail to retrieve node details from %s: %v", descriptionFileName, err)
 		return vdb, err
@@ -393,11 +541,52 @@ func (opt *DatabaseOptions) isSpreadEncryptionEnabled() (enabled bool, encryptio
 	return false, ""
 }
 
-func (opt *DatabaseOptions) runClusterOpEngine(log vlog.Printer, instructions []clusterOp) error {
+func (opt *DatabaseOptions) runClusterOpEngine(ctx context.Context, log vlog.Printer, instructions []clusterOp) error {
 	// Create a VClusterOpEngine, and add certs to the engine
 	certs := httpsCerts{key: opt.Key, cert: opt.Cert, caCert: opt.CaCert}
 	clusterOpEngine := makeClusterOpEngine(instructions, &certs)
 
 	// Give the instructions to the VClusterOpEngine to run
-	return clusterOpEngine.run(log)
+	return clusterOpEngine.run(ctx, log)
+}
+
+// setupContext is called at the start of each V* command, with its options.
+// It attaches to ctx what the op engines of the command need from the
+// options: the name of the command and its span, the plan when running in
+// plan mode, the observer of the op events, the journal, the metrics, the
+// hooks, the lock on the database, the run in the audit log, and the retry
+// policy, the ports, the concurrency limit, the timeouts and the TLS
+// verification of the requests, the faults injected into them, and the
+// replay bundle they are recorded to or replayed from.
+func (vcc VClusterCommands) setupContext(ctx context.Context, options commandOptions, command string) context.Context {
+	opt := options.databaseOptions()
+	ctx = contextWithCommand(ctx, command)
+	ctx = opt.startCommandSpan(ctx, command)
+	ctx = opt.startPlan(ctx)
+	ctx = opt.attachObserver(ctx)
+	ctx = opt.attachJournal(ctx)
+	ctx = opt.attachMetrics(ctx)
+	ctx = attachHooks(ctx, options)
+	ctx = vcc.attachLock(ctx, options)
+	ctx = vcc.attachAudit(ctx, options)
+	ctx = opt.attachRetryPolicy(ctx)
+	ctx = opt.attachPorts(ctx)
+	ctx = opt.attachMaxParallel(ctx)
+	ctx = opt.attachTimeouts(ctx)
+	ctx = opt.attachTLSVerification(ctx)
+	ctx = opt.attachDialer(ctx)
+	ctx = opt.attachFaults(ctx)
+	return opt.attachReplayBundle(ctx)
+}
+
+// finishCommand is deferred by each V* command, with the context returned by
+// setupContext and the error the command returns. It runs the post hooks of
+// the command, releases its lock on the database, ends its span, saves its
+// replay bundle and records it in the audit log.
+func (vcc VClusterCommands) finishCommand(ctx context.Context, options commandOptions, err error) {
+	vcc.runPostCommandHooks(ctx, err)
+	releaseDatabaseLock(ctx, vcc.Log)
+	endCommandSpan(ctx, err)
+	vcc.saveRecording(ctx)
+	vcc.audit(ctx, err)
 }
@@ -39,8 +39,14 @@ const (
 type Printer struct {
 	Log           logr.Logger
 	LogToFileOnly bool
-	// ForCli can indicate if vclusterops is called from vcluster cli or other clients
+	// ForCli can indicate if vclusterops is called from vcluster cli or other clients.
+	// It also enables the progress shown in the console, so the cli does not
+	// set it when it writes a result document to stdout.
 	ForCli bool
+	// OnWarning, if set, is called with each warning that is printed, so that
+	// the client can report them. The ops that run at the same time can call
+	// it concurrently.
+	OnWarning func(msg string)
 }
 
 // WithName will construct a new printer with the logger set with an additional
@@ -50,6 +56,7 @@ func (p *Printer) WithName(logName string) Printer {
 		Log:           p.Log.WithName(logName),
 		LogToFileOnly: p.LogToFileOnly,
 		ForCli:        p.ForCli,
+		OnWarning:     p.OnWarning,
 	}
 }
 
@@ -98,6 +105,9 @@ func (p *Printer) PrintWarning(msg string, v ...any) {
 	escapedFmsg := escapeSpecialCharacters(fmsg)
 	p.Log.Info(escapedFmsg)
 	p.printlnCond(WarningLog, fmsg)
+	if p.OnWarning != nil {
+		p.OnWarning(fmsg)
+	}
 }
 
 // escapeSpecialCharacters will escape special characters (tabs or newlines) in the message.
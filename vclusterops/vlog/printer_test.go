@@ -52,3 +52,12 @@ func TestPasswordRedaction(t *testing.T) {
 	assert.Len(t, unmaskedArgs, 2)
 	assert.Equal(t, pw, unmaskedArgs[1])
 }
+
+func TestOnWarning(t *testing.T) {
+	var warnings []string
+	p := Printer{OnWarning: func(msg string) { warnings = append(warnings, msg) }}
+	named := p.WithName("test")
+	named.PrintWarning("host %s is %s", "192.168.1.101", "slow")
+	named.PrintInfo("not a warning")
+	assert.Equal(t, []string{"host 192.168.1.101 is slow"}, warnings)
+}